@@ -8,6 +8,7 @@ require (
 	github.com/aymerick/raymond v2.0.2+incompatible
 	github.com/bmatcuk/doublestar/v4 v4.0.2
 	github.com/fatih/color v1.13.0
+	github.com/fsnotify/fsnotify v1.5.4
 	github.com/go-testfixtures/testfixtures/v3 v3.6.1
 	github.com/google/go-cmp v0.5.8
 	github.com/gosimple/slug v1.12.0
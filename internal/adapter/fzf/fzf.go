@@ -25,6 +25,8 @@ var (
 
 // Opts holds the options used to run fzf.
 type Opts struct {
+	// Path to the fzf executable, looked up in the PATH by default.
+	Path opt.String
 	// Preview command executed by fzf when hovering a line.
 	PreviewCmd opt.String
 	// Optionally provide additional arguments, taken from the config `fzf-options` property.
@@ -35,6 +37,8 @@ type Opts struct {
 	Delimiter string
 	// List of key bindings enabled in fzf.
 	Bindings []Binding
+	// Indicates whether several lines can be selected.
+	Multi bool
 }
 
 // Binding represents a keyboard shortcut bound to an action in fzf.
@@ -80,6 +84,10 @@ func New(opts Opts) (*Fzf, error) {
 		"--ansi",
 		"--delimiter", opts.Delimiter,
 	}
+	// Added before the user options, which may disable it with --no-multi.
+	if opts.Multi {
+		args = append(args, "--multi")
+	}
 
 	// Additional options.
 	additionalArgs, err := shellquote.Split(opts.Options.String())
@@ -107,9 +115,11 @@ func New(opts Opts) (*Fzf, error) {
 	if !opts.PreviewCmd.IsNull() {
 		args = append(args, "--preview", opts.PreviewCmd.String())
 	}
-
-	fzfPath, err := exec.LookPath("fzf")
+	fzfPath, err := exec.LookPath(opts.Path.OrString("fzf").String())
 	if err != nil {
+		if !opts.Path.IsNull() {
+			return nil, fmt.Errorf("interactive mode requires fzf, but it was not found at %s: check the tool.fzf-path setting", opts.Path)
+		}
 		return nil, fmt.Errorf("interactive mode requires fzf, try without --interactive or install fzf from https://github.com/junegunn/fzf")
 	}
 
@@ -0,0 +1,112 @@
+package fzf
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/zk-org/zk/internal/util/opt"
+	"github.com/zk-org/zk/internal/util/test/assert"
+)
+
+// fakeFzf creates an executable script standing in for fzf, which saves its
+// arguments in the args file next to it.
+func fakeFzf(t *testing.T, script string) (path string, argsPath string) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake fzf is a shell script")
+	}
+	dir := t.TempDir()
+	path = filepath.Join(dir, "fzf")
+	argsPath = filepath.Join(dir, "args")
+	content := "#!/bin/sh\nprintf '%s\\n' \"$@\" > \"$(dirname \"$0\")/args\"\n" + script + "\n"
+	assert.Nil(t, os.WriteFile(path, []byte(content), 0755))
+	return path, argsPath
+}
+
+func TestFzfSelection(t *testing.T) {
+	// Selects the first and third lines.
+	path, argsPath := fakeFzf(t, "sed -n '1p;3p'")
+
+	fzf, err := New(Opts{
+		Path:       opt.NewString(path),
+		PreviewCmd: opt.NewString("cat {-1}"),
+		Padding:    2,
+		Multi:      true,
+	})
+	assert.Nil(t, err)
+	assert.Nil(t, fzf.Add([]string{"Note one", "/notebook/one.md"}))
+	assert.Nil(t, fzf.Add([]string{"Note two", "/notebook/two.md"}))
+	assert.Nil(t, fzf.Add([]string{"Note three", "/notebook/three.md"}))
+
+	selection, err := fzf.Selection()
+	assert.Nil(t, err)
+	assert.Equal(t, selection, [][]string{
+		{"Note one", "/notebook/one.md"},
+		{"Note three", "/notebook/three.md"},
+	})
+
+	args, err := os.ReadFile(argsPath)
+	assert.Nil(t, err)
+	assert.Equal(t, strings.Split(strings.TrimSpace(string(args)), "\n"), []string{
+		"--ansi", "--delimiter", "\x01", "--multi", "--preview", "cat {-1}",
+	})
+}
+
+// The user options come after --multi, to be able to disable it.
+func TestFzfOptionsOverrideMulti(t *testing.T) {
+	path, argsPath := fakeFzf(t, "cat > /dev/null")
+
+	fzf, err := New(Opts{
+		Path:    opt.NewString(path),
+		Options: opt.NewString("--no-multi --height 40%"),
+		Multi:   true,
+	})
+	assert.Nil(t, err)
+	assert.Nil(t, fzf.Add([]string{"Note one"}))
+	_, err = fzf.Selection()
+	assert.Nil(t, err)
+
+	args, err := os.ReadFile(argsPath)
+	assert.Nil(t, err)
+	assert.Equal(t, strings.Split(strings.TrimSpace(string(args)), "\n"), []string{
+		"--ansi", "--delimiter", "\x01", "--multi", "--no-multi", "--height", "40%",
+	})
+}
+
+func TestDefaultPreviewCmdQuotesPaths(t *testing.T) {
+	assert.Equal(t,
+		defaultPreviewCmd("/usr/bin/zk", "/home/user/My \"Notes\" $HOME"),
+		`/usr/bin/zk list --notebook-dir '/home/user/My "Notes" $HOME' --no-index --no-pager --quiet --limit 1 --format "{{style 'title' title}}\n\n{{body}}" {-1}`,
+	)
+}
+
+func TestFzfNoMatch(t *testing.T) {
+	path, _ := fakeFzf(t, "cat > /dev/null; exit 1")
+
+	fzf, err := New(Opts{Path: opt.NewString(path)})
+	assert.Nil(t, err)
+	assert.Nil(t, fzf.Add([]string{"Note one"}))
+
+	selection, err := fzf.Selection()
+	assert.Nil(t, err)
+	assert.Equal(t, selection, [][]string{})
+}
+
+func TestFzfCancelled(t *testing.T) {
+	path, _ := fakeFzf(t, "cat > /dev/null; exit 130")
+
+	fzf, err := New(Opts{Path: opt.NewString(path)})
+	assert.Nil(t, err)
+	assert.Nil(t, fzf.Add([]string{"Note one"}))
+
+	_, err = fzf.Selection()
+	assert.Equal(t, err, ErrCancelled)
+}
+
+func TestFzfNotFound(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fzf")
+	_, err := New(Opts{Path: opt.NewString(path)})
+	assert.Err(t, err, "interactive mode requires fzf, but it was not found at "+path+": check the tool.fzf-path setting")
+}
@@ -7,6 +7,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/kballard/go-shellquote"
 	"github.com/zk-org/zk/internal/adapter/term"
 	"github.com/zk-org/zk/internal/core"
 	"github.com/zk-org/zk/internal/util/opt"
@@ -31,6 +32,8 @@ type NoteFilterOpts struct {
 	Interactive bool
 	// Indicates whether fzf is opened for every query, even if empty.
 	AlwaysFilter bool
+	// Path to the fzf executable, taken from the config `fzf-path` property.
+	FzfPath opt.String
 	// Format for a single line, taken from the config `fzf-line` property.
 	LineTemplate opt.String
 	// Optionally provide additional arguments, taken from the config `fzf-options` property.
@@ -103,13 +106,19 @@ func (f *NoteFilter) Apply(notes []core.ContextualNote) ([]core.ContextualNote,
 		}
 	}
 
-	previewCmd := f.opts.PreviewCmd.OrString("cat {-1}").Unwrap()
+	// By default, the preview prints the note from the index instead of
+	// reading the file.
+	previewCmd := f.opts.PreviewCmd.OrString(
+		defaultPreviewCmd(zkBin, f.opts.NotebookDir),
+	).Unwrap()
 
 	fzf, err := New(Opts{
+		Path:       f.opts.FzfPath,
 		Options:    f.opts.FzfOptions.OrString(defaultOptions),
 		PreviewCmd: opt.NewNotEmptyString(previewCmd),
 		Padding:    2,
 		Bindings:   bindings,
+		Multi:      true,
 	})
 	if err != nil {
 		return selectedNotes, err
@@ -165,6 +174,18 @@ func (f *NoteFilter) Apply(notes []core.ContextualNote) ([]core.ContextualNote,
 	return selectedNotes, nil
 }
 
+// defaultPreviewCmd returns the command printing the note from the index,
+// with the paths quoted for the shell.
+func defaultPreviewCmd(zkBin string, notebookDir string) string {
+	return fmt.Sprintf(`%s list --notebook-dir %s --no-index --no-pager --quiet --limit 1 --format "%s" {-1}`,
+		shellquote.Join(zkBin), shellquote.Join(notebookDir), defaultPreviewTemplate,
+	)
+}
+
+// defaultPreviewTemplate is the format used by the default preview command,
+// quoted for the shell.
+var defaultPreviewTemplate = `{{style 'title' title}}\n\n{{body}}`
+
 var defaultLineTemplate = `{{style "title" title-or-path}} {{style "understate" body}} {{style "understate" (json metadata)}}`
 
 // defaultOptions are the default fzf options used when filtering notes.
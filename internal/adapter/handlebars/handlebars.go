@@ -1,7 +1,6 @@
 package handlebars
 
 import (
-	"fmt"
 	"html"
 	"path/filepath"
 
@@ -17,6 +16,7 @@ func Init(supportsUTF8 bool, logger util.Logger) {
 	helpers.RegisterConcat()
 	helpers.RegisterDate(logger)
 	helpers.RegisterFormatDate(logger)
+	helpers.RegisterIndent(logger)
 	helpers.RegisterJoin()
 	helpers.RegisterJSON(logger)
 	helpers.RegisterList(supportsUTF8)
@@ -102,7 +102,7 @@ func (l *Loader) LoadTemplateAt(path string) (core.Template, error) {
 
 	path, ok := l.locateTemplate(path)
 	if !ok {
-		return nil, wrap(fmt.Errorf("cannot find template at %s", path))
+		return nil, wrap(core.TemplateNotFoundError{Path: path})
 	}
 
 	// Already loaded?
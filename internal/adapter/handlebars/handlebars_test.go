@@ -10,6 +10,7 @@ import (
 	"github.com/zk-org/zk/internal/adapter/handlebars/helpers"
 	"github.com/zk-org/zk/internal/core"
 	"github.com/zk-org/zk/internal/util"
+	"github.com/zk-org/zk/internal/util/errors"
 	"github.com/zk-org/zk/internal/util/fixtures"
 	"github.com/zk-org/zk/internal/util/paths"
 	"github.com/zk-org/zk/internal/util/test/assert"
@@ -78,6 +79,7 @@ func TestLookupPaths(t *testing.T) {
 
 	tpl1, err := sut.LoadTemplateAt(test1)
 	assert.Err(t, err, "cannot find template at "+test1)
+	assert.True(t, errors.As(err, &core.TemplateNotFoundError{}))
 	assert.Nil(t, tpl1)
 
 	paths.WriteString(test1, "Test 1")
@@ -119,6 +121,16 @@ func TestUnknownVariable(t *testing.T) {
 	)
 }
 
+func TestUnknownExtraVariable(t *testing.T) {
+	testString(t,
+		"By {{extra.author}}{{extra.unknown}}",
+		struct{ Extra map[string]string }{
+			Extra: map[string]string{"author": "Mickaël"},
+		},
+		"By Mickaël",
+	)
+}
+
 func TestDoesntEscapeHTML(t *testing.T) {
 	testString(t,
 		"Salut, {{name}}!",
@@ -186,6 +198,21 @@ func TestPrependHelper(t *testing.T) {
 	// block
 	testString(t, "{{#prepend '> '}}A quote{{/prepend}}", nil, "> A quote")
 	testString(t, "{{#prepend '> '}}A quote on\nseveral lines{{/prepend}}", nil, "> A quote on\n> several lines")
+
+	// with a variable
+	testString(t, "{{prepend '> ' body}}", map[string]interface{}{"body": "Line 1\nLine 2\n"}, "> Line 1\n> Line 2\n")
+}
+
+func TestIndentHelper(t *testing.T) {
+	// inline
+	testString(t, "{{indent 2 'A paragraph'}}", nil, "  A paragraph")
+	testString(t, "{{indent 0 'A paragraph'}}", nil, "A paragraph")
+
+	// block
+	testString(t, "{{#indent 4}}A code\nblock{{/indent}}", nil, "    A code\n    block")
+
+	// with a variable
+	testString(t, "{{indent 2 text}}", map[string]interface{}{"text": "Line 1\nLine 2\n"}, "  Line 1\n  Line 2\n")
 }
 
 func TestListHelper(t *testing.T) {
@@ -224,6 +251,10 @@ func TestSlugHelper(t *testing.T) {
 		nil,
 		"this-will-be-slugified",
 	)
+	// diacritics and other scripts
+	testString(t, `{{slug "Écrire à l'été"}}`, nil, "ecrire-a-lete")
+	testString(t, `{{slug "Straße über Ärger"}}`, nil, "strasse-uber-arger")
+	testString(t, `{{slug title}}`, map[string]interface{}{"title": "Привет, мир !"}, "privet-mir")
 }
 
 func TestFormatDateHelper(t *testing.T) {
@@ -351,6 +382,13 @@ func TestFormatDateHelperElapsedViaTimeMultiplication(t *testing.T) {
 func TestDateHelper(t *testing.T) {
 	context := map[string]interface{}{"now": time.Date(2009, 11, 17, 20, 34, 58, 651387237, time.UTC)}
 	testString(t, "{{format-date (date \"2009-11-17T20:34:58\") 'timestamp'}}", context, "200911172034")
+
+	// with a format
+	testString(t, "{{date \"2009-11-17T20:34:58\" 'timestamp'}}", context, "200911172034")
+	testString(t, "{{date now}}", context, "2009-11-17")
+	testString(t, "{{date now '%Y-%m-%d'}}", context, "2009-11-17")
+	testString(t, "{{date now 'long'}}", context, "November 17, 2009")
+	testString(t, "{{date now 'short'}}", context, "11/17/2009")
 }
 
 func TestShellHelper(t *testing.T) {
@@ -371,6 +409,15 @@ func TestShellHelper(t *testing.T) {
 	testString(t, `{{sh "echo hello | tr '[:lower:]' '[:upper:]'"}}`, nil, "HELLO")
 }
 
+func TestShellHelperFailing(t *testing.T) {
+	sut := testLoader(LoaderOpts{})
+	templ, err := sut.LoadTemplate(`Before {{sh "echo 'not found' >&2; exit 3"}} after`)
+	assert.Nil(t, err)
+
+	_, err = templ.Render(nil)
+	assert.Err(t, err, "render template failed: {{sh}} command failed: echo 'not found' >&2; exit 3: exit status 3: not found")
+}
+
 func TestStyleHelper(t *testing.T) {
 	// inline
 	testString(t, "{{style 'single' 'Some text'}}", nil, "single(Some text)")
@@ -1,7 +1,6 @@
 package helpers
 
 import (
-	"os"
 	"time"
 
 	"github.com/aymerick/raymond"
@@ -15,24 +14,43 @@ import (
 // RegisterDate registers the {{date}} template helper to use the `naturaldate` package to generate time.Time based on language strings.
 // This can be used in combination with the `format-date` helper to generate dates in the user's language.
 // {{format-date (date "last week") "timestamp"}}
+//
+// When given a format as second argument, the date is formatted like with
+// the {{format-date}} helper.
+// {{date "yesterday" "long"}} -> November 16, 2009
+// {{date now "%Y-%m-%d"}} -> 2009-11-17
 func RegisterDate(logger util.Logger) {
-	raymond.RegisterHelper("date", func(arg1 interface{}, arg2 interface{}) time.Time {
+	raymond.RegisterHelper("date", func(arg1 interface{}, arg2 interface{}) interface{} {
+		format, hasFormat := arg2.(string)
+
 		var t time.Time
 		switch date := arg1.(type) {
 		case string:
-			t, err := dateutil.TimeFromNatural(date)
+			var err error
+			t, err = dateutil.TimeFromNatural(date)
 			if err != nil {
 				logger.Err(errors.Wrap(err, "the {{date}} template helper failed to parse the date"))
+				return t
+			}
+			if !hasFormat {
+				return t
 			}
-			return t
 		case time.Time:
-			logger.Println("the {{date}} template helper was renamed to {{format-date}}, please update your configuration")
-			os.Exit(1)
-			return t
+			t = date
+			if !hasFormat {
+				format = "%Y-%m-%d"
+			}
 		default:
-			logger.Println("the {{date}} template helper expects a natural human date as a string for its only argument")
+			logger.Println("the {{date}} template helper expects a natural human date as a string or a date for its first argument")
 			return t
 		}
+
+		res, err := formatDate(t, format)
+		if err != nil {
+			logger.Printf("the {{date}} template helper failed to format the date: %v", err)
+			return ""
+		}
+		return res
 	})
 }
 
@@ -47,25 +65,29 @@ func RegisterDate(logger util.Logger) {
 func RegisterFormatDate(logger util.Logger) {
 	raymond.RegisterHelper("format-date", func(date time.Time, arg interface{}) string {
 		format := "%Y-%m-%d"
-
 		if arg, ok := arg.(string); ok {
-			format = findFormat(arg)
+			format = arg
 		}
 
-		if format == "elapsed" {
-			return elapsed.Time(date)
-
-		} else {
-			res, err := strftime.Format(format, date, strftime.WithUnixSeconds('s'))
-			if err != nil {
-				logger.Printf("the {{format-date}} template helper failed to format the date: %v", err)
-				return ""
-			}
-			return res
+		res, err := formatDate(date, format)
+		if err != nil {
+			logger.Printf("the {{format-date}} template helper failed to format the date: %v", err)
+			return ""
 		}
+		return res
 	})
 }
 
+// formatDate formats the date with one of the named styles, or a custom
+// strftime format.
+func formatDate(date time.Time, format string) (string, error) {
+	format = findFormat(format)
+	if format == "elapsed" {
+		return elapsed.Time(date), nil
+	}
+	return strftime.Format(format, date, strftime.WithUnixSeconds('s'))
+}
+
 var (
 	shortFormat         = `%m/%d/%Y`
 	mediumFormat        = `%b %d, %Y`
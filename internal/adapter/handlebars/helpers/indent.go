@@ -0,0 +1,34 @@
+package helpers
+
+import (
+	"strings"
+
+	"github.com/aymerick/raymond"
+	"github.com/zk-org/zk/internal/util"
+	strutil "github.com/zk-org/zk/internal/util/strings"
+)
+
+// RegisterIndent registers an {{indent}} template helper which indents each
+// line of the given text with a number of spaces.
+//
+// {{indent 2 'A paragraph'}} -> "  A paragraph"
+// {{#indent 4}}A code block{{/indent}} -> "    A code block"
+func RegisterIndent(logger util.Logger) {
+	raymond.RegisterHelper("indent", func(count int, opt interface{}) string {
+		if count < 0 {
+			logger.Printf("the {{indent}} template helper is expecting a positive number of spaces, received: %d", count)
+			count = 0
+		}
+		prefix := strings.Repeat(" ", count)
+
+		switch arg := opt.(type) {
+		case *raymond.Options:
+			return strutil.Prepend(arg.Fn(), prefix)
+		case string:
+			return strutil.Prepend(arg, prefix)
+		default:
+			logger.Printf("the {{indent}} template helper is expecting a string as argument, received: %v", opt)
+			return ""
+		}
+	})
+}
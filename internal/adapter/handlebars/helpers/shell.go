@@ -1,10 +1,13 @@
 package helpers
 
 import (
+	"fmt"
+	osexec "os/exec"
 	"strings"
 
 	"github.com/aymerick/raymond"
 	"github.com/zk-org/zk/internal/util"
+	"github.com/zk-org/zk/internal/util/errors"
 	"github.com/zk-org/zk/internal/util/exec"
 )
 
@@ -12,6 +15,9 @@ import (
 //
 // {{#sh "tr '[a-z]' '[A-Z]'"}}Hello, world!{{/sh}} -> HELLO, WORLD!
 // {{sh "echo 'Hello, world!'"}} -> Hello, world!
+//
+// A failing command aborts the rendering of the template with an error,
+// instead of inserting an empty output.
 func RegisterShell(logger util.Logger) {
 	raymond.RegisterHelper("sh", func(arg string, options *raymond.Options) string {
 		cmd := exec.CommandFromString(arg)
@@ -21,8 +27,14 @@ func RegisterShell(logger util.Logger) {
 
 		output, err := cmd.Output()
 		if err != nil {
-			logger.Printf("{{sh}} command failed: %v", err)
-			return ""
+			var exitErr *osexec.ExitError
+			if errors.As(err, &exitErr) {
+				if stderr := strings.TrimSpace(string(exitErr.Stderr)); stderr != "" {
+					err = fmt.Errorf("%w: %s", err, stderr)
+				}
+			}
+			// Raymond reports the errors raised by helpers when rendering.
+			panic(errors.Wrapf(err, "{{sh}} command failed: %s", arg))
 		}
 
 		return strings.TrimSpace(string(output))
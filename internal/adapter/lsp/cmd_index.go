@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/zk-org/zk/internal/core"
+	"github.com/zk-org/zk/internal/util/errors"
 )
 
 const cmdIndex = "zk.index"
@@ -23,5 +24,11 @@ func executeCommandIndex(notebook *core.Notebook, args []interface{}) (interface
 		}
 	}
 
-	return notebook.Index(opts)
+	stats, err := notebook.Index(opts)
+	// The notes which could not be indexed are reported in the stats.
+	var indexingErr core.NoteIndexingError
+	if errors.As(err, &indexingErr) {
+		err = nil
+	}
+	return stats, err
 }
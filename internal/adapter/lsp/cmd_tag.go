@@ -35,5 +35,5 @@ func executeCommandTagList(logger util.Logger, notebook *core.Notebook, args []i
 			return nil, err
 		}
 	}
-	return notebook.FindCollections(core.CollectionKindTag, sorters)
+	return notebook.FindCollections(core.CollectionKindTag, core.CollectionFindOpts{Sorters: sorters})
 }
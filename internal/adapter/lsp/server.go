@@ -648,7 +648,7 @@ func (s *Server) buildInvokedCompletionList(notebook *core.Notebook, doc *docume
 		return s.buildLinkCompletionList(notebook, doc, position)
 	}
 
-	if doc.IsTagPosition(position, notebook.Parser) {
+	if doc.IsTagPosition(position, notebook.ParserForPath(doc.Path)) {
 		return s.buildTagCompletionList(notebook, doc.WordAt(position))
 	}
 
@@ -685,7 +685,7 @@ func (s *Server) buildTriggerCompletionList(notebook *core.Notebook, doc *docume
 }
 
 func (s *Server) buildTagCompletionList(notebook *core.Notebook, prefix string) ([]protocol.CompletionItem, error) {
-	tags, err := notebook.FindCollections(core.CollectionKindTag, nil)
+	tags, err := notebook.FindCollections(core.CollectionKindTag, core.CollectionFindOpts{})
 	if err != nil {
 		return nil, err
 	}
@@ -710,6 +710,8 @@ func (s *Server) buildInsertForTag(name string, prefix string, config core.Confi
 		if strings.Contains(name, " ") {
 			if config.Format.Markdown.MultiwordTags {
 				name += "#"
+			} else if config.Format.Markdown.BracketTags {
+				name = "[[" + name + "]]"
 			} else {
 				name = strings.ReplaceAll(name, " ", "\\ ")
 			}
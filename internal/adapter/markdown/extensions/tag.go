@@ -1,6 +1,7 @@
 package extensions
 
 import (
+	"bytes"
 	"strings"
 	"unicode"
 
@@ -33,7 +34,7 @@ func (n *Tags) Kind() ast.NodeKind {
 
 // TagExt is an extension parsing various flavors of tags.
 //
-// * #hashtags, including Bear's #multi words# tags
+// * #hashtags, including Bear's #multi words# and #[[multi words]] tags
 // * :colon:separated:tags:`, e.g. vimwiki and Org mode
 //
 // Are authorized in a tag:
@@ -45,6 +46,9 @@ type TagExt struct {
 	HashtagEnabled bool
 	// Indicates whether Bear's multi-word tags are parsed. Hashtags must be enabled as well.
 	MultiWordTagEnabled bool
+	// Indicates whether Bear's #[[multi words]] tags are parsed, instead of
+	// Neuron's uplinks. Hashtags must be enabled as well.
+	BracketTagEnabled bool
 	// Indicates whether :colon:tags: are parsed.
 	ColontagEnabled bool
 }
@@ -56,6 +60,11 @@ func (t *TagExt) Extend(m goldmark.Markdown) {
 		parsers = append(parsers, util.Prioritized(&hashtagParser{
 			multiWordTagEnabled: t.MultiWordTagEnabled,
 		}, 2000))
+
+		if t.BracketTagEnabled {
+			// Must run before the wiki-link parser, which handles #[[uplinks]].
+			parsers = append(parsers, util.Prioritized(&bracketTagParser{}, 198))
+		}
 	}
 
 	if t.ColontagEnabled {
@@ -95,6 +104,7 @@ func (p *hashtagParser) Parse(parent ast.Node, block text.Reader, pc parser.Cont
 		parsingMultiWordTag = false // Finished parsing a hashtag, now attempt parsing a Bear multi-word tag
 		endPos              = 0     // Last position of the tag in the line
 		multiWordTagEndPos  = 0     // Last position of the multi-word tag in the line
+		reachedEnd          = true  // The tag runs until the end of the line
 	)
 
 	appendChar := func(c rune) {
@@ -135,6 +145,7 @@ func (p *hashtagParser) Parse(parent ast.Node, block text.Reader, pc parser.Cont
 					tag = multiWordTagCandidate
 					endPos = multiWordTagEndPos
 				}
+				reachedEnd = false
 				break
 			}
 			previousChar = char
@@ -152,6 +163,7 @@ func (p *hashtagParser) Parse(parent ast.Node, block text.Reader, pc parser.Cont
 
 		} else if !isValidTagChar(char, '#') {
 			// Found an invalid character, the hashtag is complete.
+			reachedEnd = false
 			break
 
 		} else {
@@ -159,6 +171,12 @@ func (p *hashtagParser) Parse(parent ast.Node, block text.Reader, pc parser.Cont
 		}
 	}
 
+	// The last character of the line is part of the tag, which happens at
+	// the end of a heading.
+	if reachedEnd && !parsingMultiWordTag {
+		endPos = len(line)
+	}
+
 	tag = strings.TrimSpace(tag)
 	if len(tag) == 0 || !isValidHashTag(tag) {
 		return nil
@@ -181,6 +199,41 @@ func isValidHashTag(tag string) bool {
 	return false
 }
 
+// bracketTagParser parses Bear's #[[multi words]] tags.
+type bracketTagParser struct{}
+
+func (p *bracketTagParser) Trigger() []byte {
+	return []byte{'#'}
+}
+
+func (p *bracketTagParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	// A hashtag can't be directly preceded by a # or any other valid character.
+	if isValidTagChar(block.PrecendingCharacter(), '\x00') {
+		return nil
+	}
+
+	line, _ := block.PeekLine()
+	if !bytes.HasPrefix(line, []byte("#[[")) {
+		return nil
+	}
+	end := bytes.Index(line, []byte("]]"))
+	if end < 0 {
+		return nil
+	}
+
+	tag := strings.TrimSpace(string(line[3:end]))
+	if len(tag) == 0 || strings.ContainsAny(tag, "[]") || !isValidHashTag(tag) {
+		return nil
+	}
+
+	block.Advance(end + 2)
+
+	return &Tags{
+		BaseInline: ast.BaseInline{},
+		Tags:       []string{tag},
+	}
+}
+
 // colontagParser parses :colon:separated:tags:.
 type colontagParser struct{}
 
@@ -203,8 +256,9 @@ func (p *colontagParser) Parse(parent ast.Node, block text.Reader, pc parser.Con
 	)
 
 	var (
-		escaping = false // Found a backslash, next character will be literal
-		endPos   = 0     // Last position of the colontags in the line
+		escaping   = false // Found a backslash, next character will be literal
+		endPos     = 0     // Last position of the colontags in the line
+		reachedEnd = true  // The colontags run until the end of the line
 	)
 
 	appendChar := func(c rune) {
@@ -212,7 +266,8 @@ func (p *colontagParser) Parse(parent ast.Node, block text.Reader, pc parser.Con
 	}
 
 	for i, char := range string(line[1:]) {
-		endPos = i
+		// Skip the leading :
+		endPos = i + 1
 
 		if escaping {
 			// Currently escaping? The character will be appended literally.
@@ -226,6 +281,7 @@ func (p *colontagParser) Parse(parent ast.Node, block text.Reader, pc parser.Con
 		} else if char == ':' {
 			tag = strings.TrimSpace(tag)
 			if !isValidTag(tag) {
+				reachedEnd = false
 				break
 			}
 			tags = append(tags, tag)
@@ -233,6 +289,7 @@ func (p *colontagParser) Parse(parent ast.Node, block text.Reader, pc parser.Con
 
 		} else if !isValidTagChar(char, ':') {
 			// Found an invalid character, the colontag is complete.
+			reachedEnd = false
 			break
 
 		} else {
@@ -243,6 +300,11 @@ func (p *colontagParser) Parse(parent ast.Node, block text.Reader, pc parser.Con
 	if len(tags) == 0 {
 		return nil
 	}
+	// The closing : is the last character of the line, which happens at the
+	// end of a heading.
+	if reachedEnd && tag == "" {
+		endPos = len(line)
+	}
 
 	block.Advance(endPos)
 
@@ -0,0 +1,144 @@
+package extensions
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/pelletier/go-toml"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// TOMLFrontmatterExt is an extension parsing a TOML frontmatter delimited by
+// +++ fences, as used by Hugo.
+//
+// The YAML frontmatter delimited by --- fences is handled by goldmark-meta.
+var TOMLFrontmatterExt = &tomlFrontmatter{}
+
+type tomlFrontmatter struct{}
+
+func (t *tomlFrontmatter) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(
+		parser.WithBlockParsers(
+			util.Prioritized(&tomlFrontmatterParser{}, 0),
+		),
+	)
+}
+
+var tomlFrontmatterContextKey = parser.NewContextKey()
+
+type tomlFrontmatterData struct {
+	values map[string]interface{}
+	err    error
+}
+
+// TryGetTOMLFrontmatter returns the values parsed from the TOML frontmatter
+// of the document, or nil if there is none.
+func TryGetTOMLFrontmatter(pc parser.Context) (map[string]interface{}, error) {
+	data, ok := pc.Get(tomlFrontmatterContextKey).(*tomlFrontmatterData)
+	if !ok {
+		return nil, nil
+	}
+	return data.values, data.err
+}
+
+type tomlFrontmatterParser struct{}
+
+func isTOMLFence(line []byte) bool {
+	return string(util.TrimRightSpace(util.TrimLeftSpace(line))) == "+++"
+}
+
+func (p *tomlFrontmatterParser) Trigger() []byte {
+	return []byte{'+'}
+}
+
+func (p *tomlFrontmatterParser) Open(parent ast.Node, reader text.Reader, pc parser.Context) (ast.Node, parser.State) {
+	// The frontmatter must start on the first line of the document.
+	if linenum, _ := reader.Position(); linenum != 0 {
+		return nil, parser.NoChildren
+	}
+	line, _ := reader.PeekLine()
+	if !isTOMLFence(line) {
+		return nil, parser.NoChildren
+	}
+	return ast.NewTextBlock(), parser.NoChildren
+}
+
+func (p *tomlFrontmatterParser) Continue(node ast.Node, reader text.Reader, pc parser.Context) parser.State {
+	line, segment := reader.PeekLine()
+	if isTOMLFence(line) {
+		reader.Advance(segment.Len())
+		return parser.Close
+	}
+	node.Lines().Append(segment)
+	return parser.Continue | parser.NoChildren
+}
+
+func (p *tomlFrontmatterParser) Close(node ast.Node, reader text.Reader, pc parser.Context) {
+	var buf bytes.Buffer
+	lines := node.Lines()
+	for i := 0; i < lines.Len(); i++ {
+		segment := lines.At(i)
+		buf.Write(segment.Value(reader.Source()))
+	}
+
+	data := &tomlFrontmatterData{}
+	tree, err := toml.LoadBytes(buf.Bytes())
+	if err != nil {
+		data.err = err
+	} else {
+		data.values = convertTOMLMap(tree.ToMap())
+		// The frontmatter is not part of the document content.
+		node.Parent().RemoveChild(node.Parent(), node)
+	}
+	pc.Set(tomlFrontmatterContextKey, data)
+}
+
+func (p *tomlFrontmatterParser) CanInterruptParagraph() bool {
+	return false
+}
+
+func (p *tomlFrontmatterParser) CanAcceptIndentedLine() bool {
+	return false
+}
+
+func convertTOMLMap(m map[string]interface{}) map[string]interface{} {
+	res := map[string]interface{}{}
+	for k, v := range m {
+		res[k] = convertTOMLValue(v)
+	}
+	return res
+}
+
+// convertTOMLValue normalizes a TOML value into the types produced by the
+// YAML parser, so that the metadata don't depend on the frontmatter format.
+func convertTOMLValue(v interface{}) interface{} {
+	switch x := v.(type) {
+	case int64:
+		return int(x)
+	case time.Time:
+		return x.Format(time.RFC3339)
+	case toml.LocalDate, toml.LocalDateTime, toml.LocalTime:
+		return fmt.Sprint(x)
+	case map[string]interface{}:
+		return convertTOMLMap(x)
+	case []map[string]interface{}:
+		res := make([]interface{}, 0, len(x))
+		for _, item := range x {
+			res = append(res, convertTOMLMap(item))
+		}
+		return res
+	case []interface{}:
+		res := make([]interface{}, 0, len(x))
+		for _, item := range x {
+			res = append(res, convertTOMLValue(item))
+		}
+		return res
+	default:
+		return v
+	}
+}
@@ -54,6 +54,7 @@ func (p *wlParser) Parse(parent ast.Node, block text.Reader, pc parser.Context)
 		openerCharCount = 0     // Number of [ encountered
 		closerCharCount = 0     // Number of ] encountered
 		endPos          = 0     // Last position of the link in the line
+		reachedEnd      = true  // The link runs until the end of the line
 	)
 
 	appendRune := func(c rune) {
@@ -72,6 +73,7 @@ func (p *wlParser) Parse(parent ast.Node, block text.Reader, pc parser.Context)
 			if char == '#' {
 				rel = core.LinkRelationDown
 			}
+			reachedEnd = false
 			break
 		}
 
@@ -131,6 +133,10 @@ func (p *wlParser) Parse(parent ast.Node, block text.Reader, pc parser.Context)
 	if !closed || len(href) == 0 {
 		return nil
 	}
+	// The link closes the line, which happens at the end of a heading.
+	if reachedEnd {
+		endPos = len(line)
+	}
 
 	block.Advance(endPos)
 
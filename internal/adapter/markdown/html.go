@@ -0,0 +1,139 @@
+package markdown
+
+import (
+	"bytes"
+	"net/url"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+	"github.com/zk-org/zk/internal/adapter/markdown/extensions"
+	"github.com/zk-org/zk/internal/core"
+)
+
+// RenderNoteHTML implements core.NoteHTMLRenderer.
+func (p *Parser) RenderNoteHTML(content string, resolver core.HTMLLinkResolver) (string, error) {
+	source := []byte(content)
+	root := p.md.Parser().Parse(
+		text.NewReader(source),
+		parser.WithContext(parser.NewContext()),
+	)
+
+	// The nodes are replaced after walking the tree, to not interrupt it.
+	type replacement struct {
+		old ast.Node
+		new ast.Node
+	}
+	replacements := []replacement{}
+
+	err := ast.Walk(root, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+
+		switch node := n.(type) {
+		case *extensions.WikiLink:
+			href := string(node.Destination)
+			if dest, ok := resolver.ResolveLink(href, core.LinkTypeWikiLink); ok {
+				link := ast.NewLink()
+				link.Destination = []byte(dest)
+				replacements = append(replacements, replacement{node, moveChildren(node, link)})
+			} else {
+				replacements = append(replacements, replacement{node, moveChildren(node, &deadLink{})})
+			}
+
+		case *ast.Link:
+			href, err := url.PathUnescape(string(node.Destination))
+			p.logger.Err(err)
+			if dest, ok := resolver.ResolveLink(href, core.LinkTypeMarkdown); ok {
+				node.Destination = []byte(dest)
+			} else {
+				replacements = append(replacements, replacement{node, moveChildren(node, &deadLink{})})
+			}
+
+		case *ast.Image:
+			src, err := url.PathUnescape(string(node.Destination))
+			p.logger.Err(err)
+			node.Destination = []byte(resolver.ResolveImage(src))
+		}
+
+		return ast.WalkContinue, nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	for _, r := range replacements {
+		parent := r.old.Parent()
+		parent.ReplaceChild(parent, r.old, r.new)
+	}
+
+	var buf bytes.Buffer
+	err = p.md.Renderer().Render(&buf, source, root)
+	return buf.String(), err
+}
+
+// moveChildren moves the children of the node from to the node to, which is
+// returned.
+func moveChildren(from ast.Node, to ast.Node) ast.Node {
+	for child := from.FirstChild(); child != nil; {
+		next := child.NextSibling()
+		to.AppendChild(to, child)
+		child = next
+	}
+	return to
+}
+
+// deadLink is a link which doesn't target any exported note.
+type deadLink struct {
+	ast.BaseInline
+}
+
+// kindDeadLink is a NodeKind of the deadLink node.
+var kindDeadLink = ast.NewNodeKind("DeadLink")
+
+func (n *deadLink) Kind() ast.NodeKind {
+	return kindDeadLink
+}
+
+func (n *deadLink) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, nil, nil)
+}
+
+// htmlRendererOption registers the htmlRenderer in a goldmark instance.
+var htmlRendererOption = goldmark.WithRendererOptions(
+	renderer.WithNodeRenderers(util.Prioritized(&htmlRenderer{}, 100)),
+)
+
+// htmlRenderer renders the nodes specific to zk notes to HTML.
+type htmlRenderer struct{}
+
+func (r *htmlRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(kindDeadLink, r.renderDeadLink)
+	reg.Register(extensions.KindTags, r.renderTags)
+}
+
+func (r *htmlRenderer) renderDeadLink(w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+	if entering {
+		w.WriteString(`<span class="dead-link">`)
+	} else {
+		w.WriteString("</span>")
+	}
+	return ast.WalkContinue, nil
+}
+
+func (r *htmlRenderer) renderTags(w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	tags := []string{}
+	for _, tag := range n.(*extensions.Tags).Tags {
+		tags = append(tags, `<span class="tag">#`+string(util.EscapeHTML([]byte(tag)))+`</span>`)
+	}
+	w.WriteString(strings.Join(tags, " "))
+	return ast.WalkContinue, nil
+}
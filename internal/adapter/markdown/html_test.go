@@ -0,0 +1,82 @@
+package markdown
+
+import (
+	"testing"
+
+	"github.com/zk-org/zk/internal/core"
+	"github.com/zk-org/zk/internal/util"
+	"github.com/zk-org/zk/internal/util/test/assert"
+)
+
+func TestRenderNoteHTML(t *testing.T) {
+	test := func(source string, expected string) {
+		resolver := &htmlLinkResolverMock{
+			links: map[string]string{
+				"gardening":       "gardening.html",
+				"journal/day.md":  "journal/day.html",
+				"my note.md":      "my%20note.html",
+				"gardening#tools": "gardening.html#tools",
+			},
+			images: map[string]string{
+				"images/tomato.svg": "assets/tomato.svg",
+			},
+		}
+		html, err := NewParser(ParserOpts{
+			HashtagEnabled:  true,
+			ColontagEnabled: true,
+		}, &util.NullLogger).RenderNoteHTML(source, resolver)
+		assert.Nil(t, err)
+		assert.Equal(t, html, expected)
+	}
+
+	test("# Title\n\nParagraph", "<h1>Title</h1>\n<p>Paragraph</p>\n")
+	// The frontmatter is not rendered.
+	test("---\ntitle: Title\n---\n\nParagraph", "<p>Paragraph</p>\n")
+	test("+++\ntitle = \"Title\"\n+++\n\nParagraph", "<p>Paragraph</p>\n")
+
+	// Wiki links
+	test("A [[gardening]] link", "<p>A <a href=\"gardening.html\">gardening</a> link</p>\n")
+	test("A [[gardening | label]]", "<p>A <a href=\"gardening.html\">label</a></p>\n")
+	test("A [[gardening#tools]]", "<p>A <a href=\"gardening.html#tools\">gardening#tools</a></p>\n")
+	test("A #[[gardening]] uplink", "<p>A <a href=\"gardening.html\">gardening</a> uplink</p>\n")
+	test("A [[dead]] link", "<p>A <span class=\"dead-link\">dead</span> link</p>\n")
+
+	// Markdown links
+	test("A [day](journal/day.md)", "<p>A <a href=\"journal/day.html\">day</a></p>\n")
+	test("A [note](my%20note.md)", "<p>A <a href=\"my%20note.html\">note</a></p>\n")
+	test("A [*dead* link](dead.md)", "<p>A <span class=\"dead-link\"><em>dead</em> link</span></p>\n")
+	test("An [external](https://example.com) link", "<p>An <a href=\"https://example.com\">external</a> link</p>\n")
+	test("An autolink https://example.com", "<p>An autolink <a href=\"https://example.com\">https://example.com</a></p>\n")
+
+	// Images
+	test("![A tomato](images/tomato.svg)", "<p><img src=\"assets/tomato.svg\" alt=\"A tomato\"></p>\n")
+	test("![Remote](https://example.com/a.png)", "<p><img src=\"https://example.com/a.png\" alt=\"Remote\"></p>\n")
+
+	// Tags
+	test("Growing #vegetables", "<p>Growing <span class=\"tag\">#vegetables</span></p>\n")
+	test(":rust:programming:", "<p><span class=\"tag\">#rust</span> <span class=\"tag\">#programming</span></p>\n")
+}
+
+// htmlLinkResolverMock resolves the links and images from static maps. The
+// links not found are dead, while the images not found are left unchanged.
+type htmlLinkResolverMock struct {
+	links  map[string]string
+	images map[string]string
+}
+
+func (m *htmlLinkResolverMock) ResolveLink(href string, linkType core.LinkType) (string, bool) {
+	if dest, ok := m.links[href]; ok {
+		return dest, true
+	}
+	if href == "https://example.com" {
+		return href, true
+	}
+	return "", false
+}
+
+func (m *htmlLinkResolverMock) ResolveImage(src string) string {
+	if dest, ok := m.images[src]; ok {
+		return dest
+	}
+	return src
+}
@@ -2,14 +2,17 @@ package markdown
 
 import (
 	"bufio"
+	"bytes"
 	"fmt"
 	"net/url"
 	"regexp"
 	"strings"
+	"unicode"
 
 	"github.com/zk-org/zk/internal/adapter/markdown/extensions"
 	"github.com/zk-org/zk/internal/core"
 	"github.com/zk-org/zk/internal/util"
+	"github.com/zk-org/zk/internal/util/errors"
 	"github.com/zk-org/zk/internal/util/opt"
 	strutil "github.com/zk-org/zk/internal/util/strings"
 	"github.com/zk-org/zk/internal/util/yaml"
@@ -24,8 +27,9 @@ import (
 
 // Parser parses the content of Markdown notes.
 type Parser struct {
-	md     goldmark.Markdown
-	logger util.Logger
+	md            goldmark.Markdown
+	lowercaseTags bool
+	logger        util.Logger
 }
 
 type ParserOpts struct {
@@ -33,8 +37,13 @@ type ParserOpts struct {
 	HashtagEnabled bool
 	// Indicates whether Bear's multi-word tags are parsed. Hashtags must be enabled as well.
 	MultiWordTagEnabled bool
+	// Indicates whether Bear's #[[multi words]] tags are parsed, instead of
+	// Neuron's uplinks. Hashtags must be enabled as well.
+	BracketTagEnabled bool
 	// Indicates whether :colon:tags: are parsed.
 	ColontagEnabled bool
+	// Indicates whether the tags are converted to lowercase.
+	LowercaseTags bool
 }
 
 // NewParser creates a new Markdown Parser.
@@ -43,6 +52,7 @@ func NewParser(options ParserOpts, logger util.Logger) *Parser {
 		md: goldmark.New(
 			goldmark.WithExtensions(
 				meta.Meta,
+				extensions.TOMLFrontmatterExt,
 				extension.NewLinkify(
 					extension.WithLinkifyAllowedProtocols([][]byte{
 						[]byte("http:"),
@@ -56,11 +66,14 @@ func NewParser(options ParserOpts, logger util.Logger) *Parser {
 				&extensions.TagExt{
 					HashtagEnabled:      options.HashtagEnabled,
 					MultiWordTagEnabled: options.MultiWordTagEnabled,
+					BracketTagEnabled:   options.BracketTagEnabled,
 					ColontagEnabled:     options.ColontagEnabled,
 				},
 			),
+			htmlRendererOption,
 		),
-		logger: logger,
+		lowercaseTags: options.LowercaseTags,
+		logger:        logger,
 	}
 }
 
@@ -81,7 +94,10 @@ func (p *Parser) ParseNoteContent(content string) (*core.NoteContent, error) {
 
 	frontmatter, err := parseFrontmatter(context, bytes)
 	if err != nil {
-		return nil, err
+		// A malformed frontmatter should not prevent the note from being
+		// indexed, so we parse it as part of the body instead.
+		p.logger.Printf("warning: %v", errors.Wrap(err, "invalid frontmatter, parsed as body"))
+		frontmatter = newFrontmatter()
 	}
 
 	title, bodyStart, err := parseTitle(frontmatter, root, bytes)
@@ -90,27 +106,27 @@ func (p *Parser) ParseNoteContent(content string) (*core.NoteContent, error) {
 	}
 	body := parseBody(bodyStart, bytes)
 
-	tags, err := parseTags(frontmatter, root, bytes)
+	tags, err := p.parseTags(frontmatter, root, bytes)
 	if err != nil {
 		return nil, err
 	}
 
 	return &core.NoteContent{
-		Title:    title,
-		Body:     body,
-		Lead:     parseLead(body),
-		Links:    links,
-		Tags:     tags,
-		Metadata: frontmatter.values,
+		Title:     title,
+		Body:      body,
+		Lead:      parseLead(body),
+		Links:     links,
+		Tags:      tags,
+		Aliases:   parseAliases(frontmatter),
+		Metadata:  frontmatter.values,
+		WordCount: parseWordCount(root, bytes),
 	}, nil
 }
 
-// parseTitle extracts the note title with its node.
+// parseTitle extracts the note title from its main heading, falling back on
+// the title of the frontmatter.
 func parseTitle(frontmatter frontmatter, root ast.Node, source []byte) (title opt.String, bodyStart int, err error) {
-	if title = frontmatter.getString("title", "Title"); !title.IsNull() {
-		bodyStart = frontmatter.end
-		return
-	}
+	bodyStart = frontmatter.end
 
 	var titleNode *ast.Heading
 	err = ast.Walk(root, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
@@ -130,15 +146,54 @@ func parseTitle(frontmatter frontmatter, root ast.Node, source []byte) (title op
 	}
 
 	if titleNode != nil {
-		title = opt.NewNotEmptyString(string(titleNode.Text(source)))
+		title = opt.NewNotEmptyString(headingText(titleNode, source))
 
 		if lines := titleNode.Lines(); lines.Len() > 0 {
-			bodyStart = lines.At(lines.Len() - 1).Stop
+			bodyStart = headingEnd(source, lines.At(0).Start, lines.At(lines.Len()-1).Stop)
 		}
 	}
+	if title.IsNull() {
+		title = frontmatter.getString("title", "Title")
+	}
 	return
 }
 
+// headingText returns the plain text of a heading, without its inline
+// formatting.
+func headingText(heading *ast.Heading, source []byte) string {
+	var text bytes.Buffer
+	for child := heading.FirstChild(); child != nil; child = child.NextSibling() {
+		text.Write(child.Text(source))
+		// Lines of a multi-line Setext heading are joined with a space.
+		if t, ok := child.(*ast.Text); ok && t.SoftLineBreak() {
+			text.WriteByte(' ')
+		}
+	}
+	return strings.TrimSpace(text.String())
+}
+
+// headingEnd returns the position after the heading whose text spans
+// between start and stop, skipping the closing sequence of an ATX heading or
+// the underline of a Setext heading.
+func headingEnd(source []byte, start int, stop int) int {
+	lineEnd := func(pos int) int {
+		if i := bytes.IndexByte(source[pos:], '\n'); i >= 0 {
+			return pos + i
+		}
+		return len(source)
+	}
+
+	end := lineEnd(stop)
+
+	lineStart := bytes.LastIndexByte(source[:start], '\n') + 1
+	isATX := bytes.HasPrefix(bytes.TrimLeft(source[lineStart:start], " "), []byte("#"))
+	if !isATX && end < len(source) {
+		end = lineEnd(end + 1)
+	}
+
+	return end
+}
+
 // parseBody extracts the whole content after the title.
 func parseBody(startIndex int, source []byte) opt.String {
 	return opt.NewNotEmptyString(
@@ -148,7 +203,12 @@ func parseBody(startIndex int, source []byte) opt.String {
 	)
 }
 
+// leadMaxLength is the maximum number of characters of a note lead.
+const leadMaxLength = 500
+
 // parseLead extracts the body content until the first blank line.
+//
+// A lead longer than leadMaxLength is truncated on a word boundary.
 func parseLead(body opt.String) opt.String {
 	lead := ""
 	scanner := bufio.NewScanner(strings.NewReader(body.String()))
@@ -159,11 +219,33 @@ func parseLead(body opt.String) opt.String {
 		lead += scanner.Text() + "\n"
 	}
 
-	return opt.NewNotEmptyString(strings.TrimSpace(lead))
+	lead = strings.TrimSpace(lead)
+	if runes := []rune(lead); len(runes) > leadMaxLength {
+		lead = string(runes[:leadMaxLength])
+		if i := strings.LastIndexFunc(lead, unicode.IsSpace); i > 0 {
+			lead = lead[:i]
+		}
+		lead = strings.TrimSpace(lead) + "…"
+	}
+
+	return opt.NewNotEmptyString(lead)
+}
+
+// parseAliases extracts the alternative names of the note from the `aliases`
+// key of the frontmatter, either a list of strings or a single string.
+func parseAliases(frontmatter frontmatter) []string {
+	aliases, ok := frontmatter.getStrings("aliases")
+	if !ok {
+		aliases = []string{}
+		if alias := strings.TrimSpace(frontmatter.getString("aliases").String()); alias != "" {
+			aliases = append(aliases, alias)
+		}
+	}
+	return strutil.RemoveDuplicates(aliases)
 }
 
 // parseTags extracts tags as #hashtags, :colon:tags: or from the YAML frontmatter.
-func parseTags(frontmatter frontmatter, root ast.Node, source []byte) ([]string, error) {
+func (p *Parser) parseTags(frontmatter frontmatter, root ast.Node, source []byte) ([]string, error) {
 	tags := make([]string, 0)
 
 	// Parse from YAML frontmatter, either:
@@ -208,6 +290,12 @@ func parseTags(frontmatter frontmatter, root ast.Node, source []byte) ([]string,
 		return ast.WalkContinue, nil
 	})
 
+	if p.lowercaseTags {
+		for i, tag := range tags {
+			tags[i] = strings.ToLower(tag)
+		}
+	}
+
 	return strutil.RemoveDuplicates(tags), err
 }
 
@@ -293,30 +381,48 @@ func extractLines(n ast.Node, source []byte) (content string, start, end int) {
 	return
 }
 
-// frontmatter contains metadata parsed from a YAML frontmatter.
+// frontmatter contains metadata parsed from a YAML, JSON or TOML frontmatter.
 type frontmatter struct {
 	values map[string]interface{}
 	start  int
 	end    int
 }
 
-var frontmatterRegex = regexp.MustCompile(`(?ms)^\s*-+\s*$.*?^\s*-+\s*$`)
+var (
+	// A YAML frontmatter, or a JSON object, is delimited by --- fences.
+	frontmatterRegex = regexp.MustCompile(`(?ms)^\s*-+\s*$.*?^\s*-+\s*$`)
+	// A TOML frontmatter is delimited by +++ fences.
+	tomlFrontmatterRegex = regexp.MustCompile(`(?ms)^\s*\+\+\+\s*$.*?^\s*\+\+\+\s*$`)
+)
+
+func newFrontmatter() frontmatter {
+	return frontmatter{values: map[string]interface{}{}}
+}
 
 func parseFrontmatter(context parser.Context, source []byte) (frontmatter, error) {
-	var front frontmatter
-	front.values = map[string]interface{}{}
+	front := newFrontmatter()
+
+	// The format is detected from the fence characters.
+	regex := frontmatterRegex
+	getValues := meta.TryGet
+	if bytes.HasPrefix(bytes.TrimSpace(source), []byte("+++")) {
+		regex = tomlFrontmatterRegex
+		getValues = extensions.TryGetTOMLFrontmatter
+	}
 
-	index := frontmatterRegex.FindIndex(source)
-	if index == nil {
+	index := regex.FindIndex(source)
+	// Only a block at the top of the note is a frontmatter, the other ones
+	// are thematic breaks.
+	if index == nil || len(bytes.TrimSpace(source[:index[0]])) > 0 {
 		return front, nil
 	}
 
 	front.start = index[0]
 	front.end = index[1]
 
-	values, err := meta.TryGet(context)
+	values, err := getValues(context)
 	if err != nil {
-		return front, err
+		return newFrontmatter(), err
 	}
 
 	// The YAML parser parses nested maps as map[interface{}]interface{}
@@ -1,6 +1,7 @@
 package markdown
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/zk-org/zk/internal/core"
@@ -26,8 +27,17 @@ func TestParseTitle(t *testing.T) {
 	test("# Heading 1\n## Heading 1.a\n# Heading 2", "Heading 1")
 	test("## Small Heading\n# Bigger Heading", "Bigger Heading")
 	test("# A **title** with [formatting](http://stripped)", "A title with formatting")
-
-	// From a YAML frontmatter
+	test("# *Emphasis* title", "Emphasis title")
+	test("# A title ##", "A title")
+	test("#   A title   #  ", "A title")
+	test("A title\n=======\n\nBody", "A title")
+	test("A title\n-------\n\nBody", "A title")
+	test("A multi-line\ntitle\n===", "A multi-line title")
+	test("# A title with #hashtag", "A title with")
+	test("# A title with :colon:tags:", "A title with")
+	test("# A title with [[wiki link]]", "A title with wiki link")
+
+	// The heading takes precedence over the title of the frontmatter.
 	test(`---
 Title:     A title
 Tags:
@@ -36,12 +46,31 @@ Tags:
 ---
 
 # Heading
+`, "Heading")
+	test("---\ntitle: A title\n---\nSetext heading\n===\n", "Setext heading")
+
+	// Falls back on the title of a YAML frontmatter.
+	test(`---
+Title:     A title
+Tags:
+    - tag1
+    - tag2
+---
+
+Paragraph
 `, "A title")
+	test("---\ntitle: A title\n---\n#  \nBody", "A title")
 	test(`---
 title: lowercase key
 ---
 Paragraph
 `, "lowercase key")
+	test(`---
+date: 2011-05-16 09:58:57
+---
+
+# Heading after the frontmatter
+`, "Heading after the frontmatter")
 }
 
 func TestParseBody(t *testing.T) {
@@ -54,6 +83,10 @@ func TestParseBody(t *testing.T) {
 	test("# A title\n    \n", "")
 	test("Paragraph \n\n# A title", "")
 	test("Paragraph \n\n# A title\nBody", "Body")
+	test("# A title ##\nBody", "Body")
+	test("A title\n=======\nBody", "Body")
+	test("A title\n-------\nBody", "Body")
+	test("# A title\n---\nBody", "---\nBody")
 
 	test(
 		`## Small Heading
@@ -152,6 +185,16 @@ Paragraph`,
 		`* item1
 * item2`,
 	)
+
+	// Long leads are truncated on a word boundary.
+	test(
+		"# A title\n"+strings.Repeat("lorem ", 99)+"ipsum dolor",
+		strings.TrimSpace(strings.Repeat("lorem ", 83))+"…",
+	)
+	test(
+		"# A title\n"+strings.Repeat("é", 600),
+		strings.Repeat("é", 500)+"…",
+	)
 }
 
 func TestParseHashtags(t *testing.T) {
@@ -188,10 +231,47 @@ func TestParseHashtags(t *testing.T) {
 	test("##invalid also#invalid", []string{})
 	// Bear's multi multi-word tags are disabled
 	test("#multi word# end", []string{"multi"})
+	test("#[[multi word]] end", []string{})
 
 	// Single character
 	// See https://github.com/zk-org/zk/issues/118
 	test("#a", []string{"a"})
+
+	// Hierarchical tags
+	test("#project/zk and #reading", []string{"project/zk", "reading"})
+	// Headings are not tags
+	test("# Heading\n## Sub-heading\n\n#tag", []string{"tag"})
+	// URL fragments are not tags
+	test("https://example.com/page#fragment and [link](page#anchor)", []string{})
+	// Tags in code are ignored
+	test("`#code-span` and\n\n```\n#fenced-code\n```\n\n    #indented-code", []string{})
+}
+
+func TestParseAliases(t *testing.T) {
+	test := func(source string, aliases []string) {
+		content := parse(t, source)
+		assert.Equal(t, content.Aliases, aliases)
+	}
+
+	test("# Title", []string{})
+	test("---\naliases: [GTD, Getting Things Done, GTD]\n---\n", []string{"GTD", "Getting Things Done"})
+	test("---\naliases:\n  - GTD\n  - \" \"\n---\n", []string{"GTD"})
+	test("---\naliases: Getting Things Done\n---\n", []string{"Getting Things Done"})
+	test("+++\naliases = [\"GTD\"]\n+++\n", []string{"GTD"})
+}
+
+func TestParseLowercaseTags(t *testing.T) {
+	test := func(lowercase bool, tags []string) {
+		content := parseWithOptions(t, "---\ntags: [FrontMatter]\n---\n#Project/ZK #project/zk :Colon:", ParserOpts{
+			HashtagEnabled:  true,
+			ColontagEnabled: true,
+			LowercaseTags:   lowercase,
+		})
+		assert.Equal(t, content.Tags, tags)
+	}
+
+	test(false, []string{"FrontMatter", "Project/ZK", "project/zk", "Colon"})
+	test(true, []string{"frontmatter", "project/zk", "colon"})
 }
 
 func TestParseWordtags(t *testing.T) {
@@ -238,6 +318,103 @@ func TestParseWordtags(t *testing.T) {
 	test("a #multi word# in the middle", []string{"multi word"})
 	test("a #multi word#, and a #tag", []string{"multi word", "tag"})
 	test("#multi, word#", []string{"multi"})
+	test("#projects/zk tool# end", []string{"projects/zk tool"})
+	// Bear's bracketed multi-word tags have their own option.
+	test("#[[multi word]]", []string{})
+}
+
+func TestParseBracketTags(t *testing.T) {
+	test := func(source string, tags []string) {
+		content := parseWithOptions(t, source, ParserOpts{
+			HashtagEnabled:    true,
+			BracketTagEnabled: true,
+		})
+		assert.Equal(t, content.Tags, tags)
+	}
+
+	test("#[[multi word]]", []string{"multi word"})
+	test("a #[[multi word]], and a #tag", []string{"multi word", "tag"})
+	test("#[[projects/zk tool]] end", []string{"projects/zk tool"})
+	test("#[[ trimmed ]]", []string{"trimmed"})
+	test("#[[]] #[[unclosed", []string{})
+	test("#[[ ]] #[[123]]", []string{})
+}
+
+func TestParseWikiLinks(t *testing.T) {
+	test := func(source string, links []core.Link) {
+		content := parse(t, source)
+		assert.Equal(t, content.Links, links)
+	}
+
+	link := func(title, href, snippet string, start int) core.Link {
+		return core.Link{
+			Title:        title,
+			Href:         href,
+			Type:         core.LinkTypeWikiLink,
+			Rels:         []core.LinkRelation{},
+			Snippet:      snippet,
+			SnippetStart: start,
+			SnippetEnd:   start + len(snippet),
+		}
+	}
+
+	test("[[f39c8]] at the start", []core.Link{
+		link("f39c8", "f39c8", "[[f39c8]] at the start", 0),
+	})
+	test("Intro\n\nSee [[f39c8|custom label]] and [[f39c8#Section]].", []core.Link{
+		link("custom label", "f39c8", "See [[f39c8|custom label]] and [[f39c8#Section]].", 7),
+		link("f39c8#Section", "f39c8#Section", "See [[f39c8|custom label]] and [[f39c8#Section]].", 7),
+	})
+	// Escaped and code links are skipped.
+	test("An escaped \\[[not a link]] and a `[[code span]]`.\n\n```\n[[fenced]]\n```", []core.Link{})
+}
+
+func TestParseMarkdownLinks(t *testing.T) {
+	// Images are not links.
+	snippet := "An ![image](img.png), a [note](../ref/a.md), [mail](mailto:hi@example.com) and <https://example.com>."
+	content := parse(t, snippet)
+	assert.Equal(t, content.Links, []core.Link{
+		{
+			Title:        "note",
+			Href:         "../ref/a.md",
+			Type:         core.LinkTypeMarkdown,
+			Rels:         []core.LinkRelation{},
+			IsExternal:   false,
+			Snippet:      snippet,
+			SnippetStart: 0,
+			SnippetEnd:   len(snippet),
+		},
+		{
+			Title:        "mail",
+			Href:         "mailto:hi@example.com",
+			Type:         core.LinkTypeMarkdown,
+			Rels:         []core.LinkRelation{},
+			IsExternal:   true,
+			Snippet:      snippet,
+			SnippetStart: 0,
+			SnippetEnd:   len(snippet),
+		},
+		{
+			Title:        "https://example.com",
+			Href:         "https://example.com",
+			Type:         core.LinkTypeImplicit,
+			Rels:         []core.LinkRelation{},
+			IsExternal:   true,
+			Snippet:      snippet,
+			SnippetStart: 0,
+			SnippetEnd:   len(snippet),
+		},
+	})
+}
+
+func TestParseBracketTagShadowsUplink(t *testing.T) {
+	content := parseWithOptions(t, "A #[[multi word]] tag and a [[wiki link]].", ParserOpts{
+		HashtagEnabled:    true,
+		BracketTagEnabled: true,
+	})
+	assert.Equal(t, content.Tags, []string{"multi word"})
+	assert.Equal(t, len(content.Links), 1)
+	assert.Equal(t, content.Links[0].Href, "wiki link")
 }
 
 func TestParseColontags(t *testing.T) {
@@ -278,6 +455,15 @@ func TestParseColontags(t *testing.T) {
 	test(":123:1.2.3:", []string{"123"})
 	// Must not be preceded by a : or any other valid colontag character
 	test("::invalid also:invalid:", []string{})
+	// Org mode and jrnl tag line at the end of the note
+	test("# Title\n\nSome content.\n\n:work:idea:", []string{"work", "idea"})
+	test("Some content.\n:work:idea:\n", []string{"work", "idea"})
+	// Colons in prose and URLs are not tags
+	test(
+		"Note: see http://example.com:8080/a:b: and https://example.com/x:y:z:, "+
+			"at 10:30:00 in a 16:9: ratio, or mailto:me@example.com: maybe.",
+		[]string{},
+	)
 }
 
 func TestParseMixedTags(t *testing.T) {
@@ -625,6 +811,135 @@ Paragraph
 	})
 }
 
+func TestParseWordCount(t *testing.T) {
+	test := func(source string, expected int) {
+		content := parse(t, source)
+		assert.Equal(t, content.WordCount, expected)
+	}
+
+	test("", 0)
+	test("# A title\n\nSome prose, with punctuation!", 6)
+	test("Words **with** _formatting_ and `code`", 5)
+	test("A [link with text](https://example.com/a/long/url) and [[wiki link]]", 7)
+	test("An autolink https://example.com/path is not a word", 6)
+	test("With #hashtag and :colon:tags:", 5)
+	test("A comment <!-- hidden words --> in a paragraph", 5)
+	test("<!--\nA hidden\nblock\n-->\n\nVisible", 1)
+	test("Chinese 中文字 and Japanese ひらがなカタカナ", 14)
+
+	test(`---
+title: A note mixing prose, code and links
+tags: [one, two]
+---
+
+# A title
+
+Some prose with a [link](https://example.com) to read.
+
+`+"```go"+`
+func main() {
+	fmt.Println("not counted")
+}
+`+"```"+`
+
+    indented code block
+    not counted either
+
+- A list item
+- Another one #tag
+`, 15)
+}
+
+func TestParseFrontmatterOnly(t *testing.T) {
+	content := parse(t, "---\ntitle: A title\ntags: [tag1, tag2]\n---\n")
+	assert.Equal(t, content.Title, opt.NewString("A title"))
+	assert.Equal(t, content.Body, opt.NullString)
+	assert.Equal(t, content.Tags, []string{"tag1", "tag2"})
+	assert.Equal(t, content.WordCount, 0)
+}
+
+func TestParseBodyExcludesFrontmatter(t *testing.T) {
+	content := parse(t, "---\nkey: value\n---\nJust a body\n")
+	assert.Equal(t, content.Title, opt.NullString)
+	assert.Equal(t, content.Body, opt.NewString("Just a body"))
+	assert.Equal(t, content.WordCount, 3)
+}
+
+func TestParseThematicBreaksAreNotFrontmatter(t *testing.T) {
+	content := parse(t, "Paragraph\n\n---\n\nkey: value\n\n---\n")
+	assert.Equal(t, content.Metadata, map[string]interface{}{})
+	assert.Equal(t, content.WordCount, 3)
+}
+
+func TestParseMalformedFrontmatter(t *testing.T) {
+	content := parse(t, "---\ntitle: [broken\n---\nBody #tag\n")
+	assert.Equal(t, content.Title, opt.NullString)
+	assert.Equal(t, content.Body, opt.NewString("---\ntitle: [broken\n---\nBody #tag"))
+	assert.Equal(t, content.Tags, []string{"tag"})
+	assert.Equal(t, content.Metadata, map[string]interface{}{})
+	assert.Equal(t, content.WordCount, 4)
+}
+
+func TestParseFrontmatterFormats(t *testing.T) {
+	yaml := parse(t, `---
+title: A title
+date: 2011-05-16T09:58:57Z
+tags: [tag1, "tag 2"]
+count: 2
+nested:
+  key: value
+---
+
+Paragraph
+`)
+	json := parse(t, `---
+{
+  "title": "A title",
+  "date": "2011-05-16T09:58:57Z",
+  "tags": ["tag1", "tag 2"],
+  "count": 2,
+  "nested": {"key": "value"}
+}
+---
+
+Paragraph
+`)
+	toml := parse(t, `+++
+title = "A title"
+date = 2011-05-16T09:58:57Z
+tags = ["tag1", "tag 2"]
+count = 2
+
+[nested]
+key = "value"
++++
+
+Paragraph
+`)
+
+	expected := map[string]interface{}{
+		"title": "A title",
+		"date":  "2011-05-16T09:58:57Z",
+		"tags":  []interface{}{"tag1", "tag 2"},
+		"count": 2,
+		"nested": map[string]interface{}{
+			"key": "value",
+		},
+	}
+	for _, content := range []core.NoteContent{yaml, json, toml} {
+		assert.Equal(t, content.Metadata, expected)
+		assert.Equal(t, content.Title, opt.NewString("A title"))
+		assert.Equal(t, content.Body, opt.NewString("Paragraph"))
+		assert.Equal(t, content.Tags, []string{"tag1", "tag 2"})
+	}
+}
+
+func TestParseMalformedTOMLFrontmatter(t *testing.T) {
+	content := parse(t, "+++\ntitle = broken\n+++\nBody\n")
+	assert.Equal(t, content.Body, opt.NewString("+++\ntitle = broken\n+++\nBody"))
+	assert.Equal(t, content.Metadata, map[string]interface{}{})
+}
+
 func parse(t *testing.T, source string) core.NoteContent {
 	return parseWithOptions(t, source, ParserOpts{
 		HashtagEnabled:      true,
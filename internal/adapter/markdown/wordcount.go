@@ -0,0 +1,52 @@
+package markdown
+
+import (
+	"bytes"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/zk-org/zk/internal/adapter/markdown/extensions"
+	strutil "github.com/zk-org/zk/internal/util/strings"
+)
+
+// parseWordCount counts the words of the note prose.
+//
+// The frontmatter, code blocks, HTML comments and URLs are ignored, while the
+// text of the links is counted.
+func parseWordCount(root ast.Node, source []byte) int {
+	var text bytes.Buffer
+
+	ast.Walk(root, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			if n.Type() == ast.TypeBlock {
+				text.WriteByte('\n')
+			}
+			return ast.WalkContinue, nil
+		}
+
+		switch n := n.(type) {
+		case *ast.CodeBlock, *ast.FencedCodeBlock, *ast.HTMLBlock, *ast.RawHTML, *ast.AutoLink:
+			return ast.WalkSkipChildren, nil
+
+		case *ast.Text:
+			text.Write(n.Segment.Value(source))
+			if n.SoftLineBreak() || n.HardLineBreak() {
+				text.WriteByte('\n')
+			}
+
+		case *ast.String:
+			if !n.IsCode() {
+				text.Write(n.Value)
+			}
+
+		case *extensions.Tags:
+			// Each tag counts as a single word.
+			for range n.Tags {
+				text.WriteString(" #tag ")
+			}
+		}
+
+		return ast.WalkContinue, nil
+	})
+
+	return strutil.CountWords(text.String())
+}
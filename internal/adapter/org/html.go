@@ -0,0 +1,256 @@
+package org
+
+import (
+	"fmt"
+	"html"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/mvdan/xurls"
+	"github.com/zk-org/zk/internal/core"
+)
+
+var (
+	// An item of a plain list, e.g. - item or 1. item
+	listItemRegex = regexp.MustCompile(`^\s*([-+]|\d+[.)])\s+(.*)$`)
+	// The start of a source block with its language, e.g. #+BEGIN_SRC go
+	srcBlockRegex = regexp.MustCompile(`(?i)^\s*#\+begin_src\s+(\S+)`)
+)
+
+// imageExtensions are the file extensions of the links rendered as inline
+// images, when they don't have a description.
+var imageExtensions = map[string]bool{
+	".gif":  true,
+	".jpeg": true,
+	".jpg":  true,
+	".png":  true,
+	".svg":  true,
+	".webp": true,
+}
+
+// RenderNoteHTML implements core.NoteHTMLRenderer.
+//
+// Only a subset of Org-mode is supported: the title, headlines, paragraphs,
+// plain lists, blocks and links. The keywords, comments and drawers are not
+// rendered.
+func (p *Parser) RenderNoteHTML(content string, resolver core.HTMLLinkResolver) (string, error) {
+	w := &htmlWriter{resolver: resolver}
+
+	// Name of the current block, e.g. src, and whether a drawer is open.
+	block := ""
+	blockLang := ""
+	blockLines := []string{}
+	drawer := false
+
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimRight(line, "\r")
+
+		if block != "" {
+			if isBlockEnd(line, block) {
+				w.writeBlock(block, blockLang, blockLines)
+				block = ""
+			} else {
+				blockLines = append(blockLines, line)
+			}
+			continue
+		}
+
+		if drawer {
+			drawer = !strings.EqualFold(strings.TrimSpace(line), ":END:")
+			continue
+		}
+
+		if strings.TrimSpace(line) == "" {
+			w.endList()
+			continue
+		}
+
+		if m := blockRegex.FindStringSubmatch(line); m != nil {
+			w.endList()
+			block = strings.ToLower(m[1])
+			blockLang = ""
+			if m := srcBlockRegex.FindStringSubmatch(line); m != nil {
+				blockLang = m[1]
+			}
+			blockLines = []string{}
+			continue
+		}
+		if m := keywordRegex.FindStringSubmatch(line); m != nil {
+			w.endList()
+			if strings.EqualFold(m[1], "title") && m[2] != "" {
+				fmt.Fprintf(&w.out, "<h1>%s</h1>\n", w.inline(m[2]))
+			}
+			continue
+		}
+		if commentRegex.MatchString(line) {
+			w.endList()
+			continue
+		}
+		if drawerRegex.MatchString(line) {
+			w.endList()
+			drawer = true
+			continue
+		}
+
+		if m := headlineRegex.FindStringSubmatch(line); m != nil {
+			w.endList()
+			level := strings.IndexFunc(line, func(r rune) bool { return r != '*' }) + 1
+			if level > 6 {
+				level = 6
+			}
+			fmt.Fprintf(&w.out, "<h%d>%s", level, w.inline(m[1]))
+			for _, tag := range splitTags(m[2]) {
+				fmt.Fprintf(&w.out, ` <span class="tag">#%s</span>`, html.EscapeString(tag))
+			}
+			fmt.Fprintf(&w.out, "</h%d>\n", level)
+			continue
+		}
+
+		if m := listItemRegex.FindStringSubmatch(line); m != nil {
+			w.addListItem(m[1], m[2])
+			continue
+		}
+		if w.list != "" && strings.TrimLeft(line, " \t") != line {
+			// An indented line continues the current list item.
+			w.paragraph = append(w.paragraph, strings.TrimSpace(line))
+			continue
+		}
+
+		if w.list != "" {
+			w.endList()
+		}
+		w.paragraph = append(w.paragraph, strings.TrimSpace(line))
+	}
+	if block != "" {
+		w.writeBlock(block, blockLang, blockLines)
+	}
+	w.endList()
+
+	return w.out.String(), nil
+}
+
+// htmlWriter accumulates the HTML rendering of an Org-mode note.
+type htmlWriter struct {
+	resolver core.HTMLLinkResolver
+	out      strings.Builder
+	// Lines of the current paragraph or list item.
+	paragraph []string
+	// Tag of the current plain list, e.g. ul or ol.
+	list string
+}
+
+// endParagraph writes the current paragraph, or list item.
+func (w *htmlWriter) endParagraph() {
+	if len(w.paragraph) == 0 {
+		return
+	}
+	text := w.inline(strings.Join(w.paragraph, "\n"))
+	if w.list != "" {
+		fmt.Fprintf(&w.out, "<li>%s</li>\n", text)
+	} else {
+		fmt.Fprintf(&w.out, "<p>%s</p>\n", text)
+	}
+	w.paragraph = nil
+}
+
+// addListItem starts a new item with the given bullet, e.g. - or 1.
+func (w *htmlWriter) addListItem(bullet string, text string) {
+	list := "ul"
+	if bullet != "-" && bullet != "+" {
+		list = "ol"
+	}
+	if w.list != list {
+		w.endList()
+		w.list = list
+		fmt.Fprintf(&w.out, "<%s>\n", list)
+	}
+	w.endParagraph()
+	w.paragraph = []string{text}
+}
+
+// endList closes the current paragraph and plain list.
+func (w *htmlWriter) endList() {
+	w.endParagraph()
+	if w.list != "" {
+		fmt.Fprintf(&w.out, "</%s>\n", w.list)
+		w.list = ""
+	}
+}
+
+// writeBlock writes a block, which is preformatted unless it is a quote.
+func (w *htmlWriter) writeBlock(block string, lang string, lines []string) {
+	w.endList()
+	if block == "quote" {
+		fmt.Fprintf(&w.out, "<blockquote>\n<p>%s</p>\n</blockquote>\n", w.inline(strings.TrimSpace(strings.Join(lines, "\n"))))
+		return
+	}
+
+	class := ""
+	if lang != "" {
+		class = fmt.Sprintf(` class="language-%s"`, html.EscapeString(lang))
+	}
+	code := html.EscapeString(strings.Join(lines, "\n"))
+	if code != "" {
+		code += "\n"
+	}
+	fmt.Fprintf(&w.out, "<pre><code%s>%s</code></pre>\n", class, code)
+}
+
+// inline renders the bracket links and URLs of the text, escaping the
+// rest of it.
+func (w *htmlWriter) inline(text string) string {
+	var out strings.Builder
+	last := 0
+	for _, loc := range linkRegex.FindAllStringSubmatchIndex(text, -1) {
+		out.WriteString(w.urls(text[last:loc[0]]))
+		last = loc[1]
+
+		target := text[loc[2]:loc[3]]
+		description := ""
+		if loc[4] >= 0 {
+			description = text[loc[4]:loc[5]]
+		}
+		out.WriteString(w.link(target, description))
+	}
+	out.WriteString(w.urls(text[last:]))
+	return out.String()
+}
+
+// link renders a bracket link, which is a dead link when it doesn't target
+// an exported note.
+func (w *htmlWriter) link(target string, description string) string {
+	link, ok := parseLink(target, description)
+	if !ok {
+		// An internal link to a headline, e.g. [[*Heading]].
+		if description == "" {
+			description = strings.TrimPrefix(target, "*")
+		}
+		return html.EscapeString(description)
+	}
+
+	if description == "" && imageExtensions[strings.ToLower(filepath.Ext(link.Href))] {
+		src := w.resolver.ResolveImage(link.Href)
+		return fmt.Sprintf(`<img src="%s" alt="">`, html.EscapeString(src))
+	}
+
+	label := html.EscapeString(link.Title)
+	if dest, ok := w.resolver.ResolveLink(link.Href, link.Type); ok {
+		return fmt.Sprintf(`<a href="%s">%s</a>`, html.EscapeString(dest), label)
+	}
+	return `<span class="dead-link">` + label + `</span>`
+}
+
+// urls renders the plain URLs of the text as links.
+func (w *htmlWriter) urls(text string) string {
+	var out strings.Builder
+	last := 0
+	for _, loc := range xurls.Strict.FindAllStringIndex(text, -1) {
+		out.WriteString(html.EscapeString(text[last:loc[0]]))
+		url := html.EscapeString(text[loc[0]:loc[1]])
+		fmt.Fprintf(&out, `<a href="%s">%s</a>`, url, url)
+		last = loc[1]
+	}
+	out.WriteString(html.EscapeString(text[last:]))
+	return out.String()
+}
@@ -0,0 +1,74 @@
+package org
+
+import (
+	"testing"
+
+	"github.com/zk-org/zk/internal/core"
+	"github.com/zk-org/zk/internal/util"
+	"github.com/zk-org/zk/internal/util/test/assert"
+)
+
+func TestRenderNoteHTML(t *testing.T) {
+	test := func(source string, expected string) {
+		resolver := &htmlLinkResolverMock{
+			links: map[string]string{
+				"sun.org":              "sun.html",
+				"../soil/sand.org":     "../soil/sand.html",
+				"id:5f2e-vegetables":   "vegetables.html",
+				"https://example.com/": "https://example.com/",
+			},
+			images: map[string]string{
+				"images/tomato.svg": "assets/tomato.svg",
+			},
+		}
+		html, err := NewParser(ParserOpts{}, &util.NullLogger).RenderNoteHTML(source, resolver)
+		assert.Nil(t, err)
+		assert.Equal(t, html, expected)
+	}
+
+	// The keywords, comments and drawers are not rendered, except the title.
+	test(":PROPERTIES:\n:ID: 5f2e\n:END:\n#+TITLE: Title\n#+FILETAGS: :garden:\n\nParagraph", "<h1>Title</h1>\n<p>Paragraph</p>\n")
+	test("# A comment\nParagraph\non two lines", "<p>Paragraph\non two lines</p>\n")
+	test("A <b>paragraph</b> & more", "<p>A &lt;b&gt;paragraph&lt;/b&gt; &amp; more</p>\n")
+
+	// Headlines
+	test("* Tomatoes :plant:summer:\nText\n** Cherry", "<h2>Tomatoes <span class=\"tag\">#plant</span> <span class=\"tag\">#summer</span></h2>\n<p>Text</p>\n<h3>Cherry</h3>\n")
+
+	// Lists
+	test("- one\n- two\n  continued\n\n1. first\n2. second", "<ul>\n<li>one</li>\n<li>two\ncontinued</li>\n</ul>\n<ol>\n<li>first</li>\n<li>second</li>\n</ol>\n")
+
+	// Blocks
+	test("#+BEGIN_SRC sh\necho \"<hi>\"\n#+END_SRC", "<pre><code class=\"language-sh\">echo &#34;&lt;hi&gt;&#34;\n</code></pre>\n")
+	test("#+begin_quote\nA [[file:sun.org][quote]]\n#+end_quote", "<blockquote>\n<p>A <a href=\"sun.html\">quote</a></p>\n</blockquote>\n")
+
+	// Links
+	test("See [[file:sun.org][the sun]]", "<p>See <a href=\"sun.html\">the sun</a></p>\n")
+	test("See [[file:../soil/sand.org::*Drainage]]", "<p>See <a href=\"../soil/sand.html\">../soil/sand.org</a></p>\n")
+	test("See [[id:5f2e-vegetables][vegetables]]", "<p>See <a href=\"vegetables.html\">vegetables</a></p>\n")
+	test("See [[id:8a1c-watering]]", "<p>See <span class=\"dead-link\">id:8a1c-watering</span></p>\n")
+	test("See [[*Tomatoes][the tomatoes]] and [[*Carrots]]", "<p>See the tomatoes and Carrots</p>\n")
+	test("See https://example.com/", "<p>See <a href=\"https://example.com/\">https://example.com/</a></p>\n")
+
+	// Images
+	test("[[file:images/tomato.svg]]", "<p><img src=\"assets/tomato.svg\" alt=\"\"></p>\n")
+	test("[[file:images/tomato.svg][A tomato]]", "<p><span class=\"dead-link\">A tomato</span></p>\n")
+}
+
+// htmlLinkResolverMock resolves the links and images from static maps. The
+// links not found are dead, while the images not found are left unchanged.
+type htmlLinkResolverMock struct {
+	links  map[string]string
+	images map[string]string
+}
+
+func (m *htmlLinkResolverMock) ResolveLink(href string, linkType core.LinkType) (string, bool) {
+	dest, ok := m.links[href]
+	return dest, ok
+}
+
+func (m *htmlLinkResolverMock) ResolveImage(src string) string {
+	if dest, ok := m.images[src]; ok {
+		return dest
+	}
+	return src
+}
@@ -0,0 +1,384 @@
+package org
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/mvdan/xurls"
+	"github.com/zk-org/zk/internal/core"
+	"github.com/zk-org/zk/internal/util"
+	"github.com/zk-org/zk/internal/util/opt"
+	strutil "github.com/zk-org/zk/internal/util/strings"
+)
+
+// Parser parses the content of Org-mode notes.
+type Parser struct {
+	lowercaseTags bool
+	logger        util.Logger
+}
+
+type ParserOpts struct {
+	// Indicates whether the tags are converted to lowercase.
+	LowercaseTags bool
+}
+
+// NewParser creates a new Org-mode Parser.
+func NewParser(options ParserOpts, logger util.Logger) *Parser {
+	return &Parser{
+		lowercaseTags: options.LowercaseTags,
+		logger:        logger,
+	}
+}
+
+var (
+	// An in-buffer setting, e.g. #+TITLE: A title
+	keywordRegex = regexp.MustCompile(`^\s*#\+(\w+):\s*(.*?)\s*$`)
+	// The start of a block, e.g. #+BEGIN_SRC go
+	blockRegex = regexp.MustCompile(`(?i)^\s*#\+begin_(\w+)`)
+	// A comment line, e.g. # A comment
+	commentRegex = regexp.MustCompile(`^\s*#(\s|$)`)
+	// The start of a drawer, e.g. :PROPERTIES:
+	drawerRegex = regexp.MustCompile(`^\s*:([\w-]+):\s*$`)
+	// A property of a property drawer, e.g. :ID: 1234
+	propertyRegex = regexp.MustCompile(`^\s*:([\w-]+)\+?:\s*(.*?)\s*$`)
+	// A headline with its optional tags, e.g. ** Heading :tag1:tag2:
+	headlineRegex = regexp.MustCompile(`^\*+\s+(.*?)(?:\s+(:(?:[\w@#%]+:)+))?\s*$`)
+	// A bracket link with an optional description, e.g. [[file:foo.org][label]]
+	linkRegex = regexp.MustCompile(`\[\[([^\[\]]+)\](?:\[([^\[\]]+)\])?\]`)
+	// An Org-mode timestamp, e.g. <2021-03-01 Mon 10:30>
+	timestampRegex = regexp.MustCompile(`^[<\[](\d{4}-\d{2}-\d{2})(?:\s+[^\s\d>\]]+)?(?:\s+(\d{2}:\d{2}))?[>\]]$`)
+)
+
+// proseBlocks are the kinds of blocks whose content is part of the prose.
+var proseBlocks = map[string]bool{
+	"center": true,
+	"quote":  true,
+	"verse":  true,
+}
+
+// ParseNoteContent implements core.NoteContentParser.
+//
+// The title is read from the #+TITLE keyword, ignoring the headlines. The
+// tags are collected from the #+FILETAGS keyword and the headline tags.
+// Keywords and the properties of the drawer preceding the first headline are
+// available as metadata.
+func (p *Parser) ParseNoteContent(content string) (*core.NoteContent, error) {
+	s := newScanner(content)
+	s.scan()
+
+	tags := s.tags
+	if p.lowercaseTags {
+		for i, tag := range tags {
+			tags[i] = strings.ToLower(tag)
+		}
+	}
+
+	return &core.NoteContent{
+		Title:     opt.NewNotEmptyString(s.metadataString("title")),
+		Body:      opt.NewNotEmptyString(strings.TrimSpace(content[s.bodyStart:])),
+		Lead:      opt.NewNotEmptyString(s.lead),
+		Links:     s.links,
+		Tags:      strutil.RemoveDuplicates(tags),
+		Aliases:   []string{},
+		Metadata:  s.metadata,
+		WordCount: strutil.CountWords(s.prose.String()),
+	}, nil
+}
+
+// scanner reads an Org-mode document line by line.
+type scanner struct {
+	source   string
+	metadata map[string]interface{}
+	tags     []string
+	links    []core.Link
+	// Text of the prose, used to count the words.
+	prose strings.Builder
+	lead  string
+
+	// Position of the content following the leading keywords and file
+	// properties.
+	bodyStart int
+	// Whether a headline was found, ending the file-level section.
+	inOutline bool
+
+	// Start and end positions of the current paragraph.
+	paragraphStart int
+	paragraphEnd   int
+	paragraphLines []string
+	// Whether the current paragraph is a headline.
+	isHeadline bool
+	// Index of the first link of the current paragraph.
+	paragraphLinks int
+}
+
+func newScanner(source string) *scanner {
+	return &scanner{
+		source:         source,
+		metadata:       map[string]interface{}{},
+		tags:           []string{},
+		links:          []core.Link{},
+		bodyStart:      -1,
+		paragraphStart: -1,
+	}
+}
+
+func (s *scanner) scan() {
+	// Name of the current block or drawer, e.g. src or PROPERTIES.
+	block := ""
+	drawer := ""
+
+	start := 0
+	for _, rawLine := range strings.SplitAfter(s.source, "\n") {
+		lineStart := start
+		start += len(rawLine)
+		line := strings.TrimRight(rawLine, "\r\n")
+
+		if block != "" {
+			if isBlockEnd(line, block) {
+				block = ""
+				s.endParagraph()
+			} else if proseBlocks[block] {
+				s.addProse(line, lineStart)
+			}
+			continue
+		}
+
+		if drawer != "" {
+			if strings.EqualFold(strings.TrimSpace(line), ":END:") {
+				drawer = ""
+			} else if drawer == "PROPERTIES" && !s.inOutline {
+				if m := propertyRegex.FindStringSubmatch(line); m != nil {
+					s.setMetadata(m[1], m[2])
+				}
+			}
+			continue
+		}
+
+		if strings.TrimSpace(line) == "" {
+			s.endParagraph()
+			continue
+		}
+
+		if m := keywordRegex.FindStringSubmatch(line); m != nil {
+			s.endParagraph()
+			s.addKeyword(m[1], m[2])
+			continue
+		}
+		if commentRegex.MatchString(line) {
+			s.endParagraph()
+			continue
+		}
+		if m := drawerRegex.FindStringSubmatch(line); m != nil {
+			s.endParagraph()
+			drawer = strings.ToUpper(m[1])
+			continue
+		}
+
+		// Any other line is part of the body.
+		if s.bodyStart < 0 {
+			s.bodyStart = lineStart
+		}
+
+		if m := blockRegex.FindStringSubmatch(line); m != nil {
+			s.endParagraph()
+			block = strings.ToLower(m[1])
+			continue
+		}
+
+		if m := headlineRegex.FindStringSubmatch(line); m != nil {
+			s.inOutline = true
+			s.endParagraph()
+			for _, tag := range splitTags(m[2]) {
+				s.tags = append(s.tags, tag)
+				// Each tag counts as a single word.
+				s.prose.WriteString(" #tag ")
+			}
+			// A headline is a paragraph of its own.
+			s.isHeadline = true
+			s.addProse(m[1], lineStart+strings.Index(line, m[1]))
+			s.endParagraph()
+			continue
+		}
+
+		s.addProse(line, lineStart)
+	}
+	s.endParagraph()
+
+	if s.bodyStart < 0 {
+		s.bodyStart = len(s.source)
+	}
+}
+
+// addKeyword saves the value of an in-buffer setting, e.g. #+TITLE.
+func (s *scanner) addKeyword(key string, value string) {
+	switch strings.ToLower(key) {
+	case "filetags":
+		s.tags = append(s.tags, splitTags(value)...)
+	case "date":
+		value = normalizeTimestamp(value)
+	}
+	s.setMetadata(key, value)
+}
+
+// setMetadata saves a metadata value under its lowercase key, unless it was
+// already set.
+func (s *scanner) setMetadata(key string, value string) {
+	key = strings.ToLower(key)
+	if _, ok := s.metadata[key]; !ok {
+		s.metadata[key] = value
+	}
+}
+
+func (s *scanner) metadataString(key string) string {
+	value, _ := s.metadata[key].(string)
+	return value
+}
+
+// addProse adds a line of text starting at the given position to the current
+// paragraph.
+func (s *scanner) addProse(line string, lineStart int) {
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "" {
+		return
+	}
+	if s.paragraphStart < 0 {
+		s.paragraphStart = lineStart + strings.Index(line, trimmed)
+		s.paragraphLinks = len(s.links)
+	}
+	s.paragraphEnd = lineStart + len(strings.TrimRightFunc(line, unicode.IsSpace))
+	s.paragraphLines = append(s.paragraphLines, trimmed)
+
+	// The bracket links are replaced by their description when counting the
+	// words, while the URLs are ignored.
+	text := linkRegex.ReplaceAllStringFunc(line, func(match string) string {
+		m := linkRegex.FindStringSubmatch(match)
+		link, ok := parseLink(m[1], m[2])
+		if ok {
+			s.links = append(s.links, link)
+		}
+		switch {
+		case m[2] != "":
+			return " " + m[2] + " "
+		case link.IsExternal:
+			return " "
+		default:
+			return " " + link.Title + " "
+		}
+	})
+
+	text = xurls.Strict.ReplaceAllStringFunc(text, func(url string) string {
+		s.links = append(s.links, core.Link{
+			Title:      url,
+			Href:       url,
+			Type:       core.LinkTypeImplicit,
+			Rels:       []core.LinkRelation{},
+			IsExternal: true,
+		})
+		return " "
+	})
+
+	s.prose.WriteString(text)
+	s.prose.WriteByte('\n')
+}
+
+// endParagraph closes the current paragraph, setting it as the snippet of
+// its links.
+func (s *scanner) endParagraph() {
+	if s.paragraphStart < 0 {
+		return
+	}
+
+	snippet := s.source[s.paragraphStart:s.paragraphEnd]
+	for i := s.paragraphLinks; i < len(s.links); i++ {
+		s.links[i].Snippet = snippet
+		s.links[i].SnippetStart = s.paragraphStart
+		s.links[i].SnippetEnd = s.paragraphEnd
+	}
+
+	if s.lead == "" && !s.isHeadline {
+		s.lead = truncateLead(strings.Join(s.paragraphLines, "\n"))
+	}
+
+	s.paragraphStart = -1
+	s.paragraphLines = nil
+	s.isHeadline = false
+}
+
+// parseLink converts the target of a bracket link to a note link. Only the
+// links to files, IDs and URLs are indexed, not the internal links to the
+// headlines of the note.
+func parseLink(target string, description string) (link core.Link, ok bool) {
+	link = core.Link{
+		Title: description,
+		Rels:  []core.LinkRelation{},
+	}
+
+	switch {
+	case strings.HasPrefix(target, "file:"):
+		// Drops any search option, e.g. file:foo.org::*Heading
+		link.Href = strings.SplitN(strings.TrimPrefix(target, "file:"), "::", 2)[0]
+		link.Type = core.LinkTypeMarkdown
+	case strings.HasPrefix(target, "./") || strings.HasPrefix(target, "../"):
+		link.Href = strings.SplitN(target, "::", 2)[0]
+		link.Type = core.LinkTypeMarkdown
+	case strings.HasPrefix(target, "id:"):
+		link.Href = target
+		link.Type = core.LinkTypeWikiLink
+	case strutil.IsURL(target):
+		link.Href = target
+		link.Type = core.LinkTypeMarkdown
+		link.IsExternal = true
+	default:
+		return core.Link{Title: target}, false
+	}
+
+	if link.Title == "" {
+		link.Title = link.Href
+	}
+	ok = link.Href != ""
+	return
+}
+
+// splitTags returns the tags of a :tag1:tag2: list, which may also be
+// separated by spaces in #+FILETAGS.
+func splitTags(tags string) []string {
+	return strutil.RemoveBlank(strings.FieldsFunc(tags, func(r rune) bool {
+		return r == ':' || unicode.IsSpace(r)
+	}))
+}
+
+// isBlockEnd returns whether the line ends the block with the given name,
+// e.g. #+END_SRC.
+func isBlockEnd(line string, block string) bool {
+	return strings.EqualFold(strings.TrimSpace(line), "#+end_"+block)
+}
+
+// normalizeTimestamp converts an Org-mode timestamp to a date which can be
+// parsed as the creation date of the note, e.g. 2021-03-01 10:30 for
+// <2021-03-01 Mon 10:30>.
+func normalizeTimestamp(value string) string {
+	m := timestampRegex.FindStringSubmatch(value)
+	if m == nil {
+		return value
+	}
+	if m[2] == "" {
+		return m[1]
+	}
+	return m[1] + " " + m[2]
+}
+
+// leadMaxLength is the maximum number of characters of a note lead.
+const leadMaxLength = 500
+
+// truncateLead truncates a lead longer than leadMaxLength on a word boundary.
+func truncateLead(lead string) string {
+	if runes := []rune(lead); len(runes) > leadMaxLength {
+		lead = string(runes[:leadMaxLength])
+		if i := strings.LastIndexFunc(lead, unicode.IsSpace); i > 0 {
+			lead = lead[:i]
+		}
+		lead = strings.TrimSpace(lead) + "…"
+	}
+	return lead
+}
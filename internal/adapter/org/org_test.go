@@ -0,0 +1,244 @@
+package org
+
+import (
+	"testing"
+
+	"github.com/zk-org/zk/internal/core"
+	"github.com/zk-org/zk/internal/util"
+	"github.com/zk-org/zk/internal/util/opt"
+	"github.com/zk-org/zk/internal/util/test/assert"
+)
+
+const sampleNote = `:PROPERTIES:
+:ID:       5f2e-vegetables
+:END:
+#+TITLE: Growing vegetables
+#+DATE: <2021-03-01 Mon>
+#+FILETAGS: :garden:
+
+Tomatoes need a lot of sun, see [[file:sun.org][the sun notes]].
+
+* Tomatoes :plant:summer:
+:PROPERTIES:
+:ID:       ignored
+:END:
+Water them with [[id:8a1c-watering]] every morning.
+Read [[https://example.com/tomatoes][this guide]] or https://example.com/more.
+
+#+BEGIN_SRC sh
+water --plants [[file:ignored.org]]
+#+END_SRC
+
+# A comment with [[file:ignored.org]]
+* Carrots
+They grow in [[file:../soil/sand.org::*Drainage]] or [[*Tomatoes][next to tomatoes]].
+`
+
+func TestParseSampleNote(t *testing.T) {
+	content := parse(t, sampleNote)
+
+	assert.Equal(t, content.Title, opt.NewString("Growing vegetables"))
+	assert.Equal(t, content.Lead, opt.NewString("Tomatoes need a lot of sun, see [[file:sun.org][the sun notes]]."))
+	assert.Equal(t, content.Tags, []string{"garden", "plant", "summer"})
+	assert.Equal(t, content.Metadata, map[string]interface{}{
+		"id":       "5f2e-vegetables",
+		"title":    "Growing vegetables",
+		"date":     "2021-03-01",
+		"filetags": ":garden:",
+	})
+
+	assert.Equal(t, content.Links, []core.Link{
+		{
+			Title:        "the sun notes",
+			Href:         "sun.org",
+			Type:         core.LinkTypeMarkdown,
+			Rels:         []core.LinkRelation{},
+			Snippet:      "Tomatoes need a lot of sun, see [[file:sun.org][the sun notes]].",
+			SnippetStart: 121,
+			SnippetEnd:   185,
+		},
+		{
+			Title:        "id:8a1c-watering",
+			Href:         "id:8a1c-watering",
+			Type:         core.LinkTypeWikiLink,
+			Rels:         []core.LinkRelation{},
+			Snippet:      "Water them with [[id:8a1c-watering]] every morning.\nRead [[https://example.com/tomatoes][this guide]] or https://example.com/more.",
+			SnippetStart: 251,
+			SnippetEnd:   381,
+		},
+		{
+			Title:        "this guide",
+			Href:         "https://example.com/tomatoes",
+			Type:         core.LinkTypeMarkdown,
+			Rels:         []core.LinkRelation{},
+			IsExternal:   true,
+			Snippet:      "Water them with [[id:8a1c-watering]] every morning.\nRead [[https://example.com/tomatoes][this guide]] or https://example.com/more.",
+			SnippetStart: 251,
+			SnippetEnd:   381,
+		},
+		{
+			Title:        "https://example.com/more",
+			Href:         "https://example.com/more",
+			Type:         core.LinkTypeImplicit,
+			Rels:         []core.LinkRelation{},
+			IsExternal:   true,
+			Snippet:      "Water them with [[id:8a1c-watering]] every morning.\nRead [[https://example.com/tomatoes][this guide]] or https://example.com/more.",
+			SnippetStart: 251,
+			SnippetEnd:   381,
+		},
+		{
+			Title:        "../soil/sand.org",
+			Href:         "../soil/sand.org",
+			Type:         core.LinkTypeMarkdown,
+			Rels:         []core.LinkRelation{},
+			Snippet:      "They grow in [[file:../soil/sand.org::*Drainage]] or [[*Tomatoes][next to tomatoes]].",
+			SnippetStart: 493,
+			SnippetEnd:   578,
+		},
+	})
+
+	// Tomatoes need a lot of sun, see the sun notes. (10)
+	// #plant #summer Tomatoes (3)
+	// Water them with id:8a1c-watering every morning. (6)
+	// Read this guide or. (4)
+	// Carrots (1)
+	// They grow in ../soil/sand.org or next to tomatoes. (8)
+	assert.Equal(t, content.WordCount, 32)
+}
+
+func TestParseTitle(t *testing.T) {
+	test := func(source string, expectedTitle string) {
+		content := parse(t, source)
+		assert.Equal(t, content.Title, opt.NewNotEmptyString(expectedTitle))
+	}
+
+	test("", "")
+	test("#+TITLE: A title", "A title")
+	test("#+title:   A title  ", "A title")
+	test("#+TITLE:", "")
+	test("Paragraph\n#+TITLE: A title lower", "A title lower")
+	test("#+TITLE: First\n#+TITLE: Second", "First")
+	// The headlines are not used as title.
+	test("* A headline\nBody", "")
+	test("* A headline\n#+TITLE: A title", "A title")
+	test("#+BEGIN_EXAMPLE\n#+TITLE: In a block\n#+END_EXAMPLE", "")
+}
+
+func TestParseBody(t *testing.T) {
+	test := func(source string, expectedBody string, expectedLead string) {
+		content := parse(t, source)
+		assert.Equal(t, content.Body, opt.NewNotEmptyString(expectedBody))
+		assert.Equal(t, content.Lead, opt.NewNotEmptyString(expectedLead))
+	}
+
+	test("", "", "")
+	test("#+TITLE: Title only", "", "")
+	test("#+TITLE: Title\n\nLead\nparagraph\n\nBody", "Lead\nparagraph\n\nBody", "Lead\nparagraph")
+	test(":PROPERTIES:\n:ID: 1\n:END:\n# Comment\n#+TITLE: Title\n\nBody", "Body", "Body")
+	// The headlines are part of the body, but not of the lead.
+	test("#+TITLE: Title\n* Heading\n  Indented text\n** Sub", "* Heading\n  Indented text\n** Sub", "Indented text")
+	test("#+TITLE: Title\n#+BEGIN_QUOTE\nQuoted\n#+END_QUOTE\nAfter", "#+BEGIN_QUOTE\nQuoted\n#+END_QUOTE\nAfter", "Quoted")
+}
+
+func TestParseTags(t *testing.T) {
+	test := func(source string, expectedTags []string) {
+		content := parse(t, source)
+		assert.Equal(t, content.Tags, expectedTags)
+	}
+
+	test("", []string{})
+	test("* Heading", []string{})
+	test("* Heading :tag:", []string{"tag"})
+	test("** TODO [#A] Heading   :tag1:tag2:", []string{"tag1", "tag2"})
+	test("* Heading :with@special#chars%:", []string{"with@special#chars%"})
+	test("* Heading :not tags:", []string{})
+	test("* Heading:not-tags:", []string{})
+	test("A paragraph :not:tags:", []string{})
+	test("#+FILETAGS: :a:b:", []string{"a", "b"})
+	test("#+FILETAGS: a b", []string{"a", "b"})
+	test("#+FILETAGS: :a:\n* One :a:b:\n* Two :b:c:", []string{"a", "b", "c"})
+}
+
+func TestParseLowercaseTags(t *testing.T) {
+	content, err := NewParser(ParserOpts{LowercaseTags: true}, &util.NullLogger).
+		ParseNoteContent("#+FILETAGS: :Garden:\n* Heading :Plant:")
+	assert.Nil(t, err)
+	assert.Equal(t, content.Tags, []string{"garden", "plant"})
+}
+
+func TestParseLinks(t *testing.T) {
+	test := func(source string, expectedHrefs []string) {
+		content := parse(t, source)
+		hrefs := []string{}
+		for _, link := range content.Links {
+			hrefs = append(hrefs, link.Href)
+		}
+		assert.Equal(t, hrefs, expectedHrefs)
+	}
+
+	test("", []string{})
+	test("[[file:foo.org]]", []string{"foo.org"})
+	test("[[file:foo.org][label]] and [[file:dir/bar.org][other]]", []string{"foo.org", "dir/bar.org"})
+	test("[[file:foo.org::*Heading][label]]", []string{"foo.org"})
+	test("[[./foo.org]] [[../bar.org]]", []string{"./foo.org", "../bar.org"})
+	test("[[id:1234-abcd]]", []string{"id:1234-abcd"})
+	test("[[https://example.com]]", []string{"https://example.com"})
+	test("Visit https://example.com", []string{"https://example.com"})
+	test("* Heading with [[file:foo.org]] :tag:", []string{"foo.org"})
+	test("#+BEGIN_QUOTE\nQuoting [[file:foo.org]]\n#+END_QUOTE", []string{"foo.org"})
+	// Internal links to the headlines of the note are not indexed.
+	test("[[*Heading]] [[#custom-id]] [[Fuzzy]]", []string{})
+	test("#+BEGIN_SRC\n[[file:foo.org]]\n#+END_SRC", []string{})
+	test("# [[file:foo.org]]", []string{})
+	test(":LOGBOOK:\n[[file:foo.org]]\n:END:", []string{})
+}
+
+func TestParseMetadata(t *testing.T) {
+	test := func(source string, expectedMetadata map[string]interface{}) {
+		content := parse(t, source)
+		assert.Equal(t, content.Metadata, expectedMetadata)
+	}
+
+	test("", map[string]interface{}{})
+	test("#+AUTHOR: Jane\n#+Custom_Key: value", map[string]interface{}{
+		"author":     "Jane",
+		"custom_key": "value",
+	})
+	test(":PROPERTIES:\n:ID: 1234\n:ROAM_ALIASES: Vegetables\n:END:", map[string]interface{}{
+		"id":           "1234",
+		"roam_aliases": "Vegetables",
+	})
+	// The properties of the headlines are not note metadata.
+	test("* Heading\n:PROPERTIES:\n:ID: 1234\n:END:", map[string]interface{}{})
+
+	// The dates are converted from Org-mode timestamps.
+	test("#+DATE: <2021-03-01 Mon>", map[string]interface{}{"date": "2021-03-01"})
+	test("#+DATE: [2021-03-01 Mon 10:30]", map[string]interface{}{"date": "2021-03-01 10:30"})
+	test("#+DATE: 2021-03-01", map[string]interface{}{"date": "2021-03-01"})
+	test("#+DATE: yesterday", map[string]interface{}{"date": "yesterday"})
+}
+
+func TestParseWordCount(t *testing.T) {
+	test := func(source string, expected int) {
+		content := parse(t, source)
+		assert.Equal(t, content.WordCount, expected)
+	}
+
+	test("", 0)
+	test("One two three", 3)
+	test("#+TITLE: Not counted\n#+AUTHOR: Jane\n# A comment\nCounted", 1)
+	test(":PROPERTIES:\n:ID: 1234\n:END:\nCounted", 1)
+	test("* A heading :tag1:tag2:", 4)
+	test("A [[file:foo.org][labelled link]]", 3)
+	test("A URL https://example.com/path", 2)
+	test("A [[https://example.com/path]]", 1)
+	test("#+BEGIN_SRC go\nfunc main() {}\n#+END_SRC\nText", 1)
+	test("#+BEGIN_QUOTE\nQuoted text\n#+END_QUOTE", 2)
+	test("- item one\n- item two", 4)
+}
+
+func parse(t *testing.T, source string) core.NoteContent {
+	content, err := NewParser(ParserOpts{}, &util.NullLogger).ParseNoteContent(source)
+	assert.Nil(t, err)
+	return *content
+}
@@ -0,0 +1,110 @@
+package sqlite
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/zk-org/zk/internal/util"
+	"github.com/zk-org/zk/internal/util/errors"
+)
+
+// CheckOpts holds the options used to check the health of the database.
+type CheckOpts struct {
+	// When true, the full-text search index is rebuilt if it doesn't match
+	// the notes.
+	Repair bool
+}
+
+// CheckReport holds the inconsistencies found while checking the database.
+type CheckReport struct {
+	// Problems reported by the SQLite integrity check.
+	IntegrityErrors []string
+	// Number of notes missing from the full-text search index.
+	MissingFTSCount int
+	// Number of full-text search entries without a matching note.
+	OrphanFTSCount int
+	// Number of links referencing a note which doesn't exist.
+	OrphanLinkCount int
+	// Indicates whether the full-text search index was rebuilt.
+	Repaired bool
+}
+
+// IsHealthy returns whether no inconsistency was found, not counting the
+// ones which were repaired.
+func (r CheckReport) IsHealthy() bool {
+	return len(r.IntegrityErrors) == 0 &&
+		r.OrphanLinkCount == 0 &&
+		(r.Repaired || (r.MissingFTSCount == 0 && r.OrphanFTSCount == 0))
+}
+
+// String implements Stringer.
+func (r CheckReport) String() string {
+	lines := []string{}
+	for _, err := range r.IntegrityErrors {
+		lines = append(lines, "integrity: "+err)
+	}
+	lines = append(lines,
+		fmt.Sprintf("%d notes missing from the full-text search index", r.MissingFTSCount),
+		fmt.Sprintf("%d orphan full-text search entries", r.OrphanFTSCount),
+		fmt.Sprintf("%d orphan links", r.OrphanLinkCount),
+	)
+	if r.Repaired {
+		lines = append(lines, "the full-text search index was rebuilt")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Check verifies the integrity of the database and the consistency of the
+// full-text search index and links with the notes.
+func (db *DB) Check(opts CheckOpts) (CheckReport, error) {
+	report := CheckReport{}
+
+	err := db.WithTransaction(func(tx Transaction) error {
+		rows, err := tx.Query("PRAGMA integrity_check")
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var res string
+			if err := rows.Scan(&res); err != nil {
+				return err
+			}
+			if res != "ok" {
+				report.IntegrityErrors = append(report.IntegrityErrors, res)
+			}
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		// The rows of an external content FTS table are read from the notes
+		// table, so we compare with the document sizes stored by FTS5 instead.
+		counts := []struct {
+			count *int
+			query string
+		}{
+			{&report.MissingFTSCount, `SELECT COUNT(*) FROM notes WHERE id NOT IN (SELECT id FROM notes_fts_docsize)`},
+			{&report.OrphanFTSCount, `SELECT COUNT(*) FROM notes_fts_docsize WHERE id NOT IN (SELECT id FROM notes)`},
+			{&report.OrphanLinkCount, `SELECT COUNT(*) FROM links
+			  WHERE source_id NOT IN (SELECT id FROM notes)
+			     OR (target_id IS NOT NULL AND target_id NOT IN (SELECT id FROM notes))`},
+		}
+		for _, c := range counts {
+			if err := tx.QueryRow(c.query).Scan(c.count); err != nil {
+				return err
+			}
+		}
+
+		if opts.Repair && (report.MissingFTSCount > 0 || report.OrphanFTSCount > 0) {
+			if err := NewNoteDAO(tx, &util.NullLogger).RebuildFTS(); err != nil {
+				return err
+			}
+			report.Repaired = true
+		}
+
+		return nil
+	})
+
+	return report, errors.Wrap(err, "failed to check the database")
+}
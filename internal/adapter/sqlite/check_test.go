@@ -0,0 +1,82 @@
+package sqlite
+
+import (
+	"context"
+	"testing"
+
+	"github.com/zk-org/zk/internal/util/test/assert"
+)
+
+func TestCheckHealthyDatabase(t *testing.T) {
+	db := testDB(t)
+
+	report, err := db.Check(CheckOpts{})
+	assert.Nil(t, err)
+	assert.Equal(t, report, CheckReport{})
+	assert.True(t, report.IsHealthy())
+}
+
+func TestCheckMissingFTSEntry(t *testing.T) {
+	db := testDB(t)
+	removeFTSEntry(t, db, 3)
+
+	report, err := db.Check(CheckOpts{})
+	assert.Nil(t, err)
+	assert.Equal(t, report.MissingFTSCount, 1)
+	assert.Equal(t, report.OrphanFTSCount, 0)
+	assert.False(t, report.IsHealthy())
+
+	// Checking doesn't repair by default.
+	report, err = db.Check(CheckOpts{})
+	assert.Nil(t, err)
+	assert.Equal(t, report.MissingFTSCount, 1)
+}
+
+func TestCheckRepairsFTS(t *testing.T) {
+	db := testDB(t)
+	removeFTSEntry(t, db, 3)
+
+	report, err := db.Check(CheckOpts{Repair: true})
+	assert.Nil(t, err)
+	assert.Equal(t, report.MissingFTSCount, 1)
+	assert.True(t, report.Repaired)
+	assert.True(t, report.IsHealthy())
+
+	report, err = db.Check(CheckOpts{})
+	assert.Nil(t, err)
+	assert.Equal(t, report, CheckReport{})
+	assertExist(t, db, "SELECT rowid FROM notes_fts WHERE notes_fts MATCH 'zettelkasten'")
+}
+
+func TestCheckOrphanLinks(t *testing.T) {
+	db := testDB(t)
+	// Foreign keys can't be disabled inside a transaction.
+	conn, err := db.db.Conn(context.Background())
+	assert.Nil(t, err)
+	_, err = conn.ExecContext(context.Background(), "PRAGMA foreign_keys = OFF")
+	assert.Nil(t, err)
+	_, err = conn.ExecContext(context.Background(), "UPDATE links SET target_id = 999 WHERE id = 2")
+	assert.Nil(t, err)
+	_, err = conn.ExecContext(context.Background(), "PRAGMA foreign_keys = ON")
+	assert.Nil(t, err)
+	assert.Nil(t, conn.Close())
+
+	report, err := db.Check(CheckOpts{Repair: true})
+	assert.Nil(t, err)
+	assert.Equal(t, report.OrphanLinkCount, 1)
+	assert.False(t, report.Repaired)
+	assert.False(t, report.IsHealthy())
+}
+
+// removeFTSEntry deletes the full-text search entry of a note, without
+// deleting the note.
+func removeFTSEntry(t *testing.T, db *DB, id int) {
+	err := db.WithTransaction(func(tx Transaction) error {
+		_, err := tx.Exec(`
+			INSERT INTO notes_fts(notes_fts, rowid, path, title, body)
+			SELECT 'delete', id, path, title, body FROM notes WHERE id = ?
+		`, id)
+		return err
+	})
+	assert.Nil(t, err)
+}
@@ -21,6 +21,8 @@ type CollectionDAO struct {
 	findAssociationStmt    *LazyStmt
 	createAssociationStmt  *LazyStmt
 	removeAssociationsStmt *LazyStmt
+	removeKindStmt         *LazyStmt
+	findNamesOfNoteStmt    *LazyStmt
 }
 
 // NewCollectionDAO creates a new instance of a DAO working on the given
@@ -59,6 +61,21 @@ func NewCollectionDAO(tx Transaction, logger util.Logger) *CollectionDAO {
 			DELETE FROM notes_collections
 			 WHERE note_id = ?
 		`),
+
+		// Removes the associations of the given note with a kind of collection.
+		removeKindStmt: tx.PrepareLazy(`
+			DELETE FROM notes_collections
+			 WHERE note_id = ?
+			   AND collection_id IN (SELECT id FROM collections WHERE kind = ?)
+		`),
+
+		// Finds the names of the collections of a kind associated with a note.
+		findNamesOfNoteStmt: tx.PrepareLazy(`
+			SELECT c.name FROM collections c
+			  JOIN notes_collections nc ON nc.collection_id = c.id
+			 WHERE nc.note_id = ? AND c.kind = ?
+			 ORDER BY c.name
+		`),
 	}
 }
 
@@ -77,25 +94,68 @@ func (d *CollectionDAO) FindOrCreate(kind core.CollectionKind, name string) (cor
 	}
 }
 
+// FindAll returns all the collections of the given kind, ordered with the
+// given sorters.
 func (d *CollectionDAO) FindAll(kind core.CollectionKind, sorters []core.CollectionSorter) ([]core.Collection, error) {
-	query := `
-		SELECT c.id, c.name, COUNT(nc.id) as count
-		  FROM collections c
-		 INNER JOIN notes_collections nc ON nc.collection_id = c.id
-		 WHERE kind = ?
-		 GROUP BY c.id
-	`
+	return d.Find(kind, core.CollectionFindOpts{Sorters: sorters})
+}
 
-	orderTerms := []string{}
-	if sorters != nil {
-		for _, sorter := range sorters {
-			orderTerms = append(orderTerms, collectionOrderTerm(sorter))
+// Find returns the collections of the given kind matching the given criteria.
+func (d *CollectionDAO) Find(kind core.CollectionKind, opts core.CollectionFindOpts) ([]core.Collection, error) {
+	var query string
+	args := []interface{}{kind}
+
+	if opts.RollUp {
+		// Associates each note with the parents of its nested collections,
+		// e.g. `a/b` and `a` for `a/b/c`. The parent of a name is found by
+		// trimming the characters after its last slash.
+		query = `
+			WITH RECURSIVE associations(note_id, name) AS (
+				SELECT nc.note_id, c.name
+				  FROM notes_collections nc
+				 INNER JOIN collections c ON nc.collection_id = c.id
+				 WHERE c.kind = ?
+				 UNION
+				SELECT note_id, substr(rtrim(name, replace(name, '/', '')), 1, length(rtrim(name, replace(name, '/', ''))) - 1)
+				  FROM associations
+				 WHERE instr(name, '/') > 1
+			)
+			SELECT c.id, c.name, c.count
+			  FROM (
+				SELECT col.id, a.name, COUNT(*) AS count
+				  FROM associations a
+				  LEFT JOIN collections col ON col.kind = ? AND col.name = a.name
+				 GROUP BY a.name
+			  ) c
+		`
+		args = append(args, kind)
+		if len(opts.Globs) > 0 {
+			query += "WHERE " + globExpr("c.name", opts.Globs) + "\n"
+		}
+	} else {
+		query = `
+			SELECT c.id, c.name, COUNT(nc.id) as count
+			  FROM collections c
+			 INNER JOIN notes_collections nc ON nc.collection_id = c.id
+			 WHERE kind = ?
+		`
+		if len(opts.Globs) > 0 {
+			query += "AND " + globExpr("c.name", opts.Globs) + "\n"
 		}
+		query += "GROUP BY c.id\n"
+	}
+	for _, glob := range opts.Globs {
+		args = append(args, glob)
+	}
+
+	orderTerms := []string{}
+	for _, sorter := range opts.Sorters {
+		orderTerms = append(orderTerms, collectionOrderTerm(sorter))
 	}
 	orderTerms = append(orderTerms, `c.name ASC`)
 	query += "ORDER BY " + strings.Join(orderTerms, ", ") + "\n"
 
-	rows, err := d.tx.Query(query, kind)
+	rows, err := d.tx.Query(query, args...)
 	if err != nil {
 		return []core.Collection{}, err
 	}
@@ -123,6 +183,16 @@ func (d *CollectionDAO) FindAll(kind core.CollectionKind, sorters []core.Collect
 	return collections, nil
 }
 
+// globExpr returns an SQL expression matching the given column with any of the
+// glob patterns, given as arguments.
+func globExpr(column string, globs []string) string {
+	exprs := []string{}
+	for range globs {
+		exprs = append(exprs, column+" GLOB ?")
+	}
+	return "(" + strings.Join(exprs, " OR ") + ")"
+}
+
 func collectionOrderTerm(sorter core.CollectionSorter) string {
 	order := " ASC"
 	if !sorter.Ascending {
@@ -248,3 +318,38 @@ func (d *CollectionDAO) RemoveAssociations(noteId core.NoteID) error {
 
 	return nil
 }
+
+// RemoveAssociationsOfKind deletes the associations of the given note with
+// collections of the given kind, keeping the other ones.
+func (d *CollectionDAO) RemoveAssociationsOfKind(noteId core.NoteID, kind core.CollectionKind) error {
+	if !noteId.IsValid() {
+		return fmt.Errorf("Note ID (%d) not valid", noteId)
+	}
+
+	_, err := d.removeKindStmt.Exec(noteId, kind)
+	if err != nil {
+		return errors.Wrapf(err, "failed to remove %s associations of note %d", kind, noteId)
+	}
+
+	return nil
+}
+
+// FindNamesOfNote returns the names of the collections of the given kind
+// associated with a note.
+func (d *CollectionDAO) FindNamesOfNote(noteId core.NoteID, kind core.CollectionKind) ([]string, error) {
+	rows, err := d.findNamesOfNoteStmt.Query(noteId, kind)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to find %s of note %d", kind, noteId)
+	}
+	defer rows.Close()
+
+	names := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
@@ -1,6 +1,7 @@
 package sqlite
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/zk-org/zk/internal/core"
@@ -84,6 +85,76 @@ func TestCollectionDaoFindAllSortedByNoteCount(t *testing.T) {
 	})
 }
 
+func TestCollectionDaoFindGlobs(t *testing.T) {
+	testNestedCollectionDAO(t, func(tx Transaction, dao *CollectionDAO) {
+		cs, err := dao.Find("tag", core.CollectionFindOpts{Globs: []string{"project/*", "sci*"}})
+		assert.Nil(t, err)
+		assert.Equal(t, collectionNames(cs), []string{"project/a (2)", "project/b (2)", "project/b/c (1)", "science (3)"})
+
+		cs, err = dao.Find("tag", core.CollectionFindOpts{Globs: []string{"unknown*"}})
+		assert.Nil(t, err)
+		assert.Equal(t, cs, []core.Collection{})
+	})
+}
+
+func TestCollectionDaoFindRollUp(t *testing.T) {
+	testNestedCollectionDAO(t, func(tx Transaction, dao *CollectionDAO) {
+		cs, err := dao.Find("tag", core.CollectionFindOpts{
+			Globs:  []string{"project*"},
+			RollUp: true,
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, cs, []core.Collection{
+			{ID: 0, Kind: "tag", Name: "project", NoteCount: 4},
+			{ID: 8, Kind: "tag", Name: "project/a", NoteCount: 2},
+			{ID: 9, Kind: "tag", Name: "project/b", NoteCount: 3},
+			{ID: 10, Kind: "tag", Name: "project/b/c", NoteCount: 1},
+		})
+	})
+}
+
+func TestCollectionDaoFindRollUpSortedByNoteCount(t *testing.T) {
+	testNestedCollectionDAO(t, func(tx Transaction, dao *CollectionDAO) {
+		cs, err := dao.Find("tag", core.CollectionFindOpts{
+			RollUp:  true,
+			Sorters: []core.CollectionSorter{{Field: core.CollectionSortNoteCount, Ascending: false}},
+		})
+		assert.Nil(t, err)
+		// Notes tagged several times are counted only once.
+		assert.Equal(t, collectionNames(cs), []string{
+			"project (4)", "project/b (3)", "adventure (2)", "project/a (2)",
+			"science (2)", "fantasy (1)", "fiction (1)", "history (1)", "project/b/c (1)",
+		})
+	})
+}
+
+// testNestedCollectionDAO adds nested tags to the default fixtures.
+func testNestedCollectionDAO(t *testing.T, callback func(tx Transaction, dao *CollectionDAO)) {
+	testCollectionDAO(t, func(tx Transaction, dao *CollectionDAO) {
+		tag := func(name string, noteIDs ...core.NoteID) {
+			id, err := dao.FindOrCreate(core.CollectionKindTag, name)
+			assert.Nil(t, err)
+			for _, noteID := range noteIDs {
+				_, err = dao.Associate(noteID, id)
+				assert.Nil(t, err)
+			}
+		}
+		tag("project/a", 1, 2)
+		tag("project/b", 2, 3)
+		tag("project/b/c", 4)
+
+		callback(tx, dao)
+	})
+}
+
+func collectionNames(collections []core.Collection) []string {
+	names := []string{}
+	for _, c := range collections {
+		names = append(names, fmt.Sprintf("%s (%d)", c.Name, c.NoteCount))
+	}
+	return names
+}
+
 func TestCollectionDAOAssociate(t *testing.T) {
 	testCollectionDAO(t, func(tx Transaction, dao *CollectionDAO) {
 		// Returns existing association
@@ -117,6 +188,42 @@ func TestCollectionDAORemoveAssociations(t *testing.T) {
 	})
 }
 
+func TestCollectionDAORemoveAssociationsOfKind(t *testing.T) {
+	testCollectionDAO(t, func(tx Transaction, dao *CollectionDAO) {
+		sql := "SELECT id FROM notes_collections WHERE note_id = ? AND collection_id = ?"
+		_, err := dao.Associate(core.NoteID(1), core.CollectionID(3))
+		assert.Nil(t, err)
+
+		err = dao.RemoveAssociationsOfKind(core.NoteID(1), core.CollectionKindTag)
+		assert.Nil(t, err)
+		assertNotExistTx(t, tx, sql, 1, 1)
+		assertNotExistTx(t, tx, sql, 1, 2)
+		assertExistTx(t, tx, sql, 1, 3)
+
+		// Other notes are not affected.
+		assertExistTx(t, tx, sql, 5, 2)
+	})
+}
+
+// Removing a note deletes its associations, but keeps the collections.
+func TestCollectionDAORemoveNoteCascadeAssociations(t *testing.T) {
+	testCollectionDAO(t, func(tx Transaction, dao *CollectionDAO) {
+		_, err := NewNoteDAO(tx, &util.NullLogger).Remove("ref/test/b.md")
+		assert.Nil(t, err)
+
+		assertNotExistTx(t, tx, "SELECT id FROM notes_collections WHERE note_id = 5")
+		assertExistTx(t, tx, "SELECT id FROM collections WHERE id = 7")
+
+		tags, err := dao.FindAll(core.CollectionKindTag, nil)
+		assert.Nil(t, err)
+		for _, tag := range tags {
+			if tag.Name == "science" {
+				assert.Equal(t, tag.NoteCount, 1)
+			}
+		}
+	})
+}
+
 func testCollectionDAO(t *testing.T, callback func(tx Transaction, dao *CollectionDAO)) {
 	testTransaction(t, func(tx Transaction) {
 		callback(tx, NewCollectionDAO(tx, &util.NullLogger))
@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"fmt"
 	"regexp"
+	"time"
 
 	sqlite "github.com/mattn/go-sqlite3"
 	"github.com/zk-org/zk/internal/core"
@@ -30,16 +31,43 @@ type DB struct {
 	db *sql.DB
 }
 
+// DefaultBusyTimeout is the default duration to wait for a lock held by
+// another connection, for example when a note is indexed by another process.
+const DefaultBusyTimeout = 5 * time.Second
+
+// OpenOpts holds the options used to open a SQLite database.
+type OpenOpts struct {
+	// Duration to wait for a lock held by another connection before failing
+	// with a "database is locked" error.
+	BusyTimeout time.Duration
+}
+
 // Open creates a new DB instance for the SQLite database at the given path.
 func Open(path string) (*DB, error) {
-	return open("file:" + path)
+	return OpenWithOpts(path, OpenOpts{BusyTimeout: DefaultBusyTimeout})
+}
+
+// OpenWithOpts creates a new DB instance for the SQLite database at the given
+// path, using custom options.
+//
+// The database is opened in WAL mode, to allow reading the index while it is
+// being written by another process.
+func OpenWithOpts(path string, opts OpenOpts) (*DB, error) {
+	return open(fmt.Sprintf("file:%s?_journal_mode=WAL&_busy_timeout=%d&_foreign_keys=1",
+		path, opts.BusyTimeout.Milliseconds(),
+	))
 }
 
 // OpenInMemory creates a new in-memory DB instance.
 func OpenInMemory() (*DB, error) {
-	return open(":memory:")
+	return open(":memory:?_foreign_keys=1")
 }
 
+// open connects to the database with the given URI.
+//
+// The connection settings are given as URI parameters, to make sure they are
+// applied to every connection of the pool. For example, foreign keys are
+// needed for CASCADE statements to be properly applied.
 func open(uri string) (*DB, error) {
 	wrap := errors.Wrapper("failed to open the database")
 
@@ -48,13 +76,6 @@ func open(uri string) (*DB, error) {
 		return nil, wrap(err)
 	}
 
-	// Make sure that CASCADE statements are properly applied by enabling
-	// foreign keys.
-	_, err = nativeDB.Exec("PRAGMA foreign_keys = ON")
-	if err != nil {
-		return nil, wrap(err)
-	}
-
 	db := &DB{nativeDB}
 
 	err = db.migrate()
@@ -217,6 +238,80 @@ func (db *DB) migrate() error {
 				// https://github.com/zk-org/zk/issues/170#issuecomment-1107848441
 				NeedsReindexing: true,
 			},
+
+			{ // 8
+				SQL: []string{
+					// Add a `size` column to `notes`, to detect changes without
+					// reading the files.
+					`ALTER TABLE notes ADD COLUMN size INTEGER DEFAULT(0) NOT NULL`,
+				},
+				NeedsReindexing: true,
+			},
+
+			{ // 9
+				SQL: []string{
+					// Don't update the FTS index when only the checksum of a
+					// note changes.
+					`DROP TRIGGER IF EXISTS trigger_notes_au`,
+					`CREATE TRIGGER IF NOT EXISTS trigger_notes_au AFTER UPDATE OF path, title, body ON notes BEGIN
+						INSERT INTO notes_fts(notes_fts, rowid, path, title, body) VALUES('delete', old.id, old.path, old.title, old.body);
+						INSERT INTO notes_fts(rowid, path, title, body) VALUES (new.id, new.path, new.title, new.body);
+					END`,
+				},
+			},
+
+			{ // 10
+				SQL: []string{
+					// Add an `aliases` column to `notes`, with the alternative
+					// names of the note from its frontmatter.
+					`ALTER TABLE notes ADD COLUMN aliases TEXT DEFAULT('') NOT NULL`,
+
+					// Rebuild the FTS index to search the aliases as well.
+					`DROP TRIGGER IF EXISTS trigger_notes_ai`,
+					`DROP TRIGGER IF EXISTS trigger_notes_ad`,
+					`DROP TRIGGER IF EXISTS trigger_notes_au`,
+					`DROP TABLE IF EXISTS notes_fts`,
+					`CREATE VIRTUAL TABLE IF NOT EXISTS notes_fts USING fts5(
+						path, title, body, aliases,
+						content = notes,
+						content_rowid = id,
+						tokenize = "porter unicode61 remove_diacritics 1 tokenchars '''&/'"
+					)`,
+					`CREATE TRIGGER IF NOT EXISTS trigger_notes_ai AFTER INSERT ON notes BEGIN
+						INSERT INTO notes_fts(rowid, path, title, body, aliases) VALUES (new.id, new.path, new.title, new.body, new.aliases);
+					END`,
+					`CREATE TRIGGER IF NOT EXISTS trigger_notes_ad AFTER DELETE ON notes BEGIN
+						INSERT INTO notes_fts(notes_fts, rowid, path, title, body, aliases) VALUES('delete', old.id, old.path, old.title, old.body, old.aliases);
+					END`,
+					`CREATE TRIGGER IF NOT EXISTS trigger_notes_au AFTER UPDATE OF path, title, body, aliases ON notes BEGIN
+						INSERT INTO notes_fts(notes_fts, rowid, path, title, body, aliases) VALUES('delete', old.id, old.path, old.title, old.body, old.aliases);
+						INSERT INTO notes_fts(rowid, path, title, body, aliases) VALUES (new.id, new.path, new.title, new.body, new.aliases);
+					END`,
+					`INSERT INTO notes_fts(notes_fts) VALUES('rebuild')`,
+				},
+				NeedsReindexing: true,
+			},
+
+			{ // 11
+				SQL: []string{},
+				// The checksums are now computed with normalized line endings.
+				NeedsReindexing: true,
+			},
+
+			{ // 12
+				SQL: []string{
+					// Allow NULL in the `raw_content` column, which is not
+					// stored unless enabled.
+					`ALTER TABLE notes RENAME COLUMN raw_content TO raw_content_old`,
+					`ALTER TABLE notes ADD COLUMN raw_content TEXT`,
+					`UPDATE notes SET raw_content = raw_content_old`,
+					`ALTER TABLE notes DROP COLUMN raw_content_old`,
+				},
+			},
+		}
+
+		if version > len(migrations) {
+			return fmt.Errorf("the notebook index (version %d) was created by a newer version of zk, please upgrade", version)
 		}
 
 		needsReindexing := false
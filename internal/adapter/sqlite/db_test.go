@@ -1,24 +1,112 @@
 package sqlite
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/zk-org/zk/internal/util"
 	"github.com/zk-org/zk/internal/util/fixtures"
 	"github.com/zk-org/zk/internal/util/test/assert"
 )
 
 func TestOpen(t *testing.T) {
-	_, err := Open(fixtures.Path("sample.db"))
+	db, err := Open(sampleDBCopy(t))
 	assert.Nil(t, err)
+	defer db.Close()
 }
 
 func TestClose(t *testing.T) {
-	db, err := Open(fixtures.Path("sample.db"))
+	db, err := Open(sampleDBCopy(t))
 	assert.Nil(t, err)
 	err = db.Close()
 	assert.Nil(t, err)
 }
 
+// sampleDBCopy copies the sample database fixture to a temporary directory,
+// since opening it migrates the database in place.
+func sampleDBCopy(t *testing.T) string {
+	content, err := os.ReadFile(fixtures.Path("sample.db"))
+	assert.Nil(t, err)
+	path := filepath.Join(t.TempDir(), "sample.db")
+	assert.Nil(t, os.WriteFile(path, content, 0644))
+	return path
+}
+
+func TestOpenSettings(t *testing.T) {
+	db, err := Open(filepath.Join(t.TempDir(), "notebook.db"))
+	assert.Nil(t, err)
+	defer db.Close()
+
+	err = db.WithTransaction(func(tx Transaction) error {
+		var journalMode string
+		err := tx.QueryRow("PRAGMA journal_mode").Scan(&journalMode)
+		assert.Nil(t, err)
+		assert.Equal(t, journalMode, "wal")
+
+		var busyTimeout int
+		err = tx.QueryRow("PRAGMA busy_timeout").Scan(&busyTimeout)
+		assert.Nil(t, err)
+		assert.Equal(t, busyTimeout, 5000)
+
+		var foreignKeys int
+		err = tx.QueryRow("PRAGMA foreign_keys").Scan(&foreignKeys)
+		assert.Nil(t, err)
+		assert.Equal(t, foreignKeys, 1)
+		return nil
+	})
+	assert.Nil(t, err)
+}
+
+func TestOpenWithBusyTimeout(t *testing.T) {
+	db, err := OpenWithOpts(filepath.Join(t.TempDir(), "notebook.db"), OpenOpts{
+		BusyTimeout: 200 * time.Millisecond,
+	})
+	assert.Nil(t, err)
+	defer db.Close()
+
+	var busyTimeout int
+	err = db.db.QueryRow("PRAGMA busy_timeout").Scan(&busyTimeout)
+	assert.Nil(t, err)
+	assert.Equal(t, busyTimeout, 200)
+}
+
+// The index can be read by another connection while it is being written.
+func TestReadDuringWriteTransaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notebook.db")
+	writer, err := Open(path)
+	assert.Nil(t, err)
+	defer writer.Close()
+	reader, err := Open(path)
+	assert.Nil(t, err)
+	defer reader.Close()
+
+	countNotes := func(db *DB) int {
+		var count int
+		err := db.WithTransaction(func(tx Transaction) error {
+			return tx.QueryRow("SELECT COUNT(*) FROM notes").Scan(&count)
+		})
+		assert.Nil(t, err)
+		return count
+	}
+
+	err = writer.WithTransaction(func(tx Transaction) error {
+		_, err := tx.Exec(`
+			INSERT INTO notes (path, sortable_path, title, body, word_count, checksum)
+			VALUES ("ref/tx1.md", "reftx1.md", "A reference", "Content", 1, "qwfpg")
+		`)
+		assert.Nil(t, err)
+
+		// The pending write is not visible yet.
+		assert.Equal(t, countNotes(reader), 0)
+		return nil
+	})
+	assert.Nil(t, err)
+
+	assert.Equal(t, countNotes(reader), 1)
+}
+
 func TestMigrateFrom0(t *testing.T) {
 	db, err := OpenInMemory()
 	assert.Nil(t, err)
@@ -27,7 +115,7 @@ func TestMigrateFrom0(t *testing.T) {
 		var version int
 		err := tx.QueryRow("PRAGMA user_version").Scan(&version)
 		assert.Nil(t, err)
-		assert.Equal(t, version, 7)
+		assert.Equal(t, version, 12)
 
 		_, err = tx.Exec(`
 			INSERT INTO notes (path, sortable_path, title, body, word_count, checksum)
@@ -39,3 +127,125 @@ func TestMigrateFrom0(t *testing.T) {
 	})
 	assert.Nil(t, err)
 }
+
+func TestMigrateCreatesSchema(t *testing.T) {
+	db, err := OpenInMemory()
+	assert.Nil(t, err)
+
+	err = db.WithTransaction(func(tx Transaction) error {
+		for _, name := range []string{
+			"notes", "notes_fts", "links", "collections", "notes_collections",
+			"metadata", "notes_with_metadata", "resolved_links",
+		} {
+			var count int
+			err := tx.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE name = ?", name).Scan(&count)
+			assert.Nil(t, err)
+			assert.Equal(t, count, 1)
+		}
+
+		var count int
+		err := tx.QueryRow("SELECT COUNT(*) FROM pragma_table_info('notes') WHERE name = 'size'").Scan(&count)
+		assert.Nil(t, err)
+		assert.Equal(t, count, 1)
+
+		// A new database is flagged for reindexing.
+		reindexing, err := NewMetadataDAO(tx).Get(reindexingRequiredKey)
+		assert.Nil(t, err)
+		assert.Equal(t, reindexing, "true")
+		return nil
+	})
+	assert.Nil(t, err)
+}
+
+func TestMigrateIsIdempotent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notebook.db")
+
+	db, err := Open(path)
+	assert.Nil(t, err)
+	err = db.WithTransaction(func(tx Transaction) error {
+		_, err := tx.Exec(`
+			INSERT INTO notes (path, sortable_path, title, body, word_count, checksum)
+			VALUES ("ref/tx1.md", "reftx1.md", "A reference", "Content", 1, "qwfpg")
+		`)
+		assert.Nil(t, err)
+		return NewMetadataDAO(tx).Set(reindexingRequiredKey, "false")
+	})
+	assert.Nil(t, err)
+	assert.Nil(t, db.Close())
+
+	db, err = Open(path)
+	assert.Nil(t, err)
+	defer db.Close()
+
+	err = db.WithTransaction(func(tx Transaction) error {
+		var version int
+		err := tx.QueryRow("PRAGMA user_version").Scan(&version)
+		assert.Nil(t, err)
+		assert.Equal(t, version, 12)
+
+		var count int
+		err = tx.QueryRow("SELECT COUNT(*) FROM notes").Scan(&count)
+		assert.Nil(t, err)
+		assert.Equal(t, count, 1)
+
+		// Reopening an up-to-date database doesn't require a reindexing.
+		reindexing, err := NewMetadataDAO(tx).Get(reindexingRequiredKey)
+		assert.Nil(t, err)
+		assert.Equal(t, reindexing, "false")
+		return nil
+	})
+	assert.Nil(t, err)
+}
+
+func TestMigrateFromNewerVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notebook.db")
+
+	db, err := Open(path)
+	assert.Nil(t, err)
+	err = db.WithTransaction(func(tx Transaction) error {
+		_, err := tx.Exec("PRAGMA user_version = 999")
+		return err
+	})
+	assert.Nil(t, err)
+	assert.Nil(t, db.Close())
+
+	_, err = Open(path)
+	assert.Err(t, err, "the notebook index (version 999) was created by a newer version of zk, please upgrade")
+}
+
+// The raw content indexed before version 12 is kept when the column becomes
+// nullable.
+func TestMigrateRawContentToNullable(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notebook.db")
+
+	db, err := Open(path)
+	assert.Nil(t, err)
+	err = db.WithTransaction(func(tx Transaction) error {
+		return tx.ExecStmts([]string{
+			`ALTER TABLE notes DROP COLUMN raw_content`,
+			`ALTER TABLE notes ADD COLUMN raw_content TEXT DEFAULT('') NOT NULL`,
+			`INSERT INTO notes (path, sortable_path, title, body, raw_content, word_count, checksum)
+			 VALUES ("ref/tx1.md", "reftx1.md", "A reference", "Content", "# A reference", 1, "qwfpg")`,
+			`PRAGMA user_version = 11`,
+		})
+	})
+	assert.Nil(t, err)
+	assert.Nil(t, db.Close())
+
+	db, err = Open(path)
+	assert.Nil(t, err)
+	defer db.Close()
+
+	err = db.WithTransaction(func(tx Transaction) error {
+		var notNull int
+		err := tx.QueryRow("SELECT \"notnull\" FROM pragma_table_info('notes') WHERE name = 'raw_content'").Scan(&notNull)
+		assert.Nil(t, err)
+		assert.Equal(t, notNull, 0)
+
+		content, err := NewNoteDAO(tx, &util.NullLogger).GetRawContent("ref/tx1.md")
+		assert.Nil(t, err)
+		assert.Equal(t, content, "# A reference")
+		return nil
+	})
+	assert.Nil(t, err)
+}
@@ -67,6 +67,15 @@ func (d *LinkDAO) RemoveAll(id core.NoteID) error {
 	return err
 }
 
+// SetAll replaces the outbound links of the given note with the given ones.
+func (d *LinkDAO) SetAll(id core.NoteID, links []core.ResolvedLink) error {
+	err := d.RemoveAll(id)
+	if err != nil {
+		return err
+	}
+	return d.Add(links)
+}
+
 // SetTargetID updates the target note of a link.
 func (d *LinkDAO) SetTargetID(id core.LinkID, targetID core.NoteID) error {
 	_, err := d.updateTargetIDStmt.Exec(noteIDToSQL(targetID), linkIDToSQL(id))
@@ -98,6 +107,12 @@ func (d *LinkDAO) FindBetweenNotes(ids []core.NoteID) ([]core.ResolvedLink, erro
 	return d.findWhere(fmt.Sprintf("source_id IN (%s) AND target_id IN (%s)", idsString, idsString))
 }
 
+// FindDanglingFromNotes returns the internal links of the given notes which
+// don't match any note.
+func (d *LinkDAO) FindDanglingFromNotes(ids []core.NoteID) ([]core.ResolvedLink, error) {
+	return d.findWhere(fmt.Sprintf("source_id IN (%s) AND target_id IS NULL AND external = 0", joinNoteIDs(ids, ",")))
+}
+
 // findWhere returns all the links, filtered by the given where query.
 func (d *LinkDAO) findWhere(where string) ([]core.ResolvedLink, error) {
 	links := make([]core.ResolvedLink, 0)
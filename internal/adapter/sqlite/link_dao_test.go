@@ -9,6 +9,100 @@ import (
 	"github.com/zk-org/zk/internal/util/test/assert"
 )
 
+func TestLinkDAOSetAll(t *testing.T) {
+	testLinkDAO(t, func(tx Transaction, dao *LinkDAO) {
+		err := dao.SetAll(core.NoteID(4), []core.ResolvedLink{
+			{
+				Link: core.Link{
+					Title: "New link",
+					Href:  "index.md",
+					Type:  core.LinkTypeMarkdown,
+				},
+				SourceID: core.NoteID(4),
+				TargetID: core.NoteID(3),
+			},
+			{
+				Link: core.Link{
+					Title: "Dangling link",
+					Href:  "missing.md",
+					Type:  core.LinkTypeMarkdown,
+				},
+				SourceID: core.NoteID(4),
+			},
+		})
+		assert.Nil(t, err)
+
+		assert.Equal(t, queryLinkRows(t, tx, "source_id = 4"), []linkRow{
+			{
+				SourceId: core.NoteID(4),
+				TargetId: idPointer(3),
+				Title:    "New link",
+				Href:     "index.md",
+				Type:     "markdown",
+			},
+			{
+				SourceId: core.NoteID(4),
+				Title:    "Dangling link",
+				Href:     "missing.md",
+				Type:     "markdown",
+			},
+		})
+
+		// The links of the other notes are kept.
+		assert.Equal(t, len(queryLinkRows(t, tx, "source_id = 1")), 2)
+	})
+}
+
+func TestLinkDAOSetAllEmpty(t *testing.T) {
+	testLinkDAO(t, func(tx Transaction, dao *LinkDAO) {
+		err := dao.SetAll(core.NoteID(4), []core.ResolvedLink{})
+		assert.Nil(t, err)
+		assert.Equal(t, queryLinkRows(t, tx, "source_id = 4"), []linkRow{})
+	})
+}
+
+func TestLinkDAOSetTargetID(t *testing.T) {
+	testLinkDAO(t, func(tx Transaction, dao *LinkDAO) {
+		assert.Nil(t, queryLinkRows(t, tx, "id = 1")[0].TargetId)
+
+		err := dao.SetTargetID(core.LinkID(1), core.NoteID(2))
+		assert.Nil(t, err)
+		assert.Equal(t, queryLinkRows(t, tx, "id = 1")[0].TargetId, idPointer(2))
+	})
+}
+
+func TestLinkDAOFindBetweenNotes(t *testing.T) {
+	testLinkDAO(t, func(tx Transaction, dao *LinkDAO) {
+		links, err := dao.FindBetweenNotes([]core.NoteID{1, 2, 3})
+		assert.Nil(t, err)
+		assert.Equal(t, linkTitles(links), []string{"An internal link", "A transition link"})
+	})
+}
+
+func TestLinkDAOFindDanglingFromNotes(t *testing.T) {
+	testLinkDAO(t, func(tx Transaction, dao *LinkDAO) {
+		links, err := dao.FindDanglingFromNotes([]core.NoteID{1, 3})
+		assert.Nil(t, err)
+		assert.Equal(t, len(links), 1)
+		assert.Equal(t, links[0].Title, "Missing target")
+		assert.Equal(t, links[0].SourcePath, "index.md")
+		assert.Equal(t, links[0].TargetID, core.NoteID(0))
+		assert.Equal(t, links[0].TargetPath, "")
+
+		links, err = dao.FindDanglingFromNotes([]core.NoteID{1, 2, 4})
+		assert.Nil(t, err)
+		assert.Equal(t, links, []core.ResolvedLink{})
+	})
+}
+
+func linkTitles(links []core.ResolvedLink) []string {
+	titles := []string{}
+	for _, link := range links {
+		titles = append(titles, link.Title)
+	}
+	return titles
+}
+
 func testLinkDAO(t *testing.T, callback func(tx Transaction, dao *LinkDAO)) {
 	testTransaction(t, func(tx Transaction) {
 		callback(tx, NewLinkDAO(tx, &util.NullLogger))
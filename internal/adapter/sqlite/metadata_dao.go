@@ -8,6 +8,8 @@ import (
 
 // Known metadata keys.
 var reindexingRequiredKey = "zk.reindexing_required"
+var checksumAlgorithmKey = "zk.checksum_algorithm"
+var checksumTrimSpacesKey = "zk.checksum_trim_spaces"
 
 // MetadataDAO persists arbitrary key/value pairs in the SQLite database.
 type MetadataDAO struct {
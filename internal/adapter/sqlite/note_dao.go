@@ -1,9 +1,11 @@
 package sqlite
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"path/filepath"
 	"regexp"
 	"strings"
 	"time"
@@ -17,46 +19,124 @@ import (
 )
 
 // NoteDAO persists notes in the SQLite database.
+//
+// A NoteDAO lives as long as its transaction, so its statements are prepared
+// at most once and reused by all the calls made during the transaction.
 type NoteDAO struct {
 	tx     Transaction
 	logger util.Logger
+	opts   NoteDAOOpts
+
+	// Prepared SQL statements, closed when the transaction ends.
+	indexedStmt             *LazyStmt
+	addStmt                 *LazyStmt
+	addAllStmt              *LazyStmt
+	addOrUpdateStmt         *LazyStmt
+	updateStmt              *LazyStmt
+	renameStmt              *LazyStmt
+	setChecksumStmt         *LazyStmt
+	setModifiedStmt         *LazyStmt
+	removeStmt              *LazyStmt
+	findIdByPathStmt        *LazyStmt
+	findIdsByPathRegexStmt  *LazyStmt
+	findIdsByAliasStmt      *LazyStmt
+	findIdsByMetadataIDStmt *LazyStmt
+	findMetadataIDStmt      *LazyStmt
+	findByIdStmt            *LazyStmt
+	findByChecksumStmt      *LazyStmt
+	findRawContentStmt      *LazyStmt
+}
 
-	// Prepared SQL statements
-	indexedStmt            *LazyStmt
-	addStmt                *LazyStmt
-	updateStmt             *LazyStmt
-	removeStmt             *LazyStmt
-	findIdByPathStmt       *LazyStmt
-	findIdsByPathRegexStmt *LazyStmt
-	findByIdStmt           *LazyStmt
+// NoteDAOOpts holds the options of a NoteDAO.
+type NoteDAOOpts struct {
+	// Indicates whether the raw content of the notes is stored in the
+	// index. It roughly doubles the size of the database, so the column is
+	// NULL by default.
+	StoreRawContent bool
+	// File extensions of the notes, without the leading dot. A path without
+	// extension matching ExactPaths is looked up with each of them.
+	// Defaults to md.
+	Extensions []string
 }
 
 // NewNoteDAO creates a new instance of a DAO working on the given database
-// transaction.
+// transaction, without storing the raw content of the notes.
 func NewNoteDAO(tx Transaction, logger util.Logger) *NoteDAO {
+	return NewNoteDAOWithOpts(tx, logger, NoteDAOOpts{})
+}
+
+// extensions returns the configured file extensions of the notes.
+func (d *NoteDAO) extensions() []string {
+	if len(d.opts.Extensions) == 0 {
+		return []string{"md"}
+	}
+	return d.opts.Extensions
+}
+
+// NewNoteDAOWithOpts creates a new instance of a DAO working on the given
+// database transaction, using custom options.
+func NewNoteDAOWithOpts(tx Transaction, logger util.Logger, opts NoteDAOOpts) *NoteDAO {
 	return &NoteDAO{
 		tx:     tx,
 		logger: logger,
+		opts:   opts,
 
 		// Get file info about all indexed notes.
 		indexedStmt: tx.PrepareLazy(`
-			SELECT path, modified from notes
+			SELECT path, modified, size, checksum from notes
 			 ORDER BY sortable_path ASC
 		`),
 
 		// Add a new note to the index.
 		addStmt: tx.PrepareLazy(`
-			INSERT INTO notes (path, sortable_path, title, lead, body, raw_content, word_count, metadata, checksum, created, modified)
-			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			INSERT INTO notes (path, sortable_path, title, lead, body, raw_content, word_count, metadata, aliases, checksum, size, created, modified)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`),
+
+		// Add a full chunk of new notes to the index.
+		addAllStmt: tx.PrepareLazy(addAllQuery(addAllChunkSize)),
+
+		// Add a new note to the index, or update its content if the path is
+		// already indexed. The creation date is preserved on update.
+		addOrUpdateStmt: tx.PrepareLazy(`
+			INSERT INTO notes (path, sortable_path, title, lead, body, raw_content, word_count, metadata, aliases, checksum, size, created, modified)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT(path) DO UPDATE
+			   SET title = excluded.title, lead = excluded.lead, body = excluded.body,
+			       raw_content = excluded.raw_content, word_count = excluded.word_count,
+			       metadata = excluded.metadata, aliases = excluded.aliases,
+			       checksum = excluded.checksum, size = excluded.size, modified = excluded.modified
 		`),
 
 		// Update the content of a note.
 		updateStmt: tx.PrepareLazy(`
 			UPDATE notes
-			   SET title = ?, lead = ?, body = ?, raw_content = ?, word_count = ?, metadata = ?, checksum = ?, modified = ?
+			   SET title = ?, lead = ?, body = ?, raw_content = ?, word_count = ?, metadata = ?, aliases = ?, checksum = ?, size = ?, modified = ?
 			 WHERE path = ?
 		`),
 
+		// Update the checksum of a note, without touching the FTS index.
+		setChecksumStmt: tx.PrepareLazy(`
+			UPDATE notes
+			   SET checksum = ?
+			 WHERE path = ?
+		`),
+
+		// Update the modification date and size of a note whose content
+		// didn't change, without touching the FTS index.
+		setModifiedStmt: tx.PrepareLazy(`
+			UPDATE notes
+			   SET modified = ?, size = ?
+			 WHERE path = ?
+		`),
+
+		// Move a note to a new path.
+		renameStmt: tx.PrepareLazy(`
+			UPDATE notes
+			   SET path = ?, sortable_path = ?
+			 WHERE id = ?
+		`),
+
 		// Remove a note.
 		removeStmt: tx.PrepareLazy(`
 			DELETE FROM notes
@@ -69,6 +149,12 @@ func NewNoteDAO(tx Transaction, logger util.Logger) *NoteDAO {
 			 WHERE path = ?
 		`),
 
+		// Find the raw content of a note from its exact path.
+		findRawContentStmt: tx.PrepareLazy(`
+			SELECT raw_content FROM notes
+			 WHERE path = ?
+		`),
+
 		// Find note IDs from a regex matching their path.
 		findIdsByPathRegexStmt: tx.PrepareLazy(`
 			SELECT id FROM notes
@@ -78,6 +164,35 @@ func NewNoteDAO(tx Transaction, logger util.Logger) *NoteDAO {
 			 ORDER BY LENGTH(path) ASC
 		`),
 
+		// Find note IDs from one of their aliases, ignoring the case.
+		findIdsByAliasStmt: tx.PrepareLazy(`
+			SELECT DISTINCT nc.note_id FROM notes_collections nc
+			  JOIN collections c ON c.id = nc.collection_id
+			 WHERE c.kind = '` + string(core.CollectionKindAlias) + `' AND c.name = ? COLLATE NOCASE
+			 ORDER BY nc.note_id
+		`),
+
+		// Find note IDs from their `id` metadata, e.g. the ID property of an
+		// Org-mode note.
+		findIdsByMetadataIDStmt: tx.PrepareLazy(`
+			SELECT id FROM notes
+			 WHERE json_type(metadata, '$.id') = 'text' AND json_extract(metadata, '$.id') = ?
+			 ORDER BY id
+		`),
+
+		// Find the `id` metadata of a note from its exact path.
+		findMetadataIDStmt: tx.PrepareLazy(`
+			SELECT json_extract(metadata, '$.id') FROM notes
+			 WHERE path = ? AND json_type(metadata, '$.id') = 'text'
+		`),
+
+		// Find notes from the checksum of their content.
+		findByChecksumStmt: tx.PrepareLazy(`
+			SELECT id, path, title, metadata FROM notes
+			 WHERE checksum = ?
+			 ORDER BY sortable_path ASC
+		`),
+
 		// Find a note from its ID.
 		findByIdStmt: tx.PrepareLazy(`
 			SELECT id, path, title, lead, body, raw_content, word_count, created, modified, metadata, checksum, tags, lead AS snippet
@@ -101,10 +216,12 @@ func (d *NoteDAO) Indexed() (<-chan paths.Metadata, error) {
 		var (
 			path     string
 			modified time.Time
+			size     int64
+			checksum string
 		)
 
 		for rows.Next() {
-			err := rows.Scan(&path, &modified)
+			err := rows.Scan(&path, &modified, &size, &checksum)
 			if err != nil {
 				d.logger.Err(err)
 			}
@@ -112,6 +229,8 @@ func (d *NoteDAO) Indexed() (<-chan paths.Metadata, error) {
 			c <- paths.Metadata{
 				Path:     path,
 				Modified: modified,
+				Size:     size,
+				Checksum: checksum,
 			}
 		}
 
@@ -126,20 +245,7 @@ func (d *NoteDAO) Indexed() (<-chan paths.Metadata, error) {
 
 // Add inserts a new note to the index.
 func (d *NoteDAO) Add(note core.Note) (core.NoteID, error) {
-	// For sortable_path, we replace in path / by the shortest non printable
-	// character available to make it sortable. Without this, sorting by the
-	// path would be a lexicographical sort instead of being the same order
-	// returned by filepath.Walk.
-	// \x01 is used instead of \x00, because SQLite treats \x00 as and end of
-	// string.
-	sortablePath := strings.ReplaceAll(note.Path, "/", "\x01")
-
-	metadata := d.metadataToJSON(note)
-	res, err := d.addStmt.Exec(
-		note.Path, sortablePath, note.Title, note.Lead, note.Body,
-		note.RawContent, note.WordCount, metadata, note.Checksum, note.Created,
-		note.Modified,
-	)
+	res, err := d.addStmt.Exec(d.insertArgs(note)...)
 	if err != nil {
 		return 0, err
 	}
@@ -152,6 +258,80 @@ func (d *NoteDAO) Add(note core.Note) (core.NoteID, error) {
 	return core.NoteID(lastId), err
 }
 
+// AddAll inserts the given notes to the index, in chunks of multi-row
+// INSERT statements to limit the number of round trips with the database.
+//
+// The batch should be run inside a single transaction, which will be rolled
+// back by the caller if one of the notes can't be added.
+func (d *NoteDAO) AddAll(notes []core.Note) error {
+	for len(notes) > 0 {
+		count := len(notes)
+		if count > addAllChunkSize {
+			count = addAllChunkSize
+		}
+		chunk := notes[:count]
+		notes = notes[count:]
+
+		args := make([]interface{}, 0, len(chunk)*13)
+		for _, note := range chunk {
+			args = append(args, d.insertArgs(note)...)
+		}
+
+		var err error
+		if len(chunk) == addAllChunkSize {
+			_, err = d.addAllStmt.Exec(args...)
+		} else {
+			_, err = d.tx.Exec(addAllQuery(len(chunk)), args...)
+		}
+
+		if err != nil {
+			// A failed statement doesn't insert any row, so we can retry the
+			// chunk one note at a time to find out which one is failing.
+			for _, note := range chunk {
+				if _, err := d.Add(note); err != nil {
+					return errors.Wrapf(err, "%v: failed to add the note", note.Path)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// addAllChunkSize is the number of notes inserted with a single statement in
+// AddAll. It must keep the number of arguments below the SQLite limit of host
+// parameters.
+const addAllChunkSize = 50
+
+func addAllQuery(count int) string {
+	values := strings.TrimSuffix(strings.Repeat("(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?), ", count), ", ")
+	return `INSERT INTO notes (path, sortable_path, title, lead, body, raw_content, word_count, metadata, aliases, checksum, size, created, modified)
+			VALUES ` + values
+}
+
+// AddOrUpdate inserts a new note to the index, or updates it if a note is
+// already indexed at the same path. The creation date of an existing note is
+// preserved.
+func (d *NoteDAO) AddOrUpdate(note core.Note) (core.NoteID, error) {
+	_, err := d.addOrUpdateStmt.Exec(d.insertArgs(note)...)
+	if err != nil {
+		return 0, err
+	}
+
+	// LastInsertId is not reliable when the row was updated.
+	return d.FindIdByPath(note.Path)
+}
+
+// insertArgs returns the arguments expected by the note insert statements.
+func (d *NoteDAO) insertArgs(note core.Note) []interface{} {
+	metadata := d.metadataToJSON(note)
+	return []interface{}{
+		note.Path, sortablePath(note.Path), note.Title, note.Lead, note.Body,
+		d.rawContentArg(note), note.WordCount, metadata, joinAliases(note.Aliases),
+		note.Checksum, note.Size, note.Created, note.Modified,
+	}
+}
+
 // Update modifies an existing note.
 func (d *NoteDAO) Update(note core.Note) (core.NoteID, error) {
 	id, err := d.FindIdByPath(note.Path)
@@ -164,12 +344,74 @@ func (d *NoteDAO) Update(note core.Note) (core.NoteID, error) {
 
 	metadata := d.metadataToJSON(note)
 	_, err = d.updateStmt.Exec(
-		note.Title, note.Lead, note.Body, note.RawContent, note.WordCount,
-		metadata, note.Checksum, note.Modified, note.Path,
+		note.Title, note.Lead, note.Body, d.rawContentArg(note), note.WordCount,
+		metadata, joinAliases(note.Aliases), note.Checksum, note.Size,
+		note.Modified, note.Path,
 	)
 	return id, err
 }
 
+// rawContentArg returns the value stored in the raw_content column for the
+// given note, which is NULL unless StoreRawContent is enabled.
+func (d *NoteDAO) rawContentArg(note core.Note) interface{} {
+	if !d.opts.StoreRawContent {
+		return nil
+	}
+	return note.RawContent
+}
+
+// SetModified updates the modification date and size of the note at the
+// given path.
+func (d *NoteDAO) SetModified(path string, modified time.Time, size int64) error {
+	res, err := d.setModifiedStmt.Exec(modified, size, path)
+	if err != nil {
+		return errors.Wrapf(err, "%s: failed to update the modification date", path)
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return errors.Wrapf(err, "%s: failed to update the modification date", path)
+	}
+	if count == 0 {
+		return fmt.Errorf("%s: note not found in the index", path)
+	}
+	return nil
+}
+
+// SetChecksum updates the checksum of the note at the given path.
+func (d *NoteDAO) SetChecksum(path string, checksum string) error {
+	res, err := d.setChecksumStmt.Exec(checksum, path)
+	if err != nil {
+		return errors.Wrapf(err, "%s: failed to update the checksum", path)
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return errors.Wrapf(err, "%s: failed to update the checksum", path)
+	}
+	if count == 0 {
+		return fmt.Errorf("%s: note not found in the index", path)
+	}
+	return nil
+}
+
+// joinAliases returns the value of the aliases column for the given note
+// aliases, indexed by the full-text search.
+func joinAliases(aliases []string) string {
+	return strings.Join(aliases, ", ")
+}
+
+// sortablePath returns the value of the sortable_path column for the given
+// note path.
+//
+// We replace in path / by the shortest non printable character available to
+// make it sortable. Without this, sorting by the path would be a
+// lexicographical sort instead of being the same order returned by
+// filepath.Walk.
+// \x01 is used instead of \x00, because SQLite treats \x00 as and end of
+// string.
+func sortablePath(path string) string {
+	return strings.ReplaceAll(path, "/", "\x01")
+}
+
 func (d *NoteDAO) metadataToJSON(note core.Note) string {
 	json, err := json.Marshal(note.Metadata)
 	if err != nil {
@@ -181,18 +423,177 @@ func (d *NoteDAO) metadataToJSON(note core.Note) string {
 	return string(json)
 }
 
-// Remove deletes the note with the given path from the index.
-func (d *NoteDAO) Remove(path string) error {
+// Rename moves the note at oldPath to newPath, keeping its ID so that its
+// links and collections are preserved.
+func (d *NoteDAO) Rename(oldPath string, newPath string) (core.NoteID, error) {
+	id, err := d.FindIdByPath(oldPath)
+	if err != nil {
+		return 0, err
+	}
+	if !id.IsValid() {
+		return 0, errors.New("note not found in the index")
+	}
+
+	_, err = d.renameStmt.Exec(newPath, sortablePath(newPath), id)
+	return id, err
+}
+
+// Remove deletes the note with the given path from the index and returns
+// its former ID.
+func (d *NoteDAO) Remove(path string) (core.NoteID, error) {
 	id, err := d.FindIdByPath(path)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	if !id.IsValid() {
-		return errors.New("note not found in the index")
+		return 0, errors.New("note not found in the index")
 	}
 
 	_, err = d.removeStmt.Exec(id)
-	return err
+	return id, err
+}
+
+// RebuildFTS rebuilds the full-text search index from the content of the
+// notes table.
+func (d *NoteDAO) RebuildFTS() error {
+	_, err := d.tx.Exec(`INSERT INTO notes_fts(notes_fts) VALUES('rebuild')`)
+	return errors.Wrap(err, "failed to rebuild the full-text search index")
+}
+
+// Optimize merges the full-text search index segments and updates the
+// statistics used by the SQLite query planner.
+func (d *NoteDAO) Optimize() error {
+	err := d.tx.ExecStmts([]string{
+		`INSERT INTO notes_fts(notes_fts) VALUES('optimize')`,
+		`PRAGMA optimize`,
+	})
+	return errors.Wrap(err, "failed to optimize the full-text search index")
+}
+
+// FindByChecksum returns the notes whose content matches the given checksum,
+// for example to detect a note which was moved to another path.
+// Several notes can share the same content.
+func (d *NoteDAO) FindByChecksum(checksum string) ([]core.MinimalNote, error) {
+	notes := make([]core.MinimalNote, 0)
+
+	rows, err := d.findByChecksumStmt.Query(checksum)
+	if err != nil {
+		return notes, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		note, err := d.scanMinimalNote(rows)
+		if err != nil {
+			return notes, err
+		}
+		if note != nil {
+			notes = append(notes, *note)
+		}
+	}
+
+	return notes, rows.Err()
+}
+
+// GetByIds returns the notes with the given IDs, in the same order. Unknown
+// IDs are skipped.
+func (d *NoteDAO) GetByIds(ids []core.NoteID) ([]core.MinimalNote, error) {
+	found := make(map[core.NoteID]core.MinimalNote, len(ids))
+
+	for start := 0; start < len(ids); start += getByIdsChunkSize {
+		end := start + getByIdsChunkSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+		if err := d.findMinimalByIds(ids[start:end], found); err != nil {
+			return nil, err
+		}
+	}
+
+	notes := make([]core.MinimalNote, 0, len(found))
+	for _, id := range ids {
+		if note, ok := found[id]; ok {
+			notes = append(notes, note)
+		}
+	}
+	return notes, nil
+}
+
+// findMinimalByIds adds the notes with the given IDs to the found map.
+func (d *NoteDAO) findMinimalByIds(ids []core.NoteID, found map[core.NoteID]core.MinimalNote) error {
+	args := make([]interface{}, 0, len(ids))
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ")
+
+	rows, err := d.tx.Query("SELECT id, path, title, metadata FROM notes WHERE id IN ("+placeholders+")", args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		note, err := d.scanMinimalNote(rows)
+		if err != nil {
+			return err
+		}
+		if note != nil {
+			found[note.ID] = *note
+		}
+	}
+	return rows.Err()
+}
+
+// getByIdsChunkSize is the number of IDs looked up with a single query in
+// GetByIds, to stay below the SQLite limit of host parameters.
+const getByIdsChunkSize = 500
+
+// Exists returns whether a note is indexed at exactly the given path.
+func (d *NoteDAO) Exists(path string) (bool, error) {
+	id, err := d.FindIdByPath(path)
+	if err != nil {
+		return false, err
+	}
+	return id.IsValid(), nil
+}
+
+// GetByPath returns the note indexed at exactly the given path, or nil if
+// there is none.
+func (d *NoteDAO) GetByPath(path string) (*core.ContextualNote, error) {
+	id, err := d.FindIdByPath(path)
+	if err != nil || !id.IsValid() {
+		return nil, err
+	}
+
+	notes, err := d.Find(context.Background(), core.NoteFindOpts{IncludeIDs: []core.NoteID{id}})
+	if err != nil || len(notes) == 0 {
+		return nil, err
+	}
+	return &notes[0], nil
+}
+
+// GetRawContent returns the raw content of the note indexed at exactly the
+// given path, without reading the file.
+//
+// An error is returned if the note was indexed without StoreRawContent.
+func (d *NoteDAO) GetRawContent(path string) (string, error) {
+	row, err := d.findRawContentStmt.QueryRow(path)
+	if err != nil {
+		return "", err
+	}
+	var content sql.NullString
+	err = row.Scan(&content)
+	switch {
+	case err == sql.ErrNoRows:
+		return "", fmt.Errorf("%s: note not found in the index", path)
+	case err != nil:
+		return "", err
+	case !content.Valid:
+		return "", fmt.Errorf("%s: the raw content of the note is not indexed", path)
+	default:
+		return content.String, nil
+	}
 }
 
 func (d *NoteDAO) FindIdByPath(path string) (core.NoteID, error) {
@@ -203,6 +604,22 @@ func (d *NoteDAO) FindIdByPath(path string) (core.NoteID, error) {
 	return idForRow(row)
 }
 
+// FindMetadataID returns the `id` metadata of the note at the given path, or
+// an empty string if it has none.
+func (d *NoteDAO) FindMetadataID(path string) (string, error) {
+	row, err := d.findMetadataIDStmt.QueryRow(path)
+	if err != nil {
+		return "", err
+	}
+
+	var id string
+	err = row.Scan(&id)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return id, err
+}
+
 func idForRow(row *sql.Row) (core.NoteID, error) {
 	var id sql.NullInt64
 	err := row.Scan(&id)
@@ -238,6 +655,44 @@ func (d *NoteDAO) findIdsByPathRegex(regex string) ([]core.NoteID, error) {
 	return ids, nil
 }
 
+func (d *NoteDAO) findIdsByAlias(alias string) ([]core.NoteID, error) {
+	ids := []core.NoteID{}
+	rows, err := d.findIdsByAliasStmt.Query(alias)
+	if err != nil {
+		return ids, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id core.NoteID
+		if err := rows.Scan(&id); err != nil {
+			return ids, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+func (d *NoteDAO) findIdsByMetadataID(id string) ([]core.NoteID, error) {
+	ids := []core.NoteID{}
+	rows, err := d.findIdsByMetadataIDStmt.Query(id)
+	if err != nil {
+		return ids, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id core.NoteID
+		if err := rows.Scan(&id); err != nil {
+			return ids, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
 func (d *NoteDAO) findIdWithStmt(stmt *LazyStmt, args ...interface{}) (core.NoteID, error) {
 	row, err := stmt.QueryRow(args...)
 	if err != nil {
@@ -257,6 +712,84 @@ func (d *NoteDAO) findIdWithStmt(stmt *LazyStmt, args ...interface{}) (core.Note
 	}
 }
 
+// FindByHref resolves a link href to the note it targets, trying in order:
+//
+//  1. the exact path of the note,
+//  2. the path of the note without its file extension,
+//  3. when allowPartialHref is true, a note with this filename or whose path
+//     ends with the href,
+//  4. when allowPartialHref is true, a note with this alias.
+//
+// An Org-mode `id:` href only matches the note with this `id` metadata. An
+// href matching several notes in one of the tiers is ambiguous and is left
+// unresolved.
+func (d *NoteDAO) FindByHref(href string, allowPartialHref bool) (core.NoteID, error) {
+	// A link to an anchor of the source note, e.g. #section.
+	if strings.SplitN(href, "#", 2)[0] == "" {
+		return 0, nil
+	}
+
+	id, ambiguous, err := d.findUniqueIdByHref(href, allowPartialHref)
+	if ambiguous {
+		d.logger.Printf("warning: %s: ambiguous link matching several notes, left unresolved", href)
+	}
+	return id, err
+}
+
+// findUniqueIdByHref returns the ID of the single note matching the href in
+// the resolution tiers of FindByHref.
+func (d *NoteDAO) findUniqueIdByHref(href string, allowPartialHref bool) (id core.NoteID, ambiguous bool, err error) {
+	// Remove any anchor at the end of the HREF, since it's most likely
+	// matching a sub-section in the note.
+	href = strings.SplitN(href, "#", 2)[0]
+	href = strings.TrimPrefix(href, "./")
+	if href == "" {
+		return 0, false, nil
+	}
+
+	// An Org-mode ID link targets the note with this `id` metadata.
+	if id, ok := strings.CutPrefix(href, "id:"); ok {
+		ids, err := d.findIdsByMetadataID(id)
+		return uniqueNoteID(ids), len(ids) > 1, err
+	}
+
+	quotedHref := regexp.QuoteMeta(href)
+	const ext = `\.[^./]+`
+
+	regexes := []string{
+		"^" + quotedHref + "$",
+		"^" + quotedHref + ext + "$",
+	}
+	if allowPartialHref {
+		regexes = append(regexes, "(^|/)"+quotedHref+"("+ext+")?$")
+	}
+
+	for _, regex := range regexes {
+		ids, err := d.findIdsByPathRegex(regex)
+		if err != nil || len(ids) > 0 {
+			return uniqueNoteID(ids), len(ids) > 1, err
+		}
+	}
+
+	if allowPartialHref {
+		ids, err := d.findIdsByAlias(href)
+		if err != nil || len(ids) > 0 {
+			return uniqueNoteID(ids), len(ids) > 1, err
+		}
+	}
+
+	return 0, false, nil
+}
+
+// uniqueNoteID returns the single ID of the list, or 0 if there are none or
+// several.
+func uniqueNoteID(ids []core.NoteID) core.NoteID {
+	if len(ids) != 1 {
+		return 0
+	}
+	return ids[0]
+}
+
 func (d *NoteDAO) FindIdByHref(href string, allowPartialHref bool) (core.NoteID, error) {
 	ids, err := d.FindIdsByHref(href, allowPartialHref)
 	if len(ids) == 0 || err != nil {
@@ -305,21 +838,24 @@ func (d *NoteDAO) FindIdsByHref(href string, allowPartialHref bool) ([]core.Note
 	return []core.NoteID{}, nil
 }
 
-func (d *NoteDAO) FindMinimal(opts core.NoteFindOpts) ([]core.MinimalNote, error) {
+func (d *NoteDAO) FindMinimal(ctx context.Context, opts core.NoteFindOpts) ([]core.MinimalNote, error) {
 	notes := make([]core.MinimalNote, 0)
 
-	opts, err := d.expandMentionsIntoMatch(opts)
+	opts, err := d.expandMentionsIntoMatch(ctx, opts)
 	if err != nil {
 		return notes, err
 	}
 
-	rows, err := d.findRows(opts, noteSelectionMinimal)
+	rows, err := d.findRows(ctx, opts, noteSelectionMinimal)
 	if err != nil {
 		return notes, err
 	}
 	defer rows.Close()
 
 	for rows.Next() {
+		if err := ctx.Err(); err != nil {
+			return notes, err
+		}
 		note, err := d.scanMinimalNote(rows)
 		if err != nil {
 			d.logger.Err(err)
@@ -330,36 +866,394 @@ func (d *NoteDAO) FindMinimal(opts core.NoteFindOpts) ([]core.MinimalNote, error
 		}
 	}
 
-	return notes, nil
+	if err := ctx.Err(); err != nil {
+		return notes, err
+	}
+	return notes, d.matchQueryError(opts, rows.Err())
 }
 
 // Find returns all the notes matching the given criteria.
-func (d *NoteDAO) Find(opts core.NoteFindOpts) ([]core.ContextualNote, error) {
+//
+// The query is interrupted with ctx.Err() when the context is cancelled.
+func (d *NoteDAO) Find(ctx context.Context, opts core.NoteFindOpts) ([]core.ContextualNote, error) {
 	notes := make([]core.ContextualNote, 0)
+	_, err := d.FindEach(ctx, opts, func(note core.ContextualNote) error {
+		notes = append(notes, note)
+		return nil
+	})
+	return notes, err
+}
+
+// ErrStopIteration can be returned by a FindEach callback to stop the
+// iteration early, without failing.
+var ErrStopIteration = errors.New("stop iteration")
+
+// FindEach calls the callback with each note matching the given criteria, as
+// they are read from the database.
+//
+// Returns the number of notes given to the callback. The iteration is aborted
+// with the error returned by the callback, unless it is ErrStopIteration.
+func (d *NoteDAO) FindEach(ctx context.Context, opts core.NoteFindOpts, callback func(core.ContextualNote) error) (int, error) {
+	count := 0
 
-	opts, err := d.expandMentionsIntoMatch(opts)
+	opts, err := d.expandMentionsIntoMatch(ctx, opts)
 	if err != nil {
-		return notes, err
+		return count, err
 	}
 
-	rows, err := d.findRows(opts, noteSelectionFull)
+	rows, err := d.findRows(ctx, opts, noteSelectionFull)
 	if err != nil {
-		return notes, err
+		return count, err
 	}
 	defer rows.Close()
 
 	for rows.Next() {
+		// database/sql closes the rows asynchronously after a cancellation,
+		// so we check the context before delivering each note.
+		if err := ctx.Err(); err != nil {
+			return count, err
+		}
 		note, err := d.scanNote(rows)
 		if err != nil {
 			d.logger.Err(err)
 			continue
 		}
-		if note != nil {
-			notes = append(notes, *note)
+		if note == nil {
+			continue
+		}
+		if len(opts.Match) > 0 {
+			locateMatch(note, opts)
+		}
+
+		count++
+		err = callback(*note)
+		if err == ErrStopIteration {
+			return count, nil
+		} else if err != nil {
+			return count, err
 		}
 	}
 
-	return notes, nil
+	if err := ctx.Err(); err != nil {
+		return count, err
+	}
+	if err := d.matchQueryError(opts, rows.Err()); err != nil {
+		return count, err
+	}
+
+	hrefs, err := d.findUnresolvedLinkedHrefs(ctx, opts)
+	if err != nil {
+		return count, err
+	}
+	for _, href := range hrefs {
+		if opts.Limit > 0 && count >= opts.Limit {
+			break
+		}
+		count++
+		err = callback(core.ContextualNote{Note: core.Note{Path: href}})
+		if err == ErrStopIteration {
+			return count, nil
+		} else if err != nil {
+			return count, err
+		}
+	}
+	return count, nil
+}
+
+// findUnresolvedLinkedHrefs returns the distinct hrefs of the unresolved
+// internal links of the LinkedBy sources, when the filter includes them.
+func (d *NoteDAO) findUnresolvedLinkedHrefs(ctx context.Context, opts core.NoteFindOpts) ([]string, error) {
+	hrefs := []string{}
+	filter := opts.LinkedBy
+	if filter == nil || !filter.IncludeUnresolved || filter.Negate {
+		return hrefs, nil
+	}
+
+	ids, err := d.findLinkFilterIDs(filter.Hrefs)
+	if err != nil {
+		return hrefs, err
+	}
+
+	rows, err := d.tx.QueryContext(ctx, fmt.Sprintf(`
+		SELECT DISTINCT href FROM links
+		 WHERE target_id IS NULL AND external = 0 AND source_id IN (%s)
+		 ORDER BY href
+	`, joinNoteIDs(ids, ",")))
+	if err != nil {
+		return hrefs, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var href string
+		if err := rows.Scan(&href); err != nil {
+			return hrefs, err
+		}
+		hrefs = append(hrefs, href)
+	}
+	return hrefs, rows.Err()
+}
+
+// findLinkFilterIDs returns the IDs of the notes targeted by the hrefs of a
+// link filter.
+func (d *NoteDAO) findLinkFilterIDs(hrefs []string) ([]core.NoteID, error) {
+	ids := make([]core.NoteID, 0)
+	for _, href := range hrefs {
+		// Use the note targeted by the href, as a link would, or every
+		// note matching it otherwise, e.g. a directory.
+		id, _, err := d.findUniqueIdByHref(href, true /* allowPartialHref */)
+		if err != nil {
+			return ids, err
+		}
+		if id.IsValid() {
+			ids = append(ids, id)
+			continue
+		}
+
+		cids, err := d.FindIdsByHref(href, true /* allowPartialHref */)
+		if err != nil {
+			return ids, err
+		}
+		ids = append(ids, cids...)
+	}
+	if len(ids) == 0 {
+		return ids, fmt.Errorf("could not find notes at: " + strings.Join(hrefs, ", "))
+	}
+	return ids, nil
+}
+
+// locateMatch sets the location of the first term of the note matched by
+// the match filters of opts.
+func locateMatch(note *core.ContextualNote, opts core.NoteFindOpts) {
+	index := -1
+	switch opts.MatchStrategy {
+	case core.MatchStrategyFts, core.MatchStrategyPhrase:
+		// The actual terms matched by the full-text search, e.g. the
+		// variants of a stemmed word, are highlighted in the snippets.
+		index = snippetDelimiters(opts).IndexSnippetTerm(note.RawContent, note.Snippets)
+		if index < 0 {
+			// The snippet might come from another column than the one
+			// matched, e.g. when only the title matches.
+			index = core.IndexTerms(note.RawContent, ftsQueryTerms(opts.Match))
+		}
+	case core.MatchStrategyExact:
+		index = core.IndexTerms(note.RawContent, opts.Match)
+	case core.MatchStrategyRe:
+		for _, match := range opts.Match {
+			re, err := regexp.Compile(match)
+			if err != nil {
+				continue
+			}
+			if loc := re.FindStringIndex(note.RawContent); loc != nil && (index < 0 || loc[0] < index) {
+				index = loc[0]
+			}
+		}
+	}
+	if index >= 0 {
+		note.MatchLine, note.MatchColumn = core.LocateOffset(note.RawContent, index)
+	}
+}
+
+// ftsQueryTerms returns the words of the given full-text search queries,
+// without the FTS5 operators.
+func ftsQueryTerms(queries []string) []string {
+	terms := []string{}
+	for _, query := range queries {
+		for _, word := range strings.Fields(query) {
+			switch word {
+			case "AND", "OR", "NOT", "NEAR":
+				continue
+			}
+			word = strings.TrimLeft(strings.Trim(word, `"()*^`), "-")
+			if word != "" {
+				terms = append(terms, word)
+			}
+		}
+	}
+	return terms
+}
+
+// Count returns the number of notes matching the given criteria, without
+// fetching them. The limit, offset and sorters are ignored.
+func (d *NoteDAO) Count(ctx context.Context, opts core.NoteFindOpts) (int, error) {
+	opts, err := d.expandMentionsIntoMatch(ctx, opts)
+	if err != nil {
+		return 0, err
+	}
+
+	opts.Limit = 0
+	opts.Offset = 0
+	opts.Sorters = nil
+
+	query, args, err := d.findQuery(opts, noteSelectionID)
+	if err != nil {
+		return 0, err
+	}
+
+	var count int
+	err = d.tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM (\n"+query+")", args...).Scan(&count)
+	if err != nil && ctx.Err() != nil {
+		return 0, ctx.Err()
+	}
+	if err := d.matchQueryError(opts, err); err != nil {
+		return 0, err
+	}
+
+	hrefs, err := d.findUnresolvedLinkedHrefs(ctx, opts)
+	return count + len(hrefs), err
+}
+
+// Stats aggregates statistics about the notes matching the given criteria.
+// The offset and sorters are ignored, but the limit restricts the notes
+// taken into account.
+func (d *NoteDAO) Stats(ctx context.Context, opts core.NoteFindOpts) (core.NoteStats, error) {
+	stats := core.NoteStats{
+		Directories:    []core.StatsEntry{},
+		Tags:           []core.StatsEntry{},
+		CreationMonths: []core.StatsEntry{},
+	}
+
+	opts, err := d.expandMentionsIntoMatch(ctx, opts)
+	if err != nil {
+		return stats, err
+	}
+
+	opts.Offset = 0
+	if opts.Limit == 0 {
+		opts.Sorters = nil
+	}
+
+	query, args, err := d.findQuery(opts, noteSelectionID)
+	if err != nil {
+		return stats, err
+	}
+	// Notes taken into account by the statistics.
+	scope := "WITH scope AS (SELECT id FROM (\n" + query + "))\n"
+
+	err = d.tx.QueryRowContext(ctx, scope+`
+		SELECT COUNT(*), COALESCE(SUM(word_count), 0)
+		  FROM notes
+		 WHERE id IN scope
+	`, args...).Scan(&stats.NoteCount, &stats.WordCount)
+	if err != nil {
+		return stats, d.statsError(ctx, opts, err)
+	}
+
+	err = d.tx.QueryRowContext(ctx, scope+`
+		SELECT COALESCE(SUM(external = 0 AND target_id IS NOT NULL), 0),
+		       COALESCE(SUM(external = 1), 0),
+		       COALESCE(SUM(external = 0 AND target_id IS NULL), 0)
+		  FROM links
+		 WHERE source_id IN scope
+	`, args...).Scan(&stats.Links.Internal, &stats.Links.External, &stats.Links.Dead)
+	if err != nil {
+		return stats, d.statsError(ctx, opts, err)
+	}
+
+	stats.Directories, err = d.statsEntries(ctx, opts, scope+`
+		SELECT CASE WHEN instr(path, '/') > 0 THEN substr(path, 1, instr(path, '/') - 1) ELSE '.' END AS name, COUNT(*) AS count
+		  FROM notes
+		 WHERE id IN scope
+		 GROUP BY name
+		 ORDER BY count DESC, name ASC
+	`, args...)
+	if err != nil {
+		return stats, err
+	}
+
+	stats.Tags, err = d.statsEntries(ctx, opts, scope+`
+		SELECT c.name, COUNT(DISTINCT nc.note_id) AS count
+		  FROM collections c
+		  JOIN notes_collections nc ON nc.collection_id = c.id
+		 WHERE c.kind = '`+string(core.CollectionKindTag)+`'
+		   AND nc.note_id IN scope
+		 GROUP BY c.id
+		 ORDER BY count DESC, c.name ASC
+	`, args...)
+	if err != nil {
+		return stats, err
+	}
+
+	stats.CreationMonths, err = d.statsEntries(ctx, opts, scope+`
+		SELECT substr(created, 1, 7) AS month, COUNT(*) AS count
+		  FROM notes
+		 WHERE id IN scope
+		 GROUP BY month
+		 ORDER BY count DESC, month DESC
+	`, args...)
+	return stats, err
+}
+
+// statsEntries runs a query selecting a name and a count.
+func (d *NoteDAO) statsEntries(ctx context.Context, opts core.NoteFindOpts, query string, args ...interface{}) ([]core.StatsEntry, error) {
+	entries := []core.StatsEntry{}
+
+	rows, err := d.tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return entries, d.statsError(ctx, opts, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entry core.StatsEntry
+		err := rows.Scan(&entry.Name, &entry.Count)
+		if err != nil {
+			return entries, err
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, d.statsError(ctx, opts, rows.Err())
+}
+
+func (d *NoteDAO) statsError(ctx context.Context, opts core.NoteFindOpts, err error) error {
+	if err != nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return d.matchQueryError(opts, err)
+}
+
+// ftsMatchExpr converts a match filter to the FTS5 expression of the MATCH
+// operator.
+func ftsMatchExpr(match string, opts core.NoteFindOpts) string {
+	if opts.MatchStrategy == core.MatchStrategyPhrase {
+		match = fts5.QuotePhrase(match)
+	} else {
+		match = fts5.ConvertQuery(match)
+	}
+	switch opts.MatchScope {
+	case core.MatchScopeTitle:
+		// The aliases are alternative titles of the note.
+		match = "{title aliases} : (" + match + ")"
+	case core.MatchScopeBody:
+		match = "body : (" + match + ")"
+	}
+	return match
+}
+
+// matchQueryError converts an error raised by SQLite while parsing a
+// full-text search query into a core.InvalidMatchQueryError.
+//
+// SQLite reports an unknown FTS5 column like an unknown SQL column, so each
+// MATCH expression is checked on its own to not hide actual errors in the
+// SQL query.
+func (d *NoteDAO) matchQueryError(opts core.NoteFindOpts, err error) error {
+	if err == nil || opts.MatchStrategy != core.MatchStrategyFts {
+		return err
+	}
+	for _, match := range opts.Match {
+		rows, matchErr := d.tx.Query("SELECT rowid FROM notes_fts WHERE notes_fts MATCH ? LIMIT 1", ftsMatchExpr(match, opts))
+		if matchErr == nil {
+			for rows.Next() {
+			}
+			matchErr = rows.Err()
+			rows.Close()
+		}
+		if matchErr != nil {
+			return core.InvalidMatchQueryError{Query: strings.Join(opts.Match, " "), Err: matchErr}
+		}
+	}
+	return err
 }
 
 // parseListFromNullString splits a 0-separated string.
@@ -375,7 +1269,7 @@ func parseListFromNullString(str sql.NullString) []string {
 
 // expandMentionsIntoMatch finds the titles associated with the notes in opts.Mention to
 // expand them into the opts.Match predicate.
-func (d *NoteDAO) expandMentionsIntoMatch(opts core.NoteFindOpts) (core.NoteFindOpts, error) {
+func (d *NoteDAO) expandMentionsIntoMatch(ctx context.Context, opts core.NoteFindOpts) (core.NoteFindOpts, error) {
 	if opts.Mention == nil {
 		return opts, nil
 	}
@@ -397,7 +1291,7 @@ func (d *NoteDAO) expandMentionsIntoMatch(opts core.NoteFindOpts) (core.NoteFind
 
 	// Find their titles.
 	titlesQuery := "SELECT title, metadata FROM notes WHERE id IN (" + joinNoteIDs(ids, ",") + ")"
-	rows, err := d.tx.Query(titlesQuery)
+	rows, err := d.tx.QueryContext(ctx, titlesQuery)
 	if err != nil {
 		return opts, err
 	}
@@ -434,8 +1328,20 @@ const (
 	noteSelectionFull
 )
 
-func (d *NoteDAO) findRows(opts core.NoteFindOpts, selection noteSelection) (*sql.Rows, error) {
+func (d *NoteDAO) findRows(ctx context.Context, opts core.NoteFindOpts, selection noteSelection) (*sql.Rows, error) {
+	query, args, err := d.findQuery(opts, selection)
+	if err != nil {
+		return nil, err
+	}
+	return d.tx.QueryContext(ctx, query, args...)
+}
+
+// findQuery builds the SQL query and its arguments to find the notes matching
+// the given criteria.
+func (d *NoteDAO) findQuery(opts core.NoteFindOpts, selection noteSelection) (string, []interface{}, error) {
 	snippetCol := `n.lead`
+	// Rank of the notes matching the full-text search, lower is better.
+	rankCol := ""
 	joinClauses := []string{}
 	whereExprs := []string{}
 	additionalOrderTerms := []string{}
@@ -446,13 +1352,10 @@ func (d *NoteDAO) findRows(opts core.NoteFindOpts, selection noteSelection) (*sq
 	maxDistance := 0
 
 	setupLinkFilter := func(tableAlias string, hrefs []string, direction int, negate, recursive bool) error {
-		ids, err := d.findIdsByHrefs(hrefs, true /* allowPartialHrefs */)
+		ids, err := d.findLinkFilterIDs(hrefs)
 		if err != nil {
 			return err
 		}
-		if len(ids) == 0 {
-			return fmt.Errorf("could not find notes at: " + strings.Join(hrefs, ", "))
-		}
 		idsList := "(" + joinNoteIDs(ids, ",") + ")"
 
 		linksSrc := "links"
@@ -465,7 +1368,10 @@ func (d *NoteDAO) findRows(opts core.NoteFindOpts, selection noteSelection) (*sq
 
 		if !negate {
 			if direction != 0 {
-				snippetCol = fmt.Sprintf("GROUP_CONCAT(REPLACE(%s.snippet, %[1]s.title, '<zk:match>' || %[1]s.title || '</zk:match>'), '\x01')", tableAlias)
+				delimiters := snippetDelimiters(opts)
+				snippetCol = fmt.Sprintf("GROUP_CONCAT(REPLACE(%s.snippet, %[1]s.title, %[2]s || %[1]s.title || %[3]s), '\x01')",
+					tableAlias, sqlString(delimiters.Open), sqlString(delimiters.Close),
+				)
 			}
 
 			joinOns := make([]string, 0)
@@ -517,23 +1423,54 @@ func (d *NoteDAO) findRows(opts core.NoteFindOpts, selection noteSelection) (*sq
 	}
 
 	if 0 < len(opts.Match) {
+		// Column searched with the exact and regular expression strategies,
+		// and index of the FTS column used to build the snippets. The body
+		// is searched when the raw content is not stored.
+		matchCol := "COALESCE(n.raw_content, n.body)"
+		snippetColIndex := 2
+		switch opts.MatchScope {
+		case core.MatchScopeTitle:
+			matchCol = "n.title"
+			snippetColIndex = 1
+		case core.MatchScopeBody:
+			matchCol = "n.body"
+		}
+
 		switch opts.MatchStrategy {
 		case core.MatchStrategyExact:
 			for _, match := range opts.Match {
-				whereExprs = append(whereExprs, `n.raw_content LIKE '%' || ? || '%' ESCAPE '\'`)
+				whereExprs = append(whereExprs, matchCol+` LIKE '%' || ? || '%' ESCAPE '\'`)
 				args = append(args, escapeLikeTerm(match, '\\'))
 			}
-		case core.MatchStrategyFts:
-			snippetCol = `snippet(fts_match.notes_fts, 2, '<zk:match>', '</zk:match>', '…', 20)`
+		case core.MatchStrategyFts, core.MatchStrategyPhrase:
+			snippetCol = snippetExpr("fts_match", snippetColIndex, opts)
+			if opts.MatchScope != core.MatchScopeBody {
+				// Shows the matching alias when the hit was only via an alias.
+				snippetCol = fmt.Sprintf(
+					"CASE WHEN NOT %[1]s AND %[2]s THEN 'alias: ' || %[3]s ELSE %[4]s END",
+					ftsColumnMatches("fts_match", snippetColIndex), ftsColumnMatches("fts_match", 3),
+					snippetExpr("fts_match", 3, opts), snippetCol,
+				)
+			}
+			if opts.MaxSnippets > 1 && opts.MatchScope == core.MatchScopeAny {
+				// Returns the snippets of both the title and body when they
+				// match, otherwise the body snippet.
+				titleSnippet := snippetExpr("fts_match", 1, opts)
+				snippetCol = fmt.Sprintf(
+					"CASE WHEN NOT %[1]s THEN %[3]s WHEN %[2]s THEN %[4]s || '\x01' || %[3]s ELSE %[4]s END",
+					ftsColumnMatches("fts_match", 1), ftsColumnMatches("fts_match", 2), snippetCol, titleSnippet,
+				)
+			}
 			joinClauses = append(joinClauses, "JOIN notes_fts fts_match ON n.id = fts_match.rowid")
-			additionalOrderTerms = append(additionalOrderTerms, `bm25(fts_match.notes_fts, 1000.0, 500.0, 1.0)`)
+			rankCol = `bm25(fts_match.notes_fts, 1000.0, 500.0, 1.0, 500.0)`
+			additionalOrderTerms = append(additionalOrderTerms, rankCol)
 			for _, match := range opts.Match {
 				whereExprs = append(whereExprs, "fts_match.notes_fts MATCH ?")
-				args = append(args, fts5.ConvertQuery(match))
+				args = append(args, ftsMatchExpr(match, opts))
 			}
 		case core.MatchStrategyRe:
 			for _, match := range opts.Match {
-				whereExprs = append(whereExprs, "n.raw_content REGEXP ?")
+				whereExprs = append(whereExprs, matchCol+" REGEXP ?")
 				args = append(args, match)
 			}
 			break
@@ -543,7 +1480,7 @@ func (d *NoteDAO) findRows(opts core.NoteFindOpts, selection noteSelection) (*sq
 	if opts.IncludeHrefs != nil {
 		ids, err := d.findIdsByHrefs(opts.IncludeHrefs, opts.AllowPartialHrefs)
 		if err != nil {
-			return nil, err
+			return "", nil, err
 		}
 		opts = opts.IncludingIDs(ids)
 	}
@@ -551,11 +1488,44 @@ func (d *NoteDAO) findRows(opts core.NoteFindOpts, selection noteSelection) (*sq
 	if opts.ExcludeHrefs != nil {
 		ids, err := d.findIdsByHrefs(opts.ExcludeHrefs, opts.AllowPartialHrefs)
 		if err != nil {
-			return nil, err
+			return "", nil, err
 		}
 		opts = opts.ExcludingIDs(ids)
 	}
 
+	if opts.ExactPaths != nil {
+		placeholders := make([]string, 0)
+		for _, path := range opts.ExactPaths {
+			path = filepath.Clean(path)
+			placeholders = append(placeholders, "?")
+			args = append(args, path)
+			if filepath.Ext(path) == "" {
+				for _, ext := range d.extensions() {
+					placeholders = append(placeholders, "?")
+					args = append(args, path+"."+ext)
+				}
+			}
+		}
+		whereExprs = append(whereExprs, "n.path IN ("+strings.Join(placeholders, ", ")+")")
+	}
+
+	for _, filter := range []struct {
+		pattern string
+		expr    string
+	}{
+		{opts.PathRegex, "n.path REGEXP ?"},
+		{opts.ExcludePathRegex, "n.path NOT REGEXP ?"},
+	} {
+		if filter.pattern == "" {
+			continue
+		}
+		if _, err := regexp.Compile(filter.pattern); err != nil {
+			return "", nil, errors.Wrapf(err, "%s: invalid path regular expression", filter.pattern)
+		}
+		whereExprs = append(whereExprs, filter.expr)
+		args = append(args, filter.pattern)
+	}
+
 	if opts.Tags != nil {
 		separatorRegex := regexp.MustCompile(`(\ OR\ )|\|`)
 		for _, tagsArg := range opts.Tags {
@@ -586,7 +1556,7 @@ func (d *NoteDAO) findRows(opts core.NoteFindOpts, selection noteSelection) (*sq
 				continue
 			}
 			if negate && len(globs) > 1 {
-				return nil, fmt.Errorf("cannot negate a tag in a OR group: %s", tagsArg)
+				return "", nil, fmt.Errorf("cannot negate a tag in a OR group: %s", tagsArg)
 			}
 
 			expr := "n.id"
@@ -607,17 +1577,19 @@ WHERE collection_id IN (SELECT id FROM collections t WHERE kind = '%s' AND (%s))
 	if opts.MentionedBy != nil {
 		ids, err := d.findIdsByHrefs(opts.MentionedBy, true /* allowPartialHrefs */)
 		if err != nil {
-			return nil, err
+			return "", nil, err
 		}
 		if len(ids) == 0 {
-			return nil, fmt.Errorf("could not find notes at: " + strings.Join(opts.MentionedBy, ", "))
+			return "", nil, fmt.Errorf("could not find notes at: " + strings.Join(opts.MentionedBy, ", "))
 		}
 
 		// Exclude the mentioning notes from the results.
 		opts = opts.ExcludingIDs(ids)
 
-		snippetCol = `snippet(nsrc.notes_fts, 2, '<zk:match>', '</zk:match>', '…', 20)`
-		joinClauses = append(joinClauses, "JOIN notes_fts nsrc ON nsrc.rowid IN ("+joinNoteIDs(ids, ",")+") AND nsrc.notes_fts MATCH mention_query(n.title, n.metadata)")
+		snippetCol = snippetExpr("nsrc", 2, opts)
+		// Joins only the first mentioning note, to return a note mentioned
+		// by several sources only once.
+		joinClauses = append(joinClauses, "JOIN notes_fts nsrc ON nsrc.rowid = (SELECT s.rowid FROM notes_fts s WHERE s.rowid IN ("+joinNoteIDs(ids, ",")+") AND s.notes_fts MATCH mention_query(n.title, n.metadata) LIMIT 1) AND nsrc.notes_fts MATCH mention_query(n.title, n.metadata)")
 	}
 
 	if opts.LinkedBy != nil {
@@ -625,7 +1597,7 @@ WHERE collection_id IN (SELECT id FROM collections t WHERE kind = '%s' AND (%s))
 		maxDistance = filter.MaxDistance
 		err := setupLinkFilter("l_by", filter.Hrefs, -1, filter.Negate, filter.Recursive)
 		if err != nil {
-			return nil, err
+			return "", nil, err
 		}
 	}
 
@@ -634,7 +1606,7 @@ WHERE collection_id IN (SELECT id FROM collections t WHERE kind = '%s' AND (%s))
 		maxDistance = filter.MaxDistance
 		err := setupLinkFilter("l_to", filter.Hrefs, 1, filter.Negate, filter.Recursive)
 		if err != nil {
-			return nil, err
+			return "", nil, err
 		}
 	}
 
@@ -642,14 +1614,21 @@ WHERE collection_id IN (SELECT id FROM collections t WHERE kind = '%s' AND (%s))
 		maxDistance = 2
 		err := setupLinkFilter("l_rel", opts.Related, 0, false, true)
 		if err != nil {
-			return nil, err
+			return "", nil, err
 		}
 		groupBy += " HAVING MIN(l_rel.distance) = 2"
 	}
 
 	if opts.Orphan {
+		// Links from a note to itself are not considered as incoming links.
 		whereExprs = append(whereExprs, `n.id NOT IN (
-			SELECT target_id FROM links WHERE target_id IS NOT NULL
+			SELECT target_id FROM links WHERE target_id IS NOT NULL AND target_id != source_id
+		)`)
+	}
+
+	if opts.DeadLinks {
+		whereExprs = append(whereExprs, `n.id IN (
+			SELECT source_id FROM links WHERE target_id IS NULL AND external = 0
 		)`)
 	}
 
@@ -657,40 +1636,67 @@ WHERE collection_id IN (SELECT id FROM collections t WHERE kind = '%s' AND (%s))
 		whereExprs = append(whereExprs, `tags IS NULL`)
 	}
 
-	if opts.CreatedStart != nil {
-		whereExprs = append(whereExprs, "created >= ?")
-		args = append(args, opts.CreatedStart)
+	if opts.Untitled {
+		whereExprs = append(whereExprs, `(n.title = '' OR n.title IS NULL)`)
 	}
 
-	if opts.CreatedEnd != nil {
-		whereExprs = append(whereExprs, "created < ?")
-		args = append(args, opts.CreatedEnd)
+	if expr, dateArgs := dateRangeExpr("created", opts.CreatedStart, opts.CreatedEnd); expr != "" {
+		whereExprs = append(whereExprs, expr)
+		args = append(args, dateArgs...)
 	}
 
-	if opts.ModifiedStart != nil {
-		whereExprs = append(whereExprs, "modified >= ?")
-		args = append(args, opts.ModifiedStart)
+	if expr, dateArgs := dateRangeExpr("modified", opts.ModifiedStart, opts.ModifiedEnd); expr != "" {
+		whereExprs = append(whereExprs, expr)
+		args = append(args, dateArgs...)
 	}
 
-	if opts.ModifiedEnd != nil {
-		whereExprs = append(whereExprs, "modified < ?")
-		args = append(args, opts.ModifiedEnd)
+	for _, filter := range opts.Metadata {
+		// json_extract() returns booleans as 1 and 0.
+		whereExprs = append(whereExprs, `CASE json_type(n.metadata, ?)
+			WHEN 'true' THEN 'true'
+			WHEN 'false' THEN 'false'
+			ELSE CAST(json_extract(n.metadata, ?) AS TEXT)
+		END = ?`)
+		path := jsonPath(filter.Key)
+		args = append(args, path, path, filter.Value)
+	}
+
+	if opts.WordCount != nil {
+		if opts.WordCount.Min > 0 {
+			whereExprs = append(whereExprs, "n.word_count >= ?")
+			args = append(args, opts.WordCount.Min)
+		}
+		if opts.WordCount.Max > 0 {
+			whereExprs = append(whereExprs, "n.word_count <= ?")
+			args = append(args, opts.WordCount.Max)
+		}
 	}
 
 	if opts.IncludeIDs != nil {
 		whereExprs = append(whereExprs, "n.id IN ("+joinNoteIDs(opts.IncludeIDs, ",")+")")
 	}
 
-	if opts.ExcludeIDs != nil {
+	// The IDs are inlined with the query instead of being bound as arguments,
+	// to not reach the SQLite limit of host parameters with large lists.
+	if len(opts.ExcludeIDs) > 0 {
 		whereExprs = append(whereExprs, "n.id NOT IN ("+joinNoteIDs(opts.ExcludeIDs, ",")+")")
 	}
 
 	orderTerms := []string{}
 	for _, sorter := range opts.Sorters {
+		if sorter.Field == core.NoteSortRelevance {
+			// Notes can only be sorted by relevance with a full-text search.
+			if rankCol != "" {
+				orderTerms = append(orderTerms, rankTerm(rankCol, sorter.Ascending))
+			}
+			continue
+		}
 		orderTerms = append(orderTerms, orderTerm(sorter))
 	}
 	orderTerms = append(orderTerms, additionalOrderTerms...)
-	orderTerms = append(orderTerms, `n.title ASC`)
+	// Ties are broken by the title, then by the path to get a deterministic
+	// order.
+	orderTerms = append(orderTerms, `n.title ASC`, `n.path ASC`)
 
 	query := ""
 
@@ -726,7 +1732,11 @@ WHERE collection_id IN (SELECT id FROM collections t WHERE kind = '%s' AND (%s))
 	if selection != noteSelectionID {
 		query += ", n.path, n.title, n.metadata"
 		if selection != noteSelectionMinimal {
-			query += fmt.Sprintf(", n.lead, n.body, n.raw_content, n.word_count, n.created, n.modified, n.checksum, n.tags, %s AS snippet", snippetCol)
+			scoreCol := "0"
+			if rankCol != "" {
+				scoreCol = "-" + rankCol
+			}
+			query += fmt.Sprintf(", n.lead, n.body, n.raw_content, n.word_count, n.created, n.modified, n.checksum, n.tags, %s AS snippet, %s AS score", snippetCol, scoreCol)
 		}
 	}
 
@@ -748,12 +1758,19 @@ WHERE collection_id IN (SELECT id FROM collections t WHERE kind = '%s' AND (%s))
 
 	if opts.Limit > 0 {
 		query += fmt.Sprintf("LIMIT %d\n", opts.Limit)
+	} else if opts.Offset > 0 {
+		// SQLite requires a LIMIT clause to use OFFSET, -1 means no limit.
+		query += "LIMIT -1\n"
+	}
+
+	if opts.Offset > 0 {
+		query += fmt.Sprintf("OFFSET %d\n", opts.Offset)
 	}
 
 	// d.logger.Println(query)
 	// d.logger.Println(args)
 
-	return d.tx.Query(query, args...)
+	return query, args, nil
 }
 
 func (d *NoteDAO) scanNoteID(row RowScanner) (core.NoteID, error) {
@@ -798,16 +1815,17 @@ func (d *NoteDAO) scanMinimalNote(row RowScanner) (*core.MinimalNote, error) {
 
 func (d *NoteDAO) scanNote(row RowScanner) (*core.ContextualNote, error) {
 	var (
-		id, wordCount                 int
-		title, lead, body, rawContent string
-		snippets, tags                sql.NullString
-		path, metadataJSON, checksum  string
-		created, modified             time.Time
+		id, wordCount                int
+		title, lead, body            string
+		rawContent, snippets, tags   sql.NullString
+		path, metadataJSON, checksum string
+		created, modified            time.Time
+		score                        float64
 	)
 
 	err := row.Scan(
 		&id, &path, &title, &metadataJSON, &lead, &body, &rawContent,
-		&wordCount, &created, &modified, &checksum, &tags, &snippets,
+		&wordCount, &created, &modified, &checksum, &tags, &snippets, &score,
 	)
 	switch {
 	case err == sql.ErrNoRows:
@@ -820,15 +1838,21 @@ func (d *NoteDAO) scanNote(row RowScanner) (*core.ContextualNote, error) {
 			d.logger.Err(errors.Wrap(err, path))
 		}
 
+		// Without the raw content, the matches are located in the body.
+		if !rawContent.Valid {
+			rawContent.String = body
+		}
+
 		return &core.ContextualNote{
 			Snippets: parseListFromNullString(snippets),
+			Score:    score,
 			Note: core.Note{
 				ID:         core.NoteID(id),
 				Path:       path,
 				Title:      title,
 				Lead:       lead,
 				Body:       body,
-				RawContent: rawContent,
+				RawContent: rawContent.String,
 				WordCount:  wordCount,
 				Links:      []core.Link{},
 				Tags:       parseListFromNullString(tags),
@@ -841,6 +1865,74 @@ func (d *NoteDAO) scanNote(row RowScanner) (*core.ContextualNote, error) {
 	}
 }
 
+// dateRangeExpr builds a single predicate matching the given date column
+// against a range, inclusive of start and exclusive of end. Each bound is
+// optional.
+func dateRangeExpr(column string, start, end *time.Time) (string, []interface{}) {
+	switch {
+	case start != nil && end != nil:
+		return fmt.Sprintf("(%[1]s >= ? AND %[1]s < ?)", column), []interface{}{start, end}
+	case start != nil:
+		return column + " >= ?", []interface{}{start}
+	case end != nil:
+		return column + " < ?", []interface{}{end}
+	default:
+		return "", nil
+	}
+}
+
+// jsonPath converts a dot-separated metadata key into a SQLite JSON path.
+func jsonPath(key string) string {
+	path := "$"
+	for _, segment := range strings.Split(key, ".") {
+		path += `."` + strings.ReplaceAll(segment, `"`, `\"`) + `"`
+	}
+	return path
+}
+
+// snippetExpr returns the SQL expression building a snippet of the FTS
+// column at the given index, for the FTS table alias.
+func snippetExpr(tableAlias string, colIndex int, opts core.NoteFindOpts) string {
+	length := opts.SnippetLength
+	if length <= 0 {
+		length = 20
+	} else if length > 64 {
+		length = 64
+	}
+	delimiters := snippetDelimiters(opts)
+	return fmt.Sprintf(`snippet(%s.notes_fts, %d, %s, %s, '…', %d)`,
+		tableAlias, colIndex, sqlString(delimiters.Open), sqlString(delimiters.Close), length,
+	)
+}
+
+func snippetDelimiters(opts core.NoteFindOpts) core.SnippetDelimiters {
+	if opts.SnippetDelimiters == nil {
+		return core.DefaultSnippetDelimiters
+	}
+	return *opts.SnippetDelimiters
+}
+
+// sqlString returns the given string as a SQL string literal.
+func sqlString(str string) string {
+	return "'" + strings.ReplaceAll(str, "'", "''") + "'"
+}
+
+// ftsColumnMatches returns a SQL predicate checking whether the FTS column at
+// the given index contains a match for the current full-text search.
+func ftsColumnMatches(tableAlias string, colIndex int) string {
+	return fmt.Sprintf(`instr(highlight(%s.notes_fts, %d, char(2), char(3)), char(2)) > 0`, tableAlias, colIndex)
+}
+
+// rankTerm returns the ORDER BY term to sort the notes by relevance, using
+// the given rank expression.
+func rankTerm(rankCol string, ascending bool) string {
+	// A lower rank means a more relevant note.
+	if ascending {
+		return rankCol + " DESC"
+	}
+	return rankCol + " ASC"
+}
+
 func orderTerm(sorter core.NoteSorter) string {
 	order := " ASC"
 	if !sorter.Ascending {
@@ -1,6 +1,7 @@
 package sqlite
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"testing"
@@ -8,6 +9,7 @@ import (
 
 	"github.com/zk-org/zk/internal/core"
 	"github.com/zk-org/zk/internal/util"
+	"github.com/zk-org/zk/internal/util/errors"
 	"github.com/zk-org/zk/internal/util/opt"
 	"github.com/zk-org/zk/internal/util/paths"
 	"github.com/zk-org/zk/internal/util/test/assert"
@@ -19,6 +21,8 @@ func TestNoteDAOIndexed(t *testing.T) {
 			{
 				Path:     "a.md",
 				Modified: time.Date(2020, 1, 20, 8, 52, 42, 0, time.UTC),
+				Size:     42,
+				Checksum: "qwfpgj",
 			},
 			{
 				Path:     "dir1/a.md",
@@ -64,6 +68,8 @@ func TestNoteDAOIndexed(t *testing.T) {
 			{
 				Path:     "a.md",
 				Modified: time.Date(2020, 1, 20, 8, 52, 42, 0, time.UTC),
+				Size:     42,
+				Checksum: "qwfpgj",
 			},
 			{
 				Path:     "b.md",
@@ -104,7 +110,7 @@ func TestNoteDAOIndexed(t *testing.T) {
 
 func TestNoteDAOAdd(t *testing.T) {
 	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
-		_, err := dao.Add(core.Note{
+		id, err := dao.Add(core.Note{
 			Path:       "log/added.md",
 			Title:      "Added note",
 			Lead:       "Note",
@@ -132,6 +138,12 @@ func TestNoteDAOAdd(t *testing.T) {
 			Modified:   time.Date(2020, 11, 22, 16, 49, 47, 0, time.UTC),
 			Metadata:   `{"key":"value"}`,
 		})
+
+		var rowID core.NoteID
+		err = tx.QueryRow(`SELECT id FROM notes WHERE path = "log/added.md"`).Scan(&rowID)
+		assert.Nil(t, err)
+		assert.Equal(t, id, rowID)
+		assert.Equal(t, id, core.NoteID(9))
 	})
 }
 
@@ -143,6 +155,209 @@ func TestNoteDAOAddExistingNote(t *testing.T) {
 	})
 }
 
+func TestNoteDAOAddAll(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		// More notes than a single chunk.
+		notes := benchmarkNotes(120)
+		err := dao.AddAll(notes)
+		assert.Nil(t, err)
+
+		var count int
+		err = tx.QueryRow(`SELECT COUNT(*) FROM notes`).Scan(&count)
+		assert.Nil(t, err)
+		assert.Equal(t, count, 128)
+
+		row, err := queryNoteRow(tx, `path = "dir7/note117.md"`)
+		assert.Nil(t, err)
+		assert.Equal(t, row.Title, "Note 117")
+		assert.Equal(t, row.Checksum, "checksum117")
+
+		// The FTS index is populated as well.
+		found, err := dao.Find(context.Background(), core.NoteFindOpts{
+			Match:         []string{"synthetic"},
+			MatchStrategy: core.MatchStrategyFts,
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, len(found), 120)
+	})
+}
+
+// The error reports the path of the note which could not be added.
+func TestNoteDAOAddAllWithExistingNote(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		err := dao.AddAll([]core.Note{
+			{Path: "log/added.md"},
+			{Path: "ref/test/a.md"},
+			{Path: "log/other.md"},
+		})
+		assert.Err(t, err, "ref/test/a.md: failed to add the note")
+		assert.Err(t, err, "UNIQUE constraint failed: notes.path")
+	})
+}
+
+func BenchmarkNoteDAOAdd(b *testing.B) {
+	notes := benchmarkNotes(1000)
+	for i := 0; i < b.N; i++ {
+		benchmarkNoteDAO(b, func(dao *NoteDAO) {
+			for _, note := range notes {
+				if _, err := dao.Add(note); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkNoteDAOAddAll(b *testing.B) {
+	notes := benchmarkNotes(1000)
+	for i := 0; i < b.N; i++ {
+		benchmarkNoteDAO(b, func(dao *NoteDAO) {
+			if err := dao.AddAll(notes); err != nil {
+				b.Fatal(err)
+			}
+		})
+	}
+}
+
+func BenchmarkNoteDAOFind(b *testing.B) {
+	benchmarkNoteDAOFind(b, func(dao *NoteDAO) error {
+		_, err := dao.Find(context.Background(), core.NoteFindOpts{})
+		return err
+	})
+}
+
+func BenchmarkNoteDAOFindMinimal(b *testing.B) {
+	benchmarkNoteDAOFind(b, func(dao *NoteDAO) error {
+		_, err := dao.FindMinimal(context.Background(), core.NoteFindOpts{})
+		return err
+	})
+}
+
+// benchmarkNoteDAOFind runs the find callback against 10,000 synthetic notes.
+func benchmarkNoteDAOFind(b *testing.B, find func(dao *NoteDAO) error) {
+	benchmarkNoteDAO(b, func(dao *NoteDAO) {
+		if err := dao.AddAll(benchmarkNotes(10000)); err != nil {
+			b.Fatal(err)
+		}
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if err := find(dao); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// benchmarkNotes creates a list of synthetic notes.
+func benchmarkNotes(count int) []core.Note {
+	notes := make([]core.Note, 0, count)
+	for i := 0; i < count; i++ {
+		notes = append(notes, core.Note{
+			Path:       fmt.Sprintf("dir%d/note%d.md", i%10, i),
+			Title:      fmt.Sprintf("Note %d", i),
+			Body:       "Synthetic note body with a few words",
+			RawContent: fmt.Sprintf("# Note %d\nSynthetic note body with a few words", i),
+			WordCount:  7,
+			Checksum:   fmt.Sprintf("checksum%d", i),
+		})
+	}
+	return notes
+}
+
+func benchmarkNoteDAO(b *testing.B, callback func(dao *NoteDAO)) {
+	db, err := OpenInMemory()
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer db.Close()
+
+	err = db.WithTransaction(func(tx Transaction) error {
+		callback(NewNoteDAO(tx, &util.NullLogger))
+		return nil
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+}
+
+func TestNoteDAOAddOrUpdateNewNote(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		id, err := dao.AddOrUpdate(core.Note{
+			Path:       "log/added.md",
+			Title:      "Added note",
+			Lead:       "Note",
+			Body:       "Note body",
+			RawContent: "# Added note\nNote body",
+			WordCount:  2,
+			Metadata:   map[string]interface{}{"key": "value"},
+			Created:    time.Date(2019, 11, 20, 20, 32, 56, 0, time.UTC),
+			Modified:   time.Date(2020, 11, 22, 16, 49, 47, 0, time.UTC),
+			Checksum:   "check",
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, id, core.NoteID(9))
+
+		row, err := queryNoteRow(tx, `path = "log/added.md"`)
+		assert.Nil(t, err)
+		assert.Equal(t, row, noteRow{
+			Path:       "log/added.md",
+			Title:      "Added note",
+			Lead:       "Note",
+			Body:       "Note body",
+			RawContent: "# Added note\nNote body",
+			WordCount:  2,
+			Checksum:   "check",
+			Created:    time.Date(2019, 11, 20, 20, 32, 56, 0, time.UTC),
+			Modified:   time.Date(2020, 11, 22, 16, 49, 47, 0, time.UTC),
+			Metadata:   `{"key":"value"}`,
+		})
+	})
+}
+
+// The creation date of an existing note is not overwritten.
+func TestNoteDAOAddOrUpdateExistingNote(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		id, err := dao.AddOrUpdate(core.Note{
+			Path:       "ref/test/a.md",
+			Title:      "Updated note",
+			Lead:       "Updated lead",
+			Body:       "Updated body",
+			RawContent: "Updated raw content",
+			Checksum:   "updated checksum",
+			Metadata:   map[string]interface{}{"updated-key": "updated-value"},
+			WordCount:  42,
+			Created:    time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+			Modified:   time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC),
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, id, core.NoteID(6))
+
+		row, err := queryNoteRow(tx, `path = "ref/test/a.md"`)
+		assert.Nil(t, err)
+		assert.Equal(t, row, noteRow{
+			Path:       "ref/test/a.md",
+			Title:      "Updated note",
+			Lead:       "Updated lead",
+			Body:       "Updated body",
+			RawContent: "Updated raw content",
+			Checksum:   "updated checksum",
+			WordCount:  42,
+			Created:    time.Date(2019, 11, 20, 20, 32, 56, 0, time.UTC),
+			Modified:   time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC),
+			Metadata:   `{"updated-key":"updated-value"}`,
+		})
+
+		// The FTS index is updated as well.
+		notes, err := dao.Find(context.Background(), core.NoteFindOpts{
+			Match:         []string{"updated"},
+			MatchStrategy: core.MatchStrategyFts,
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, len(notes), 1)
+		assert.Equal(t, notes[0].ID, core.NoteID(6))
+	})
+}
+
 func TestNoteDAOUpdate(t *testing.T) {
 	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
 		id, err := dao.Update(core.Note{
@@ -186,13 +401,114 @@ func TestNoteDAOUpdateUnknown(t *testing.T) {
 	})
 }
 
+func TestNoteDAOSetChecksum(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		err := dao.SetChecksum("ref/test/a.md", "new checksum")
+		assert.Nil(t, err)
+
+		row, err := queryNoteRow(tx, `path = "ref/test/a.md"`)
+		assert.Nil(t, err)
+		assert.Equal(t, row.Checksum, "new checksum")
+		assert.Equal(t, row.Title, "Another nested note")
+	})
+}
+
+func TestNoteDAOSetChecksumUnknown(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		err := dao.SetChecksum("unknown/unknown.md", "new checksum")
+		assert.Err(t, err, "unknown/unknown.md: note not found in the index")
+	})
+}
+
+func TestNoteDAOSetModified(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		modified := time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)
+		err := dao.SetModified("ref/test/a.md", modified, 42)
+		assert.Nil(t, err)
+
+		row, err := queryNoteRow(tx, `path = "ref/test/a.md"`)
+		assert.Nil(t, err)
+		assert.Equal(t, row.Modified, modified)
+		assert.Equal(t, row.Title, "Another nested note")
+
+		var size int64
+		err = tx.QueryRow(`SELECT size FROM notes WHERE path = "ref/test/a.md"`).Scan(&size)
+		assert.Nil(t, err)
+		assert.Equal(t, size, int64(42))
+	})
+}
+
+func TestNoteDAOSetModifiedUnknown(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		err := dao.SetModified("unknown/unknown.md", time.Now(), 42)
+		assert.Err(t, err, "unknown/unknown.md: note not found in the index")
+	})
+}
+
+func TestNoteDAORename(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		id, err := dao.Rename("log/2021-01-03.md", "archive/2021-01-03.md")
+		assert.Nil(t, err)
+		assert.Equal(t, id, core.NoteID(1))
+
+		_, err = queryNoteRow(tx, `path = "log/2021-01-03.md"`)
+		assert.Equal(t, err, sql.ErrNoRows)
+
+		row, err := queryNoteRow(tx, `path = "archive/2021-01-03.md"`)
+		assert.Nil(t, err)
+		assert.Equal(t, row, noteRow{
+			Path:       "archive/2021-01-03.md",
+			Title:      "Daily note",
+			Lead:       "A daily note",
+			Body:       "A daily note\n\nWith lot of content",
+			RawContent: "# Daily note\nA note\n\nWith lot of content",
+			WordCount:  3,
+			Checksum:   "qwfpgj",
+			Created:    time.Date(2020, 11, 22, 16, 27, 45, 0, time.UTC),
+			Modified:   time.Date(2020, 11, 22, 16, 27, 45, 0, time.UTC),
+			Metadata:   `{"author":"Dom"}`,
+		})
+
+		// Links and tags still point to the renamed note.
+		links := queryLinkRows(t, tx, `source_id = 4 AND target_id = 1`)
+		assert.Equal(t, len(links), 1)
+		notes, err := dao.Find(context.Background(), core.NoteFindOpts{Tags: []string{"fiction"}})
+		assert.Nil(t, err)
+		assert.Equal(t, notes[0].Path, "archive/2021-01-03.md")
+
+		// The FTS index is updated with the new path.
+		notes, err = dao.Find(context.Background(), core.NoteFindOpts{
+			Match:         []string{"path:archive*"},
+			MatchStrategy: core.MatchStrategyFts,
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, len(notes), 1)
+		assert.Equal(t, notes[0].ID, core.NoteID(1))
+	})
+}
+
+func TestNoteDAORenameUnknown(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		_, err := dao.Rename("unknown/unknown.md", "unknown/other.md")
+		assert.Err(t, err, "note not found in the index")
+	})
+}
+
+func TestNoteDAORenameToExistingPath(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		_, err := dao.Rename("log/2021-01-03.md", "index.md")
+		assert.Err(t, err, "UNIQUE constraint failed: notes.path")
+	})
+}
+
 func TestNoteDAORemove(t *testing.T) {
 	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
 		_, err := queryNoteRow(tx, `path = "ref/test/a.md"`)
 		assert.Nil(t, err)
 
-		err = dao.Remove("ref/test/a.md")
+		id, err := dao.Remove("ref/test/a.md")
 		assert.Nil(t, err)
+		assert.Equal(t, id, core.NoteID(6))
 
 		_, err = queryNoteRow(tx, `path = "ref/test/a.md"`)
 		assert.Equal(t, err, sql.ErrNoRows)
@@ -201,7 +517,7 @@ func TestNoteDAORemove(t *testing.T) {
 
 func TestNoteDAORemoveUnknown(t *testing.T) {
 	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
-		err := dao.Remove("unknown/unknown.md")
+		_, err := dao.Remove("unknown/unknown.md")
 		assert.Err(t, err, "note not found in the index")
 	})
 }
@@ -215,14 +531,307 @@ func TestNoteDAORemoveCascadeLinks(t *testing.T) {
 		links = queryLinkRows(t, tx, `id = 4`)
 		assert.Equal(t, *links[0].TargetId, core.NoteID(1))
 
-		err := dao.Remove("log/2021-01-03.md")
+		_, err := dao.Remove("log/2021-01-03.md")
+		assert.Nil(t, err)
+
+		links = queryLinkRows(t, tx, `source_id = 1`)
+		assert.Equal(t, len(links), 0)
+
+		links = queryLinkRows(t, tx, `id = 4`)
+		assert.Nil(t, links[0].TargetId)
+	})
+}
+
+func TestNoteDAOGetByIds(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		notes, err := dao.GetByIds([]core.NoteID{7, 42, 3})
+		assert.Nil(t, err)
+		assert.Equal(t, notes, []core.MinimalNote{
+			{ID: 7, Path: "log/2021-02-04.md", Title: "February 4, 2021", Metadata: map[string]interface{}{}},
+			{ID: 3, Path: "index.md", Title: "Index", Metadata: map[string]interface{}{
+				"aliases": []interface{}{"First page"},
+			}},
+		})
+	})
+}
+
+// The IDs are looked up in several queries above the chunk size.
+func TestNoteDAOGetByIdsInChunks(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		ids := []core.NoteID{}
+		for i := 0; i < getByIdsChunkSize*2; i++ {
+			ids = append(ids, core.NoteID(i))
+		}
+		ids = append(ids, 1)
+
+		notes, err := dao.GetByIds(ids)
+		assert.Nil(t, err)
+		assert.Equal(t, len(notes), 9)
+		assert.Equal(t, notes[0].ID, core.NoteID(1))
+		assert.Equal(t, notes[7].ID, core.NoteID(8))
+		assert.Equal(t, notes[8].ID, core.NoteID(1))
+	})
+}
+
+func TestNoteDAOGetByIdsEmpty(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		notes, err := dao.GetByIds([]core.NoteID{})
+		assert.Nil(t, err)
+		assert.Equal(t, notes, []core.MinimalNote{})
+	})
+}
+
+func TestNoteDAOExists(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		test := func(path string, expected bool) {
+			exists, err := dao.Exists(path)
+			assert.Nil(t, err)
+			assert.Equal(t, exists, expected)
+		}
+
+		test("ref/test/a.md", true)
+		test("index.md", true)
+		test("a.md", false)
+		test("ref/test", false)
+		test("ref/test/a", false)
+		test("unknown.md", false)
+	})
+}
+
+func TestNoteDAOGetByPath(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		note, err := dao.GetByPath("ref/test/a.md")
+		assert.Nil(t, err)
+		assert.NotNil(t, note)
+		assert.Equal(t, note.ID, core.NoteID(6))
+		assert.Equal(t, note.Path, "ref/test/a.md")
+		assert.Equal(t, note.Title, "Another nested note")
+		assert.Equal(t, note.Checksum, "iecywst")
+	})
+}
+
+func TestNoteDAOGetByPathUnknown(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		test := func(path string) {
+			note, err := dao.GetByPath(path)
+			assert.Nil(t, err)
+			assert.Nil(t, note)
+		}
+
+		test("a.md")
+		test("ref/test")
+		test("unknown/unknown.md")
+	})
+}
+
+func TestNoteDAOGetRawContent(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		content, err := dao.GetRawContent("index.md")
+		assert.Nil(t, err)
+		assert.Equal(t, content, "# Index\nIndex of the Zettelkasten")
+
+		_, err = dao.GetRawContent("unknown.md")
+		assert.Err(t, err, "unknown.md: note not found in the index")
+	})
+}
+
+// The raw content is not stored by default.
+func TestNoteDAOAddWithoutRawContent(t *testing.T) {
+	testTransaction(t, func(tx Transaction) {
+		dao := NewNoteDAO(tx, &util.NullLogger)
+		note := core.Note{
+			Path:       "log/added.md",
+			Title:      "Added note",
+			Body:       "Note body",
+			RawContent: "# Added note\nNote body",
+		}
+		_, err := dao.Add(note)
+		assert.Nil(t, err)
+		assertRawContentIsNull(t, tx, "log/added.md")
+
+		_, err = dao.GetRawContent("log/added.md")
+		assert.Err(t, err, "log/added.md: the raw content of the note is not indexed")
+
+		// An indexed raw content is cleared when the note is updated.
+		_, err = dao.Update(core.Note{Path: "index.md", RawContent: "Updated raw content"})
+		assert.Nil(t, err)
+		assertRawContentIsNull(t, tx, "index.md")
+
+		// The matches fall back on the body.
+		notes, err := dao.Find(context.Background(), core.NoteFindOpts{
+			Match:         []string{"note body"},
+			MatchStrategy: core.MatchStrategyExact,
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, len(notes), 1)
+		assert.Equal(t, notes[0].Path, "log/added.md")
+		assert.Equal(t, notes[0].RawContent, "Note body")
+	})
+}
+
+func TestNoteDAOAddWithRawContent(t *testing.T) {
+	testTransaction(t, func(tx Transaction) {
+		dao := NewNoteDAOWithOpts(tx, &util.NullLogger, NoteDAOOpts{StoreRawContent: true})
+		_, err := dao.Add(core.Note{
+			Path:       "log/added.md",
+			Body:       "Note body",
+			RawContent: "# Added note\nNote body",
+		})
+		assert.Nil(t, err)
+
+		content, err := dao.GetRawContent("log/added.md")
+		assert.Nil(t, err)
+		assert.Equal(t, content, "# Added note\nNote body")
+
+		_, err = dao.Update(core.Note{Path: "log/added.md", RawContent: "Updated raw content"})
+		assert.Nil(t, err)
+		content, err = dao.GetRawContent("log/added.md")
+		assert.Nil(t, err)
+		assert.Equal(t, content, "Updated raw content")
+	})
+}
+
+func assertRawContentIsNull(t *testing.T, tx Transaction, path string) {
+	var isNull bool
+	err := tx.QueryRow("SELECT raw_content IS NULL FROM notes WHERE path = ?", path).Scan(&isNull)
+	assert.Nil(t, err)
+	assert.True(t, isNull)
+}
+
+// The full-text search indexes the body and not the raw content.
+func TestNoteDAOFindMatchIgnoresRawContent(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		_, err := dao.Add(core.Note{
+			Path:       "frontmatter.md",
+			Title:      "Frontmatter",
+			Body:       "Visible body",
+			RawContent: "---\nstatus: hidden\n---\n# Frontmatter\nVisible body",
+		})
+		assert.Nil(t, err)
+
+		test := func(match string, expected []string) {
+			notes, err := dao.Find(context.Background(), core.NoteFindOpts{
+				Match:         []string{match},
+				MatchStrategy: core.MatchStrategyFts,
+			})
+			assert.Nil(t, err)
+			actual := []string{}
+			for _, note := range notes {
+				actual = append(actual, note.Path)
+			}
+			assert.Equal(t, actual, expected)
+		}
+
+		test("visible", []string{"frontmatter.md"})
+		test("hidden", []string{})
+	})
+}
+
+// The notes found with a match filter are located at their first matched
+// term in the raw content.
+func TestNoteDAOFindMatchLocation(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		_, err := dao.Add(core.Note{
+			Path:       "location.md",
+			Title:      "Location",
+			Body:       "The quick fox.\nA slow Turtle and a quick turtle.",
+			RawContent: "---\nstatus: turtle\n---\n# Location\n\nThe quick fox.\nA slow Turtle and a quick turtle.",
+		})
+		assert.Nil(t, err)
+
+		test := func(strategy core.MatchStrategy, match string, expectedLine, expectedColumn int) {
+			notes, err := dao.Find(context.Background(), core.NoteFindOpts{
+				Match:         []string{match},
+				MatchStrategy: strategy,
+				IncludeHrefs:  []string{"location.md"},
+			})
+			assert.Nil(t, err)
+			assert.Equal(t, len(notes), 1)
+			assert.Equal(t, notes[0].MatchLine, expectedLine)
+			assert.Equal(t, notes[0].MatchColumn, expectedColumn)
+		}
+
+		// The occurrence shown in the body snippet is located, not the one
+		// of the frontmatter.
+		test(core.MatchStrategyFts, "slow turtle", 7, 3)
+		test(core.MatchStrategyFts, "fox", 6, 11)
+		// Only the title matches.
+		test(core.MatchStrategyFts, "location", 4, 3)
+		test(core.MatchStrategyExact, "TURTLE", 2, 9)
+		test(core.MatchStrategyRe, "quick t", 7, 21)
+	})
+
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		notes, err := dao.Find(context.Background(), core.NoteFindOpts{
+			IncludeHrefs: []string{"index.md"},
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, notes[0].MatchLine, 0)
+		assert.Equal(t, notes[0].MatchColumn, 0)
+	})
+}
+
+func TestNoteDAORebuildFTS(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		opts := core.NoteFindOpts{
+			Match:         []string{"zettelkasten"},
+			MatchStrategy: core.MatchStrategyFts,
+		}
+
+		_, err := tx.Exec(`INSERT INTO notes_fts(notes_fts) VALUES('delete-all')`)
+		assert.Nil(t, err)
+		notes, err := dao.Find(context.Background(), opts)
+		assert.Nil(t, err)
+		assert.Equal(t, len(notes), 0)
+
+		err = dao.RebuildFTS()
+		assert.Nil(t, err)
+		notes, err = dao.Find(context.Background(), opts)
+		assert.Nil(t, err)
+		assert.Equal(t, len(notes), 1)
+		assert.Equal(t, notes[0].Path, "index.md")
+	})
+}
+
+func TestNoteDAOOptimize(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		err := dao.Optimize()
+		assert.Nil(t, err)
+
+		notes, err := dao.Find(context.Background(), core.NoteFindOpts{
+			Match:         []string{"zettelkasten"},
+			MatchStrategy: core.MatchStrategyFts,
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, len(notes), 1)
+	})
+}
+
+func TestNoteDAOFindByChecksum(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		notes, err := dao.FindByChecksum("irkwyc")
+		assert.Nil(t, err)
+		assert.Equal(t, notes, []core.MinimalNote{
+			{ID: 4, Path: "f39c8.md", Title: "An interesting note", Metadata: map[string]interface{}{}},
+		})
+
+		notes, err = dao.FindByChecksum("unknown")
 		assert.Nil(t, err)
+		assert.Equal(t, notes, []core.MinimalNote{})
+	})
+}
 
-		links = queryLinkRows(t, tx, `source_id = 1`)
-		assert.Equal(t, len(links), 0)
+func TestNoteDAOFindByChecksumWithDuplicates(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		_, err := dao.Add(core.Note{Path: "archive/f39c8.md", Title: "An interesting note", Checksum: "irkwyc", Metadata: map[string]interface{}{}})
+		assert.Nil(t, err)
 
-		links = queryLinkRows(t, tx, `id = 4`)
-		assert.Nil(t, links[0].TargetId)
+		notes, err := dao.FindByChecksum("irkwyc")
+		assert.Nil(t, err)
+		assert.Equal(t, notes, []core.MinimalNote{
+			{ID: 9, Path: "archive/f39c8.md", Title: "An interesting note", Metadata: map[string]interface{}{}},
+			{ID: 4, Path: "f39c8.md", Title: "An interesting note", Metadata: map[string]interface{}{}},
+		})
 	})
 }
 
@@ -243,6 +852,43 @@ func TestNoteDAOFindIdsByHref(t *testing.T) {
 	test("ref", true, []core.NoteID{8})
 }
 
+func TestNoteDAOFindByHref(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		_, err := dao.Add(core.Note{Path: "archive/a.md", Metadata: map[string]interface{}{}})
+		assert.Nil(t, err)
+
+		test := func(href string, allowPartialHref bool, expected core.NoteID) {
+			actual, err := dao.FindByHref(href, allowPartialHref)
+			assert.Nil(t, err)
+			assert.Equal(t, actual, expected)
+		}
+
+		test("", true, 0)
+		test("#section", true, 0)
+		test("unknown", true, 0)
+
+		// Exact path.
+		test("log/2021-01-03.md", false, 1)
+		test("./log/2021-01-03.md#section", false, 1)
+		// Path without the file extension.
+		test("log/2021-01-03", false, 1)
+		test("index", false, 3)
+		// Unique filename.
+		test("2021-01-03", false, 0)
+		test("2021-01-03", true, 1)
+		test("2021-01-03.md", true, 1)
+		// Unique path suffix.
+		test("test/b", true, 5)
+		test("test/a.md", true, 6)
+		// Ambiguous filename, matching ref/test/a.md and archive/a.md.
+		test("a", true, 0)
+		test("a.md", true, 0)
+		// A prefix of the path is not resolved.
+		test("log/2021-01", false, 0)
+		test("f39", true, 0)
+	})
+}
+
 func TestNoteDAOFindIncludingHrefs(t *testing.T) {
 	test := func(href string, allowPartialHref bool, expected []string) {
 		testNoteDAOFindPaths(t,
@@ -285,9 +931,237 @@ func TestNoteDAOFindExcludingHrefs(t *testing.T) {
 		"log/2021-01-03.md", "log/2021-02-04.md", "index.md", "log/2021-01-04.md"})
 }
 
+func TestNoteDAOFindEach(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		found := []string{}
+		count, err := dao.FindEach(context.Background(), core.NoteFindOpts{IncludeHrefs: []string{"log"}}, func(note core.ContextualNote) error {
+			found = append(found, note.Path)
+			return nil
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, count, 3)
+		assert.Equal(t, found, []string{"log/2021-01-03.md", "log/2021-02-04.md", "log/2021-01-04.md"})
+	})
+}
+
+func TestNoteDAOFindEachStopIteration(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		found := []string{}
+		count, err := dao.FindEach(context.Background(), core.NoteFindOpts{}, func(note core.ContextualNote) error {
+			found = append(found, note.Path)
+			if len(found) == 2 {
+				return ErrStopIteration
+			}
+			return nil
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, count, 2)
+		assert.Equal(t, len(found), 2)
+	})
+}
+
+func TestNoteDAOFindEachCallbackError(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		count, err := dao.FindEach(context.Background(), core.NoteFindOpts{}, func(note core.ContextualNote) error {
+			return errors.New("render failed")
+		})
+		assert.Err(t, err, "render failed")
+		assert.Equal(t, count, 1)
+	})
+}
+
+func TestNoteDAOFindEachCancelled(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		found := []string{}
+		count, err := dao.FindEach(ctx, core.NoteFindOpts{}, func(note core.ContextualNote) error {
+			found = append(found, note.Path)
+			cancel()
+			return nil
+		})
+		assert.Equal(t, err, context.Canceled)
+		assert.Equal(t, count, 1)
+		assert.Equal(t, len(found), 1)
+	})
+}
+
+func TestNoteDAOFindWithCancelledContext(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		notes, err := dao.Find(ctx, core.NoteFindOpts{})
+		assert.Equal(t, err, context.Canceled)
+		assert.Equal(t, len(notes), 0)
+
+		_, err = dao.Count(ctx, core.NoteFindOpts{})
+		assert.Equal(t, err, context.Canceled)
+	})
+}
+
+func TestNoteDAOCount(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		test := func(opts core.NoteFindOpts) {
+			t.Helper()
+			count, err := dao.Count(context.Background(), opts)
+			assert.Nil(t, err)
+
+			notes, err := dao.Find(context.Background(), opts)
+			assert.Nil(t, err)
+			assert.Equal(t, count, len(notes))
+		}
+
+		start := time.Date(2020, 11, 22, 0, 0, 0, 0, time.UTC)
+
+		test(core.NoteFindOpts{})
+		test(core.NoteFindOpts{IncludeHrefs: []string{"log"}})
+		test(core.NoteFindOpts{ExcludeHrefs: []string{"log"}})
+		test(core.NoteFindOpts{Tags: []string{"fiction | adventure"}})
+		test(core.NoteFindOpts{Tagless: true})
+		test(core.NoteFindOpts{Orphan: true})
+		test(core.NoteFindOpts{CreatedStart: &start})
+		test(core.NoteFindOpts{
+			Match:         []string{"daily | index"},
+			MatchStrategy: core.MatchStrategyFts,
+		})
+		test(core.NoteFindOpts{
+			Match:         []string{"note"},
+			MatchStrategy: core.MatchStrategyFts,
+			IncludeHrefs:  []string{"ref"},
+		})
+		test(core.NoteFindOpts{
+			Match:         []string{"nested"},
+			MatchStrategy: core.MatchStrategyExact,
+		})
+		test(core.NoteFindOpts{
+			Match:         []string{"^# [A-Z]"},
+			MatchStrategy: core.MatchStrategyRe,
+		})
+		test(core.NoteFindOpts{LinkedBy: &core.LinkFilter{Hrefs: []string{"f39c8.md"}}})
+		test(core.NoteFindOpts{LinkTo: &core.LinkFilter{Hrefs: []string{"log/2021-01-03.md"}, Recursive: true}})
+		test(core.NoteFindOpts{Related: []string{"log/2021-02-04.md"}})
+		test(core.NoteFindOpts{
+			Mention:       []string{"log/2021-01-03.md"},
+			MatchStrategy: core.MatchStrategyFts,
+		})
+	})
+}
+
+// The limit, offset and sorters don't change the number of matching notes.
+func TestNoteDAOCountIgnoresPaging(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		count, err := dao.Count(context.Background(), core.NoteFindOpts{
+			IncludeHrefs: []string{"log"},
+			Limit:        1,
+			Offset:       1,
+			Sorters:      []core.NoteSorter{{Field: core.NoteSortPath, Ascending: false}},
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, count, 3)
+	})
+}
+
+func TestNoteDAOCountWithInvalidFilter(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		_, err := dao.Count(context.Background(), core.NoteFindOpts{LinkTo: &core.LinkFilter{Hrefs: []string{"unknown.md"}}})
+		assert.Err(t, err, "could not find notes at: unknown.md")
+	})
+}
+
+func TestNoteDAOStats(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		stats, err := dao.Stats(context.Background(), core.NoteFindOpts{})
+		assert.Nil(t, err)
+		assert.Equal(t, stats, core.NoteStats{
+			NoteCount: 8,
+			WordCount: 38,
+			Directories: []core.StatsEntry{
+				{Name: "log", Count: 3},
+				{Name: "ref", Count: 3},
+				{Name: ".", Count: 2},
+			},
+			Tags: []core.StatsEntry{
+				{Name: "adventure", Count: 2},
+				{Name: "science", Count: 2},
+				{Name: "fantasy", Count: 1},
+				{Name: "fiction", Count: 1},
+				{Name: "history", Count: 1},
+			},
+			Links: core.LinkStats{Internal: 6, External: 1, Dead: 1},
+			CreationMonths: []core.StatsEntry{
+				{Name: "2020-11", Count: 3},
+				{Name: "2019-11", Count: 3},
+				{Name: "2020-01", Count: 1},
+				{Name: "2019-12", Count: 1},
+			},
+		})
+		assert.Equal(t, stats.AverageWordCount(), 4.75)
+	})
+}
+
+func TestNoteDAOStatsWithFilter(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		stats, err := dao.Stats(context.Background(), core.NoteFindOpts{IncludeHrefs: []string{"log"}})
+		assert.Nil(t, err)
+		assert.Equal(t, stats, core.NoteStats{
+			NoteCount:   3,
+			WordCount:   11,
+			Directories: []core.StatsEntry{{Name: "log", Count: 3}},
+			Tags: []core.StatsEntry{
+				{Name: "adventure", Count: 1},
+				{Name: "fiction", Count: 1},
+			},
+			Links:          core.LinkStats{Internal: 2, External: 1, Dead: 0},
+			CreationMonths: []core.StatsEntry{{Name: "2020-11", Count: 3}},
+		})
+	})
+}
+
+func TestNoteDAOStatsWithRecursiveLinkFilter(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		stats, err := dao.Stats(context.Background(), core.NoteFindOpts{
+			LinkedBy: &core.LinkFilter{Hrefs: []string{"log/2021-01-03.md"}, Recursive: true},
+		})
+		assert.Nil(t, err)
+
+		count, err := dao.Count(context.Background(), core.NoteFindOpts{
+			LinkedBy: &core.LinkFilter{Hrefs: []string{"log/2021-01-03.md"}, Recursive: true},
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, stats.NoteCount, count)
+	})
+}
+
+func TestNoteDAOStatsWithLimit(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		stats, err := dao.Stats(context.Background(), core.NoteFindOpts{
+			Limit:   2,
+			Sorters: []core.NoteSorter{{Field: core.NoteSortWordCount, Ascending: false}},
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, stats.NoteCount, 2)
+		assert.Equal(t, stats.WordCount, 13)
+	})
+}
+
+func TestNoteDAOStatsEmpty(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		stats, err := dao.Stats(context.Background(), core.NoteFindOpts{IncludeHrefs: []string{"unknown"}})
+		assert.Nil(t, err)
+		assert.Equal(t, stats, core.NoteStats{
+			Directories:    []core.StatsEntry{},
+			Tags:           []core.StatsEntry{},
+			CreationMonths: []core.StatsEntry{},
+		})
+		assert.Equal(t, stats.AverageWordCount(), 0.0)
+	})
+}
+
 func TestNoteDAOFindMinimalAll(t *testing.T) {
 	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
-		notes, err := dao.FindMinimal(core.NoteFindOpts{})
+		notes, err := dao.FindMinimal(context.Background(), core.NoteFindOpts{})
 		assert.Nil(t, err)
 
 		assert.Equal(t, notes, []core.MinimalNote{
@@ -311,7 +1185,7 @@ func TestNoteDAOFindMinimalAll(t *testing.T) {
 
 func TestNoteDAOFindMinimalWithFilter(t *testing.T) {
 	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
-		notes, err := dao.FindMinimal(core.NoteFindOpts{
+		notes, err := dao.FindMinimal(context.Background(), core.NoteFindOpts{
 			Match:         []string{"daily | index"},
 			MatchStrategy: core.MatchStrategyFts,
 			Sorters:       []core.NoteSorter{{Field: core.NoteSortWordCount, Ascending: true}},
@@ -346,6 +1220,64 @@ func TestNoteDAOFindLimit(t *testing.T) {
 	})
 }
 
+func TestNoteDAOFindExcludingIDs(t *testing.T) {
+	test := func(ids []core.NoteID, expected []string) {
+		testNoteDAOFindPaths(t, core.NoteFindOpts{ExcludeIDs: ids}, expected)
+	}
+
+	test([]core.NoteID{4, 1}, []string{
+		"ref/test/ref.md", "ref/test/b.md", "ref/test/a.md",
+		"log/2021-02-04.md", "index.md", "log/2021-01-04.md",
+	})
+	test([]core.NoteID{}, []string{
+		"ref/test/ref.md", "ref/test/b.md", "f39c8.md", "ref/test/a.md", "log/2021-01-03.md",
+		"log/2021-02-04.md", "index.md", "log/2021-01-04.md",
+	})
+
+	// Large lists don't reach the SQLite limit of host parameters.
+	ids := []core.NoteID{}
+	for i := 1; i <= 50000; i++ {
+		if i != 3 {
+			ids = append(ids, core.NoteID(i))
+		}
+	}
+	test(ids, []string{"index.md"})
+}
+
+func TestNoteDAOFindOffset(t *testing.T) {
+	testNoteDAOFindPaths(t, core.NoteFindOpts{Offset: 6}, []string{
+		"index.md",
+		"log/2021-01-04.md",
+	})
+	testNoteDAOFindPaths(t, core.NoteFindOpts{Offset: 8}, []string{})
+}
+
+func TestNoteDAOFindPaging(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		all, err := dao.Find(context.Background(), core.NoteFindOpts{})
+		assert.Nil(t, err)
+
+		paged := make([]string, 0)
+		for offset := 0; ; offset += 2 {
+			notes, err := dao.Find(context.Background(), core.NoteFindOpts{Limit: 2, Offset: offset})
+			assert.Nil(t, err)
+			if len(notes) == 0 {
+				break
+			}
+			assert.Equal(t, len(notes) <= 2, true)
+			for _, n := range notes {
+				paged = append(paged, n.Path)
+			}
+		}
+
+		expected := make([]string, 0)
+		for _, n := range all {
+			expected = append(expected, n.Path)
+		}
+		assert.Equal(t, paged, expected)
+	})
+}
+
 func TestNoteDAOFindTag(t *testing.T) {
 	test := func(tags []string, expectedPaths []string) {
 		testNoteDAOFindPaths(t, core.NoteFindOpts{Tags: tags}, expectedPaths)
@@ -365,6 +1297,35 @@ func TestNoteDAOFindTag(t *testing.T) {
 	test([]string{"NOTfiction"}, []string{"ref/test/ref.md", "ref/test/b.md", "f39c8.md", "ref/test/a.md", "log/2021-02-04.md", "index.md", "log/2021-01-04.md"})
 }
 
+func TestNoteDAOFindTagGlob(t *testing.T) {
+	test := func(tags []string, expectedPaths []string) {
+		testNoteDAOFindPaths(t, core.NoteFindOpts{Tags: tags}, expectedPaths)
+	}
+
+	test([]string{"fan*"}, []string{"f39c8.md"})
+	test([]string{"*ence"}, []string{"ref/test/b.md", "f39c8.md"})
+	test([]string{"f*"}, []string{"f39c8.md", "log/2021-01-03.md"})
+	test([]string{"f* | hist*"}, []string{"ref/test/b.md", "f39c8.md", "log/2021-01-03.md"})
+	test([]string{"-sci*"}, []string{"ref/test/ref.md", "ref/test/a.md", "log/2021-01-03.md", "log/2021-02-04.md", "index.md", "log/2021-01-04.md"})
+	// Collections of another kind are not matched.
+	test([]string{"fict*"}, []string{"log/2021-01-03.md"})
+}
+
+func TestNoteDAOFindTagWithMatch(t *testing.T) {
+	test := func(tags []string, match string, expectedPaths []string) {
+		testNoteDAOFindPaths(t, core.NoteFindOpts{
+			Tags:          tags,
+			Match:         []string{match},
+			MatchStrategy: core.MatchStrategyFts,
+		}, expectedPaths)
+	}
+
+	test([]string{"science"}, "surprise", []string{"f39c8.md"})
+	test([]string{"adventure"}, "daily", []string{"log/2021-01-03.md"})
+	test([]string{"adventure"}, "interesting", []string{})
+	test([]string{"science | fiction"}, "note", []string{"f39c8.md", "log/2021-01-03.md", "ref/test/b.md"})
+}
+
 func TestNoteDAOFindMatch(t *testing.T) {
 	testNoteDAOFind(t,
 		core.NoteFindOpts{
@@ -390,7 +1351,10 @@ func TestNoteDAOFindMatch(t *testing.T) {
 					Modified: time.Date(2019, 12, 4, 12, 17, 21, 0, time.UTC),
 					Checksum: "iaefhv",
 				},
-				Snippets: []string{"<zk:match>Index</zk:match> of the Zettelkasten"},
+				Snippets:    []string{"<zk:match>Index</zk:match> of the Zettelkasten"},
+				Score:       3.538607157563684,
+				MatchLine:   1,
+				MatchColumn: 3,
 			},
 			{
 				Note: core.Note{
@@ -410,7 +1374,10 @@ func TestNoteDAOFindMatch(t *testing.T) {
 					Modified: time.Date(2020, 11, 22, 16, 27, 45, 0, time.UTC),
 					Checksum: "qwfpgj",
 				},
-				Snippets: []string{"A <zk:match>daily</zk:match> note\n\nWith lot of content"},
+				Snippets:    []string{"A <zk:match>daily</zk:match> note\n\nWith lot of content"},
+				Score:       0.9915145139573839,
+				MatchLine:   1,
+				MatchColumn: 3,
 			},
 			{
 				Note: core.Note{
@@ -428,7 +1395,10 @@ func TestNoteDAOFindMatch(t *testing.T) {
 					Modified:   time.Date(2020, 11, 10, 8, 20, 18, 0, time.UTC),
 					Checksum:   "earkte",
 				},
-				Snippets: []string{"A third <zk:match>daily</zk:match> note"},
+				Snippets:    []string{"A third <zk:match>daily</zk:match> note"},
+				Score:       0.43884884996365736,
+				MatchLine:   1,
+				MatchColumn: 11,
 			},
 			{
 				Note: core.Note{
@@ -446,7 +1416,10 @@ func TestNoteDAOFindMatch(t *testing.T) {
 					Modified:   time.Date(2020, 11, 29, 8, 20, 18, 0, time.UTC),
 					Checksum:   "arstde",
 				},
-				Snippets: []string{"A second <zk:match>daily</zk:match> note"},
+				Snippets:    []string{"A second <zk:match>daily</zk:match> note"},
+				Score:       0.43884884996365736,
+				MatchLine:   1,
+				MatchColumn: 12,
 			},
 		},
 	)
@@ -485,6 +1458,286 @@ func TestNoteDAOFindMatchWithSort(t *testing.T) {
 	)
 }
 
+func TestNoteDAOFindMatchPhrase(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		for _, note := range []core.Note{
+			{Path: "compile.md", Title: "Compiling", Body: "Build with c++ -O2 to optimize."},
+			{Path: "other.md", Title: "Other", Body: "O2 is used with c when writing -c."},
+		} {
+			_, err := dao.Add(note)
+			assert.Nil(t, err)
+		}
+
+		test := func(match string, expected []string) {
+			t.Helper()
+			notes, err := dao.Find(context.Background(), core.NoteFindOpts{
+				Match:         []string{match},
+				MatchStrategy: core.MatchStrategyPhrase,
+			})
+			assert.Nil(t, err)
+			actual := []string{}
+			for _, note := range notes {
+				actual = append(actual, note.Path)
+			}
+			assert.Equal(t, actual, expected)
+		}
+
+		test("c++ -O2", []string{"compile.md"})
+		test(`"c++ -O2"`, []string{"compile.md"})
+		test("daily | index", []string{})
+		test("NEAR(", []string{})
+		test("A daily note", []string{"log/2021-01-03.md"})
+	})
+}
+
+func TestNoteDAOFindMatchScope(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		for _, note := range []core.Note{
+			{Path: "title.md", Title: "Photosynthesis", Body: "How plants make food.", RawContent: "# Photosynthesis\nHow plants make food."},
+			{Path: "body.md", Title: "Plants", Body: "Plants rely on photosynthesis.", RawContent: "# Plants\nPlants rely on photosynthesis."},
+		} {
+			_, err := dao.Add(note)
+			assert.Nil(t, err)
+		}
+
+		test := func(strategy core.MatchStrategy, match string, scope core.MatchScope, expected []string) {
+			t.Helper()
+			notes, err := dao.Find(context.Background(), core.NoteFindOpts{
+				Match:         []string{match},
+				MatchStrategy: strategy,
+				MatchScope:    scope,
+			})
+			assert.Nil(t, err)
+			actual := []string{}
+			for _, note := range notes {
+				actual = append(actual, note.Path)
+			}
+			assert.Equal(t, actual, expected)
+		}
+
+		for _, strategy := range []core.MatchStrategy{core.MatchStrategyFts, core.MatchStrategyPhrase} {
+			test(strategy, "photosynthesis", core.MatchScopeAny, []string{"title.md", "body.md"})
+			test(strategy, "photosynthesis", core.MatchScopeTitle, []string{"title.md"})
+			test(strategy, "photosynthesis", core.MatchScopeBody, []string{"body.md"})
+		}
+
+		test(core.MatchStrategyFts, "photosynthesis | plants", core.MatchScopeTitle, []string{"title.md", "body.md"})
+		test(core.MatchStrategyFts, "photosynthesis -food", core.MatchScopeBody, []string{"body.md"})
+
+		test(core.MatchStrategyExact, "Photosynthesis", core.MatchScopeAny, []string{"title.md", "body.md"})
+		test(core.MatchStrategyExact, "Photosynthesis", core.MatchScopeTitle, []string{"title.md"})
+		test(core.MatchStrategyExact, "Photosynthesis", core.MatchScopeBody, []string{"body.md"})
+
+		test(core.MatchStrategyRe, "^Photo", core.MatchScopeTitle, []string{"title.md"})
+		test(core.MatchStrategyRe, "^Photo", core.MatchScopeBody, []string{})
+	})
+}
+
+// The snippets are built from the column searched.
+func TestNoteDAOFindMatchScopeSnippets(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		test := func(scope core.MatchScope, expected []string) {
+			t.Helper()
+			notes, err := dao.Find(context.Background(), core.NoteFindOpts{
+				Match:         []string{"daily"},
+				MatchStrategy: core.MatchStrategyFts,
+				MatchScope:    scope,
+				IncludeHrefs:  []string{"log/2021-01-03.md"},
+			})
+			assert.Nil(t, err)
+			assert.Equal(t, len(notes), 1)
+			assert.Equal(t, notes[0].Snippets, expected)
+		}
+
+		test(core.MatchScopeAny, []string{"A <zk:match>daily</zk:match> note\n\nWith lot of content"})
+		test(core.MatchScopeTitle, []string{"<zk:match>Daily</zk:match> note"})
+		test(core.MatchScopeBody, []string{"A <zk:match>daily</zk:match> note\n\nWith lot of content"})
+	})
+}
+
+func TestNoteDAOFindMatchRelevance(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		for _, note := range []core.Note{
+			{Path: "once.md", Title: "Once", Body: "A note about a kettle and some other things."},
+			{Path: "many.md", Title: "Many", Body: "Kettle, kettle, kettle: the kettle is boiling."},
+		} {
+			_, err := dao.Add(note)
+			assert.Nil(t, err)
+		}
+
+		test := func(sorters []core.NoteSorter, expected []string) {
+			t.Helper()
+			notes, err := dao.Find(context.Background(), core.NoteFindOpts{
+				Match:         []string{"kettle"},
+				MatchStrategy: core.MatchStrategyFts,
+				Sorters:       sorters,
+			})
+			assert.Nil(t, err)
+			actual := []string{}
+			for _, note := range notes {
+				actual = append(actual, note.Path)
+				assert.True(t, note.Score > 0)
+			}
+			assert.Equal(t, actual, expected)
+			assert.True(t, notes[0].Score != notes[1].Score)
+		}
+
+		// Sorted by relevance by default.
+		test(nil, []string{"many.md", "once.md"})
+		test([]core.NoteSorter{{Field: core.NoteSortRelevance, Ascending: false}}, []string{"many.md", "once.md"})
+		test([]core.NoteSorter{{Field: core.NoteSortRelevance, Ascending: true}}, []string{"once.md", "many.md"})
+		// Explicit sorters take precedence.
+		test([]core.NoteSorter{{Field: core.NoteSortPath, Ascending: false}}, []string{"once.md", "many.md"})
+	})
+}
+
+// The score is zero and sorting by relevance is ignored without a full-text
+// search.
+func TestNoteDAOFindRelevanceWithoutFts(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		notes, err := dao.Find(context.Background(), core.NoteFindOpts{
+			Match:         []string{"daily"},
+			MatchStrategy: core.MatchStrategyExact,
+			Sorters:       []core.NoteSorter{{Field: core.NoteSortRelevance, Ascending: false}},
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, len(notes), 3)
+		for _, note := range notes {
+			assert.Equal(t, note.Score, 0.0)
+		}
+
+		testNoteDAOFindPaths(t,
+			core.NoteFindOpts{
+				IncludeHrefs: []string{"log"},
+				Sorters:      []core.NoteSorter{{Field: core.NoteSortRelevance, Ascending: false}},
+			},
+			[]string{"log/2021-01-03.md", "log/2021-02-04.md", "log/2021-01-04.md"},
+		)
+	})
+}
+
+func TestNoteDAOFindMatchSnippets(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		test := func(opts core.NoteFindOpts, expected map[string][]string) {
+			t.Helper()
+			opts.MatchStrategy = core.MatchStrategyFts
+			notes, err := dao.Find(context.Background(), opts)
+			assert.Nil(t, err)
+			actual := map[string][]string{}
+			for _, note := range notes {
+				actual[note.Path] = note.Snippets
+			}
+			assert.Equal(t, actual, expected)
+		}
+
+		// A single body snippet by default.
+		test(core.NoteFindOpts{Match: []string{"daily"}}, map[string][]string{
+			"log/2021-01-03.md": {"A <zk:match>daily</zk:match> note\n\nWith lot of content"},
+			"log/2021-02-04.md": {"A third <zk:match>daily</zk:match> note"},
+			"log/2021-01-04.md": {"A second <zk:match>daily</zk:match> note"},
+		})
+
+		// Both the title and body snippets, when they match.
+		test(core.NoteFindOpts{Match: []string{"daily"}, MaxSnippets: 2}, map[string][]string{
+			"log/2021-01-03.md": {"<zk:match>Daily</zk:match> note", "A <zk:match>daily</zk:match> note\n\nWith lot of content"},
+			"log/2021-02-04.md": {"A third <zk:match>daily</zk:match> note"},
+			"log/2021-01-04.md": {"A second <zk:match>daily</zk:match> note"},
+		})
+		test(core.NoteFindOpts{Match: []string{"index"}, MaxSnippets: 2}, map[string][]string{
+			"index.md": {"<zk:match>Index</zk:match>", "<zk:match>Index</zk:match> of the Zettelkasten"},
+		})
+		// Only the title matches.
+		test(core.NoteFindOpts{Match: []string{"interesting"}, MaxSnippets: 2}, map[string][]string{
+			"f39c8.md": {"An <zk:match>interesting</zk:match> note"},
+		})
+
+		// Shorter snippets.
+		test(core.NoteFindOpts{Match: []string{"content"}, SnippetLength: 3}, map[string][]string{
+			"f39c8.md":          {"Its <zk:match>content</zk:match> will…"},
+			"log/2021-01-03.md": {"…lot of <zk:match>content</zk:match>"},
+		})
+	})
+}
+
+func TestNoteDAOFindSnippetDelimiters(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		test := func(opts core.NoteFindOpts, expected []string) {
+			t.Helper()
+			notes, err := dao.Find(context.Background(), opts)
+			assert.Nil(t, err)
+			assert.Equal(t, len(notes) > 0, true)
+			assert.Equal(t, notes[0].Snippets, expected)
+		}
+
+		match := func(delimiters *core.SnippetDelimiters) core.NoteFindOpts {
+			return core.NoteFindOpts{
+				Match:             []string{"daily"},
+				MatchStrategy:     core.MatchStrategyFts,
+				IncludeHrefs:      []string{"log/2021-01-03.md"},
+				MaxSnippets:       2,
+				SnippetDelimiters: delimiters,
+			}
+		}
+
+		test(match(nil), []string{
+			"<zk:match>Daily</zk:match> note",
+			"A <zk:match>daily</zk:match> note\n\nWith lot of content",
+		})
+		test(match(&core.SnippetDelimiters{Open: "\x1b[31m", Close: "\x1b[0m"}), []string{
+			"\x1b[31mDaily\x1b[0m note",
+			"A \x1b[31mdaily\x1b[0m note\n\nWith lot of content",
+		})
+		test(match(&core.SnippetDelimiters{Open: "", Close: ""}), []string{
+			"Daily note",
+			"A daily note\n\nWith lot of content",
+		})
+		test(match(&core.SnippetDelimiters{Open: "'", Close: "''"}), []string{
+			"'Daily'' note",
+			"A 'daily'' note\n\nWith lot of content",
+		})
+
+		// The link snippets are highlighted with the same delimiters.
+		test(core.NoteFindOpts{
+			LinkedBy:          &core.LinkFilter{Hrefs: []string{"f39c8.md"}},
+			SnippetDelimiters: &core.SnippetDelimiters{Open: "\x1b[31m", Close: "\x1b[0m"},
+		}, []string{
+			"[[\x1b[31mLink from 4 to 6\x1b[0m]]",
+			"[[\x1b[31mDuplicated link\x1b[0m]]",
+		})
+	})
+}
+
+func TestNoteDAOFindMatchInvalidQuery(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		opts := core.NoteFindOpts{
+			Match:         []string{"daily AND"},
+			MatchStrategy: core.MatchStrategyFts,
+		}
+
+		_, err := dao.Find(context.Background(), opts)
+		var queryErr core.InvalidMatchQueryError
+		assert.True(t, errors.As(err, &queryErr))
+		assert.Equal(t, queryErr.Query, "daily AND")
+		assert.Err(t, err, "\"daily AND\": invalid search query (fts5: syntax error")
+		assert.Err(t, err, "try --match-strategy=phrase to search for a literal phrase")
+
+		_, err = dao.FindMinimal(context.Background(), opts)
+		assert.True(t, errors.As(err, &queryErr))
+		_, err = dao.Count(context.Background(), opts)
+		assert.True(t, errors.As(err, &queryErr))
+
+		opts.Match = []string{"unknown: foo"}
+		_, err = dao.Find(context.Background(), opts)
+		assert.True(t, errors.As(err, &queryErr))
+		assert.Err(t, err, "no such column: unknown")
+
+		// The other SQL errors are not caused by the search query.
+		opts.Match = []string{"daily"}
+		sqlErr := errors.New("no such column: n.unknown")
+		assert.Equal(t, dao.matchQueryError(opts, sqlErr), sqlErr)
+	})
+}
+
 func TestNoteDAOFindExactMatch(t *testing.T) {
 	test := func(match string, expected []string) {
 		testNoteDAOFindPaths(t,
@@ -504,21 +1757,21 @@ func TestNoteDAOFindExactMatch(t *testing.T) {
 
 func TestNoteDAOFindMentionRequiresFtsMatchStrategy(t *testing.T) {
 	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
-		_, err := dao.Find(core.NoteFindOpts{
+		_, err := dao.Find(context.Background(), core.NoteFindOpts{
 			MatchStrategy: core.MatchStrategyExact,
 			Mention:       []string{"mention"},
 		})
 		assert.Err(t, err, "--mention can only be used with --match-strategy=fts")
 	})
 	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
-		_, err := dao.Find(core.NoteFindOpts{
+		_, err := dao.Find(context.Background(), core.NoteFindOpts{
 			MatchStrategy: core.MatchStrategyRe,
 			Mention:       []string{"mention"},
 		})
 		assert.Err(t, err, "--mention can only be used with --match-strategy=fts")
 	})
 	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
-		_, err := dao.Find(core.NoteFindOpts{
+		_, err := dao.Find(context.Background(), core.NoteFindOpts{
 			MatchStrategy: core.MatchStrategyFts,
 			Mention:       []string{"mention"},
 		})
@@ -591,6 +1844,77 @@ func TestNoteDAOFindExcludingMultiplePaths(t *testing.T) {
 	)
 }
 
+func TestNoteDAOFindExactPaths(t *testing.T) {
+	testNoteDAOFindPaths(t,
+		core.NoteFindOpts{
+			ExactPaths: []string{"./log/2021-01-03.md", "ref/test/a.md"},
+		},
+		[]string{"ref/test/a.md", "log/2021-01-03.md"},
+	)
+}
+
+func TestNoteDAOFindExactPathsWithoutExtension(t *testing.T) {
+	testNoteDAOFindPaths(t,
+		core.NoteFindOpts{
+			ExactPaths: []string{"index"},
+		},
+		[]string{"index.md"},
+	)
+}
+
+func TestNoteDAOFindExactPathsWithoutExtensionUsesConfiguredExtensions(t *testing.T) {
+	testTransaction(t, func(tx Transaction) {
+		dao := NewNoteDAOWithOpts(tx, &util.NullLogger, NoteDAOOpts{
+			Extensions: []string{"markdown", "txt"},
+		})
+		_, err := dao.Add(core.Note{Path: "inbox.txt"})
+		assert.Nil(t, err)
+
+		matches, err := dao.Find(context.Background(), core.NoteFindOpts{
+			ExactPaths: []string{"inbox", "index"},
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, len(matches), 1)
+		assert.Equal(t, matches[0].Path, "inbox.txt")
+	})
+}
+
+// Exact paths are not expanded as globs or prefixes.
+func TestNoteDAOFindExactPathsAreLiteral(t *testing.T) {
+	testNoteDAOFindPaths(t,
+		core.NoteFindOpts{
+			ExactPaths: []string{"log/2021-01-*", "ref/test"},
+		},
+		[]string{},
+	)
+}
+
+func TestNoteDAOFindPathRegex(t *testing.T) {
+	testNoteDAOFindPaths(t,
+		core.NoteFindOpts{
+			PathRegex: `^log/\d{4}-01-`,
+		},
+		[]string{"log/2021-01-03.md", "log/2021-01-04.md"},
+	)
+}
+
+func TestNoteDAOFindExcludingPathRegex(t *testing.T) {
+	testNoteDAOFindPaths(t,
+		core.NoteFindOpts{
+			PathRegex:        `^(log|ref)/`,
+			ExcludePathRegex: `/(a|b)\.md$`,
+		},
+		[]string{"ref/test/ref.md", "log/2021-01-03.md", "log/2021-02-04.md", "log/2021-01-04.md"},
+	)
+}
+
+func TestNoteDAOFindInvalidPathRegex(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		_, err := dao.Find(context.Background(), core.NoteFindOpts{ExcludePathRegex: `^log/(`})
+		assert.Err(t, err, "^log/(: invalid path regular expression: error parsing regexp")
+	})
+}
+
 func TestNoteDAOFindMentions(t *testing.T) {
 	testNoteDAOFind(t,
 		core.NoteFindOpts{
@@ -614,7 +1938,10 @@ func TestNoteDAOFindMentions(t *testing.T) {
 					Modified:   time.Date(2019, 11, 20, 20, 34, 6, 0, time.UTC),
 					Checksum:   "yvwbae",
 				},
-				Snippets: []string{"This one is in a sub sub directory, not the <zk:match>first page</zk:match>"},
+				Snippets:    []string{"This one is in a sub sub directory, not the <zk:match>first page</zk:match>"},
+				Score:       1.2678716131140249,
+				MatchLine:   1,
+				MatchColumn: 12,
 			},
 			{
 				Note: core.Note{
@@ -632,7 +1959,10 @@ func TestNoteDAOFindMentions(t *testing.T) {
 					Modified:   time.Date(2020, 11, 10, 8, 20, 18, 0, time.UTC),
 					Checksum:   "earkte",
 				},
-				Snippets: []string{"A third <zk:match>daily note</zk:match>"},
+				Snippets:    []string{"A third <zk:match>daily note</zk:match>"},
+				Score:       0.43884884996365736,
+				MatchLine:   1,
+				MatchColumn: 11,
 			},
 			{
 				Note: core.Note{
@@ -650,12 +1980,31 @@ func TestNoteDAOFindMentions(t *testing.T) {
 					Modified:   time.Date(2020, 11, 29, 8, 20, 18, 0, time.UTC),
 					Checksum:   "arstde",
 				},
-				Snippets: []string{"A second <zk:match>daily note</zk:match>"},
+				Snippets:    []string{"A second <zk:match>daily note</zk:match>"},
+				Score:       0.43884884996365736,
+				MatchLine:   1,
+				MatchColumn: 12,
 			},
 		},
 	)
 }
 
+// The mentioned note is excluded, and the snippets highlight the mention.
+func TestNoteDAOFindMentionsSingleNote(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		notes, err := dao.Find(context.Background(), core.NoteFindOpts{
+			MatchStrategy: core.MatchStrategyFts,
+			Mention:       []string{"index.md"},
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, len(notes), 1)
+		assert.Equal(t, notes[0].Path, "ref/test/b.md")
+		assert.Equal(t, notes[0].Snippets, []string{
+			"This one is in a sub sub directory, not the <zk:match>first page</zk:match>",
+		})
+	})
+}
+
 // Common use case: `--mention x --no-link-to x`
 func TestNoteDAOFindUnlinkedMentions(t *testing.T) {
 	testNoteDAOFindPaths(t,
@@ -677,7 +2026,7 @@ func TestNoteDAOFindMentionUnknown(t *testing.T) {
 			MatchStrategy: core.MatchStrategyFts,
 			Mention:       []string{"will-not-be-found"},
 		}
-		_, err := dao.Find(opts)
+		_, err := dao.Find(context.Background(), opts)
 		assert.Err(t, err, "could not find notes at: will-not-be-found")
 	})
 }
@@ -735,6 +2084,17 @@ func TestNoteDAOFindMentionedBy(t *testing.T) {
 	)
 }
 
+// A note mentioned by several of the given notes is returned once.
+func TestNoteDAOFindMentionedByDeduplicatesResults(t *testing.T) {
+	testNoteDAOFindPaths(t,
+		core.NoteFindOpts{
+			MatchStrategy: core.MatchStrategyFts,
+			MentionedBy:   []string{"log/2021-01-04.md", "log/2021-02-04.md"},
+		},
+		[]string{"log/2021-01-03.md"},
+	)
+}
+
 // Common use case: `--mentioned-by x --no-linked-by x`
 func TestNoteDAOFindUnlinkedMentionedBy(t *testing.T) {
 	testNoteDAOFindPaths(t,
@@ -756,7 +2116,7 @@ func TestNoteDAOFindMentionedByUnknown(t *testing.T) {
 			MatchStrategy: core.MatchStrategyFts,
 			MentionedBy:   []string{"will-not-be-found"},
 		}
-		_, err := dao.Find(opts)
+		_, err := dao.Find(context.Background(), opts)
 		assert.Err(t, err, "could not find notes at: will-not-be-found")
 	})
 }
@@ -774,6 +2134,66 @@ func TestNoteDAOFindLinkedBy(t *testing.T) {
 	)
 }
 
+// Duplicated links to the same target produce a single match, and unresolved
+// or external links are skipped.
+func TestNoteDAOFindLinkedByDeduplicatesTargets(t *testing.T) {
+	test := func(href string, expected []string) {
+		testNoteDAOFindPaths(t,
+			core.NoteFindOpts{
+				LinkedBy: &core.LinkFilter{Hrefs: []string{href}},
+			},
+			expected,
+		)
+	}
+
+	test("f39c8.md", []string{"ref/test/a.md", "log/2021-01-03.md"})
+	test("index.md", []string{"f39c8.md"})
+	test("log/2021-01-03.md", []string{"log/2021-01-04.md"})
+}
+
+// The unresolved targets are included once, with only their path, after the
+// indexed notes.
+func TestNoteDAOFindLinkedByIncludesUnresolved(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		// A duplicated link to the missing target.
+		_, err := tx.Exec(`
+			INSERT INTO links (source_id, target_id, title, href, type, external, rels, snippet)
+			VALUES (3, NULL, 'Missing again', 'missing', 'wiki-link', 0, '', '')
+		`)
+		assert.Nil(t, err)
+
+		test := func(filter core.LinkFilter, expected []string) {
+			opts := core.NoteFindOpts{LinkedBy: &filter}
+			matches, err := dao.Find(context.Background(), opts)
+			assert.Nil(t, err)
+			actual := []string{}
+			for _, m := range matches {
+				// The unresolved targets don't have an ID.
+				actual = append(actual, fmt.Sprintf("%d:%s", m.ID, m.Path))
+			}
+			assert.Equal(t, actual, expected)
+
+			count, err := dao.Count(context.Background(), opts)
+			assert.Nil(t, err)
+			assert.Equal(t, count, len(expected))
+		}
+
+		// Skipped by default.
+		test(core.LinkFilter{Hrefs: []string{"index.md"}}, []string{"4:f39c8.md"})
+		test(core.LinkFilter{Hrefs: []string{"index.md"}, IncludeUnresolved: true}, []string{"4:f39c8.md", "0:missing"})
+		// External links are not unresolved targets.
+		test(core.LinkFilter{Hrefs: []string{"log/2021-01-03.md"}, IncludeUnresolved: true}, []string{"2:log/2021-01-04.md"})
+
+		// The limit applies to the unresolved targets as well.
+		actual, err := dao.Find(context.Background(), core.NoteFindOpts{
+			LinkedBy: &core.LinkFilter{Hrefs: []string{"index.md"}, IncludeUnresolved: true},
+			Limit:    1,
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, len(actual), 1)
+	})
+}
+
 func TestNoteDAOFindLinkedByRecursive(t *testing.T) {
 	testNoteDAOFindPaths(t,
 		core.NoteFindOpts{
@@ -863,7 +2283,7 @@ func TestNoteDAOFindLinkedByUnknown(t *testing.T) {
 				Hrefs: []string{"will-not-be-found"},
 			},
 		}
-		_, err := dao.Find(opts)
+		_, err := dao.Find(context.Background(), opts)
 		assert.Err(t, err, "could not find notes at: will-not-be-found")
 	})
 }
@@ -894,6 +2314,24 @@ func TestNoteDAOFindLinkTo(t *testing.T) {
 	)
 }
 
+// Backlinks can be found from partial hrefs, with or without the extension.
+func TestNoteDAOFindBacklinks(t *testing.T) {
+	test := func(href string, expected []string) {
+		testNoteDAOFindPaths(t,
+			core.NoteFindOpts{
+				LinkTo: &core.LinkFilter{Hrefs: []string{href}},
+			},
+			expected,
+		)
+	}
+
+	test("f39c8", []string{"index.md"})
+	test("f39c8.md", []string{"index.md"})
+	test("log/2021-01-03", []string{"f39c8.md"})
+	// f39c8.md links twice to ref/test/a.md but is returned only once.
+	test("ref/test/a.md", []string{"f39c8.md"})
+}
+
 func TestNoteDAOFindLinkToRecursive(t *testing.T) {
 	testNoteDAOFindPaths(t,
 		core.NoteFindOpts{
@@ -937,7 +2375,7 @@ func TestNoteDAOFindLinkToUnknown(t *testing.T) {
 				Hrefs: []string{"will-not-be-found"},
 			},
 		}
-		_, err := dao.Find(opts)
+		_, err := dao.Find(context.Background(), opts)
 		assert.Err(t, err, "could not find notes at: will-not-be-found")
 	})
 }
@@ -965,6 +2403,76 @@ func TestNoteDAOFindOrphan(t *testing.T) {
 	)
 }
 
+// External links are never considered as dead links.
+func TestNoteDAOFindDeadLinks(t *testing.T) {
+	testNoteDAOFindPaths(t,
+		core.NoteFindOpts{DeadLinks: true},
+		[]string{"index.md"},
+	)
+}
+
+func TestNoteDAOFindDeadLinksAfterRemovingTarget(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		_, err := dao.Remove("log/2021-01-04.md")
+		assert.Nil(t, err)
+
+		notes, err := dao.Find(context.Background(), core.NoteFindOpts{
+			DeadLinks: true,
+			Sorters:   []core.NoteSorter{{Field: core.NoteSortPath, Ascending: true}},
+		})
+		assert.Nil(t, err)
+		actual := make([]string, 0)
+		for _, n := range notes {
+			actual = append(actual, n.Path)
+		}
+		assert.Equal(t, actual, []string{"index.md", "log/2021-01-03.md"})
+	})
+}
+
+func TestNoteDAOFindOrphanInPath(t *testing.T) {
+	testNoteDAOFindPaths(t,
+		core.NoteFindOpts{Orphan: true, IncludeHrefs: []string{"ref"}},
+		[]string{"ref/test/ref.md", "ref/test/b.md"},
+	)
+}
+
+// A note linking only to itself is still an orphan.
+func TestNoteDAOFindOrphanWithSelfLink(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		err := NewLinkDAO(tx, &util.NullLogger).Add([]core.ResolvedLink{
+			{SourceID: 7, TargetID: 7, Link: core.Link{Href: "log/2021-02-04.md"}},
+			{SourceID: 5, TargetID: 8, Link: core.Link{Href: "ref/test/ref.md"}},
+		})
+		assert.Nil(t, err)
+
+		notes, err := dao.Find(context.Background(), core.NoteFindOpts{Orphan: true})
+		assert.Nil(t, err)
+
+		actual := make([]string, 0)
+		for _, n := range notes {
+			actual = append(actual, n.Path)
+		}
+		assert.Equal(t, actual, []string{"ref/test/b.md", "log/2021-02-04.md"})
+	})
+}
+
+func TestNoteDAOFindUntitled(t *testing.T) {
+	testNoteDAOFindPaths(t,
+		core.NoteFindOpts{Untitled: true},
+		[]string{"ref/test/ref.md"},
+	)
+}
+
+func TestNoteDAOFindUntitledExcludingPath(t *testing.T) {
+	testNoteDAOFindPaths(t,
+		core.NoteFindOpts{
+			Untitled:     true,
+			ExcludeHrefs: []string{"ref"},
+		},
+		[]string{},
+	)
+}
+
 func TestNoteDAOFindCreatedOn(t *testing.T) {
 	start := time.Date(2020, 11, 22, 0, 0, 0, 0, time.UTC)
 	end := time.Date(2020, 11, 23, 0, 0, 0, 0, time.UTC)
@@ -1009,6 +2517,40 @@ func TestNoteDAOFindModifiedOn(t *testing.T) {
 	)
 }
 
+// The start of a date range is inclusive, while its end is exclusive.
+func TestNoteDAOFindCreatedRange(t *testing.T) {
+	start := time.Date(2020, 1, 19, 10, 58, 41, 0, time.UTC)
+	end := start.Add(time.Second)
+	testNoteDAOFindPaths(t,
+		core.NoteFindOpts{
+			CreatedStart: &start,
+			CreatedEnd:   &end,
+		},
+		[]string{"f39c8.md"},
+	)
+
+	end = start
+	start = start.Add(-time.Hour)
+	testNoteDAOFindPaths(t,
+		core.NoteFindOpts{
+			CreatedStart: &start,
+			CreatedEnd:   &end,
+		},
+		[]string{},
+	)
+}
+
+func TestNoteDAOFindEmptyDateRange(t *testing.T) {
+	date := time.Date(2020, 11, 29, 8, 20, 18, 0, time.UTC)
+	testNoteDAOFindPaths(t,
+		core.NoteFindOpts{
+			ModifiedStart: &date,
+			ModifiedEnd:   &date,
+		},
+		[]string{},
+	)
+}
+
 func TestNoteDAOFindModifiedBefore(t *testing.T) {
 	end := time.Date(2020, 01, 20, 8, 52, 42, 0, time.UTC)
 	testNoteDAOFindPaths(t,
@@ -1029,6 +2571,116 @@ func TestNoteDAOFindModifiedAfter(t *testing.T) {
 	)
 }
 
+func TestNoteDAOFindMetadata(t *testing.T) {
+	testNoteDAOFindPaths(t,
+		core.NoteFindOpts{
+			Metadata: []core.MetadataFilter{{Key: "author", Value: "Dom"}},
+		},
+		[]string{"log/2021-01-03.md"},
+	)
+}
+
+func TestNoteDAOFindNestedMetadata(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		_, err := dao.Add(core.Note{
+			Path: "work/draft.md",
+			Metadata: map[string]interface{}{
+				"status": "draft",
+				"project": map[string]interface{}{
+					"area":     "work",
+					"archived": false,
+					"priority": 2,
+				},
+			},
+		})
+		assert.Nil(t, err)
+		_, err = dao.Add(core.Note{
+			Path: "work/done.md",
+			Metadata: map[string]interface{}{
+				"status": "done",
+				"project": map[string]interface{}{
+					"area":     "work",
+					"archived": true,
+				},
+			},
+		})
+		assert.Nil(t, err)
+
+		test := func(filters []core.MetadataFilter, expected []string) {
+			notes, err := dao.Find(context.Background(), core.NoteFindOpts{
+				Metadata: filters,
+				Sorters:  []core.NoteSorter{{Field: core.NoteSortPath, Ascending: true}},
+			})
+			assert.Nil(t, err)
+			actual := []string{}
+			for _, note := range notes {
+				actual = append(actual, note.Path)
+			}
+			assert.Equal(t, actual, expected)
+		}
+
+		test([]core.MetadataFilter{{Key: "project.area", Value: "work"}}, []string{"work/done.md", "work/draft.md"})
+		test([]core.MetadataFilter{{Key: "project.archived", Value: "true"}}, []string{"work/done.md"})
+		test([]core.MetadataFilter{{Key: "project.archived", Value: "false"}}, []string{"work/draft.md"})
+		test([]core.MetadataFilter{{Key: "project.priority", Value: "2"}}, []string{"work/draft.md"})
+		test([]core.MetadataFilter{
+			{Key: "project.area", Value: "work"},
+			{Key: "status", Value: "draft"},
+		}, []string{"work/draft.md"})
+		test([]core.MetadataFilter{{Key: "project", Value: "work"}}, []string{})
+		test([]core.MetadataFilter{{Key: "unknown.key", Value: "work"}}, []string{})
+	})
+}
+
+func TestNoteDAOFindWordCountRange(t *testing.T) {
+	testNoteDAOFindPaths(t,
+		core.NoteFindOpts{
+			WordCount: &core.WordCountFilter{Min: 4, Max: 5},
+			Sorters:   []core.NoteSorter{{Field: core.NoteSortPath, Ascending: true}},
+		},
+		[]string{"f39c8.md", "index.md", "log/2021-01-04.md", "log/2021-02-04.md", "ref/test/a.md", "ref/test/ref.md"},
+	)
+}
+
+func TestNoteDAOFindWordCountMin(t *testing.T) {
+	testNoteDAOFindPaths(t,
+		core.NoteFindOpts{
+			WordCount: &core.WordCountFilter{Min: 5},
+			Sorters:   []core.NoteSorter{{Field: core.NoteSortPath, Ascending: true}},
+		},
+		[]string{"f39c8.md", "ref/test/a.md", "ref/test/b.md", "ref/test/ref.md"},
+	)
+}
+
+func TestNoteDAOFindWordCountMax(t *testing.T) {
+	testNoteDAOFindPaths(t,
+		core.NoteFindOpts{
+			WordCount: &core.WordCountFilter{Max: 3},
+		},
+		[]string{"log/2021-01-03.md"},
+	)
+}
+
+func TestNoteDAOFindWordCountOutOfRange(t *testing.T) {
+	testNoteDAOFindPaths(t,
+		core.NoteFindOpts{
+			WordCount: &core.WordCountFilter{Min: 6, Max: 7},
+		},
+		[]string{},
+	)
+}
+
+func TestNoteDAOFindWordCountInPathSortedByWordCount(t *testing.T) {
+	testNoteDAOFindPaths(t,
+		core.NoteFindOpts{
+			IncludeHrefs: []string{"log", "ref"},
+			WordCount:    &core.WordCountFilter{Min: 4, Max: 8},
+			Sorters:      []core.NoteSorter{{Field: core.NoteSortWordCount, Ascending: false}},
+		},
+		[]string{"ref/test/b.md", "ref/test/ref.md", "ref/test/a.md", "log/2021-02-04.md", "log/2021-01-04.md"},
+	)
+}
+
 func TestNoteDAOFindSortCreated(t *testing.T) {
 	testNoteDAOFindSort(t, core.NoteSortCreated, true, []string{
 		"ref/test/ref.md", "ref/test/b.md", "ref/test/a.md", "index.md", "f39c8.md",
@@ -1084,6 +2736,72 @@ func TestNoteDAOFindSortWordCount(t *testing.T) {
 	})
 }
 
+// The order is not predictable, so we only check the membership of the results.
+func TestNoteDAOFindSortRandom(t *testing.T) {
+	start := time.Date(2020, 11, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2020, 12, 1, 0, 0, 0, 0, time.UTC)
+
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		for i := 0; i < 10; i++ {
+			notes, err := dao.Find(context.Background(), core.NoteFindOpts{
+				IncludeHrefs: []string{"log"},
+				CreatedStart: &start,
+				CreatedEnd:   &end,
+				Sorters:      []core.NoteSorter{{Field: core.NoteSortRandom}},
+				Limit:        2,
+			})
+			assert.Nil(t, err)
+			assert.Equal(t, len(notes), 2)
+			assert.NotEqual(t, notes[0].Path, notes[1].Path)
+
+			for _, note := range notes {
+				switch note.Path {
+				case "log/2021-01-03.md", "log/2021-01-04.md", "log/2021-02-04.md":
+				default:
+					t.Errorf("unexpected random note: %s", note.Path)
+				}
+			}
+		}
+	})
+}
+
+func TestNoteDAOFindMultipleSorters(t *testing.T) {
+	testNoteDAOFindPaths(t,
+		core.NoteFindOpts{
+			Sorters: []core.NoteSorter{
+				{Field: core.NoteSortWordCount, Ascending: true},
+				{Field: core.NoteSortCreated, Ascending: false},
+			},
+		},
+		[]string{
+			"log/2021-01-03.md", "log/2021-02-04.md", "log/2021-01-04.md",
+			"index.md", "f39c8.md", "ref/test/ref.md", "ref/test/a.md", "ref/test/b.md",
+		},
+	)
+}
+
+// Notes with identical sort terms and titles are ordered by their path.
+func TestNoteDAOFindSortTiesBrokenByPath(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		for _, path := range []string{"z.md", "a.md", "m.md"} {
+			_, err := dao.Add(core.Note{Path: path, Title: "Duplicate", WordCount: 1})
+			assert.Nil(t, err)
+		}
+
+		notes, err := dao.Find(context.Background(), core.NoteFindOpts{
+			Sorters: []core.NoteSorter{{Field: core.NoteSortWordCount, Ascending: true}},
+			Limit:   3,
+		})
+		assert.Nil(t, err)
+
+		actual := make([]string, 0)
+		for _, n := range notes {
+			actual = append(actual, n.Path)
+		}
+		assert.Equal(t, actual, []string{"a.md", "m.md", "z.md"})
+	})
+}
+
 func testNoteDAOFindSort(t *testing.T, field core.NoteSortField, ascending bool, expected []string) {
 	testNoteDAOFindPaths(t,
 		core.NoteFindOpts{
@@ -1095,7 +2813,7 @@ func testNoteDAOFindSort(t *testing.T, field core.NoteSortField, ascending bool,
 
 func testNoteDAOFindPaths(t *testing.T, opts core.NoteFindOpts, expected []string) {
 	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
-		matches, err := dao.Find(opts)
+		matches, err := dao.Find(context.Background(), opts)
 		assert.Nil(t, err)
 
 		actual := make([]string, 0)
@@ -1108,21 +2826,23 @@ func testNoteDAOFindPaths(t *testing.T, opts core.NoteFindOpts, expected []strin
 
 func testNoteDAOFind(t *testing.T, opts core.NoteFindOpts, expected []core.ContextualNote) {
 	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
-		actual, err := dao.Find(opts)
+		actual, err := dao.Find(context.Background(), opts)
 		assert.Nil(t, err)
 		assert.Equal(t, actual, expected)
 	})
 }
 
+// testNoteDAO runs the callback with a DAO storing the raw content of the
+// notes, like the fixtures.
 func testNoteDAO(t *testing.T, callback func(tx Transaction, dao *NoteDAO)) {
 	testTransaction(t, func(tx Transaction) {
-		callback(tx, NewNoteDAO(tx, &util.NullLogger))
+		callback(tx, NewNoteDAOWithOpts(tx, &util.NullLogger, NoteDAOOpts{StoreRawContent: true}))
 	})
 }
 
 func testNoteDAOWithFixtures(t *testing.T, fixtures string, callback func(tx Transaction, dao *NoteDAO)) {
 	testTransactionWithFixtures(t, opt.NewNotEmptyString(fixtures), func(tx Transaction) {
-		callback(tx, NewNoteDAO(tx, &util.NullLogger))
+		callback(tx, NewNoteDAOWithOpts(tx, &util.NullLogger, NoteDAOOpts{StoreRawContent: true}))
 	})
 }
 
@@ -1135,7 +2855,7 @@ type noteRow struct {
 func queryNoteRow(tx Transaction, where string) (noteRow, error) {
 	var row noteRow
 	err := tx.QueryRow(fmt.Sprintf(`
-		SELECT path, title, lead, body, raw_content, word_count, checksum, created, modified, metadata
+		SELECT path, title, lead, body, IFNULL(raw_content, ''), word_count, checksum, created, modified, metadata
 		  FROM notes
 		 WHERE %v
 	`, where)).Scan(&row.Path, &row.Title, &row.Lead, &row.Body, &row.RawContent, &row.WordCount, &row.Checksum, &row.Created, &row.Modified, &row.Metadata)
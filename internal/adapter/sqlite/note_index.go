@@ -1,9 +1,11 @@
 package sqlite
 
 import (
+	"context"
 	"path/filepath"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/zk-org/zk/internal/core"
 	"github.com/zk-org/zk/internal/util"
@@ -19,6 +21,16 @@ type NoteIndex struct {
 	db           *DB
 	dao          *dao
 	logger       util.Logger
+	opts         NoteIndexOpts
+}
+
+// NoteIndexOpts holds the options of a NoteIndex.
+type NoteIndexOpts struct {
+	// Indicates whether the raw content of the notes is stored in the index,
+	// see NoteDAOOpts.
+	StoreRawContent bool
+	// File extensions of the notes, see NoteDAOOpts.
+	Extensions []string
 }
 
 type dao struct {
@@ -29,26 +41,50 @@ type dao struct {
 }
 
 func NewNoteIndex(notebookPath string, db *DB, logger util.Logger) *NoteIndex {
+	return NewNoteIndexWithOpts(notebookPath, db, logger, NoteIndexOpts{})
+}
+
+// NewNoteIndexWithOpts creates a new NoteIndex using custom options.
+func NewNoteIndexWithOpts(notebookPath string, db *DB, logger util.Logger, opts NoteIndexOpts) *NoteIndex {
 	return &NoteIndex{
 		notebookPath: notebookPath,
 		db:           db,
 		logger:       logger,
+		opts:         opts,
 	}
 }
 
 // Find implements core.NoteIndex.
-func (ni *NoteIndex) Find(opts core.NoteFindOpts) (notes []core.ContextualNote, err error) {
-	err = ni.commit(func(dao *dao) error {
-		notes, err = dao.notes.Find(opts)
+func (ni *NoteIndex) Find(ctx context.Context, opts core.NoteFindOpts) (notes []core.ContextualNote, err error) {
+	err = ni.commitContext(ctx, func(dao *dao) error {
+		notes, err = dao.notes.Find(ctx, opts)
 		return err
 	})
 	return
 }
 
 // FindMinimal implements core.NoteIndex.
-func (ni *NoteIndex) FindMinimal(opts core.NoteFindOpts) (notes []core.MinimalNote, err error) {
-	err = ni.commit(func(dao *dao) error {
-		notes, err = dao.notes.FindMinimal(opts)
+func (ni *NoteIndex) FindMinimal(ctx context.Context, opts core.NoteFindOpts) (notes []core.MinimalNote, err error) {
+	err = ni.commitContext(ctx, func(dao *dao) error {
+		notes, err = dao.notes.FindMinimal(ctx, opts)
+		return err
+	})
+	return
+}
+
+// Count implements core.NoteIndex.
+func (ni *NoteIndex) Count(ctx context.Context, opts core.NoteFindOpts) (count int, err error) {
+	err = ni.commitContext(ctx, func(dao *dao) error {
+		count, err = dao.notes.Count(ctx, opts)
+		return err
+	})
+	return
+}
+
+// Stats implements core.NoteIndex.
+func (ni *NoteIndex) Stats(ctx context.Context, opts core.NoteFindOpts) (stats core.NoteStats, err error) {
+	err = ni.commitContext(ctx, func(dao *dao) error {
+		stats, err = dao.notes.Stats(ctx, opts)
 		return err
 	})
 	return
@@ -67,6 +103,9 @@ func (ni *NoteIndex) findLinkMatch(dao *dao, baseDir string, href string, linkTy
 	if strutil.IsURL(href) {
 		return 0, nil
 	}
+	if strings.HasPrefix(href, "id:") {
+		return dao.notes.FindByHref(href, false)
+	}
 
 	id, _ := ni.findPathMatch(dao, baseDir, href)
 	if id.IsValid() {
@@ -74,7 +113,7 @@ func (ni *NoteIndex) findLinkMatch(dao *dao, baseDir string, href string, linkTy
 	}
 
 	allowPartialMatch := (linkType == core.LinkTypeWikiLink)
-	return dao.notes.FindIdByHref(href, allowPartialMatch)
+	return dao.notes.FindByHref(href, allowPartialMatch)
 }
 
 func (ni *NoteIndex) findPathMatch(dao *dao, baseDir string, href string) (core.NoteID, error) {
@@ -82,7 +121,7 @@ func (ni *NoteIndex) findPathMatch(dao *dao, baseDir string, href string) (core.
 	if err != nil {
 		return 0, err
 	}
-	return dao.notes.FindIdByHref(href, false)
+	return dao.notes.FindByHref(href, false)
 }
 
 // FindLinksBetweenNotes implements core.NoteIndex.
@@ -94,10 +133,19 @@ func (ni *NoteIndex) FindLinksBetweenNotes(ids []core.NoteID) (links []core.Reso
 	return
 }
 
+// FindDanglingLinks implements core.NoteIndex.
+func (ni *NoteIndex) FindDanglingLinks(ids []core.NoteID) (links []core.ResolvedLink, err error) {
+	err = ni.commit(func(dao *dao) error {
+		links, err = dao.links.FindDanglingFromNotes(ids)
+		return err
+	})
+	return
+}
+
 // FindCollections implements core.NoteIndex.
-func (ni *NoteIndex) FindCollections(kind core.CollectionKind, sorters []core.CollectionSorter) (collections []core.Collection, err error) {
+func (ni *NoteIndex) FindCollections(kind core.CollectionKind, opts core.CollectionFindOpts) (collections []core.Collection, err error) {
 	err = ni.commit(func(dao *dao) error {
-		collections, err = dao.collections.FindAll(kind, sorters)
+		collections, err = dao.collections.Find(kind, opts)
 		return err
 	})
 	return
@@ -127,12 +175,16 @@ func (ni *NoteIndex) Add(note core.Note) (id core.NoteID, err error) {
 			return err
 		}
 
-		err = ni.fixExistingLinks(dao, note.ID, note.Path)
+		err = ni.associateCollections(dao.collections, id, core.CollectionKindTag, note.Tags)
+		if err != nil {
+			return err
+		}
+		err = ni.associateCollections(dao.collections, id, core.CollectionKindAlias, note.Aliases)
 		if err != nil {
 			return err
 		}
 
-		return ni.associateTags(dao.collections, id, note.Tags)
+		return ni.fixExistingLinks(dao, note.ID, note.Path, note.Aliases, noteMetadataID(note))
 	})
 
 	err = errors.Wrapf(err, "%v: failed to index the note", note.Path)
@@ -141,14 +193,41 @@ func (ni *NoteIndex) Add(note core.Note) (id core.NoteID, err error) {
 
 // fixExistingLinks will go over all indexed links and update their target to
 // the given id if they match the given path better than their current
-// targetPath.
-func (ni *NoteIndex) fixExistingLinks(dao *dao, id core.NoteID, path string) error {
+// targetPath, or the `id` metadata of the note.
+func (ni *NoteIndex) fixExistingLinks(dao *dao, id core.NoteID, path string, aliases []string, metadataID string) error {
 	links, err := dao.links.FindInternal()
 	if err != nil {
 		return err
 	}
 
 	for _, link := range links {
+		if strings.HasPrefix(link.Href, "id:") {
+			// An ID link never matches a path, only the `id` metadata.
+			if link.TargetPath == "" && metadataID != "" && link.Href == "id:"+metadataID {
+				targetID, err := dao.notes.FindByHref(link.Href, false)
+				if err == nil && targetID == id {
+					err = dao.links.SetTargetID(link.ID, id)
+				}
+				if err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		if link.TargetPath == "" && link.Type == core.LinkTypeWikiLink && hrefMatchesAlias(link.Href, aliases) {
+			// The link can be resolved with the aliases of the note, unless
+			// they are shared with another note.
+			targetID, err := dao.notes.FindByHref(link.Href, true)
+			if err == nil && targetID == id {
+				err = dao.links.SetTargetID(link.ID, id)
+			}
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
 		// To find the best match possible, shortest paths take precedence.
 		// See https://github.com/zk-org/zk/issues/23
 		if link.TargetPath != "" && len(link.TargetPath) < len(path) {
@@ -166,6 +245,99 @@ func (ni *NoteIndex) fixExistingLinks(dao *dao, id core.NoteID, path string) err
 	return nil
 }
 
+// hrefMatchesAlias returns whether the given link href targets one of the
+// aliases, ignoring the case.
+func hrefMatchesAlias(href string, aliases []string) bool {
+	href = strings.SplitN(href, "#", 2)[0]
+	for _, alias := range aliases {
+		if strings.EqualFold(href, alias) {
+			return true
+		}
+	}
+	return false
+}
+
+// noteMetadataID returns the `id` metadata of the note, which is targeted by
+// the Org-mode `id:` links.
+func noteMetadataID(note core.Note) string {
+	id, _ := note.Metadata["id"].(string)
+	return id
+}
+
+// resolveLinksToAliases resolves again the wiki-links targeting one of the
+// given aliases, which were added to or removed from a note.
+func (ni *NoteIndex) resolveLinksToAliases(dao *dao, aliases []string) error {
+	if len(aliases) == 0 {
+		return nil
+	}
+	return ni.resolveLinks(dao, func(link core.ResolvedLink) bool {
+		return link.Type == core.LinkTypeWikiLink && hrefMatchesAlias(link.Href, aliases)
+	})
+}
+
+// resolveLinksToMetadataIDs resolves again the `id:` links targeting one of
+// the given `id` metadata, which were changed in a note.
+func (ni *NoteIndex) resolveLinksToMetadataIDs(dao *dao, ids ...string) error {
+	return ni.resolveLinks(dao, func(link core.ResolvedLink) bool {
+		for _, id := range ids {
+			if id != "" && link.Href == "id:"+id {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// resolveLinks resolves again the internal links accepted by matches.
+func (ni *NoteIndex) resolveLinks(dao *dao, matches func(link core.ResolvedLink) bool) error {
+	links, err := dao.links.FindInternal()
+	if err != nil {
+		return err
+	}
+
+	for _, link := range links {
+		if !matches(link) {
+			continue
+		}
+
+		targetID, err := ni.findLinkMatch(dao, "" /* base dir */, link.Href, link.Type)
+		if err == nil && targetID != link.TargetID {
+			err = dao.links.SetTargetID(link.ID, targetID)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// changedAliases returns the aliases which are only in one of the given
+// lists, ignoring the case.
+func changedAliases(old []string, new []string) []string {
+	contains := func(aliases []string, alias string) bool {
+		for _, a := range aliases {
+			if strings.EqualFold(a, alias) {
+				return true
+			}
+		}
+		return false
+	}
+
+	changed := []string{}
+	for _, alias := range old {
+		if !contains(new, alias) {
+			changed = append(changed, alias)
+		}
+	}
+	for _, alias := range new {
+		if !contains(old, alias) {
+			changed = append(changed, alias)
+		}
+	}
+	return changed
+}
+
 // linkMatchesPath returns whether the given link can be used to reach the
 // given note path.
 func (ni *NoteIndex) linkMatchesPath(link core.ResolvedLink, path string) (bool, error) {
@@ -220,39 +392,66 @@ func (ni *NoteIndex) relNotebookPath(baseDir string, href string) (string, error
 // Update implements core.NoteIndex.
 func (ni *NoteIndex) Update(note core.Note) error {
 	err := ni.commit(func(dao *dao) error {
+		oldMetadataID, err := dao.notes.FindMetadataID(note.Path)
+		if err != nil {
+			return err
+		}
+
 		id, err := dao.notes.Update(note)
 		if err != nil {
 			return err
 		}
 
 		// Reset links
-		err = dao.links.RemoveAll(id)
+		links, err := ni.resolveLinkNoteIDs(dao, id, note.Links)
 		if err != nil {
 			return err
 		}
-		err = ni.addLinks(dao, id, note.Links)
+		err = dao.links.SetAll(id, links)
 		if err != nil {
 			return err
 		}
 
-		// Reset tags
+		oldAliases, err := dao.collections.FindNamesOfNote(id, core.CollectionKindAlias)
+		if err != nil {
+			return err
+		}
+
+		// Reset tags and aliases
 		err = dao.collections.RemoveAssociations(id)
 		if err != nil {
 			return err
 		}
-		return ni.associateTags(dao.collections, id, note.Tags)
+		err = ni.associateCollections(dao.collections, id, core.CollectionKindTag, note.Tags)
+		if err != nil {
+			return err
+		}
+		err = ni.associateCollections(dao.collections, id, core.CollectionKindAlias, note.Aliases)
+		if err != nil {
+			return err
+		}
+
+		err = ni.resolveLinksToAliases(dao, changedAliases(oldAliases, note.Aliases))
+		if err != nil {
+			return err
+		}
+
+		if metadataID := noteMetadataID(note); metadataID != oldMetadataID {
+			return ni.resolveLinksToMetadataIDs(dao, oldMetadataID, metadataID)
+		}
+		return nil
 	})
 
 	return errors.Wrapf(err, "%v: failed to update note index", note.Path)
 }
 
-func (ni *NoteIndex) associateTags(collections *CollectionDAO, noteId core.NoteID, tags []string) error {
-	for _, tag := range tags {
-		tagId, err := collections.FindOrCreate(core.CollectionKindTag, tag)
+func (ni *NoteIndex) associateCollections(collections *CollectionDAO, noteId core.NoteID, kind core.CollectionKind, names []string) error {
+	for _, name := range names {
+		collectionId, err := collections.FindOrCreate(kind, name)
 		if err != nil {
 			return err
 		}
-		_, err = collections.Associate(noteId, tagId)
+		_, err = collections.Associate(noteId, collectionId)
 		if err != nil {
 			return err
 		}
@@ -288,14 +487,42 @@ func (ni *NoteIndex) resolveLinkNoteIDs(dao *dao, sourceID core.NoteID, links []
 	return resolvedLinks, nil
 }
 
+// Exists implements core.NoteIndex.
+func (ni *NoteIndex) Exists(path string) (exists bool, err error) {
+	err = ni.commit(func(dao *dao) error {
+		exists, err = dao.notes.Exists(path)
+		return err
+	})
+	return
+}
+
 // Remove implements core.NoteIndex
 func (ni *NoteIndex) Remove(path string) error {
 	err := ni.commit(func(dao *dao) error {
-		return dao.notes.Remove(path)
+		_, err := dao.notes.Remove(path)
+		return err
 	})
 	return errors.Wrapf(err, "%v: failed to remove note from index", path)
 }
 
+// Rename implements core.NoteIndex.
+func (ni *NoteIndex) Rename(oldPath string, newPath string) error {
+	err := ni.commit(func(dao *dao) error {
+		_, err := dao.notes.Rename(oldPath, newPath)
+		return err
+	})
+	return errors.Wrapf(err, "%v: failed to rename note to %v", oldPath, newPath)
+}
+
+// FindByChecksum implements core.NoteIndex.
+func (ni *NoteIndex) FindByChecksum(checksum string) (notes []core.MinimalNote, err error) {
+	err = ni.commit(func(dao *dao) error {
+		notes, err = dao.notes.FindByChecksum(checksum)
+		return err
+	})
+	return
+}
+
 // Commit implements core.NoteIndex.
 func (ni *NoteIndex) Commit(transaction func(idx core.NoteIndex) error) error {
 	return ni.commit(func(dao *dao) error {
@@ -307,6 +534,82 @@ func (ni *NoteIndex) Commit(transaction func(idx core.NoteIndex) error) error {
 	})
 }
 
+// RebuildSearchIndex implements core.NoteIndex.
+func (ni *NoteIndex) RebuildSearchIndex() error {
+	return ni.commit(func(dao *dao) error {
+		err := dao.notes.RebuildFTS()
+		if err != nil {
+			return err
+		}
+		return dao.notes.Optimize()
+	})
+}
+
+// Check implements core.NoteIndex.
+func (ni *NoteIndex) Check(repair bool) (core.NoteIndexCheckReport, error) {
+	return ni.db.Check(CheckOpts{Repair: repair})
+}
+
+// ChecksumAlgorithm implements core.NoteIndex.
+func (ni *NoteIndex) ChecksumAlgorithm() (algorithm core.ChecksumAlgorithm, err error) {
+	err = ni.commit(func(dao *dao) error {
+		res, err := dao.metadata.Get(checksumAlgorithmKey)
+		if err != nil {
+			return err
+		}
+		// Indexes created before the algorithm was configurable use SHA-256.
+		if res == "" {
+			algorithm = core.ChecksumSHA256
+			return nil
+		}
+		algorithm, err = core.ChecksumAlgorithmFromString(res)
+		return err
+	})
+	return
+}
+
+// SetChecksumAlgorithm implements core.NoteIndex.
+func (ni *NoteIndex) SetChecksumAlgorithm(algorithm core.ChecksumAlgorithm) error {
+	return ni.commit(func(dao *dao) error {
+		return dao.metadata.Set(checksumAlgorithmKey, string(algorithm))
+	})
+}
+
+// ChecksumTrimSpaces implements core.NoteIndex.
+func (ni *NoteIndex) ChecksumTrimSpaces() (trimSpaces bool, err error) {
+	err = ni.commit(func(dao *dao) error {
+		res, err := dao.metadata.Get(checksumTrimSpacesKey)
+		trimSpaces = (res == "true")
+		return err
+	})
+	return
+}
+
+// SetChecksumTrimSpaces implements core.NoteIndex.
+func (ni *NoteIndex) SetChecksumTrimSpaces(trimSpaces bool) error {
+	return ni.commit(func(dao *dao) error {
+		value := "false"
+		if trimSpaces {
+			value = "true"
+		}
+		return dao.metadata.Set(checksumTrimSpacesKey, value)
+	})
+}
+
+// SetModified implements core.NoteIndex.
+func (ni *NoteIndex) SetModified(path string, modified time.Time, size int64) error {
+	return ni.commit(func(dao *dao) error {
+		return dao.notes.SetModified(path, modified, size)
+	})
+}
+
+// SetChecksum implements core.NoteIndex.
+func (ni *NoteIndex) SetChecksum(path string, checksum string) error {
+	return ni.commit(func(dao *dao) error {
+		return dao.notes.SetChecksum(path, checksum)
+	})
+}
+
 // NeedsReindexing implements core.NoteIndex.
 func (ni *NoteIndex) NeedsReindexing() (needsReindexing bool, err error) {
 	err = ni.commit(func(dao *dao) error {
@@ -330,12 +633,21 @@ func (ni *NoteIndex) SetNeedsReindexing(needsReindexing bool) error {
 }
 
 func (ni *NoteIndex) commit(transaction func(dao *dao) error) error {
+	return ni.commitContext(context.Background(), transaction)
+}
+
+// commitContext is like commit, but the transaction is rolled back when the
+// context is cancelled.
+func (ni *NoteIndex) commitContext(ctx context.Context, transaction func(dao *dao) error) error {
 	if ni.dao != nil {
 		return transaction(ni.dao)
 	} else {
-		return ni.db.WithTransaction(func(tx Transaction) error {
+		return ni.db.WithTransactionContext(ctx, func(tx Transaction) error {
 			dao := dao{
-				notes:       NewNoteDAO(tx, ni.logger),
+				notes: NewNoteDAOWithOpts(tx, ni.logger, NoteDAOOpts{
+					StoreRawContent: ni.opts.StoreRawContent,
+					Extensions:      ni.opts.Extensions,
+				}),
 				links:       NewLinkDAO(tx, ni.logger),
 				collections: NewCollectionDAO(tx, ni.logger),
 				metadata:    NewMetadataDAO(tx),
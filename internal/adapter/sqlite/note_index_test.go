@@ -1,11 +1,17 @@
 package sqlite
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/zk-org/zk/internal/adapter/fs"
 	"github.com/zk-org/zk/internal/core"
 	"github.com/zk-org/zk/internal/util"
+	"github.com/zk-org/zk/internal/util/opt"
 	"github.com/zk-org/zk/internal/util/test/assert"
 )
 
@@ -218,6 +224,446 @@ func TestNoteIndexUpdateWithTags(t *testing.T) {
 	assertSQL(true)
 }
 
+func TestNoteIndexFindLinkMatchWithAlias(t *testing.T) {
+	_, index := testNoteIndex(t)
+
+	id, err := index.Add(core.Note{
+		Path:    "productivity/method.md",
+		Aliases: []string{"GTD", "Getting Things Done"},
+	})
+	assert.Nil(t, err)
+
+	test := func(href string, linkType core.LinkType, expected core.NoteID) {
+		actual, err := index.FindLinkMatch("", href, linkType)
+		assert.Nil(t, err)
+		assert.Equal(t, actual, expected)
+	}
+
+	test("GTD", core.LinkTypeWikiLink, id)
+	test("getting things done#review", core.LinkTypeWikiLink, id)
+	// Markdown links target paths.
+	test("GTD", core.LinkTypeMarkdown, 0)
+
+	// An alias shared by two notes is ambiguous.
+	_, err = index.Add(core.Note{
+		Path:    "other.md",
+		Aliases: []string{"gtd"},
+	})
+	assert.Nil(t, err)
+	test("GTD", core.LinkTypeWikiLink, 0)
+	test("Getting Things Done", core.LinkTypeWikiLink, id)
+}
+
+func TestNoteIndexFindLinkMatchWithOtherExtensions(t *testing.T) {
+	_, index := testNoteIndex(t)
+
+	markdownID, err := index.Add(core.Note{Path: "mixed/orange.markdown"})
+	assert.Nil(t, err)
+	txtID, err := index.Add(core.Note{Path: "mixed/lemon.txt"})
+	assert.Nil(t, err)
+
+	test := func(href string, linkType core.LinkType, expected core.NoteID) {
+		actual, err := index.FindLinkMatch("", href, linkType)
+		assert.Nil(t, err)
+		assert.Equal(t, actual, expected)
+	}
+
+	test("orange", core.LinkTypeWikiLink, markdownID)
+	test("mixed/orange", core.LinkTypeMarkdown, markdownID)
+	test("mixed/orange.markdown", core.LinkTypeMarkdown, markdownID)
+	test("lemon", core.LinkTypeWikiLink, txtID)
+	test("mixed/lemon.txt", core.LinkTypeMarkdown, txtID)
+}
+
+func TestNoteIndexAddFillsLinksToAlias(t *testing.T) {
+	db, index := testNoteIndex(t)
+
+	sourceID, err := index.Add(core.Note{
+		Path: "inbox.md",
+		Links: []core.Link{
+			{Title: "GTD", Href: "GTD", Type: core.LinkTypeWikiLink},
+		},
+	})
+	assert.Nil(t, err)
+
+	id, err := index.Add(core.Note{
+		Path:    "productivity/method.md",
+		Aliases: []string{"GTD"},
+	})
+	assert.Nil(t, err)
+
+	rows := queryLinkRows(t, db.db, fmt.Sprintf("source_id = %d", sourceID))
+	assert.Equal(t, rows, []linkRow{
+		{
+			SourceId: sourceID,
+			TargetId: &id,
+			Title:    "GTD",
+			Href:     "GTD",
+			Type:     "wiki-link",
+		},
+	})
+}
+
+func TestNoteIndexUpdateResolvesLinksToAddedAlias(t *testing.T) {
+	db, index := testNoteIndex(t)
+
+	sourceID, err := index.Add(core.Note{
+		Path: "inbox.md",
+		Links: []core.Link{
+			{Title: "GTD", Href: "GTD", Type: core.LinkTypeWikiLink},
+		},
+	})
+	assert.Nil(t, err)
+
+	id, err := index.Add(core.Note{Path: "productivity/method.md"})
+	assert.Nil(t, err)
+
+	rows := queryLinkRows(t, db.db, fmt.Sprintf("source_id = %d", sourceID))
+	assert.Nil(t, rows[0].TargetId)
+
+	err = index.Update(core.Note{
+		Path:    "productivity/method.md",
+		Aliases: []string{"gtd"},
+	})
+	assert.Nil(t, err)
+
+	rows = queryLinkRows(t, db.db, fmt.Sprintf("source_id = %d", sourceID))
+	assert.Equal(t, rows[0].TargetId, &id)
+}
+
+func TestNoteIndexResolvesIDLinksWithTheIDMetadata(t *testing.T) {
+	db, index := testNoteIndex(t)
+
+	// The link is indexed before its target.
+	sourceID, err := index.Add(core.Note{
+		Path: "garden/vegetables.org",
+		Links: []core.Link{
+			{Title: "Sun", Href: "id:4b2a", Type: core.LinkTypeWikiLink},
+		},
+	})
+	assert.Nil(t, err)
+
+	rows := queryLinkRows(t, db.db, fmt.Sprintf("source_id = %d", sourceID))
+	assert.Nil(t, rows[0].TargetId)
+
+	// A note whose path contains the href is not a match.
+	_, err = index.Add(core.Note{Path: "id:4b2a.md"})
+	assert.Nil(t, err)
+	id, err := index.Add(core.Note{
+		Path:     "sun.org",
+		Metadata: map[string]interface{}{"id": "4b2a"},
+	})
+	assert.Nil(t, err)
+
+	rows = queryLinkRows(t, db.db, fmt.Sprintf("source_id = %d", sourceID))
+	assert.Equal(t, rows[0].TargetId, &id)
+
+	targetID, err := index.FindLinkMatch("", "id:4b2a", core.LinkTypeWikiLink)
+	assert.Nil(t, err)
+	assert.Equal(t, targetID, id)
+
+	// Changing the ID of the note unresolves the link.
+	err = index.Update(core.Note{
+		Path:     "sun.org",
+		Metadata: map[string]interface{}{"id": "9f1c"},
+	})
+	assert.Nil(t, err)
+
+	rows = queryLinkRows(t, db.db, fmt.Sprintf("source_id = %d", sourceID))
+	assert.Nil(t, rows[0].TargetId)
+
+	err = index.Update(core.Note{
+		Path:     "sun.org",
+		Metadata: map[string]interface{}{"id": "4b2a"},
+	})
+	assert.Nil(t, err)
+
+	rows = queryLinkRows(t, db.db, fmt.Sprintf("source_id = %d", sourceID))
+	assert.Equal(t, rows[0].TargetId, &id)
+}
+
+func TestNoteIndexUpdateUnresolvesLinksToRemovedAlias(t *testing.T) {
+	db, index := testNoteIndex(t)
+
+	_, err := index.Add(core.Note{
+		Path:    "productivity/method.md",
+		Aliases: []string{"GTD"},
+	})
+	assert.Nil(t, err)
+
+	sourceID, err := index.Add(core.Note{
+		Path: "inbox.md",
+		Links: []core.Link{
+			{Title: "GTD", Href: "GTD", Type: core.LinkTypeWikiLink},
+		},
+	})
+	assert.Nil(t, err)
+
+	err = index.Update(core.Note{Path: "productivity/method.md"})
+	assert.Nil(t, err)
+
+	rows := queryLinkRows(t, db.db, fmt.Sprintf("source_id = %d", sourceID))
+	assert.Nil(t, rows[0].TargetId)
+}
+
+func TestNoteIndexUpdateUnresolvesLinksToSharedAlias(t *testing.T) {
+	db, index := testNoteIndex(t)
+
+	_, err := index.Add(core.Note{
+		Path:    "productivity/method.md",
+		Aliases: []string{"GTD"},
+	})
+	assert.Nil(t, err)
+	_, err = index.Add(core.Note{Path: "productivity/book.md"})
+	assert.Nil(t, err)
+
+	sourceID, err := index.Add(core.Note{
+		Path: "inbox.md",
+		Links: []core.Link{
+			{Title: "GTD", Href: "GTD", Type: core.LinkTypeWikiLink},
+		},
+	})
+	assert.Nil(t, err)
+
+	// The alias is now ambiguous.
+	err = index.Update(core.Note{
+		Path:    "productivity/book.md",
+		Aliases: []string{"GTD"},
+	})
+	assert.Nil(t, err)
+
+	rows := queryLinkRows(t, db.db, fmt.Sprintf("source_id = %d", sourceID))
+	assert.Nil(t, rows[0].TargetId)
+}
+
+func TestNoteIndexFindMatchingAlias(t *testing.T) {
+	_, index := testNoteIndex(t)
+
+	_, err := index.Add(core.Note{
+		Path:    "productivity/method.md",
+		Title:   "Method",
+		Body:    "Capture everything.",
+		Aliases: []string{"GTD", "Getting Things Done"},
+	})
+	assert.Nil(t, err)
+
+	notes, err := index.Find(context.Background(), core.NoteFindOpts{
+		Match:         []string{"things done"},
+		MatchStrategy: core.MatchStrategyFts,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, len(notes), 1)
+	assert.Equal(t, notes[0].Path, "productivity/method.md")
+	// The snippet shows the matching alias.
+	assert.Equal(t, notes[0].Snippets, []string{"alias: GTD, Getting <zk:match>Things</zk:match> <zk:match>Done</zk:match>"})
+
+	notes, err = index.Find(context.Background(), core.NoteFindOpts{
+		Match:         []string{"gtd"},
+		MatchStrategy: core.MatchStrategyFts,
+		MatchScope:    core.MatchScopeTitle,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, len(notes), 1)
+
+	notes, err = index.Find(context.Background(), core.NoteFindOpts{
+		Match:         []string{"gtd"},
+		MatchStrategy: core.MatchStrategyFts,
+		MatchScope:    core.MatchScopeBody,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, len(notes), 0)
+}
+
+func TestNoteIndexFindWithCancelledContext(t *testing.T) {
+	_, index := testNoteIndex(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := index.Find(ctx, core.NoteFindOpts{})
+	assert.Err(t, err, context.Canceled.Error())
+	_, err = index.FindMinimal(ctx, core.NoteFindOpts{})
+	assert.Err(t, err, context.Canceled.Error())
+	_, err = index.Count(ctx, core.NoteFindOpts{})
+	assert.Err(t, err, context.Canceled.Error())
+	_, err = index.Stats(ctx, core.NoteFindOpts{})
+	assert.Err(t, err, context.Canceled.Error())
+}
+
+func TestNoteIndexCheck(t *testing.T) {
+	db, index := testNoteIndex(t)
+	removeFTSEntry(t, db, 3)
+
+	report, err := index.Check(false)
+	assert.Nil(t, err)
+	assert.False(t, report.IsHealthy())
+
+	report, err = index.Check(true)
+	assert.Nil(t, err)
+	assert.True(t, report.IsHealthy())
+	assert.Equal(t, report.(CheckReport).Repaired, true)
+
+	report, err = index.Check(false)
+	assert.Nil(t, err)
+	assert.Equal(t, report, CheckReport{})
+}
+
+func TestNoteIndexChecksumAlgorithmDefaultsToSHA256(t *testing.T) {
+	_, index := testNoteIndex(t)
+
+	algorithm, err := index.ChecksumAlgorithm()
+	assert.Nil(t, err)
+	assert.Equal(t, algorithm, core.ChecksumSHA256)
+}
+
+func TestNoteIndexSetChecksumAlgorithm(t *testing.T) {
+	_, index := testNoteIndex(t)
+
+	err := index.SetChecksumAlgorithm(core.ChecksumFNV64)
+	assert.Nil(t, err)
+
+	algorithm, err := index.ChecksumAlgorithm()
+	assert.Nil(t, err)
+	assert.Equal(t, algorithm, core.ChecksumFNV64)
+}
+
+func TestNoteIndexChecksumTrimSpacesDefaultsToFalse(t *testing.T) {
+	_, index := testNoteIndex(t)
+
+	trimSpaces, err := index.ChecksumTrimSpaces()
+	assert.Nil(t, err)
+	assert.False(t, trimSpaces)
+}
+
+func TestNoteIndexSetChecksumTrimSpaces(t *testing.T) {
+	_, index := testNoteIndex(t)
+
+	err := index.SetChecksumTrimSpaces(true)
+	assert.Nil(t, err)
+	trimSpaces, err := index.ChecksumTrimSpaces()
+	assert.Nil(t, err)
+	assert.True(t, trimSpaces)
+
+	err = index.SetChecksumTrimSpaces(false)
+	assert.Nil(t, err)
+	trimSpaces, err = index.ChecksumTrimSpaces()
+	assert.Nil(t, err)
+	assert.False(t, trimSpaces)
+}
+
+func TestNoteIndexAddErrorIncludesPath(t *testing.T) {
+	_, index := testNoteIndex(t)
+
+	_, err := index.Add(core.Note{Path: "log/2021-01-03.md"})
+	assert.Err(t, err, "log/2021-01-03.md: failed to index the note")
+}
+
+func TestNoteIndexIndexingMovedNote(t *testing.T) {
+	dir := t.TempDir()
+	for path, content := range map[string]string{
+		"dir1/moved.md": "# Moved\n\nContent of the moved note\n",
+		"other.md":      "# Other\n\nContent of the other note\n",
+	} {
+		path = filepath.Join(dir, path)
+		assert.Nil(t, os.MkdirAll(filepath.Dir(path), os.ModePerm))
+		assert.Nil(t, os.WriteFile(path, []byte(content), 0644))
+	}
+
+	db := testDBWithFixtures(t, opt.NullString)
+	storage, err := fs.NewFileStorage(dir, &util.NullLogger)
+	assert.Nil(t, err)
+	notebook := core.NewNotebook(dir, core.NewDefaultConfig(), core.NotebookPorts{
+		NoteIndex:         NewNoteIndex(dir, db, &util.NullLogger),
+		NoteContentParser: noteContentParserFunc(parseTitleForTest),
+		FS:                storage,
+		Logger:            &util.NullLogger,
+	})
+
+	stats, err := notebook.Index(core.NoteIndexOpts{})
+	assert.Nil(t, err)
+	assert.Equal(t, stats.AddedCount, 2)
+
+	var id core.NoteID
+	var before noteRow
+	err = db.WithTransaction(func(tx Transaction) error {
+		id, err = NewNoteDAO(tx, &util.NullLogger).FindIdByPath("dir1/moved.md")
+		assert.Nil(t, err)
+		before, err = queryNoteRow(tx, "path = 'dir1/moved.md'")
+		return err
+	})
+	assert.Nil(t, err)
+
+	assert.Nil(t, os.MkdirAll(filepath.Join(dir, "dir2"), os.ModePerm))
+	assert.Nil(t, os.Rename(filepath.Join(dir, "dir1/moved.md"), filepath.Join(dir, "dir2/moved.md")))
+
+	stats, err = notebook.Index(core.NoteIndexOpts{})
+	assert.Nil(t, err)
+	assert.Equal(t, stats.MovedCount, 1)
+	assert.Equal(t, stats.AddedCount, 0)
+	assert.Equal(t, stats.ModifiedCount, 0)
+	assert.Equal(t, stats.RemovedCount, 0)
+	assert.Equal(t, stats.UnchangedCount, 1)
+
+	err = db.WithTransaction(func(tx Transaction) error {
+		movedID, err := NewNoteDAO(tx, &util.NullLogger).FindIdByPath("dir2/moved.md")
+		assert.Nil(t, err)
+		assert.Equal(t, movedID, id)
+
+		after, err := queryNoteRow(tx, "path = 'dir2/moved.md'")
+		assert.Nil(t, err)
+		assert.Equal(t, after.Created, before.Created)
+		assert.Equal(t, after.Title, "Moved")
+		return nil
+	})
+	assert.Nil(t, err)
+}
+
+// BenchmarkNoteIndexAdd indexes notes in a single transaction, like
+// `zk index`, which reuses the prepared statements for all the notes.
+func BenchmarkNoteIndexAdd(b *testing.B) {
+	notes := benchmarkNotes(5000)
+	for i := 0; i < b.N; i++ {
+		benchmarkNoteIndex(b, func(index *NoteIndex) {
+			err := index.Commit(func(index core.NoteIndex) error {
+				for _, note := range notes {
+					if _, err := index.Add(note); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				b.Fatal(err)
+			}
+		})
+	}
+}
+
+// BenchmarkNoteIndexAddWithoutCommit indexes each note in its own
+// transaction, which prepares the statements again for every note.
+func BenchmarkNoteIndexAddWithoutCommit(b *testing.B) {
+	notes := benchmarkNotes(5000)
+	for i := 0; i < b.N; i++ {
+		benchmarkNoteIndex(b, func(index *NoteIndex) {
+			for _, note := range notes {
+				if _, err := index.Add(note); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func benchmarkNoteIndex(b *testing.B, callback func(index *NoteIndex)) {
+	db, err := OpenInMemory()
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer db.Close()
+
+	callback(NewNoteIndex("", db, &util.NullLogger))
+}
+
 func testNoteIndex(t *testing.T) (*DB, *NoteIndex) {
 	db := testDB(t)
 	return db, NewNoteIndex("", db, &util.NullLogger)
@@ -230,3 +676,15 @@ func assertTagExistsOrNot(t *testing.T, db *DB, shouldExist bool, tag string) {
 func assertTaggedOrNot(t *testing.T, db *DB, shouldBeTagged bool, noteId core.NoteID, tag string) {
 	assertExistOrNot(t, db, shouldBeTagged, "SELECT id FROM notes_collections WHERE note_id = ? AND collection_id IS (SELECT id FROM collections WHERE kind = 'tag' AND name = ?)", noteId, tag)
 }
+
+type noteContentParserFunc func(content string) (*core.NoteContent, error)
+
+func (f noteContentParserFunc) ParseNoteContent(content string) (*core.NoteContent, error) {
+	return f(content)
+}
+
+// parseTitleForTest parses the first line of the content as the note title.
+func parseTitleForTest(content string) (*core.NoteContent, error) {
+	title := strings.TrimPrefix(strings.SplitN(content, "\n", 2)[0], "# ")
+	return &core.NoteContent{Title: opt.NewNotEmptyString(title)}, nil
+}
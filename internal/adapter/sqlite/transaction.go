@@ -1,6 +1,9 @@
 package sqlite
 
-import "database/sql"
+import (
+	"context"
+	"database/sql"
+)
 
 // Inspired by https://pseudomuto.com/2018/01/clean-sql-transactions-in-golang/
 
@@ -11,11 +14,14 @@ import "database/sql"
 // handled by `WithTransaction`), those methods are not included here.
 type Transaction interface {
 	Exec(query string, args ...interface{}) (sql.Result, error)
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
 	ExecStmts(stmts []string) error
 	Prepare(query string) (*sql.Stmt, error)
 	PrepareLazy(query string) *LazyStmt
 	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
 	QueryRow(query string, args ...interface{}) *sql.Row
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
 }
 
 // txWrapper wraps a native sql.Tx to fully implement the Transaction interface.
@@ -46,7 +52,13 @@ type TxFn func(tx Transaction) error
 // WithTransaction creates a new transaction and handles rollback/commit based
 // on the error object returned by the TxFn closure.
 func (db *DB) WithTransaction(fn TxFn) error {
-	tx, err := db.db.Begin()
+	return db.WithTransactionContext(context.Background(), fn)
+}
+
+// WithTransactionContext is like WithTransaction, but the transaction is
+// rolled back if the context is cancelled before it is committed.
+func (db *DB) WithTransactionContext(ctx context.Context, fn TxFn) error {
+	tx, err := db.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
@@ -2,6 +2,7 @@ package term
 
 import (
 	"fmt"
+	"os"
 
 	"github.com/fatih/color"
 	"github.com/zk-org/zk/internal/core"
@@ -19,7 +20,39 @@ func (t *Terminal) Style(text string, rules ...core.Style) (string, error) {
 	if len(attrs) == 0 {
 		return text, nil
 	}
-	return color.New(attrs...).Sprint(text), nil
+	c := color.New(attrs...)
+	if !color.NoColor {
+		// color.New() disables the colors when NO_COLOR is set, which
+		// would prevent forcing them with --color=always.
+		c.EnableColor()
+	}
+	return c.Sprint(text), nil
+}
+
+// ColorMode controls when the styled text is colorized with ANSI escape codes.
+type ColorMode string
+
+const (
+	// ColorAuto colorizes the output only when it is a terminal and the
+	// NO_COLOR environment variable is not set.
+	ColorAuto ColorMode = "auto"
+	// ColorAlways colorizes the output, even when piped to another program.
+	ColorAlways ColorMode = "always"
+	// ColorNever never colorizes the output.
+	ColorNever ColorMode = "never"
+)
+
+// SetColorMode changes when the text is colorized by Style.
+func (t *Terminal) SetColorMode(mode ColorMode) {
+	switch mode {
+	case ColorAlways:
+		color.NoColor = false
+	case ColorNever:
+		color.NoColor = true
+	default:
+		_, noColor := os.LookupEnv("NO_COLOR")
+		color.NoColor = noColor || os.Getenv("TERM") == "dumb" || !t.IsOutputTTY()
+	}
 }
 
 func (t *Terminal) MustStyle(text string, rules ...core.Style) string {
@@ -101,3 +101,26 @@ func TestStyleAllRules(t *testing.T) {
 	test("bright-cyan-bg", "106m")
 	test("bright-white-bg", "107m")
 }
+
+func TestStyleColorModes(t *testing.T) {
+	test := func(mode ColorMode, noColor bool, expected string) {
+		if noColor {
+			t.Setenv("NO_COLOR", "1")
+		}
+		term := createTerminal()
+		term.SetColorMode(mode)
+		defer term.SetColorMode(ColorAlways)
+
+		res, err := term.Style("Hello", core.Style("red"))
+		assert.Nil(t, err)
+		assert.Equal(t, res, expected)
+	}
+
+	test(ColorAlways, false, "\033[31mHello\033[0m")
+	test(ColorNever, false, "Hello")
+	// The standard output is not a terminal while testing.
+	test(ColorAuto, false, "Hello")
+	// --color=always takes precedence over NO_COLOR.
+	test(ColorAlways, true, "\033[31mHello\033[0m")
+	test(ColorAuto, true, "Hello")
+}
@@ -30,6 +30,12 @@ func (t *Terminal) IsTTY() bool {
 	return isatty.IsTerminal(os.Stdin.Fd())
 }
 
+// IsOutputTTY returns whether the standard output is a terminal, instead of
+// being piped or redirected to a file.
+func (t *Terminal) IsOutputTTY() bool {
+	return isatty.IsTerminal(os.Stdout.Fd())
+}
+
 // SupportsUTF8 returns whether the computer is configured to support UTF-8.
 func (t *Terminal) SupportsUTF8() bool {
 	lang := strings.ToUpper(os.Getenv("LANG"))
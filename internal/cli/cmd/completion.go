@@ -0,0 +1,527 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/alecthomas/kong"
+	"github.com/zk-org/zk/internal/cli"
+	"github.com/zk-org/zk/internal/core"
+)
+
+// Completion prints a shell completion script.
+type Completion struct {
+	Shell string `arg help:"Shell for which to print the completion script, among: bash, zsh, fish." enum:"bash,zsh,fish"`
+}
+
+func (cmd *Completion) Run(ctx *kong.Context) error {
+	commands := completionCommands(ctx.Model.Node)
+
+	var script string
+	switch cmd.Shell {
+	case "zsh":
+		script = zshCompletion(commands)
+	case "fish":
+		script = fishCompletion(commands)
+	default:
+		script = bashCompletion(commands)
+	}
+	fmt.Print(script)
+	return nil
+}
+
+// Complete prints the candidates used by the completion scripts to complete
+// the dynamic parts of a command line, such as the tags.
+type Complete struct {
+	Kind   string `arg help:"Kind of candidates among: tags, paths, aliases, groups." enum:"tags,paths,aliases,groups"`
+	Prefix string `arg optional help:"Only print the candidates starting with this prefix."`
+}
+
+func (cmd *Complete) Run(container *cli.Container) error {
+	candidates, err := cmd.candidates(container)
+	if err != nil {
+		return err
+	}
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, cmd.Prefix) {
+			fmt.Println(candidate)
+		}
+	}
+	return nil
+}
+
+func (cmd *Complete) candidates(container *cli.Container) ([]string, error) {
+	switch cmd.Kind {
+	case "aliases":
+		return sortedKeys(container.Config.Aliases), nil
+	case "groups":
+		names := []string{}
+		for name := range container.Config.Groups {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return names, nil
+	}
+
+	// Completing outside a notebook is not an error, there are simply no
+	// candidates.
+	notebook, err := container.CurrentNotebook()
+	if err != nil {
+		return []string{}, nil
+	}
+
+	switch cmd.Kind {
+	case "tags":
+		tags, err := notebook.FindCollections(core.CollectionKindTag, core.CollectionFindOpts{})
+		if err != nil {
+			return nil, err
+		}
+		names := []string{}
+		for _, tag := range tags {
+			names = append(names, tag.Name)
+		}
+		return names, nil
+
+	default:
+		notes, err := notebook.FindMinimalNotes(core.NoteFindOpts{
+			Sorters: []core.NoteSorter{{Field: core.NoteSortPath, Ascending: true}},
+		})
+		if err != nil {
+			return nil, err
+		}
+		paths := []string{}
+		for _, note := range notes {
+			paths = append(paths, note.Path)
+		}
+		return pathCandidates(paths, cmd.Prefix), nil
+	}
+}
+
+// pathCandidates returns the paths completing the given prefix, one directory
+// level at a time. Directories are suffixed with a slash.
+func pathCandidates(paths []string, prefix string) []string {
+	candidates := []string{}
+	found := map[string]bool{}
+	for _, path := range paths {
+		if !strings.HasPrefix(path, prefix) {
+			continue
+		}
+		candidate := path
+		if i := strings.Index(path[len(prefix):], "/"); i >= 0 {
+			candidate = path[:len(prefix)+i+1]
+		}
+		if !found[candidate] {
+			found[candidate] = true
+			candidates = append(candidates, candidate)
+		}
+	}
+	sort.Strings(candidates)
+	return candidates
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := []string{}
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// completionKind is a kind of values completed by the scripts.
+type completionKind int
+
+const (
+	completionNone completionKind = iota
+	// Values chosen among a fixed list of words.
+	completionWords
+	// Files from the file system.
+	completionFiles
+	// Directories from the file system.
+	completionDirs
+	// Candidates printed by `zk complete`.
+	completionTags
+	completionPaths
+	completionGroups
+)
+
+// completionValues describes how to complete the value of a flag or a
+// positional argument.
+type completionValues struct {
+	Kind  completionKind
+	Words []string
+}
+
+// candidatesKind returns the kind given to `zk complete` to print the
+// candidates, if any.
+func (v completionValues) candidatesKind() string {
+	switch v.Kind {
+	case completionTags:
+		return "tags"
+	case completionPaths:
+		return "paths"
+	case completionGroups:
+		return "groups"
+	default:
+		return ""
+	}
+}
+
+// completionFlag describes a flag to complete.
+type completionFlag struct {
+	Long  string
+	Short string
+	Help  string
+	// Whether the flag is followed by a value.
+	HasValue bool
+	Values   completionValues
+}
+
+// Names returns the flag names with their dashes.
+func (f completionFlag) Names() []string {
+	names := []string{"--" + f.Long}
+	if f.Short != "" {
+		names = append(names, "-"+f.Short)
+	}
+	return names
+}
+
+// completionCommand describes a command to complete.
+type completionCommand struct {
+	// Full path of the command, e.g. `tag list`, empty for the root.
+	Path        string
+	Help        string
+	Subcommands []*completionCommand
+	Flags       []completionFlag
+	// Values of the positional arguments.
+	Args completionValues
+}
+
+// Name returns the last component of the command path.
+func (c *completionCommand) Name() string {
+	return c.Path[strings.LastIndex(c.Path, " ")+1:]
+}
+
+// completionCommands returns the visible commands of the CLI described by
+// the given Kong model, starting with the root.
+func completionCommands(root *kong.Node) []*completionCommand {
+	commands := []*completionCommand{}
+
+	var visit func(node *kong.Node, path string) *completionCommand
+	visit = func(node *kong.Node, path string) *completionCommand {
+		cmd := &completionCommand{Path: path, Help: node.Help}
+		commands = append(commands, cmd)
+
+		for _, flags := range node.AllFlags(true) {
+			for _, flag := range flags {
+				cmd.Flags = append(cmd.Flags, completionFlagFor(flag))
+			}
+		}
+		for _, arg := range node.Positional {
+			cmd.Args = positionalCompletion(arg)
+		}
+		for _, child := range node.Children {
+			if child.Hidden || child.Type != kong.CommandNode {
+				continue
+			}
+			cmd.Subcommands = append(cmd.Subcommands, visit(child, strings.TrimSpace(path+" "+child.Name)))
+		}
+		return cmd
+	}
+	visit(root, "")
+
+	return commands
+}
+
+func completionFlagFor(flag *kong.Flag) completionFlag {
+	res := completionFlag{
+		Long:     flag.Name,
+		Help:     flag.Help,
+		HasValue: !flag.IsBool() && !flag.IsCounter(),
+	}
+	if flag.Short != 0 {
+		res.Short = string(flag.Short)
+	}
+	if !res.HasValue {
+		return res
+	}
+
+	switch {
+	case flag.Enum != "":
+		res.Values = completionValues{Kind: completionWords, Words: flag.EnumSlice()}
+	case flag.Name == "tag":
+		res.Values = completionValues{Kind: completionTags}
+	case flag.Name == "group":
+		res.Values = completionValues{Kind: completionGroups}
+	case flag.Tag != nil && flag.Tag.Type == "path":
+		res.Values = completionValues{Kind: completionDirs}
+	case flag.PlaceHolder == "PATH" && flag.Group != nil && flag.Group.Key == "filter":
+		res.Values = completionValues{Kind: completionPaths}
+	case flag.PlaceHolder == "PATH":
+		res.Values = completionValues{Kind: completionFiles}
+	}
+	return res
+}
+
+func positionalCompletion(arg *kong.Positional) completionValues {
+	switch arg.Name {
+	case "path":
+		return completionValues{Kind: completionPaths}
+	case "glob":
+		return completionValues{Kind: completionTags}
+	case "directory":
+		return completionValues{Kind: completionDirs}
+	case "shell":
+		return completionValues{Kind: completionWords, Words: arg.EnumSlice()}
+	default:
+		return completionValues{}
+	}
+}
+
+// completionCase returns a shell case pattern matching the words leading to
+// each subcommand, formatted as `<parent command>/<name>`.
+func completionCase(commands []*completionCommand) string {
+	patterns := []string{}
+	for _, cmd := range commands {
+		for _, sub := range cmd.Subcommands {
+			patterns = append(patterns, fmt.Sprintf(`"%s/%s"`, cmd.Path, sub.Name()))
+		}
+	}
+	return strings.Join(patterns, "|")
+}
+
+func subcommandNames(cmd *completionCommand) []string {
+	names := []string{}
+	for _, sub := range cmd.Subcommands {
+		names = append(names, sub.Name())
+	}
+	return names
+}
+
+func flagNames(cmd *completionCommand) []string {
+	names := []string{}
+	for _, flag := range cmd.Flags {
+		names = append(names, flag.Names()...)
+	}
+	return names
+}
+
+// shellValueCompletion returns the bash or zsh statements completing the
+// given values, using the provided helpers.
+func shellValueCompletion(values completionValues, words, files, dirs string) string {
+	switch values.Kind {
+	case completionWords:
+		return fmt.Sprintf(words, strings.Join(values.Words, " "))
+	case completionFiles:
+		return files
+	case completionDirs:
+		return dirs
+	case completionNone:
+		return ""
+	default:
+		return fmt.Sprintf(`_zk_candidates %s "$cur"`, values.candidatesKind())
+	}
+}
+
+func bashCompletion(commands []*completionCommand) string {
+	return shellCompletion(commands, shellCompletionOpts{
+		header: `# bash completion for zk, generated by ` + "`zk completion bash`" + `.
+
+_zk_candidates() {
+    local IFS=$'\n' candidate
+    for candidate in $(zk --no-index complete "$1" "$2" 2>/dev/null); do
+        COMPREPLY+=("$candidate")
+        [[ "$candidate" == */ ]] && compopt -o nospace 2>/dev/null
+    done
+}
+
+_zk() {
+    local cur="${COMP_WORDS[COMP_CWORD]}"
+    local prev="${COMP_WORDS[COMP_CWORD-1]}"
+    local cmd="" word i
+    for ((i = 1; i < COMP_CWORD; i++)); do
+        word="${COMP_WORDS[i]}"
+`,
+		footer: `}
+
+complete -F _zk zk
+`,
+		words: `COMPREPLY=($(compgen -W "%s" -- "$cur"))`,
+		files: `COMPREPLY=($(compgen -f -- "$cur"))`,
+		dirs:  `COMPREPLY=($(compgen -d -- "$cur"))`,
+	})
+}
+
+func zshCompletion(commands []*completionCommand) string {
+	return shellCompletion(commands, shellCompletionOpts{
+		header: `#compdef zk
+# zsh completion for zk, generated by ` + "`zk completion zsh`" + `.
+
+_zk_candidates() {
+    local output candidate
+    output="$(zk --no-index complete "$1" "$2" 2>/dev/null)"
+    [[ -z "$output" ]] && return
+    for candidate in "${(@f)output}"; do
+        if [[ "$candidate" == */ ]]; then
+            compadd -S '' -- "$candidate"
+        else
+            compadd -- "$candidate"
+        fi
+    done
+}
+
+_zk() {
+    local cur="${words[CURRENT]}"
+    local prev="${words[CURRENT-1]}"
+    local cmd="" word i
+    for ((i = 2; i < CURRENT; i++)); do
+        word="${words[i]}"
+`,
+		footer: `}
+
+if [[ "${funcstack[1]}" == "_zk" ]]; then
+    _zk "$@"
+else
+    compdef _zk zk
+fi
+`,
+		words: `compadd -- %s`,
+		files: `_files`,
+		dirs:  `_files -/`,
+	})
+}
+
+type shellCompletionOpts struct {
+	header string
+	footer string
+	// Statements completing a list of words, given as a format.
+	words string
+	// Statements completing files and directories.
+	files string
+	dirs  string
+}
+
+// shellCompletion generates the bash or zsh completion script, which share the
+// same syntax apart from the shell-specific helpers.
+func shellCompletion(commands []*completionCommand, opts shellCompletionOpts) string {
+	var b strings.Builder
+	b.WriteString(opts.header)
+	fmt.Fprintf(&b, "        case \"$cmd/$word\" in\n")
+	fmt.Fprintf(&b, "            %s) cmd=\"${cmd:+$cmd }$word\" ;;\n", completionCase(commands))
+	b.WriteString("        esac\n    done\n\n    case \"$cmd\" in\n")
+
+	for _, cmd := range commands {
+		fmt.Fprintf(&b, "        %q)\n", cmd.Path)
+
+		b.WriteString("            case \"$prev\" in\n")
+		for _, flag := range cmd.Flags {
+			if !flag.HasValue {
+				continue
+			}
+			fmt.Fprintf(&b, "                %s) ", strings.Join(flag.Names(), "|"))
+			if stmt := shellValueCompletion(flag.Values, opts.words, opts.files, opts.dirs); stmt != "" {
+				b.WriteString(stmt + "; ")
+			}
+			b.WriteString("return ;;\n")
+		}
+		b.WriteString("            esac\n")
+
+		b.WriteString("            if [[ \"$cur\" == -* ]]; then\n")
+		fmt.Fprintf(&b, "                %s\n", fmt.Sprintf(opts.words, strings.Join(flagNames(cmd), " ")))
+		b.WriteString("            else\n")
+		if len(cmd.Subcommands) > 0 {
+			fmt.Fprintf(&b, "                %s\n", fmt.Sprintf(opts.words, strings.Join(subcommandNames(cmd), " ")))
+		}
+		if cmd.Path == "" {
+			b.WriteString("                _zk_candidates aliases \"$cur\"\n")
+		}
+		if stmt := shellValueCompletion(cmd.Args, opts.words, opts.files, opts.dirs); stmt != "" {
+			fmt.Fprintf(&b, "                %s\n", stmt)
+		}
+		b.WriteString("                :\n            fi\n            ;;\n")
+	}
+
+	b.WriteString("    esac\n")
+	b.WriteString(opts.footer)
+	return b.String()
+}
+
+func fishCompletion(commands []*completionCommand) string {
+	var b strings.Builder
+	b.WriteString(`# fish completion for zk, generated by ` + "`zk completion fish`" + `.
+
+function __zk_command
+    set -l cmd ""
+    for word in (commandline -opc)[2..-1]
+        switch "$cmd/$word"
+            case ` + strings.ReplaceAll(completionCase(commands), "|", " ") + `
+                set cmd (string trim -- "$cmd $word")
+        end
+    end
+    echo $cmd
+end
+
+function __zk_command_is
+    set -l cmd (__zk_command)
+    test "$cmd" = "$argv[1]"
+end
+
+complete -c zk -f
+`)
+
+	for _, cmd := range commands {
+		cond := fmt.Sprintf(`-n '__zk_command_is "%s"'`, cmd.Path)
+
+		for _, sub := range cmd.Subcommands {
+			fmt.Fprintf(&b, "complete -c zk %s -a %s -d %s\n", cond, sub.Name(), fishQuote(sub.Help))
+		}
+		if cmd.Path == "" {
+			fmt.Fprintf(&b, "complete -c zk %s -a '(zk --no-index complete aliases)' -d 'Alias'\n", cond)
+		}
+		if args := fishValueCompletion(cmd.Args); args != "" {
+			fmt.Fprintf(&b, "complete -c zk %s %s\n", cond, args)
+		}
+
+		for _, flag := range cmd.Flags {
+			fmt.Fprintf(&b, "complete -c zk %s -l %s", cond, flag.Long)
+			if flag.Short != "" {
+				fmt.Fprintf(&b, " -s %s", flag.Short)
+			}
+			if flag.HasValue {
+				b.WriteString(" -r")
+				if values := fishValueCompletion(flag.Values); values != "" {
+					b.WriteString(" " + values)
+				}
+			}
+			if flag.Help != "" {
+				fmt.Fprintf(&b, " -d %s", fishQuote(flag.Help))
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	return b.String()
+}
+
+func fishValueCompletion(values completionValues) string {
+	switch values.Kind {
+	case completionWords:
+		return fmt.Sprintf("-a %s", fishQuote(strings.Join(values.Words, " ")))
+	case completionFiles:
+		return "-F"
+	case completionDirs:
+		return "-a '(__fish_complete_directories)'"
+	case completionNone:
+		return ""
+	default:
+		return fmt.Sprintf("-a '(zk --no-index complete %s (commandline -ct))'", values.candidatesKind())
+	}
+}
+
+// fishQuote quotes the given string for the fish shell.
+func fishQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `'`, `\'`)
+	return "'" + s + "'"
+}
@@ -0,0 +1,27 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/zk-org/zk/internal/util/test/assert"
+)
+
+func TestPathCandidates(t *testing.T) {
+	paths := []string{"a.md", "ab.md", "dir/b.md", "dir/sub/c.md", "dir/sub/d.md", "other/e.md"}
+
+	test := func(prefix string, expected []string) {
+		assert.Equal(t, pathCandidates(paths, prefix), expected)
+	}
+
+	test("", []string{"a.md", "ab.md", "dir/", "other/"})
+	test("a", []string{"a.md", "ab.md"})
+	test("d", []string{"dir/"})
+	test("dir/", []string{"dir/b.md", "dir/sub/"})
+	test("dir/sub/", []string{"dir/sub/c.md", "dir/sub/d.md"})
+	test("unknown", []string{})
+}
+
+func TestFishQuote(t *testing.T) {
+	assert.Equal(t, fishQuote(`Print the note's path`), `'Print the note\'s path'`)
+	assert.Equal(t, fishQuote(`a\b`), `'a\\b'`)
+}
@@ -49,9 +49,25 @@ func (cmd *Edit) Run(container *cli.Container) error {
 	}
 
 	count := len(notes)
+	maxNotes := notebook.Config.Tool.EditorMaxNotes
 
-	if count > 0 {
-		if !cmd.Force && count > 5 {
+	if !cmd.Force && maxNotes > 0 && count > maxNotes {
+		if !cmd.Interactive && container.Terminal.IsInteractive() {
+			// Let the user pick the notes to open, rather than opening
+			// them all at once.
+			notes, err = container.NewNoteFilter(fzf.NoteFilterOpts{
+				Interactive:  true,
+				AlwaysFilter: true,
+				NotebookDir:  notebook.Path,
+			}).Apply(notes)
+			if err != nil {
+				if err == fzf.ErrCancelled {
+					return nil
+				}
+				return err
+			}
+			count = len(notes)
+		} else {
 			confirmed, skipped := container.Terminal.Confirm(fmt.Sprintf("Are you sure you want to open %v notes in the editor?", count), false)
 			if skipped {
 				return fmt.Errorf("too many notes to be opened in the editor, aborting…")
@@ -59,6 +75,9 @@ func (cmd *Edit) Run(container *cli.Container) error {
 				return nil
 			}
 		}
+	}
+
+	if count > 0 {
 		paths := make([]string, 0)
 		for _, note := range notes {
 			absPath := filepath.Join(notebook.Path, note.Path)
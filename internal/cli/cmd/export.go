@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/zk-org/zk/internal/adapter/fzf"
+	"github.com/zk-org/zk/internal/cli"
+	"github.com/zk-org/zk/internal/util/errors"
+	strutil "github.com/zk-org/zk/internal/util/strings"
+)
+
+// Export renders the notes matching a set of criteria to HTML files.
+type Export struct {
+	Output string `short:o placeholder:DIR required help:"Directory where the HTML files are written."`
+	Quiet  bool   `short:q help:"Do not print the number of exported notes."`
+	cli.Filtering
+}
+
+func (cmd *Export) Run(container *cli.Container) error {
+	notebook, err := container.CurrentNotebook()
+	if err != nil {
+		return err
+	}
+
+	findOpts, err := cmd.Filtering.NewNoteFindOpts(notebook)
+	if err != nil {
+		return errors.Wrapf(err, "incorrect criteria")
+	}
+
+	notes, err := notebook.FindNotes(findOpts)
+	if err != nil {
+		return err
+	}
+
+	filter := container.NewNoteFilter(fzf.NoteFilterOpts{
+		Interactive:  cmd.Interactive,
+		AlwaysFilter: false,
+		NotebookDir:  notebook.Path,
+	})
+
+	notes, err = filter.Apply(notes)
+	if err != nil {
+		if err == fzf.ErrCancelled {
+			return nil
+		}
+		return err
+	}
+
+	outputDir, err := container.FS.Abs(cmd.Output)
+	if err != nil {
+		return err
+	}
+
+	stats, err := notebook.ExportHTML(notes, outputDir)
+	if err != nil {
+		return err
+	}
+
+	if !cmd.Quiet {
+		fmt.Fprintf(os.Stderr, "Exported %d %s to %s\n",
+			stats.NoteCount, strutil.Pluralize("note", stats.NoteCount), cmd.Output,
+		)
+	}
+	return nil
+}
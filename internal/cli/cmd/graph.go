@@ -4,18 +4,21 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"sort"
+	"strings"
 
 	"github.com/zk-org/zk/internal/adapter/fzf"
 	"github.com/zk-org/zk/internal/cli"
 	"github.com/zk-org/zk/internal/core"
 	"github.com/zk-org/zk/internal/util/errors"
-	"github.com/zk-org/zk/internal/util/strings"
+	strutil "github.com/zk-org/zk/internal/util/strings"
 )
 
 // Graph produces a directed graph of the notes matching a set of criteria.
 type Graph struct {
-	Format string `group:format short:f                        help:"Format of the graph among: json." enum:"json" required`
-	Quiet  bool   `group:format short:q help:"Do not print the total number of notes found."`
+	Format   string `group:format short:f                        help:"Format of the graph among: json, dot." enum:"json,dot" required`
+	Dangling bool   `group:format help:"Include the links which don't match any note."`
+	Quiet    bool   `group:format short:q help:"Do not print the total number of notes found."`
 	cli.Filtering
 }
 
@@ -25,11 +28,6 @@ func (cmd *Graph) Run(container *cli.Container) error {
 		return err
 	}
 
-	format, err := notebook.NewNoteFormatter("{{json .}}")
-	if err != nil {
-		return err
-	}
-
 	findOpts, err := cmd.Filtering.NewNoteFindOpts(notebook)
 	if err != nil {
 		return errors.Wrapf(err, "incorrect criteria")
@@ -39,14 +37,6 @@ func (cmd *Graph) Run(container *cli.Container) error {
 	if err != nil {
 		return err
 	}
-	noteIDs := []core.NoteID{}
-	for _, note := range notes {
-		noteIDs = append(noteIDs, note.ID)
-	}
-	links, err := notebook.FindLinksBetweenNotes(noteIDs)
-	if err != nil {
-		return err
-	}
 
 	filter := container.NewNoteFilter(fzf.NoteFilterOpts{
 		Interactive:  cmd.Interactive,
@@ -62,6 +52,86 @@ func (cmd *Graph) Run(container *cli.Container) error {
 		return err
 	}
 
+	noteIDs := []core.NoteID{}
+	for _, note := range notes {
+		noteIDs = append(noteIDs, note.ID)
+	}
+	links, err := notebook.FindLinksBetweenNotes(noteIDs)
+	if err != nil {
+		return err
+	}
+	if cmd.Dangling {
+		danglingLinks, err := notebook.FindDanglingLinks(noteIDs)
+		if err != nil {
+			return err
+		}
+		links = append(links, danglingLinks...)
+	}
+	sortLinks(links)
+
+	switch cmd.Format {
+	case "dot":
+		err = printDotGraph(notes, links)
+	default:
+		err = printJSONGraph(notebook, notes, links, cmd.Dangling)
+	}
+
+	if err == nil && !cmd.Quiet {
+		count := len(notes)
+		fmt.Fprintf(os.Stderr, "\n\nFound %d %s\n", count, strutil.Pluralize("note", count))
+	}
+
+	return err
+}
+
+// sortLinks orders the links by source and target, to produce a stable graph.
+func sortLinks(links []core.ResolvedLink) {
+	sort.SliceStable(links, func(i, j int) bool {
+		a, b := links[i], links[j]
+		if a.SourcePath != b.SourcePath {
+			return a.SourcePath < b.SourcePath
+		}
+		if linkTarget(a) != linkTarget(b) {
+			return linkTarget(a) < linkTarget(b)
+		}
+		return a.SnippetStart < b.SnippetStart
+	})
+}
+
+// linkTarget returns the ID of the node targeted by a link, which is the
+// target note path or a dangling node ID.
+func linkTarget(link core.ResolvedLink) string {
+	if link.TargetPath == "" {
+		return danglingNodeID(link.Href)
+	}
+	return link.TargetPath
+}
+
+// danglingNodeID returns the ID of the node standing for the target of a
+// dangling link. It is namespaced to never collide with a note path.
+func danglingNodeID(href string) string {
+	return "dangling:" + href
+}
+
+// danglingNode is the JSON representation of the target of a dangling link.
+type danglingNode struct {
+	ID   string `json:"id"`
+	Href string `json:"href"`
+}
+
+// jsonLink is a link in the JSON graph, which points to its dangling node
+// when it doesn't match any note.
+type jsonLink struct {
+	core.ResolvedLink
+	DanglingID string `json:"danglingId,omitempty"`
+}
+
+func printJSONGraph(notebook *core.Notebook, notes []core.ContextualNote, links []core.ResolvedLink, dangling bool) error {
+	format, err := notebook.NewNoteFormatter("{{json .}}")
+	if err != nil {
+		return err
+	}
+
 	fmt.Print("{\n  \"notes\": [\n")
 	for i, note := range notes {
 		if i > 0 {
@@ -79,19 +149,83 @@ func (cmd *Graph) Run(container *cli.Container) error {
 		if i > 0 {
 			fmt.Print(",\n")
 		}
-		ft, err := json.Marshal(link)
+		jl := jsonLink{ResolvedLink: link}
+		if link.TargetPath == "" {
+			jl.DanglingID = danglingNodeID(link.Href)
+		}
+		ft, err := json.Marshal(jl)
 		if err != nil {
 			return err
 		}
 		fmt.Printf("    %s", string(ft))
 	}
+	fmt.Print("\n  ]")
 
-	fmt.Print("\n  ]\n}\n")
+	if dangling {
+		fmt.Print(",\n  \"dangling\": [\n")
+		seen := map[string]bool{}
+		for _, link := range links {
+			if link.TargetPath != "" || seen[link.Href] {
+				continue
+			}
+			if len(seen) > 0 {
+				fmt.Print(",\n")
+			}
+			seen[link.Href] = true
+			ft, err := json.Marshal(danglingNode{ID: danglingNodeID(link.Href), Href: link.Href})
+			if err != nil {
+				return err
+			}
+			fmt.Printf("    %s", string(ft))
+		}
+		fmt.Print("\n  ]")
+	}
 
-	if err == nil && !cmd.Quiet {
-		count := len(notes)
-		fmt.Fprintf(os.Stderr, "\n\nFound %d %s\n", count, strings.Pluralize("note", count))
+	fmt.Print("\n}\n")
+	return nil
+}
+
+// printDotGraph prints the graph in the Graphviz DOT language. Notes are
+// identified by their paths and labelled with their titles, while the targets
+// of dangling links are drawn with dashed nodes labelled with their hrefs.
+func printDotGraph(notes []core.ContextualNote, links []core.ResolvedLink) error {
+	fmt.Println("digraph {")
+
+	for _, note := range notes {
+		label := note.Title
+		if label == "" {
+			label = note.Path
+		}
+		fmt.Printf("  %s [label=%s];\n", dotQuote(note.Path), dotQuote(label))
 	}
 
-	return err
+	danglingNodes := map[string]bool{}
+	for _, link := range links {
+		target := linkTarget(link)
+		if link.TargetPath == "" && !danglingNodes[target] {
+			danglingNodes[target] = true
+			fmt.Printf("  %s [label=%s, style=dashed];\n", dotQuote(target), dotQuote(link.Href))
+		}
+	}
+
+	edges := map[[2]string]bool{}
+	for _, link := range links {
+		edge := [2]string{link.SourcePath, linkTarget(link)}
+		if edges[edge] {
+			continue
+		}
+		edges[edge] = true
+		fmt.Printf("  %s -> %s;\n", dotQuote(edge[0]), dotQuote(edge[1]))
+	}
+
+	fmt.Println("}")
+	return nil
+}
+
+// dotQuote returns the given string as a quoted DOT identifier.
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return `"` + s + `"`
 }
@@ -7,15 +7,20 @@ import (
 
 	"github.com/zk-org/zk/internal/cli"
 	"github.com/zk-org/zk/internal/core"
+	"github.com/zk-org/zk/internal/util/errors"
 	"github.com/zk-org/zk/internal/util/paths"
 	"github.com/schollz/progressbar/v3"
 )
 
 // Index indexes the content of all the notes in the notebook.
 type Index struct {
-	Force   bool `short:"f" help:"Force indexing all the notes."`
-	Verbose bool `short:"v" xor:"print" help:"Print detailed information about the indexing process."`
-	Quiet   bool `short:"q" xor:"print" help:"Do not print statistics nor progress."`
+	Force   bool          `short:"f" help:"Force indexing all the notes."`
+	Rebuild bool          `help:"Rebuild the full-text search index."`
+	Check   bool          `help:"Check the integrity of the index instead of indexing, and repair the full-text search index."`
+	Verbose bool          `short:"v" xor:"print" help:"Print detailed information about the indexing process."`
+	Quiet   bool          `short:"q" xor:"print" help:"Do not print statistics nor progress."`
+	Jobs    int           `short:"j" placeholder:"COUNT" help:"Number of notes parsed in parallel, defaults to the number of CPUs."`
+	Wait    time.Duration `placeholder:"DURATION" help:"Wait up to the given duration when the notebook is already being indexed, e.g. 10s."`
 }
 
 func (cmd *Index) Help() string {
@@ -32,11 +37,13 @@ func (cmd *Index) Run(container *cli.Container) error {
 }
 
 func (cmd *Index) RunWithNotebook(container *cli.Container, notebook *core.Notebook) error {
-	showProgress := container.Terminal.IsInteractive()
+	if cmd.Check {
+		return cmd.check(notebook)
+	}
 
-	var bar *progressbar.ProgressBar
-	if showProgress {
-		bar = progressbar.NewOptions(-1,
+	progress := &indexProgress{}
+	if container.Terminal.IsInteractive() {
+		progress.bar = progressbar.NewOptions(-1,
 			progressbar.OptionSetWriter(os.Stderr),
 			progressbar.OptionThrottle(100*time.Millisecond),
 			progressbar.OptionSpinnerType(14),
@@ -44,28 +51,63 @@ func (cmd *Index) RunWithNotebook(container *cli.Container, notebook *core.Noteb
 	}
 
 	opts := core.NoteIndexOpts{
-		Force:   cmd.Force,
-		Verbose: cmd.Verbose,
+		Force:       cmd.Force,
+		Rebuild:     cmd.Rebuild,
+		Verbose:     cmd.Verbose,
+		Workers:     cmd.Jobs,
+		LockTimeout: cmd.Wait,
 	}
 
-	stats, err := notebook.IndexWithCallback(opts, func(change paths.DiffChange) {
-		if showProgress {
-			bar.Add(1)
-			bar.Describe(change.String())
-		}
-	})
+	stats, err := notebook.IndexWithProgress(opts, progress)
+	// The stats are printed even when some notes could not be indexed.
+	var indexingErr core.NoteIndexingError
+	if err != nil && !errors.As(err, &indexingErr) {
+		progress.clear()
+		return err
+	}
 
-	if showProgress {
-		bar.Clear()
+	if !cmd.Quiet {
+		fmt.Println(stats)
 	}
 
+	return err
+}
+
+// check prints the inconsistencies of the notebook index, after repairing the
+// full-text search index.
+func (cmd *Index) check(notebook *core.Notebook) error {
+	report, err := notebook.CheckIndex(true)
 	if err != nil {
 		return err
 	}
-
 	if !cmd.Quiet {
-		fmt.Println(stats)
+		fmt.Println(report)
+	}
+	if !report.IsHealthy() {
+		return errors.New("the notebook index is inconsistent, run `zk index --force` to reindex the notes")
 	}
-
 	return nil
 }
+
+// indexProgress renders the progress of the indexing with a spinner, when
+// the terminal is interactive.
+type indexProgress struct {
+	bar *progressbar.ProgressBar
+}
+
+func (p *indexProgress) FileIndexed(path string, action paths.DiffKind) {
+	if p.bar != nil {
+		p.bar.Add(1)
+		p.bar.Describe(action.String() + " " + path)
+	}
+}
+
+func (p *indexProgress) Done(stats core.NoteIndexingStats) {
+	p.clear()
+}
+
+func (p *indexProgress) clear() {
+	if p.bar != nil {
+		p.bar.Clear()
+	}
+}
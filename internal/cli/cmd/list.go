@@ -1,39 +1,156 @@
 package cmd
 
 import (
+	"encoding/csv"
 	"fmt"
 	"io"
 	"os"
+	"strings"
 
+	"github.com/alecthomas/kong"
 	"github.com/zk-org/zk/internal/adapter/fzf"
 	"github.com/zk-org/zk/internal/cli"
+	"github.com/zk-org/zk/internal/core"
 	"github.com/zk-org/zk/internal/util/errors"
-	"github.com/zk-org/zk/internal/util/strings"
+	strutil "github.com/zk-org/zk/internal/util/strings"
 )
 
 // List displays notes matching a set of criteria.
 type List struct {
-	Format     string `group:format short:f placeholder:TEMPLATE   help:"Pretty print the list using a custom template or one of the predefined formats: oneline, short, medium, long, full, json, jsonl."`
-	Header     string `group:format                                help:"Arbitrary text printed at the start of the list."`
-	Footer     string `group:format default:\n                     help:"Arbitrary text printed at the end of the list."`
-	Delimiter  string "group:format short:d default:\n             help:\"Print notes delimited by the given separator.\""
-	Delimiter0 bool   "group:format short:0 name:delimiter0        help:\"Print notes delimited by ASCII NUL characters. This is useful when used in conjunction with `xargs -0`.\""
-	NoPager    bool   `group:format short:P help:"Do not pipe output into a pager."`
-	Quiet      bool   `group:format short:q help:"Do not print the total number of notes found."`
+	Format     string     `group:format short:f placeholder:TEMPLATE   help:"Pretty print the list using a custom template or one of the predefined formats: oneline, short, medium, long, full, json, jsonl, csv, tsv, vimgrep."`
+	Columns    []string   `group:format placeholder:FIELD              help:"Note fields printed with the csv and tsv formats, defaults to path,title,created,modified,word-count."`
+	Header     string     `group:format                                help:"Arbitrary text printed at the start of the list."`
+	Footer     string     `group:format default:\n                     help:"Arbitrary text printed at the end of the list."`
+	Delimiter  string     "group:format short:d default:\n             help:\"Print notes delimited by the given separator.\""
+	Delimiter0 bool       "group:format short:0 name:delimiter0        help:\"Print notes delimited by ASCII NUL characters. This is useful when used in conjunction with `xargs -0`.\""
+	NoPager    bool       `group:format short:P help:"Do not pipe output into a pager."`
+	Quiet      quietLevel `group:format short:q help:"Do not print the total number of notes found. Repeat it, e.g. -qq, to print nothing at all."`
+	Count      bool       `group:format help:"Print only the number of notes found."`
+	ExitCode   bool       `help:"Exit with the status 2 when no notes are found."`
 	cli.Filtering
 }
 
+// quietLevel is a boolean flag which can be repeated, e.g. -qq, to silence
+// more of the output.
+type quietLevel int
+
+func (q *quietLevel) Decode(ctx *kong.DecodeContext) error {
+	*q++
+	return nil
+}
+
+func (q quietLevel) IsBool() bool {
+	return true
+}
+
+// listNoMatchExitCode is the exit status of `zk list --exit-code` when no
+// notes are found.
+const listNoMatchExitCode = cli.ExitCodeError(2)
+
 func (cmd *List) Run(container *cli.Container) error {
-	cmd.Header = strings.ExpandWhitespaceLiterals(cmd.Header)
-	cmd.Footer = strings.ExpandWhitespaceLiterals(cmd.Footer)
-	cmd.Delimiter = strings.ExpandWhitespaceLiterals(cmd.Delimiter)
+	err := cmd.parseFormatOptions()
+	if err != nil {
+		return err
+	}
+	isCSV := cmd.isCSV()
+
+	notebook, err := container.CurrentNotebook()
+	if err != nil {
+		return err
+	}
+
+	var format core.NoteFormatter
+	var formatFields core.NoteFieldsFormatter
+	if isCSV {
+		formatFields, err = notebook.NewNoteFieldsFormatter(cmd.Columns)
+	} else {
+		format, err = cmd.newNoteFormatter(notebook)
+	}
+	if err != nil {
+		return err
+	}
+
+	findOpts, err := cmd.Filtering.NewNoteFindOpts(notebook)
+	if err != nil {
+		return errors.Wrapf(err, "incorrect criteria")
+	}
+
+	// The notes don't need to be fetched when only their number is needed.
+	if cmd.Count || cmd.Quiet > 1 {
+		if cmd.Interactive {
+			return errors.New("--interactive can't be used with --count or -qq")
+		}
+		count, err := notebook.CountNotes(findOpts)
+		if err != nil {
+			return err
+		}
+		if cmd.Quiet < 2 {
+			fmt.Println(count)
+		}
+		return cmd.exitStatus(count)
+	}
+
+	notes, err := notebook.FindNotes(findOpts)
+	if err != nil {
+		return err
+	}
+
+	filter := container.NewNoteFilter(fzf.NoteFilterOpts{
+		Interactive:  cmd.Interactive,
+		AlwaysFilter: false,
+		NotebookDir:  notebook.Path,
+	})
+
+	notes, err = filter.Apply(notes)
+	if err != nil {
+		if err == fzf.ErrCancelled {
+			return nil
+		}
+		return err
+	}
+
+	count := len(notes)
+	if count > 0 {
+		err = container.Paginate(cmd.NoPager, func(out io.Writer) error {
+			if isCSV {
+				return writeNotesCSV(out, cmd.Format, cmd.Columns, notes, formatFields)
+			}
+			return cmd.writeNotes(out, notes, format)
+		})
+	}
+
+	if err == nil && cmd.Quiet == 0 {
+		fmt.Fprintf(os.Stderr, "\nFound %d %s\n", count, strutil.Pluralize("note", count))
+	}
+
+	if err != nil {
+		return err
+	}
+	return cmd.exitStatus(count)
+}
+
+// exitStatus returns the error exiting with listNoMatchExitCode when no
+// notes are found and --exit-code is enabled.
+func (cmd *List) exitStatus(count int) error {
+	if cmd.ExitCode && count == 0 {
+		return listNoMatchExitCode
+	}
+	return nil
+}
+
+// parseFormatOptions validates the formatting options, and sets the
+// delimiters required by the predefined formats.
+func (cmd *List) parseFormatOptions() error {
+	cmd.Header = strutil.ExpandWhitespaceLiterals(cmd.Header)
+	cmd.Footer = strutil.ExpandWhitespaceLiterals(cmd.Footer)
+	cmd.Delimiter = strutil.ExpandWhitespaceLiterals(cmd.Delimiter)
 
 	if cmd.Delimiter0 {
 		if cmd.Delimiter != "\n" {
 			return errors.New("--delimiter and --delimiter0 can't be used together")
 		}
 		if cmd.Header != "" {
-			return errors.New("--footer and --delimiter0 can't be used together")
+			return errors.New("--header and --delimiter0 can't be used together")
 		}
 		if cmd.Footer != "\n" {
 			return errors.New("--footer and --delimiter0 can't be used together")
@@ -43,6 +160,26 @@ func (cmd *List) Run(container *cli.Container) error {
 		cmd.Footer = "\x00"
 	}
 
+	isCSV := cmd.isCSV()
+	if len(cmd.Columns) > 0 && !isCSV {
+		return errors.New("--columns can only be used with the csv and tsv formats")
+	}
+	if isCSV {
+		if cmd.Header != "" {
+			return errors.New("--header can't be used with CSV format")
+		}
+		if cmd.Footer != "\n" {
+			return errors.New("--footer can't be used with CSV format")
+		}
+		if cmd.Delimiter != "\n" {
+			return errors.New("--delimiter can't be used with CSV format")
+		}
+		cmd.Footer = ""
+		if len(cmd.Columns) == 0 {
+			cmd.Columns = defaultCSVColumns
+		}
+	}
+
 	if cmd.Format == "json" || cmd.Format == "jsonl" {
 		if cmd.Header != "" {
 			return errors.New("--header can't be used with JSON format")
@@ -69,70 +206,82 @@ func (cmd *List) Run(container *cli.Container) error {
 		}
 	}
 
-	notebook, err := container.CurrentNotebook()
-	if err != nil {
-		return err
+	return nil
+}
+
+// isCSV returns whether the notes are printed as CSV rows.
+func (cmd *List) isCSV() bool {
+	return cmd.Format == "csv" || cmd.Format == "tsv"
+}
+
+// writeNotes writes the formatted notes between the header and footer. The
+// delimiter separates the notes, even when they span several lines.
+func (cmd *List) writeNotes(out io.Writer, notes []core.ContextualNote, format core.NoteFormatter) error {
+	if cmd.Header != "" {
+		fmt.Fprint(out, cmd.Header)
 	}
+	for i, note := range notes {
+		if i > 0 {
+			fmt.Fprint(out, cmd.Delimiter)
+		}
 
-	format, err := notebook.NewNoteFormatter(cmd.noteTemplate())
-	if err != nil {
-		return err
+		ft, err := format(note)
+		if err != nil {
+			return err
+		}
+		fmt.Fprint(out, ft)
+	}
+	if cmd.Footer != "" {
+		fmt.Fprint(out, cmd.Footer)
 	}
 
-	findOpts, err := cmd.Filtering.NewNoteFindOpts(notebook)
-	if err != nil {
-		return errors.Wrapf(err, "incorrect criteria")
+	return nil
+}
+
+// defaultCSVColumns are the note fields printed by default with the csv and
+// tsv formats.
+var defaultCSVColumns = []string{"path", "title", "created", "modified", "word-count"}
+
+// writeNotesCSV writes the notes as CSV rows following RFC 4180, after a
+// header row with the names of the columns. The tsv format uses tabs as
+// separators.
+func writeNotesCSV(out io.Writer, format string, columns []string, notes []core.ContextualNote, formatFields core.NoteFieldsFormatter) error {
+	writer := csv.NewWriter(out)
+	if format == "tsv" {
+		writer.Comma = '\t'
 	}
 
-	notes, err := notebook.FindNotes(findOpts)
+	err := writer.Write(columns)
 	if err != nil {
 		return err
 	}
-
-	filter := container.NewNoteFilter(fzf.NoteFilterOpts{
-		Interactive:  cmd.Interactive,
-		AlwaysFilter: false,
-		NotebookDir:  notebook.Path,
-	})
-
-	notes, err = filter.Apply(notes)
-	if err != nil {
-		if err == fzf.ErrCancelled {
-			return nil
+	for _, note := range notes {
+		row, err := formatFields(note)
+		if err != nil {
+			return err
+		}
+		err = writer.Write(row)
+		if err != nil {
+			return err
 		}
-		return err
 	}
 
-	count := len(notes)
-	if count > 0 {
-		err = container.Paginate(cmd.NoPager, func(out io.Writer) error {
-			if cmd.Header != "" {
-				fmt.Fprint(out, cmd.Header)
-			}
-			for i, note := range notes {
-				if i > 0 {
-					fmt.Fprint(out, cmd.Delimiter)
-				}
-
-				ft, err := format(note)
-				if err != nil {
-					return err
-				}
-				fmt.Fprint(out, ft)
-			}
-			if cmd.Footer != "" {
-				fmt.Fprint(out, cmd.Footer)
-			}
-
-			return nil
-		})
-	}
+	writer.Flush()
+	return writer.Error()
+}
 
-	if err == nil && !cmd.Quiet {
-		fmt.Fprintf(os.Stderr, "\nFound %d %s\n", count, strings.Pluralize("note", count))
+// newNoteFormatter creates the formatter for the --format option. A format
+// without any {{variable}} can also be the name of a template file, e.g.
+// `weekly.hbs` in the .zk/templates directory.
+func (cmd *List) newNoteFormatter(notebook *core.Notebook) (core.NoteFormatter, error) {
+	if _, ok := defaultNoteFormats[cmd.Format]; !ok && cmd.Format != "" && !strings.Contains(cmd.Format, "{{") {
+		format, err := notebook.NewNoteFormatterAt(cmd.Format)
+		var notFoundErr core.TemplateNotFoundError
+		if !errors.As(err, &notFoundErr) {
+			return format, err
+		}
 	}
-
-	return err
+	return notebook.NewNoteFormatter(cmd.noteTemplate())
 }
 
 func (cmd *List) noteTemplate() string {
@@ -143,7 +292,7 @@ func (cmd *List) noteTemplate() string {
 
 	templ, ok := defaultNoteFormats[format]
 	if !ok {
-		templ = strings.ExpandWhitespaceLiterals(format)
+		templ = strutil.ExpandWhitespaceLiterals(format)
 	}
 
 	return templ
@@ -155,6 +304,8 @@ var defaultNoteFormats = map[string]string{
 	"path":  `{{path}}`,
 	"link":  `{{link}}`,
 
+	"vimgrep": `{{path}}:{{match-line}}:{{match-column}}: {{match-text}}`,
+
 	"oneline": `{{style "title" title}} {{style "path" path}} ({{format-date created "elapsed"}})`,
 
 	"short": `{{style "title" title}} {{style "path" path}} ({{format-date created "elapsed"}})
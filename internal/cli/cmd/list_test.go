@@ -1,8 +1,11 @@
 package cmd
 
 import (
+	"bytes"
+	"encoding/csv"
 	"testing"
 
+	"github.com/zk-org/zk/internal/core"
 	"github.com/zk-org/zk/internal/util/test/assert"
 )
 
@@ -65,3 +68,82 @@ func TestListFormatCustom(t *testing.T) {
 	// \n and \t in custom formats are expanded.
 	test(`{{title}}\t{{path}}\n{{snippet}}`, "{{title}}\t{{path}}\n{{snippet}}")
 }
+
+func TestListWriteNotesCSV(t *testing.T) {
+	notes := []core.ContextualNote{
+		{Note: core.Note{Path: "a.md", Title: `Buy low, sell "high"`}},
+		{Note: core.Note{Path: "b.md", Title: "Multi\nline, title"}},
+	}
+	formatFields := func(note core.ContextualNote) ([]string, error) {
+		return []string{note.Path, note.Title}, nil
+	}
+
+	test := func(format string, comma rune, expected string) {
+		var out bytes.Buffer
+		err := writeNotesCSV(&out, format, []string{"path", "title"}, notes, formatFields)
+		assert.Nil(t, err)
+		assert.Equal(t, out.String(), expected)
+
+		reader := csv.NewReader(&out)
+		reader.Comma = comma
+		records, err := reader.ReadAll()
+		assert.Nil(t, err)
+		assert.Equal(t, records, [][]string{
+			{"path", "title"},
+			{"a.md", `Buy low, sell "high"`},
+			{"b.md", "Multi\nline, title"},
+		})
+	}
+
+	test("csv", ',', "path,title\na.md,\"Buy low, sell \"\"high\"\"\"\nb.md,\"Multi\nline, title\"\n")
+	test("tsv", '\t', "path\ttitle\na.md\t\"Buy low, sell \"\"high\"\"\"\nb.md\t\"Multi\nline, title\"\n")
+}
+
+func TestListWriteNotesDelimiters(t *testing.T) {
+	notes := []core.ContextualNote{
+		{Note: core.Note{Path: "a.md", Title: "Note A"}},
+		{Note: core.Note{Path: "b.md", Title: "Note B"}},
+	}
+	// The formatted notes span several lines.
+	format := func(note core.ContextualNote) (string, error) {
+		return note.Title + "\n" + note.Path, nil
+	}
+
+	test := func(cmd List, expected string) {
+		// Default values set by kong.
+		if cmd.Footer == "" {
+			cmd.Footer = "\n"
+		}
+		if cmd.Delimiter == "" {
+			cmd.Delimiter = "\n"
+		}
+		assert.Nil(t, cmd.parseFormatOptions())
+
+		var out bytes.Buffer
+		assert.Nil(t, cmd.writeNotes(&out, notes, format))
+		assert.Equal(t, out.Bytes(), []byte(expected))
+	}
+
+	test(List{}, "Note A\na.md\nNote B\nb.md\n")
+	test(List{Delimiter0: true}, "Note A\na.md\x00Note B\nb.md\x00")
+	test(List{Delimiter: ";"}, "Note A\na.md;Note B\nb.md\n")
+	test(List{Delimiter: `\t`, Header: "<", Footer: `>\n`}, "<Note A\na.md\tNote B\nb.md>\n")
+	test(List{Format: "jsonl"}, "Note A\na.md\nNote B\nb.md\n")
+}
+
+func TestListDelimiter0Conflicts(t *testing.T) {
+	test := func(cmd List, expected string) {
+		if cmd.Footer == "" {
+			cmd.Footer = "\n"
+		}
+		if cmd.Delimiter == "" {
+			cmd.Delimiter = "\n"
+		}
+		cmd.Delimiter0 = true
+		assert.Err(t, cmd.parseFormatOptions(), expected)
+	}
+
+	test(List{Delimiter: ","}, "--delimiter and --delimiter0 can't be used together")
+	test(List{Header: "-"}, "--header and --delimiter0 can't be used together")
+	test(List{Footer: "-"}, "--footer and --delimiter0 can't be used together")
+}
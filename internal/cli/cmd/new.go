@@ -1,7 +1,6 @@
 package cmd
 
 import (
-	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -11,15 +10,17 @@ import (
 	"github.com/zk-org/zk/internal/cli"
 	"github.com/zk-org/zk/internal/core"
 	dateutil "github.com/zk-org/zk/internal/util/date"
+	"github.com/zk-org/zk/internal/util/errors"
 	"github.com/zk-org/zk/internal/util/opt"
 )
 
 // New adds a new note to the notebook.
 type New struct {
 	Directory   string            `arg optional default:"." help:"Directory in which to create the note."`
-	Interactive bool              `short:i                  help:"Read contents from standard input."`
+	Interactive bool              `short:i                     help:"Read contents from standard input." xor:"content"`
+	Content     string            `          placeholder:TEXT  help:"Content of the new note, instead of reading the standard input." xor:"content"`
 	Title       string            `short:t   placeholder:TITLE help:"Title of the new note."`
-	Date        string            `          placeholder:DATE  help:"Set the current date."`
+	Date        string            `          placeholder:DATE  help:"Date of the note, instead of the current date."`
 	Group       string            `short:g   placeholder:NAME  help:"Name of the config group this note belongs to. Takes precedence over the config of the directory."`
 	Extra       map[string]string `                            help:"Extra variables passed to the templates." mapsep:","`
 	Template    string            `          placeholder:PATH  help:"Custom template used to render the note."`
@@ -34,7 +35,7 @@ func (cmd *New) Run(container *cli.Container) error {
 		return err
 	}
 
-	var content []byte
+	content := []byte(cmd.Content)
 	if cmd.Interactive {
 		content, err = io.ReadAll(os.Stdin)
 		if err != nil {
@@ -81,12 +82,16 @@ func (cmd *New) Run(container *cli.Container) error {
 			return err
 		}
 
-		if confirmed, _ := container.Terminal.Confirm(
-			fmt.Sprintf("%s already exists, do you want to edit this note instead?", noteExists.Name),
-			true,
-		); !confirmed {
-			// abort...
-			return nil
+		// A note dated with --date, e.g. a daily note, is expected to
+		// exist already when running the command again for the same day.
+		if cmd.Date == "" {
+			if confirmed, _ := container.Terminal.Confirm(
+				fmt.Sprintf("%s already exists, do you want to edit this note instead?", noteExists.Name),
+				true,
+			); !confirmed {
+				// abort...
+				return nil
+			}
 		}
 
 		path = noteExists.Path
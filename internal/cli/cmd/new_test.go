@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/alecthomas/kong"
+	"github.com/zk-org/zk/internal/util/test/assert"
+)
+
+func TestNewParsesExtra(t *testing.T) {
+	test := func(args []string, expected map[string]string) {
+		var cmd New
+		parser, err := kong.New(&cmd)
+		assert.Nil(t, err)
+		_, err = parser.Parse(args)
+		assert.Nil(t, err)
+		assert.Equal(t, cmd.Extra, expected)
+	}
+
+	test([]string{}, nil)
+	test([]string{"--extra", "author=Mickaël"}, map[string]string{"author": "Mickaël"})
+	// Repeated flags and comma-separated pairs are merged.
+	test([]string{"--extra", "author=Mickaël", "--extra", "lang=fr,count=2"}, map[string]string{
+		"author": "Mickaël",
+		"lang":   "fr",
+		"count":  "2",
+	})
+	// Only the first `=` separates the key from the value.
+	test([]string{"--extra", "query=tag=draft", "--extra", "empty="}, map[string]string{
+		"query": "tag=draft",
+		"empty": "",
+	})
+	// The last value of a key wins.
+	test([]string{"--extra", "author=A", "--extra", "author=B"}, map[string]string{"author": "B"})
+}
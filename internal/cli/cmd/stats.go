@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/zk-org/zk/internal/cli"
+	"github.com/zk-org/zk/internal/core"
+	"github.com/zk-org/zk/internal/util/errors"
+)
+
+// Stats prints statistics about the notes matching a set of criteria.
+type Stats struct {
+	Format string `group:format short:f default:table help:"Format of the statistics among: table, json." enum:"table,json"`
+	cli.Filtering
+}
+
+// Number of entries printed in the table for the tags and creation months.
+const (
+	statsMaxTags   = 10
+	statsMaxMonths = 5
+)
+
+func (cmd *Stats) Run(container *cli.Container) error {
+	if cmd.Interactive {
+		return errors.New("--interactive can't be used with zk stats")
+	}
+
+	notebook, err := container.CurrentNotebook()
+	if err != nil {
+		return err
+	}
+
+	findOpts, err := cmd.Filtering.NewNoteFindOpts(notebook)
+	if err != nil {
+		return errors.Wrapf(err, "incorrect criteria")
+	}
+
+	stats, err := notebook.NoteStats(findOpts)
+	if err != nil {
+		return err
+	}
+
+	switch cmd.Format {
+	case "json":
+		return printJSONStats(os.Stdout, stats)
+	default:
+		return printTableStats(os.Stdout, stats)
+	}
+}
+
+func printJSONStats(out io.Writer, stats core.NoteStats) error {
+	// The average is computed, so it needs to be added to the serialized
+	// fields.
+	data, err := json.MarshalIndent(struct {
+		core.NoteStats
+		AverageWordCount float64 `json:"averageWordCount"`
+	}{stats, stats.AverageWordCount()}, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(out, "%s\n", data)
+	return err
+}
+
+func printTableStats(out io.Writer, stats core.NoteStats) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintf(w, "Notes\t%d\n", stats.NoteCount)
+	fmt.Fprintf(w, "Words\t%d\n", stats.WordCount)
+	fmt.Fprintf(w, "Words per note\t%.1f\n", stats.AverageWordCount())
+
+	fmt.Fprint(w, "\nLinks\n")
+	fmt.Fprintf(w, "  internal\t%d\n", stats.Links.Internal)
+	fmt.Fprintf(w, "  external\t%d\n", stats.Links.External)
+	fmt.Fprintf(w, "  dead\t%d\n", stats.Links.Dead)
+
+	printStatsEntries(w, "Directories", stats.Directories, 0)
+	printStatsEntries(w, "Tags", stats.Tags, statsMaxTags)
+	printStatsEntries(w, "Busiest months", stats.CreationMonths, statsMaxMonths)
+
+	return w.Flush()
+}
+
+// printStatsEntries prints a section of the table with at most max entries,
+// if max is not 0.
+func printStatsEntries(w io.Writer, title string, entries []core.StatsEntry, max int) {
+	if len(entries) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "\n%s\n", title)
+	for i, entry := range entries {
+		if max > 0 && i >= max {
+			fmt.Fprintf(w, "  ...and %d more\n", len(entries)-max)
+			break
+		}
+		fmt.Fprintf(w, "  %s\t%d\n", entry.Name, entry.Count)
+	}
+}
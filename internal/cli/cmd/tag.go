@@ -26,6 +26,8 @@ type TagList struct {
 	NoPager    bool     `group:format short:P help:"Do not pipe output into a pager."`
 	Quiet      bool     `group:format short:q help:"Do not print the total number of tags found."`
 	Sort       []string `group:sort short:s placeholder:TERM help:"Order the tags by the given criterion."`
+	RollUp     bool     `help:"Count the notes of the nested tags, e.g. project/a, in their parent tags, e.g. project."`
+	Glob       []string `arg optional placeholder:GLOB help:"Only list the tags matching the given glob patterns, e.g. 'project/*'."`
 }
 
 func (cmd *TagList) Run(container *cli.Container) error {
@@ -89,7 +91,11 @@ func (cmd *TagList) Run(container *cli.Container) error {
 		return err
 	}
 
-	tags, err := notebook.FindCollections(core.CollectionKindTag, sorters)
+	tags, err := notebook.FindCollections(core.CollectionKindTag, core.CollectionFindOpts{
+		Globs:   cmd.Glob,
+		RollUp:  cmd.RollUp,
+		Sorters: sorters,
+	})
 	if err != nil {
 		return err
 	}
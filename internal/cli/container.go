@@ -11,6 +11,7 @@ import (
 	"github.com/zk-org/zk/internal/adapter/handlebars"
 	hbhelpers "github.com/zk-org/zk/internal/adapter/handlebars/helpers"
 	"github.com/zk-org/zk/internal/adapter/markdown"
+	"github.com/zk-org/zk/internal/adapter/org"
 	"github.com/zk-org/zk/internal/adapter/sqlite"
 	"github.com/zk-org/zk/internal/adapter/term"
 	"github.com/zk-org/zk/internal/core"
@@ -74,11 +75,7 @@ func NewContainer(version string) (*Container, error) {
 	// Set the default notebook if not already set
 	// might be overrided if --notebook-dir flag is present
 	if osutil.GetOptEnv("ZK_NOTEBOOK_DIR").IsNull() && !config.Notebook.Dir.IsNull() {
-		notebookDir, err := paths.ExpandPath(config.Notebook.Dir.Unwrap())
-		if err != nil {
-			return nil, wrap(err)
-		}
-		os.Setenv("ZK_NOTEBOOK_DIR", notebookDir)
+		os.Setenv("ZK_NOTEBOOK_DIR", config.Notebook.Dir.Unwrap())
 	}
 
 	// Set the default shell if not already set
@@ -99,21 +96,42 @@ func NewContainer(version string) (*Container, error) {
 			TemplateLoader: templateLoader,
 			NotebookFactory: func(path string, config core.Config) (*core.Notebook, error) {
 				dbPath := filepath.Join(path, ".zk/notebook.db")
-				db, err := sqlite.Open(dbPath)
+				db, err := sqlite.OpenWithOpts(dbPath, sqlite.OpenOpts{
+					BusyTimeout: config.Notebook.BusyTimeout,
+				})
 				if err != nil {
 					return nil, err
 				}
 
+				parser := markdown.NewParser(
+					markdown.ParserOpts{
+						HashtagEnabled:      config.Format.Markdown.Hashtags,
+						MultiWordTagEnabled: config.Format.Markdown.MultiwordTags,
+						BracketTagEnabled:   config.Format.Markdown.BracketTags,
+						ColontagEnabled:     config.Format.Markdown.ColonTags,
+						LowercaseTags:       config.Format.Markdown.LowercaseTags,
+					},
+					logger,
+				)
+				orgParser := org.NewParser(
+					org.ParserOpts{
+						LowercaseTags: config.Format.Markdown.LowercaseTags,
+					},
+					logger,
+				)
 				notebook := core.NewNotebook(path, config, core.NotebookPorts{
-					NoteIndex: sqlite.NewNoteIndex(path, db, logger),
-					NoteContentParser: markdown.NewParser(
-						markdown.ParserOpts{
-							HashtagEnabled:      config.Format.Markdown.Hashtags,
-							MultiWordTagEnabled: config.Format.Markdown.MultiwordTags,
-							ColontagEnabled:     config.Format.Markdown.ColonTags,
-						},
-						logger,
-					),
+					NoteIndex: sqlite.NewNoteIndexWithOpts(path, db, logger, sqlite.NoteIndexOpts{
+						StoreRawContent: config.Notebook.RawContent,
+						Extensions:      config.Note.IndexedExtensions(),
+					}),
+					NoteContentParser: parser,
+					NoteContentParsers: map[string]core.NoteContentParser{
+						"org": orgParser,
+					},
+					NoteHTMLRenderer: parser,
+					NoteHTMLRenderers: map[string]core.NoteHTMLRenderer{
+						"org": orgParser,
+					},
 					TemplateLoaderFactory: func(language string) (core.TemplateLoader, error) {
 						loader := handlebars.NewLoader(handlebars.LoaderOpts{
 							LookupPaths: []string{
@@ -223,6 +241,7 @@ func (c *Container) CurrentNotebook() (*core.Notebook, error) {
 }
 
 func (c *Container) NewNoteFilter(opts fzf.NoteFilterOpts) *fzf.NoteFilter {
+	opts.FzfPath = c.Config.Tool.FzfPath
 	opts.PreviewCmd = c.Config.Tool.FzfPreview
 	opts.LineTemplate = c.Config.Tool.FzfLine
 	opts.FzfOptions = c.Config.Tool.FzfOptions
@@ -249,9 +268,16 @@ func (c *Container) Paginate(noPager bool, run func(out io.Writer) error) error
 }
 
 func (c *Container) pager(noPager bool) (*pager.Pager, error) {
-	if noPager || !c.Terminal.IsInteractive() {
+	if !isPaginated(noPager, c.Terminal.IsInteractive(), c.Terminal.IsOutputTTY()) {
 		return pager.PassthroughPager, nil
 	} else {
 		return pager.New(c.Config.Tool.Pager, c.Logger)
 	}
 }
+
+// isPaginated returns whether the output is sent to the pager, which requires
+// an interactive terminal. The pager is skipped when the output is piped or
+// redirected, to not hinder scripts.
+func isPaginated(noPager bool, isInteractive bool, isOutputTTY bool) bool {
+	return !noPager && isInteractive && isOutputTTY
+}
@@ -0,0 +1,22 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/zk-org/zk/internal/util/test/assert"
+)
+
+func TestIsPaginated(t *testing.T) {
+	test := func(noPager bool, isInteractive bool, isOutputTTY bool, expected bool) {
+		assert.Equal(t, isPaginated(noPager, isInteractive, isOutputTTY), expected)
+	}
+
+	test(false, true, true, true)
+	// Disabled with --no-pager or an empty tool.pager setting.
+	test(true, true, true, false)
+	// The output is piped or redirected.
+	test(false, true, false, false)
+	// Not attached to an interactive terminal, or with --no-input.
+	test(false, false, true, false)
+	test(false, false, false, false)
+}
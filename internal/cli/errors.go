@@ -0,0 +1,11 @@
+package cli
+
+import "fmt"
+
+// ExitCodeError is returned by a command to exit with the given status code,
+// without printing an error message.
+type ExitCodeError int
+
+func (e ExitCodeError) Error() string {
+	return fmt.Sprintf("exit status %d", int(e))
+}
@@ -2,6 +2,7 @@ package cli
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/alecthomas/kong"
@@ -9,7 +10,7 @@ import (
 	"github.com/zk-org/zk/internal/core"
 	dateutil "github.com/zk-org/zk/internal/util/date"
 	"github.com/zk-org/zk/internal/util/errors"
-	"github.com/zk-org/zk/internal/util/strings"
+	strutil "github.com/zk-org/zk/internal/util/strings"
 )
 
 // Filtering holds filtering options to select notes.
@@ -19,7 +20,7 @@ type Filtering struct {
 	Interactive    bool     `kong:"group='filter',short='i',help='Select notes interactively with fzf.'" json:"-"`
 	Limit          int      `kong:"group='filter',short='n',placeholder='COUNT',help='Limit the number of notes found.'" json:"limit"`
 	Match          []string `kong:"group='filter',short='m',placeholder='QUERY',help='Terms to search for in the notes.'" json:"match"`
-	MatchStrategy  string   `kong:"group='filter',short='M',default='fts',placeholder='STRATEGY',help='Text matching strategy among: fts, re, exact.'" json:"matchStrategy"`
+	MatchStrategy  string   `kong:"group='filter',short='M',default='fts',placeholder='STRATEGY',help='Text matching strategy among: fts, re, exact, phrase.'" json:"matchStrategy"`
 	Exclude        []string `kong:"group='filter',short='x',placeholder='PATH',help='Ignore notes matching the given path, including its descendants.'" json:"excludeHrefs"`
 	Tag            []string `kong:"group='filter',short='t',help='Find notes tagged with the given tags.'" json:"tags"`
 	Mention        []string `kong:"group='filter',placeholder='PATH',help='Find notes mentioning the title of the given ones.'" json:"mention"`
@@ -33,7 +34,7 @@ type Filtering struct {
 	Related        []string `kong:"group='filter',placeholder='PATH',help='Find notes which might be related to the given ones.'" json:"related"`
 	MaxDistance    int      `kong:"group='filter',placeholder='COUNT',help='Maximum distance between two linked notes.'" json:"maxDistance"`
 	Recursive      bool     `kong:"group='filter',short='r',help='Follow links recursively.'" json:"recursive"`
-	Created        string   `kong:"group='filter',placeholder='DATE',help:'Find notes created on the given date.'" json:"created"`
+	Created        string   `kong:"group='filter',placeholder='DATE',help='Find notes created on the given date.'" json:"created"`
 	CreatedBefore  string   `kong:"group='filter',placeholder='DATE',help='Find notes created before the given date.'" json:"createdBefore"`
 	CreatedAfter   string   `kong:"group='filter',placeholder='DATE',help='Find notes created after the given date.'" json:"createdAfter"`
 	Modified       string   `kong:"group='filter',placeholder='DATE',help='Find notes modified on the given date.'" json:"modified"`
@@ -47,11 +48,18 @@ type Filtering struct {
 }
 
 // ExpandNamedFilters expands recursively any named filter found in the Path field.
+//
+// A filter referring to itself selects the path named after it, but a cycle
+// between several filters is an error.
 func (f Filtering) ExpandNamedFilters(filters map[string]string, expandedFilters []string) (Filtering, error) {
 	actualPaths := []string{}
 
 	for _, path := range f.Path {
-		if filter, ok := filters[path]; ok && !strings.Contains(expandedFilters, path) {
+		if cycle := filterCycle(expandedFilters, path); cycle != nil {
+			return f, fmt.Errorf("named filters form a cycle: %s", strings.Join(cycle, " -> "))
+		}
+
+		if filter, ok := filters[path]; ok && !strutil.Contains(expandedFilters, path) {
 			wrap := errors.Wrapperf("failed to expand named filter `%v`", path)
 
 			var parsedFilter Filtering
@@ -132,6 +140,22 @@ func (f Filtering) ExpandNamedFilters(filters map[string]string, expandedFilters
 	return f, nil
 }
 
+// filterCycle returns the cycle formed by expanding the named filter in the
+// given chain of expanded filters, or nil. The last expanded filter can refer
+// to itself without forming a cycle.
+func filterCycle(expandedFilters []string, name string) []string {
+	for i, filter := range expandedFilters {
+		if filter == name {
+			if i == len(expandedFilters)-1 {
+				return nil
+			}
+			cycle := strutil.CopyList(expandedFilters[i:])
+			return append(cycle, name)
+		}
+	}
+	return nil
+}
+
 // NewNoteFindOpts creates an instance of core.NoteFindOpts from a set of user flags.
 func (f Filtering) NewNoteFindOpts(notebook *core.Notebook) (core.NoteFindOpts, error) {
 	opts := core.NoteFindOpts{}
@@ -208,7 +232,7 @@ func (f Filtering) NewNoteFindOpts(notebook *core.Notebook) (core.NoteFindOpts,
 	opts.Tagless = f.Tagless
 
 	if f.Created != "" {
-		start, end, err := parseDayRange(f.Created)
+		start, end, err := parseDateRange(f.Created)
 		if err != nil {
 			return opts, err
 		}
@@ -232,7 +256,7 @@ func (f Filtering) NewNoteFindOpts(notebook *core.Notebook) (core.NoteFindOpts,
 	}
 
 	if f.Modified != "" {
-		start, end, err := parseDayRange(f.Modified)
+		start, end, err := parseDateRange(f.Modified)
 		if err != nil {
 			return opts, err
 		}
@@ -277,20 +301,21 @@ func relPaths(notebook *core.Notebook, paths []string) ([]string, bool) {
 	return relPaths, len(relPaths) > 0
 }
 
-func parseDayRange(date string) (start time.Time, end time.Time, err error) {
-	day, err := dateutil.TimeFromNatural(date)
+// parseDateRange returns the period covered by the given date, e.g. a whole
+// month for `2021-01`. Exact dates cover their whole day.
+func parseDateRange(date string) (start time.Time, end time.Time, err error) {
+	t, precision, err := dateutil.Parse(date, time.Now())
 	if err != nil {
 		return
 	}
+	if precision == dateutil.PrecisionExact {
+		precision = dateutil.PrecisionDay
+	}
 
-    // we add -1 second so that the day range ends at 23:59:59
-    // i.e, the 'new day' begins at 00:00:00
-	start = startOfDay(day).Add(time.Second * -1)
-	end = start.AddDate(0, 0, 1)
+	// we add -1 second so that the range ends at 23:59:59
+	// i.e, the 'new day' begins at 00:00:00
+	start = precision.Start(t)
+	end = precision.Next(start).Add(time.Second * -1)
+	start = start.Add(time.Second * -1)
 	return start, end, nil
 }
-
-func startOfDay(t time.Time) time.Time {
-	year, month, day := t.Date()
-	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
-}
@@ -2,7 +2,13 @@ package cli
 
 import (
 	"testing"
+	"time"
 
+	"github.com/alecthomas/kong"
+	"github.com/zk-org/zk/internal/adapter/fs"
+	"github.com/zk-org/zk/internal/core"
+	"github.com/zk-org/zk/internal/util"
+	dateutil "github.com/zk-org/zk/internal/util/date"
 	"github.com/zk-org/zk/internal/util/test/assert"
 )
 
@@ -191,6 +197,74 @@ func TestExpandNamedFiltersExpandsRecursively(t *testing.T) {
 	assert.Equal(t, res.Sort, []string{"created"})
 }
 
+func TestExpandNamedFiltersRespectsQuoting(t *testing.T) {
+	f := Filtering{Path: []string{"todo"}}
+
+	res, err := f.ExpandNamedFilters(
+		map[string]string{
+			"todo": "--match 'TODO | FIXME' \"in progress\"",
+		},
+		[]string{},
+	)
+
+	assert.Nil(t, err)
+	assert.Equal(t, res.Match, []string{"TODO | FIXME"})
+	assert.Equal(t, res.Path, []string{"in progress"})
+}
+
+func TestExpandNamedFiltersComposesWithFlags(t *testing.T) {
+	f := Filtering{
+		Path:    []string{"recents", "todo"},
+		Exclude: []string{"archive"},
+		Limit:   5,
+	}
+
+	res, err := f.ExpandNamedFilters(
+		map[string]string{
+			"recents": "--sort modified- --limit 20",
+			"todo":    "--match 'TODO | FIXME'",
+		},
+		[]string{},
+	)
+
+	assert.Nil(t, err)
+	assert.Equal(t, res.Path, []string{})
+	assert.Equal(t, res.Exclude, []string{"archive"})
+	assert.Equal(t, res.Sort, []string{"modified-"})
+	assert.Equal(t, res.Match, []string{"TODO | FIXME"})
+	assert.Equal(t, res.Limit, 5)
+}
+
+func TestExpandNamedFiltersSelfReference(t *testing.T) {
+	f := Filtering{Path: []string{"journal"}}
+
+	res, err := f.ExpandNamedFilters(
+		map[string]string{
+			"journal": "journal --sort created",
+		},
+		[]string{},
+	)
+
+	assert.Nil(t, err)
+	assert.Equal(t, res.Path, []string{"journal"})
+	assert.Equal(t, res.Sort, []string{"created"})
+}
+
+func TestExpandNamedFiltersReportsCycle(t *testing.T) {
+	f := Filtering{Path: []string{"a"}}
+
+	_, err := f.ExpandNamedFilters(
+		map[string]string{
+			"a": "b",
+			"b": "c --limit 2",
+			"c": "a",
+		},
+		[]string{},
+	)
+
+	assert.Err(t, err, "named filters form a cycle: a -> b -> c -> a")
+}
+
 func TestExpandNamedFiltersReportsParsingError(t *testing.T) {
 	f := Filtering{Path: []string{"f1"}}
 
@@ -203,3 +277,217 @@ func TestExpandNamedFiltersReportsParsingError(t *testing.T) {
 
 	assert.Err(t, err, "failed to expand named filter `f1`: unknown flag --test")
 }
+
+func TestNoteFindOptsWithoutFlags(t *testing.T) {
+	opts, err := findOptsFromArgs(t)
+	assert.Nil(t, err)
+	assert.Equal(t, opts.Match, []string{})
+	assert.Equal(t, opts.MatchStrategy, core.MatchStrategyFts)
+	assert.Equal(t, opts.IncludeHrefs, []string(nil))
+	assert.Equal(t, opts.ExcludeHrefs, []string(nil))
+	assert.Nil(t, opts.CreatedStart)
+	assert.Nil(t, opts.CreatedEnd)
+	assert.Nil(t, opts.ModifiedStart)
+	assert.Nil(t, opts.ModifiedEnd)
+	assert.Equal(t, opts.Limit, 0)
+}
+
+// Positional paths are relative to the notebook, and cumulated.
+func TestNoteFindOptsPaths(t *testing.T) {
+	opts, err := findOptsFromArgs(t, "dir1", "dir2/note.md", "/notebook/dir3")
+	assert.Nil(t, err)
+	assert.Equal(t, opts.IncludeHrefs, []string{"dir1", "dir2/note.md", "dir3"})
+}
+
+// Paths outside the notebook are ignored.
+func TestNoteFindOptsPathsOutsideNotebook(t *testing.T) {
+	opts, err := findOptsFromArgs(t, "dir1", "/other/dir")
+	assert.Nil(t, err)
+	assert.Equal(t, opts.IncludeHrefs, []string{"dir1"})
+
+	opts, err = findOptsFromArgs(t, "/other/dir")
+	assert.Nil(t, err)
+	assert.Equal(t, opts.IncludeHrefs, []string(nil))
+}
+
+func TestNoteFindOptsExclude(t *testing.T) {
+	opts, err := findOptsFromArgs(t, "--exclude", "dir1", "-x", "dir2/note.md")
+	assert.Nil(t, err)
+	assert.Equal(t, opts.ExcludeHrefs, []string{"dir1", "dir2/note.md"})
+}
+
+func TestNoteFindOptsMatch(t *testing.T) {
+	opts, err := findOptsFromArgs(t, "--match", "banana", "-m", "apple")
+	assert.Nil(t, err)
+	assert.Equal(t, opts.Match, []string{"banana", "apple"})
+	assert.Equal(t, opts.MatchStrategy, core.MatchStrategyFts)
+
+	opts, err = findOptsFromArgs(t, "-m", "ban.*", "--match-strategy", "re")
+	assert.Nil(t, err)
+	assert.Equal(t, opts.Match, []string{"ban.*"})
+	assert.Equal(t, opts.MatchStrategy, core.MatchStrategyRe)
+
+	opts, err = findOptsFromArgs(t, "-m", "banana", "-Mexact")
+	assert.Nil(t, err)
+	assert.Equal(t, opts.MatchStrategy, core.MatchStrategyExact)
+}
+
+func TestNoteFindOptsMatchUnknownStrategy(t *testing.T) {
+	_, err := findOptsFromArgs(t, "-m", "banana", "-Mfoo")
+	assert.Err(t, err, "foo: unknown match strategy")
+}
+
+// --created selects the whole given day.
+func TestNoteFindOptsCreated(t *testing.T) {
+	opts, err := findOptsFromArgs(t, "--created", "2021-02-04")
+	assert.Nil(t, err)
+	assert.Equal(t, *opts.CreatedStart, time.Date(2021, 2, 3, 23, 59, 59, 0, time.Local))
+	assert.Equal(t, *opts.CreatedEnd, time.Date(2021, 2, 4, 23, 59, 59, 0, time.Local))
+	assert.Nil(t, opts.ModifiedStart)
+	assert.Nil(t, opts.ModifiedEnd)
+}
+
+// --created selects the whole month or year of a partial date.
+func TestNoteFindOptsCreatedPeriod(t *testing.T) {
+	opts, err := findOptsFromArgs(t, "--created", "2021-01")
+	assert.Nil(t, err)
+	assert.Equal(t, *opts.CreatedStart, time.Date(2020, 12, 31, 23, 59, 59, 0, time.Local))
+	assert.Equal(t, *opts.CreatedEnd, time.Date(2021, 1, 31, 23, 59, 59, 0, time.Local))
+
+	opts, err = findOptsFromArgs(t, "--created", "2020")
+	assert.Nil(t, err)
+	assert.Equal(t, *opts.CreatedStart, time.Date(2019, 12, 31, 23, 59, 59, 0, time.Local))
+	assert.Equal(t, *opts.CreatedEnd, time.Date(2020, 12, 31, 23, 59, 59, 0, time.Local))
+
+	opts, err = findOptsFromArgs(t, "--modified", "february 2021")
+	assert.Nil(t, err)
+	assert.Equal(t, *opts.ModifiedStart, time.Date(2021, 1, 31, 23, 59, 59, 0, time.Local))
+	assert.Equal(t, *opts.ModifiedEnd, time.Date(2021, 2, 28, 23, 59, 59, 0, time.Local))
+}
+
+// --created "last week" selects the whole week, from Monday to Sunday.
+func TestNoteFindOptsCreatedWeek(t *testing.T) {
+	monday := dateutil.PrecisionWeek.Start(time.Now())
+
+	opts, err := findOptsFromArgs(t, "--created", "last week")
+	assert.Nil(t, err)
+	assert.Equal(t, *opts.CreatedStart, monday.AddDate(0, 0, -7).Add(-time.Second))
+	assert.Equal(t, *opts.CreatedEnd, monday.Add(-time.Second))
+
+	opts, err = findOptsFromArgs(t, "--modified", "next week")
+	assert.Nil(t, err)
+	assert.Equal(t, *opts.ModifiedStart, monday.AddDate(0, 0, 7).Add(-time.Second))
+	assert.Equal(t, *opts.ModifiedEnd, monday.AddDate(0, 0, 14).Add(-time.Second))
+}
+
+func TestNoteFindOptsInvalidDate(t *testing.T) {
+	_, err := findOptsFromArgs(t, "--created", "01/02/2021")
+	assert.Err(t, err, "01/02/2021: ambiguous date, use the YYYY-MM-DD format")
+
+	_, err = findOptsFromArgs(t, "--modified-after", "foobar")
+	assert.Err(t, err, "foobar: invalid date")
+}
+
+func TestNoteFindOptsCreatedBeforeAndAfter(t *testing.T) {
+	opts, err := findOptsFromArgs(t, "--created-before", "2021-02-04", "--created-after", "2021-01-10")
+	assert.Nil(t, err)
+	assert.Equal(t, *opts.CreatedStart, time.Date(2021, 1, 10, 0, 0, 0, 0, time.Local))
+	assert.Equal(t, *opts.CreatedEnd, time.Date(2021, 2, 4, 0, 0, 0, 0, time.Local))
+
+	opts, err = findOptsFromArgs(t, "--created-after", "2021-01-10")
+	assert.Nil(t, err)
+	assert.Equal(t, *opts.CreatedStart, time.Date(2021, 1, 10, 0, 0, 0, 0, time.Local))
+	assert.Nil(t, opts.CreatedEnd)
+}
+
+// --created takes precedence over --created-before and --created-after.
+func TestNoteFindOptsCreatedOverridesRange(t *testing.T) {
+	opts, err := findOptsFromArgs(t, "--created", "2021-02-04", "--created-after", "2021-01-10")
+	assert.Nil(t, err)
+	assert.Equal(t, *opts.CreatedStart, time.Date(2021, 2, 3, 23, 59, 59, 0, time.Local))
+	assert.Equal(t, *opts.CreatedEnd, time.Date(2021, 2, 4, 23, 59, 59, 0, time.Local))
+}
+
+func TestNoteFindOptsModified(t *testing.T) {
+	opts, err := findOptsFromArgs(t, "--modified", "2021-02-04")
+	assert.Nil(t, err)
+	assert.Equal(t, *opts.ModifiedStart, time.Date(2021, 2, 3, 23, 59, 59, 0, time.Local))
+	assert.Equal(t, *opts.ModifiedEnd, time.Date(2021, 2, 4, 23, 59, 59, 0, time.Local))
+	assert.Nil(t, opts.CreatedStart)
+	assert.Nil(t, opts.CreatedEnd)
+
+	opts, err = findOptsFromArgs(t, "--modified-before", "2021-02-04", "--modified-after", "2021-01-10")
+	assert.Nil(t, err)
+	assert.Equal(t, *opts.ModifiedStart, time.Date(2021, 1, 10, 0, 0, 0, 0, time.Local))
+	assert.Equal(t, *opts.ModifiedEnd, time.Date(2021, 2, 4, 0, 0, 0, 0, time.Local))
+}
+
+func TestNoteFindOptsLimit(t *testing.T) {
+	opts, err := findOptsFromArgs(t, "--limit", "10")
+	assert.Nil(t, err)
+	assert.Equal(t, opts.Limit, 10)
+
+	opts, err = findOptsFromArgs(t, "-n", "3")
+	assert.Nil(t, err)
+	assert.Equal(t, opts.Limit, 3)
+}
+
+// The last --sort flag is the primary criterion, to override the ones of a
+// named filter.
+func TestNoteFindOptsSort(t *testing.T) {
+	opts, err := findOptsFromArgs(t, "--sort", "created-", "-s", "title")
+	assert.Nil(t, err)
+	assert.Equal(t, opts.Sorters, []core.NoteSorter{
+		{Field: core.NoteSortTitle, Ascending: true},
+		{Field: core.NoteSortCreated, Ascending: false},
+	})
+}
+
+func TestNoteFindOptsUnknownSort(t *testing.T) {
+	_, err := findOptsFromArgs(t, "--sort", "foo")
+	assert.Err(t, err, "foo: unknown sorting term")
+}
+
+// Different kinds of filters are set together.
+func TestNoteFindOptsCombined(t *testing.T) {
+	opts, err := findOptsFromArgs(t,
+		"dir1", "dir2",
+		"--exclude", "dir1/drafts",
+		"--match", "banana",
+		"--created-after", "2021-01-10",
+		"--modified-before", "2021-02-04",
+		"--limit", "5",
+		"--sort", "modified",
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, opts.IncludeHrefs, []string{"dir1", "dir2"})
+	assert.Equal(t, opts.ExcludeHrefs, []string{"dir1/drafts"})
+	assert.Equal(t, opts.Match, []string{"banana"})
+	assert.Equal(t, *opts.CreatedStart, time.Date(2021, 1, 10, 0, 0, 0, 0, time.Local))
+	assert.Nil(t, opts.CreatedEnd)
+	assert.Nil(t, opts.ModifiedStart)
+	assert.Equal(t, *opts.ModifiedEnd, time.Date(2021, 2, 4, 0, 0, 0, 0, time.Local))
+	assert.Equal(t, opts.Limit, 5)
+	assert.Equal(t, opts.Sorters, []core.NoteSorter{
+		{Field: core.NoteSortModified, Ascending: false},
+	})
+}
+
+// findOptsFromArgs parses the filtering flags from the command line arguments,
+// for a notebook at /notebook.
+func findOptsFromArgs(t *testing.T, args ...string) (core.NoteFindOpts, error) {
+	var f Filtering
+	parser, err := kong.New(&f)
+	assert.Nil(t, err)
+	_, err = parser.Parse(args)
+	assert.Nil(t, err)
+
+	storage, err := fs.NewFileStorage("/notebook", &util.NullLogger)
+	assert.Nil(t, err)
+	notebook := core.NewNotebook("/notebook", core.NewDefaultConfig(), core.NotebookPorts{
+		FS:     storage,
+		Logger: &util.NullLogger,
+	})
+
+	return f.NewNoteFindOpts(notebook)
+}
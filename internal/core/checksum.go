@@ -0,0 +1,64 @@
+package core
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"hash/fnv"
+)
+
+// ChecksumAlgorithm is the hash function used to compute the checksum of the
+// note contents.
+type ChecksumAlgorithm string
+
+const (
+	// ChecksumSHA256 is the default algorithm, resistant to collisions.
+	ChecksumSHA256 ChecksumAlgorithm = "sha256"
+	// ChecksumFNV64 is a faster non-cryptographic alternative, for large
+	// notebooks.
+	ChecksumFNV64 ChecksumAlgorithm = "fnv64"
+)
+
+// ChecksumAlgorithmFromString returns the checksum algorithm matching the
+// given name.
+func ChecksumAlgorithmFromString(s string) (ChecksumAlgorithm, error) {
+	switch ChecksumAlgorithm(s) {
+	case ChecksumSHA256, ChecksumFNV64:
+		return ChecksumAlgorithm(s), nil
+	default:
+		return ChecksumSHA256, fmt.Errorf("%s: unknown checksum algorithm - may be sha256 or fnv64", s)
+	}
+}
+
+// Sum returns the hexadecimal checksum of the given content.
+//
+// The line endings are normalized beforehand, so that a note checked out with
+// CRLF or LF line endings has the same checksum. When trimSpaces is true, the
+// trailing whitespace of each line is ignored as well.
+func (a ChecksumAlgorithm) Sum(content []byte, trimSpaces bool) string {
+	content = normalizeChecksumContent(content, trimSpaces)
+
+	switch a {
+	case ChecksumFNV64:
+		hash := fnv.New64a()
+		hash.Write(content)
+		return fmt.Sprintf("%016x", hash.Sum64())
+	default:
+		return fmt.Sprintf("%x", sha256.Sum256(content))
+	}
+}
+
+// normalizeChecksumContent converts the CRLF line endings to LF and
+// optionally removes the trailing whitespace of each line.
+func normalizeChecksumContent(content []byte, trimSpaces bool) []byte {
+	content = bytes.ReplaceAll(content, []byte("\r\n"), []byte("\n"))
+	if !trimSpaces {
+		return content
+	}
+
+	lines := bytes.Split(content, []byte("\n"))
+	for i, line := range lines {
+		lines[i] = bytes.TrimRight(line, " \t\r")
+	}
+	return bytes.Join(lines, []byte("\n"))
+}
@@ -0,0 +1,45 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/zk-org/zk/internal/util/test/assert"
+)
+
+func TestChecksumAlgorithmFromString(t *testing.T) {
+	test := func(s string, expected ChecksumAlgorithm) {
+		actual, err := ChecksumAlgorithmFromString(s)
+		assert.Nil(t, err)
+		assert.Equal(t, actual, expected)
+	}
+
+	test("sha256", ChecksumSHA256)
+	test("fnv64", ChecksumFNV64)
+
+	_, err := ChecksumAlgorithmFromString("md5")
+	assert.Err(t, err, "md5: unknown checksum algorithm - may be sha256 or fnv64")
+}
+
+func TestChecksumAlgorithmSum(t *testing.T) {
+	content := []byte("Hello, world")
+
+	assert.Equal(t, ChecksumSHA256.Sum(content, false), "4ae7c3b6ac0beff671efa8cf57386151c06e58ca53a78d83f36107316cec125f")
+	assert.Equal(t, ChecksumFNV64.Sum(content, false), "dd7b24779de0921d")
+	// The zero value falls back on SHA-256.
+	assert.Equal(t, ChecksumAlgorithm("").Sum(content, false), ChecksumSHA256.Sum(content, false))
+}
+
+func TestChecksumAlgorithmSumNormalizesLineEndings(t *testing.T) {
+	lf := []byte("# Title\n\nParagraph  \nwith trailing spaces\t\n")
+	crlf := []byte("# Title\r\n\r\nParagraph  \r\nwith trailing spaces\t\r\n")
+	trimmed := []byte("# Title\n\nParagraph\nwith trailing spaces\n")
+
+	for _, algorithm := range []ChecksumAlgorithm{ChecksumSHA256, ChecksumFNV64} {
+		assert.Equal(t, algorithm.Sum(crlf, false), algorithm.Sum(lf, false))
+		assert.Equal(t, algorithm.Sum(crlf, true), algorithm.Sum(lf, true))
+		// The trailing whitespace is significant unless trimmed.
+		assert.NotEqual(t, algorithm.Sum(lf, false), algorithm.Sum(trimmed, false))
+		assert.Equal(t, algorithm.Sum(lf, true), algorithm.Sum(trimmed, false))
+		assert.Equal(t, algorithm.Sum(crlf, true), algorithm.Sum(trimmed, true))
+	}
+}
@@ -38,7 +38,8 @@ func (id NoteCollectionID) IsValid() bool {
 type CollectionKind string
 
 const (
-	CollectionKindTag CollectionKind = "tag"
+	CollectionKindTag   CollectionKind = "tag"
+	CollectionKindAlias CollectionKind = "alias"
 )
 
 // CollectionRepository persists note collection across sessions.
@@ -48,9 +49,9 @@ type CollectionRepository interface {
 	// If the collection does not exist, creates a new one.
 	FindOrCreateCollection(name string, kind CollectionKind) (CollectionID, error)
 
-	// FindCollections returns the list of all collections in the repository
-	// for the given kind, ordered with the given sorters.
-	FindCollections(kind CollectionKind, sorters []CollectionSorter) ([]Collection, error)
+	// FindCollections returns the list of collections in the repository for
+	// the given kind, matching the given criteria.
+	FindCollections(kind CollectionKind, opts CollectionFindOpts) ([]Collection, error)
 
 	// AssociateNoteCollection creates a new association between a note and a
 	// collection, if it does not already exist.
@@ -61,6 +62,20 @@ type CollectionRepository interface {
 	RemoveNoteAssociations(noteId NoteID) error
 }
 
+// CollectionFindOpts holds a set of filtering and sorting criteria to find
+// collections.
+type CollectionFindOpts struct {
+	// Glob patterns matching the names of the collections to find, e.g.
+	// `project/*`.
+	Globs []string
+	// Count the notes of the nested collections, e.g. `project/a`, in their
+	// parent collections, e.g. `project`. The notes are counted only once
+	// per collection.
+	RollUp bool
+	// Order terms used to sort the collections.
+	Sorters []CollectionSorter
+}
+
 // CollectionSorter represents an order term used to sort a list of collections.
 type CollectionSorter struct {
 	Field     CollectionSortField
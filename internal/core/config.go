@@ -2,13 +2,18 @@ package core
 
 import (
 	"fmt"
+	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
+	"time"
 
+	"github.com/kballard/go-shellquote"
 	toml "github.com/pelletier/go-toml"
 	"github.com/zk-org/zk/internal/util/errors"
 	"github.com/zk-org/zk/internal/util/opt"
 	"github.com/zk-org/zk/internal/util/paths"
+	strutil "github.com/zk-org/zk/internal/util/strings"
 )
 
 // Config holds the user configuration.
@@ -32,7 +37,11 @@ type Config struct {
 func NewDefaultConfig() Config {
 	return Config{
 		Notebook: NotebookConfig{
-			Dir: opt.NullString,
+			Dir:         opt.NullString,
+			Checksum:    ChecksumSHA256,
+			MaxNoteSize: 5 * 1024 * 1024,
+			BusyTimeout: 5 * time.Second,
+			RawContent:  true,
 		},
 		Note: NoteConfig{
 			FilenameTemplate: "{{id}}",
@@ -58,6 +67,9 @@ func NewDefaultConfig() Config {
 				LinkDropExtension: true,
 			},
 		},
+		Tool: ToolConfig{
+			EditorMaxNotes: 5,
+		},
 		LSP: LSPConfig{
 			Completion: LSPCompletionConfig{
 				Note: LSPCompletionTemplates{
@@ -112,22 +124,30 @@ func (c Config) GroupConfigNamed(name string) (GroupConfig, error) {
 
 // GroupNameForPath returns the name of the GroupConfig matching the given
 // path, relative to the notebook.
+//
+// When several groups match the path, the one with the longest matching
+// group path wins, e.g. `log/work` over `log`.
 func (c Config) GroupNameForPath(path string) (string, error) {
+	res := ""
+	longest := -1
 	for name, config := range c.Groups {
 		for _, groupPath := range config.Paths {
 			matches, err := filepath.Match(groupPath, path)
 			if err != nil {
 				return "", errors.Wrapf(err, "failed to match group %s to %s", name, path)
-			} else if matches {
-				return name, nil
 			}
-			if strings.HasPrefix(path, groupPath+"/") {
-				return name, nil
+			if !matches && !strings.HasPrefix(path, groupPath+"/") {
+				continue
+			}
+			// Ties are broken by name, to not depend on the map order.
+			if len(groupPath) > longest || (len(groupPath) == longest && name < res) {
+				res = name
+				longest = len(groupPath)
 			}
 		}
 	}
 
-	return "", nil
+	return res, nil
 }
 
 // FormatConfig holds the configuration for document formats, such as Markdown.
@@ -143,6 +163,11 @@ type MarkdownConfig struct {
 	ColonTags bool
 	// MultiwordTags indicates whether #multi-word tags# are supported.
 	MultiwordTags bool
+	// BracketTags indicates whether #[[multi-word tags]] are supported,
+	// instead of Neuron's uplinks.
+	BracketTags bool
+	// LowercaseTags indicates whether the tags are converted to lowercase.
+	LowercaseTags bool
 
 	// Format used to generate links between notes.
 	// Either "wiki", "markdown" or a custom template. Default is "markdown".
@@ -156,13 +181,19 @@ type MarkdownConfig struct {
 
 // ToolConfig holds the external tooling configuration.
 type ToolConfig struct {
-	Editor     opt.String
-	Shell      opt.String
-	Pager      opt.String
-	FzfPreview opt.String
-	FzfLine    opt.String
-	FzfOptions opt.String
-	FzfBindNew opt.String
+	Editor opt.String
+	// Number of notes above which `zk edit` requires --force or an
+	// interactive selection. 0 disables the limit.
+	EditorMaxNotes int
+	Shell          opt.String
+	Pager          opt.String
+	FzfPath        opt.String
+	FzfPreview     opt.String
+	FzfLine        opt.String
+	FzfOptions     opt.String
+	FzfBindNew     opt.String
+	// Whether command aliases can shadow the built-in commands.
+	AliasOverride bool
 }
 
 // LSPConfig holds the Language Server Protocol configuration.
@@ -204,6 +235,23 @@ const (
 // NotebookConfig holds configuration about the default notebook
 type NotebookConfig struct {
 	Dir opt.String
+	// Algorithm used to compute the checksum of the notes.
+	Checksum ChecksumAlgorithm
+	// Indicates whether the trailing whitespace of the lines is ignored when
+	// computing the checksum of the notes.
+	ChecksumTrimSpaces bool
+	// Indicates whether the symbolic links to directories are followed when
+	// indexing the notebook.
+	FollowSymlinks bool
+	// Size in bytes above which the note files are not indexed. 0 disables
+	// the limit.
+	MaxNoteSize int64
+	// Duration to wait for the notebook database locked by another process,
+	// e.g. the LSP server, before failing.
+	BusyTimeout time.Duration
+	// Indicates whether the raw content of the notes is stored in the index,
+	// to search the frontmatter or print it without reading the files.
+	RawContent bool
 }
 
 // NoteConfig holds the user configuration used when generating new notes.
@@ -212,18 +260,34 @@ type NoteConfig struct {
 	FilenameTemplate string
 	// Extension appended to the filename.
 	Extension string
+	// Extensions of the files indexed as notes, in addition to Extension.
+	Extensions []string
 	// Path to the handlebars template used when generating the note content.
 	BodyTemplatePath opt.String
 	// Language of the note content.
 	Lang string
 	// Default title to use when none is provided.
 	DefaultTitle string
+	// Go layout of a creation date embedded in the filename, e.g. 2006-01-02.
+	FilenameDateFormat string
 	// Settings used when generating a random ID.
 	IDOptions IDOptions
 	// Path globs to ignore when indexing notes.
 	Exclude []string
 }
 
+// IndexedExtensions returns the file extensions of the notes to index,
+// including the one of the new notes.
+func (c NoteConfig) IndexedExtensions() []string {
+	exts := []string{c.Extension}
+	for _, ext := range c.Extensions {
+		if !strutil.Contains(exts, ext) {
+			exts = append(exts, ext)
+		}
+	}
+	return exts
+}
+
 // GroupConfig holds the user configuration for a given group of notes.
 type GroupConfig struct {
 	Paths []string
@@ -288,9 +352,18 @@ func ParseConfig(content []byte, path string, parentConfig Config, isGlobal bool
 
 	config := parentConfig
 
+	tree, err := toml.LoadBytes(content)
+	if err != nil {
+		return config, wrap(err)
+	}
 	var tomlConf tomlConfig
-	err := toml.Unmarshal(content, &tomlConf)
+	err = tree.Unmarshal(&tomlConf)
 	if err != nil {
+		// The decoding errors are reported with the position of the invalid
+		// value, which is easier to fix with the key.
+		if key := tomlKeyAt(tree, err); key != "" {
+			err = errors.Wrap(err, key)
+		}
 		return config, wrap(err)
 	}
 
@@ -298,11 +371,45 @@ func ParseConfig(content []byte, path string, parentConfig Config, isGlobal bool
 	notebook := tomlConf.Notebook
 	if notebook.Dir != "" {
 		if isGlobal {
-			config.Notebook.Dir = opt.NewNotEmptyString(notebook.Dir)
+			dir, err := expandConfigValue("notebook.dir", notebook.Dir)
+			if err != nil {
+				return config, wrap(err)
+			}
+			config.Notebook.Dir = opt.NewNotEmptyString(dir)
 		} else {
 			return config, wrap(errors.New("notebook.dir should not be set on local configuration"))
 		}
 	}
+	if notebook.Checksum != "" {
+		config.Notebook.Checksum, err = ChecksumAlgorithmFromString(notebook.Checksum)
+		if err != nil {
+			return config, wrap(errors.Wrap(err, "notebook.checksum"))
+		}
+	}
+	if notebook.ChecksumTrimSpaces != nil {
+		config.Notebook.ChecksumTrimSpaces = *notebook.ChecksumTrimSpaces
+	}
+	if notebook.FollowSymlinks != nil {
+		config.Notebook.FollowSymlinks = *notebook.FollowSymlinks
+	}
+	if notebook.MaxNoteSize != nil {
+		if *notebook.MaxNoteSize < 0 {
+			return config, wrap(errors.New("notebook.max-note-size should not be negative"))
+		}
+		config.Notebook.MaxNoteSize = *notebook.MaxNoteSize
+	}
+	if notebook.RawContent != nil {
+		config.Notebook.RawContent = *notebook.RawContent
+	}
+	if notebook.BusyTimeout != "" {
+		config.Notebook.BusyTimeout, err = time.ParseDuration(notebook.BusyTimeout)
+		if err != nil {
+			return config, wrap(errors.Wrap(err, "notebook.busy-timeout"))
+		}
+		if config.Notebook.BusyTimeout < 0 {
+			return config, wrap(errors.New("notebook.busy-timeout should not be negative"))
+		}
+	}
 
 	// Note
 	note := tomlConf.Note
@@ -312,13 +419,19 @@ func ParseConfig(content []byte, path string, parentConfig Config, isGlobal bool
 	if note.Extension != "" {
 		config.Note.Extension = note.Extension
 	}
+	if note.Extensions != nil {
+		config.Note.Extensions = parseExtensions(note.Extensions)
+	}
 	if note.Template != "" {
-		expanded, err := paths.ExpandPath(note.Template)
+		expanded, err := expandConfigValue("note.template", note.Template)
 		if err != nil {
 			return config, wrap(err)
 		}
 		config.Note.BodyTemplatePath = opt.NewNotEmptyString(expanded)
 	}
+	if note.IDLength < 0 {
+		return config, wrap(errors.New("note.id-length should not be negative"))
+	}
 	if note.IDLength != 0 {
 		config.Note.IDOptions.Length = note.IDLength
 	}
@@ -326,7 +439,10 @@ func ParseConfig(content []byte, path string, parentConfig Config, isGlobal bool
 		config.Note.IDOptions.Charset = charsetFromString(note.IDCharset)
 	}
 	if note.IDCase != "" {
-		config.Note.IDOptions.Case = caseFromString(note.IDCase)
+		config.Note.IDOptions.Case, err = caseFromString(note.IDCase)
+		if err != nil {
+			return config, wrap(errors.Wrap(err, "note.id-case"))
+		}
 	}
 	if note.Lang != "" {
 		config.Note.Lang = note.Lang
@@ -334,6 +450,9 @@ func ParseConfig(content []byte, path string, parentConfig Config, isGlobal bool
 	if note.DefaultTitle != "" {
 		config.Note.DefaultTitle = note.DefaultTitle
 	}
+	if note.FilenameDateFormat != "" {
+		config.Note.FilenameDateFormat = note.FilenameDateFormat
+	}
 	for _, v := range note.Exclude {
 		config.Note.Exclude = append(config.Note.Exclude, v)
 	}
@@ -353,7 +472,10 @@ func ParseConfig(content []byte, path string, parentConfig Config, isGlobal bool
 			parent = config.RootGroupConfig()
 		}
 
-		config.Groups[name] = parent.merge(dirTOML, name)
+		config.Groups[name], err = parent.merge(dirTOML, name)
+		if err != nil {
+			return config, wrap(err)
+		}
 	}
 
 	// Format
@@ -367,6 +489,12 @@ func ParseConfig(content []byte, path string, parentConfig Config, isGlobal bool
 	if markdown.MultiwordTags != nil {
 		config.Format.Markdown.MultiwordTags = *markdown.MultiwordTags
 	}
+	if markdown.BracketTags != nil {
+		config.Format.Markdown.BracketTags = *markdown.BracketTags
+	}
+	if markdown.LowercaseTags != nil {
+		config.Format.Markdown.LowercaseTags = *markdown.LowercaseTags
+	}
 	if markdown.LinkFormat != nil && *markdown.LinkFormat == "" {
 		*markdown.LinkFormat = "markdown"
 	}
@@ -385,13 +513,45 @@ func ParseConfig(content []byte, path string, parentConfig Config, isGlobal bool
 	// Tool
 	tool := tomlConf.Tool
 	if tool.Editor != nil {
-		config.Tool.Editor = opt.NewNotEmptyString(*tool.Editor)
+		editor, err := expandConfigCommand("tool.editor", *tool.Editor)
+		if err != nil {
+			return config, wrap(err)
+		}
+		config.Tool.Editor = opt.NewNotEmptyString(editor)
+	}
+	if tool.EditorMaxNotes != nil {
+		if *tool.EditorMaxNotes < 0 {
+			return config, wrap(errors.New("tool.editor-max-notes should not be negative"))
+		}
+		config.Tool.EditorMaxNotes = *tool.EditorMaxNotes
 	}
 	if tool.Shell != nil {
-		config.Tool.Shell = opt.NewNotEmptyString(*tool.Shell)
+		shell, err := expandConfigCommand("tool.shell", *tool.Shell)
+		if err != nil {
+			return config, wrap(err)
+		}
+		config.Tool.Shell = opt.NewNotEmptyString(shell)
+	}
+	if tool.AliasOverride != nil {
+		config.Tool.AliasOverride = *tool.AliasOverride
 	}
 	if tool.Pager != nil {
-		config.Tool.Pager = opt.NewStringWithPtr(tool.Pager)
+		// An empty pager disables the pagination, but a pager expanded from
+		// an unset variable keeps the default one.
+		pager, err := expandConfigCommand("tool.pager", *tool.Pager)
+		if err != nil {
+			return config, wrap(err)
+		}
+		if pager != "" || *tool.Pager == "" {
+			config.Tool.Pager = opt.NewString(pager)
+		}
+	}
+	if tool.FzfPath != nil {
+		fzfPath, err := expandConfigValue("tool.fzf-path", *tool.FzfPath)
+		if err != nil {
+			return config, wrap(err)
+		}
+		config.Tool.FzfPath = opt.NewNotEmptyString(fzfPath)
 	}
 	if tool.FzfPreview != nil {
 		config.Tool.FzfPreview = opt.NewStringWithPtr(tool.FzfPreview)
@@ -424,19 +584,22 @@ func ParseConfig(content []byte, path string, parentConfig Config, isGlobal bool
 	if lspDiags.WikiTitle != nil {
 		config.LSP.Diagnostics.WikiTitle, err = lspDiagnosticSeverityFromString(*lspDiags.WikiTitle)
 		if err != nil {
-			return config, wrap(err)
+			return config, wrap(errors.Wrap(err, "lsp.diagnostics.wiki-title"))
 		}
 	}
 	if lspDiags.DeadLink != nil {
 		config.LSP.Diagnostics.DeadLink, err = lspDiagnosticSeverityFromString(*lspDiags.DeadLink)
 		if err != nil {
-			return config, wrap(err)
+			return config, wrap(errors.Wrap(err, "lsp.diagnostics.dead-link"))
 		}
 	}
 
 	// Filters
 	if tomlConf.Filters != nil {
 		for k, v := range tomlConf.Filters {
+			if err := validateFilterSort(v); err != nil {
+				return config, wrap(errors.Wrap(err, "filter."+k))
+			}
 			config.Filters[k] = v
 		}
 	}
@@ -451,8 +614,10 @@ func ParseConfig(content []byte, path string, parentConfig Config, isGlobal bool
 	return config, nil
 }
 
-func (c GroupConfig) merge(tomlConf tomlGroupConfig, name string) GroupConfig {
+func (c GroupConfig) merge(tomlConf tomlGroupConfig, name string) (GroupConfig, error) {
 	res := c.Clone()
+	// Errors are reported with the full key path, e.g. group.journal.note.id-length.
+	key := "group." + name + "."
 
 	if tomlConf.Paths != nil {
 		for _, p := range tomlConf.Paths {
@@ -471,8 +636,18 @@ func (c GroupConfig) merge(tomlConf tomlGroupConfig, name string) GroupConfig {
 	if note.Extension != "" {
 		res.Note.Extension = note.Extension
 	}
+	if note.Extensions != nil {
+		res.Note.Extensions = parseExtensions(note.Extensions)
+	}
 	if note.Template != "" {
-		res.Note.BodyTemplatePath = opt.NewNotEmptyString(note.Template)
+		expanded, err := expandConfigValue(key+"note.template", note.Template)
+		if err != nil {
+			return res, err
+		}
+		res.Note.BodyTemplatePath = opt.NewNotEmptyString(expanded)
+	}
+	if note.IDLength < 0 {
+		return res, errors.New(key + "note.id-length should not be negative")
 	}
 	if note.IDLength != 0 {
 		res.Note.IDOptions.Length = note.IDLength
@@ -481,7 +656,11 @@ func (c GroupConfig) merge(tomlConf tomlGroupConfig, name string) GroupConfig {
 		res.Note.IDOptions.Charset = charsetFromString(note.IDCharset)
 	}
 	if note.IDCase != "" {
-		res.Note.IDOptions.Case = caseFromString(note.IDCase)
+		var err error
+		res.Note.IDOptions.Case, err = caseFromString(note.IDCase)
+		if err != nil {
+			return res, errors.Wrap(err, key+"note.id-case")
+		}
 	}
 	if note.Lang != "" {
 		res.Note.Lang = note.Lang
@@ -489,6 +668,9 @@ func (c GroupConfig) merge(tomlConf tomlGroupConfig, name string) GroupConfig {
 	if note.DefaultTitle != "" {
 		res.Note.DefaultTitle = note.DefaultTitle
 	}
+	if note.FilenameDateFormat != "" {
+		res.Note.FilenameDateFormat = note.FilenameDateFormat
+	}
 	for _, v := range note.Exclude {
 		res.Note.Exclude = append(res.Note.Exclude, v)
 	}
@@ -501,7 +683,28 @@ func (c GroupConfig) merge(tomlConf tomlGroupConfig, name string) GroupConfig {
 		}
 	}
 
-	return res
+	return res, nil
+}
+
+// tomlKeyAt returns the dotted path of the key located at the position
+// reported by the given decoding error, or an empty string if not found.
+func tomlKeyAt(tree *toml.Tree, err error) string {
+	var line, col int
+	if _, scanErr := fmt.Sscanf(err.Error(), "(%d, %d)", &line, &col); scanErr != nil {
+		return ""
+	}
+
+	for _, key := range tree.Keys() {
+		pos := tree.GetPosition(key)
+		if sub, ok := tree.GetPath([]string{key}).(*toml.Tree); ok {
+			if subKey := tomlKeyAt(sub, err); subKey != "" {
+				return key + "." + subKey
+			}
+		} else if pos.Line == line && pos.Col == col {
+			return key
+		}
+	}
+	return ""
 }
 
 // tomlConfig holds the TOML representation of Config
@@ -518,20 +721,28 @@ type tomlConfig struct {
 }
 
 type tomlNotebookConfig struct {
-	Dir string
+	Dir                string
+	Checksum           string
+	ChecksumTrimSpaces *bool  `toml:"checksum-trim-spaces"`
+	FollowSymlinks     *bool  `toml:"follow-symlinks"`
+	MaxNoteSize        *int64 `toml:"max-note-size"`
+	BusyTimeout        string `toml:"busy-timeout"`
+	RawContent         *bool  `toml:"raw-content"`
 }
 
 type tomlNoteConfig struct {
-	Filename     string
-	Extension    string
-	Template     string
-	Lang         string   `toml:"language"`
-	DefaultTitle string   `toml:"default-title"`
-	IDCharset    string   `toml:"id-charset"`
-	IDLength     int      `toml:"id-length"`
-	IDCase       string   `toml:"id-case"`
-	Exclude      []string `toml:"exclude"`
-	Ignore       []string `toml:"ignore"` // Legacy alias to `exclude`
+	Filename           string
+	Extension          string
+	Extensions         []string `toml:"extensions"`
+	Template           string
+	Lang               string   `toml:"language"`
+	DefaultTitle       string   `toml:"default-title"`
+	FilenameDateFormat string   `toml:"filename-date-format"`
+	IDCharset          string   `toml:"id-charset"`
+	IDLength           int      `toml:"id-length"`
+	IDCase             string   `toml:"id-case"`
+	Exclude            []string `toml:"exclude"`
+	Ignore             []string `toml:"ignore"` // Legacy alias to `exclude`
 }
 
 type tomlGroupConfig struct {
@@ -548,19 +759,24 @@ type tomlMarkdownConfig struct {
 	Hashtags          *bool   `toml:"hashtags"`
 	ColonTags         *bool   `toml:"colon-tags"`
 	MultiwordTags     *bool   `toml:"multiword-tags"`
+	BracketTags       *bool   `toml:"bracket-tags"`
+	LowercaseTags     *bool   `toml:"lowercase-tags"`
 	LinkFormat        *string `toml:"link-format"`
 	LinkEncodePath    *bool   `toml:"link-encode-path"`
 	LinkDropExtension *bool   `toml:"link-drop-extension"`
 }
 
 type tomlToolConfig struct {
-	Editor     *string
-	Shell      *string
-	Pager      *string
-	FzfPreview *string `toml:"fzf-preview"`
-	FzfLine    *string `toml:"fzf-line"`
-	FzfOptions *string `toml:"fzf-options"`
-	FzfBindNew *string `toml:"fzf-bind-new"`
+	Editor         *string
+	EditorMaxNotes *int `toml:"editor-max-notes"`
+	Shell          *string
+	AliasOverride  *bool `toml:"alias-override"`
+	Pager          *string
+	FzfPath        *string `toml:"fzf-path"`
+	FzfPreview     *string `toml:"fzf-preview"`
+	FzfLine        *string `toml:"fzf-line"`
+	FzfOptions     *string `toml:"fzf-options"`
+	FzfBindNew     *string `toml:"fzf-bind-new"`
 }
 
 type tomlLSPConfig struct {
@@ -576,6 +792,85 @@ type tomlLSPConfig struct {
 	}
 }
 
+// expandConfigValue expands the environment variables and a leading `~` in
+// the value of the given config key. It is applied only to the paths and
+// commands, as the templates have their own syntax.
+func expandConfigValue(key string, value string) (string, error) {
+	expanded, err := paths.ExpandPath(value)
+	if err != nil {
+		return "", errors.Wrap(err, key)
+	}
+	return expanded, nil
+}
+
+// envVarRegex matches a value made only of an environment variable, e.g.
+// $EDITOR or ${EDITOR}.
+var envVarRegex = regexp.MustCompile(`^\$(\w+|\{\w+\})$`)
+
+// expandConfigCommand expands a command of the configuration. Unlike a path,
+// only a leading `~` or a whole value made of an environment variable are
+// expanded, because the command may use `$` for its own arguments, e.g.
+// sh -c 'vim "$1"'.
+func expandConfigCommand(key string, value string) (string, error) {
+	if envVarRegex.MatchString(value) {
+		return os.ExpandEnv(value), nil
+	}
+	if value == "~" || strings.HasPrefix(value, "~/") {
+		home, err := paths.ExpandPath("~")
+		if err != nil {
+			return "", errors.Wrap(err, key)
+		}
+		return home + value[1:], nil
+	}
+	return value, nil
+}
+
+// validateFilterSort checks the sorting terms given with `--sort` or `-s` in
+// the arguments of a named filter.
+func validateFilterSort(filter string) error {
+	args, err := shellquote.Split(filter)
+	if err != nil {
+		return err
+	}
+
+	for i, arg := range args {
+		var terms string
+		switch {
+		case arg == "--sort" || arg == "-s":
+			if i+1 >= len(args) {
+				return fmt.Errorf("%s: missing sorting term", arg)
+			}
+			terms = args[i+1]
+		case strings.HasPrefix(arg, "--sort="):
+			terms = strings.TrimPrefix(arg, "--sort=")
+		case strings.HasPrefix(arg, "-s") && !strings.HasPrefix(arg, "--"):
+			terms = strings.TrimPrefix(strings.TrimPrefix(arg, "-s"), "=")
+		default:
+			continue
+		}
+
+		for _, term := range strings.Split(terms, ",") {
+			if _, err := NoteSorterFromString(term); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// parseExtensions normalizes the file extensions from the config, which may be
+// written with or without their leading dot.
+func parseExtensions(extensions []string) []string {
+	res := []string{}
+	for _, ext := range extensions {
+		ext = strings.TrimPrefix(strings.TrimSpace(ext), ".")
+		if ext != "" {
+			res = append(res, ext)
+		}
+	}
+	return res
+}
+
 func charsetFromString(charset string) Charset {
 	switch charset {
 	case "alphanum":
@@ -591,16 +886,16 @@ func charsetFromString(charset string) Charset {
 	}
 }
 
-func caseFromString(c string) Case {
+func caseFromString(c string) (Case, error) {
 	switch c {
 	case "lower":
-		return CaseLower
+		return CaseLower, nil
 	case "upper":
-		return CaseUpper
+		return CaseUpper, nil
 	case "mixed":
-		return CaseMixed
+		return CaseMixed, nil
 	default:
-		return CaseLower
+		return CaseLower, fmt.Errorf("%s: unknown case - may be lower, upper or mixed", c)
 	}
 }
 
@@ -2,7 +2,9 @@ package core
 
 import (
 	"fmt"
+	"os/user"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/zk-org/zk/internal/util/opt"
@@ -15,7 +17,11 @@ func TestParseDefaultConfig(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, conf, Config{
 		Notebook: NotebookConfig{
-			Dir: opt.NullString,
+			Dir:         opt.NullString,
+			Checksum:    ChecksumSHA256,
+			MaxNoteSize: 5242880,
+			BusyTimeout: 5 * time.Second,
+			RawContent:  true,
 		},
 		Note: NoteConfig{
 			FilenameTemplate: "{{id}}",
@@ -42,11 +48,12 @@ func TestParseDefaultConfig(t *testing.T) {
 			},
 		},
 		Tool: ToolConfig{
-			Editor:     opt.NullString,
-			Shell:      opt.NullString,
-			Pager:      opt.NullString,
-			FzfPreview: opt.NullString,
-			FzfLine:    opt.NullString,
+			Editor:         opt.NullString,
+			EditorMaxNotes: 5,
+			Shell:          opt.NullString,
+			Pager:          opt.NullString,
+			FzfPreview:     opt.NullString,
+			FzfLine:        opt.NullString,
 		},
 		LSP: LSPConfig{
 			Diagnostics: LSPDiagnosticConfig{
@@ -60,21 +67,55 @@ func TestParseDefaultConfig(t *testing.T) {
 	})
 }
 
+func TestOpenMissingConfig(t *testing.T) {
+	fs := newFileStorageMock("/notebook", []string{})
+	conf, err := OpenConfig("/notebook/.zk/config.toml", NewDefaultConfig(), fs, false)
+	assert.Nil(t, err)
+	assert.Equal(t, conf, NewDefaultConfig())
+}
+
 func TestParseInvalidConfig(t *testing.T) {
 	_, err := ParseConfig([]byte(`;`), ".zk/config.toml", NewDefaultConfig(), false)
 	assert.NotNil(t, err)
 }
 
+func TestParseInvalidValueType(t *testing.T) {
+	test := func(toml string, expectedErr string) {
+		_, err := ParseConfig([]byte(toml), ".zk/config.toml", NewDefaultConfig(), false)
+		assert.Err(t, err, expectedErr)
+	}
+
+	test(`
+		[note]
+		id-length = "long"
+	`, "failed to read config: note.id-length: (3, 3): Can't convert long(string) to int")
+	test(`
+		[group.journal.note]
+		id-length = true
+	`, "failed to read config: group.journal.note.id-length: (3, 3): Can't convert true(bool) to int")
+	test(`
+		[tool]
+		editor-max-notes = "ten"
+	`, "failed to read config: tool.editor-max-notes: (3, 3): Can't convert ten(string) to int")
+}
+
 func TestParseComplete(t *testing.T) {
 	conf, err := ParseConfig([]byte(`
 		# Comment
 
 		[notebook]
 		dir = "~/notebook"
+		checksum = "fnv64"
+		checksum-trim-spaces = true
+		follow-symlinks = true
+		max-note-size = 1024
+		busy-timeout = "500ms"
+		raw-content = false
 
 		[note]
 		filename = "{{id}}.note"
 		extension = "txt"
+		extensions = ["md", ".markdown"]
 		template = "default.note"
 		language = "fr"
 		default-title = "Sans titre"
@@ -82,19 +123,25 @@ func TestParseComplete(t *testing.T) {
 		id-length = 4
 		id-case = "lower"
 		exclude = ["ignored", ".git"]
+		filename-date-format = "2006-01-02"
 
 		[format.markdown]
 		hashtags = false
 		colon-tags = true
 		multiword-tags = true
+		bracket-tags = true
+		lowercase-tags = true
 		link-format = "custom"
 		link-encode-path = true
 		link-drop-extension = false
 
 		[tool]
 		editor = "vim"
+		editor-max-notes = 10
 		shell = "/bin/bash"
+		alias-override = true
 		pager = "less"
+		fzf-path = "/opt/fzf/bin/fzf"
 		fzf-preview = "bat {1}"
 		fzf-line = "{{title}}"
 		fzf-options = "--border --height 40%"
@@ -118,6 +165,7 @@ func TestParseComplete(t *testing.T) {
 		[group.log.note]
 		filename = "{{date}}.md"
 		extension = "note"
+		extensions = ["journal"]
 		template = "log.md"
 		language = "de"
 		default-title = "Ohne Titel"
@@ -146,23 +194,32 @@ func TestParseComplete(t *testing.T) {
 		dead-link = "none"
 	`), ".zk/config.toml", NewDefaultConfig(), true)
 
+	assert.Nil(t, err)
+	usr, err := user.Current()
 	assert.Nil(t, err)
 	assert.Equal(t, conf, Config{
 		Notebook: NotebookConfig{
-			Dir: opt.NewString("~/notebook"),
+			Dir:                opt.NewString(usr.HomeDir + "/notebook"),
+			Checksum:           ChecksumFNV64,
+			ChecksumTrimSpaces: true,
+			FollowSymlinks:     true,
+			MaxNoteSize:        1024,
+			BusyTimeout:        500 * time.Millisecond,
 		},
 		Note: NoteConfig{
 			FilenameTemplate: "{{id}}.note",
 			Extension:        "txt",
+			Extensions:       []string{"md", "markdown"},
 			BodyTemplatePath: opt.NewString("default.note"),
 			IDOptions: IDOptions{
 				Length:  4,
 				Charset: CharsetAlphanum,
 				Case:    CaseLower,
 			},
-			Lang:         "fr",
-			DefaultTitle: "Sans titre",
-			Exclude:      []string{"ignored", ".git"},
+			Lang:               "fr",
+			DefaultTitle:       "Sans titre",
+			Exclude:            []string{"ignored", ".git"},
+			FilenameDateFormat: "2006-01-02",
 		},
 		Groups: map[string]GroupConfig{
 			"log": {
@@ -170,15 +227,17 @@ func TestParseComplete(t *testing.T) {
 				Note: NoteConfig{
 					FilenameTemplate: "{{date}}.md",
 					Extension:        "note",
+					Extensions:       []string{"journal"},
 					BodyTemplatePath: opt.NewString("log.md"),
 					IDOptions: IDOptions{
 						Length:  8,
 						Charset: CharsetLetters,
 						Case:    CaseMixed,
 					},
-					Lang:         "de",
-					DefaultTitle: "Ohne Titel",
-					Exclude:      []string{"ignored", ".git", "new-ignored"},
+					Lang:               "de",
+					DefaultTitle:       "Ohne Titel",
+					Exclude:            []string{"ignored", ".git", "new-ignored"},
+					FilenameDateFormat: "2006-01-02",
 				},
 				Extra: map[string]string{
 					"hello":   "world",
@@ -191,15 +250,17 @@ func TestParseComplete(t *testing.T) {
 				Note: NoteConfig{
 					FilenameTemplate: "{{slug title}}.md",
 					Extension:        "txt",
+					Extensions:       []string{"md", "markdown"},
 					BodyTemplatePath: opt.NewString("default.note"),
 					IDOptions: IDOptions{
 						Length:  4,
 						Charset: CharsetAlphanum,
 						Case:    CaseLower,
 					},
-					Lang:         "fr",
-					DefaultTitle: "Sans titre",
-					Exclude:      []string{"ignored", ".git"},
+					Lang:               "fr",
+					DefaultTitle:       "Sans titre",
+					Exclude:            []string{"ignored", ".git"},
+					FilenameDateFormat: "2006-01-02",
 				},
 				Extra: map[string]string{
 					"hello": "world",
@@ -211,15 +272,17 @@ func TestParseComplete(t *testing.T) {
 				Note: NoteConfig{
 					FilenameTemplate: "{{id}}.note",
 					Extension:        "txt",
+					Extensions:       []string{"md", "markdown"},
 					BodyTemplatePath: opt.NewString("default.note"),
 					IDOptions: IDOptions{
 						Length:  4,
 						Charset: CharsetAlphanum,
 						Case:    CaseLower,
 					},
-					Lang:         "fr",
-					DefaultTitle: "Sans titre",
-					Exclude:      []string{"ignored", ".git"},
+					Lang:               "fr",
+					DefaultTitle:       "Sans titre",
+					Exclude:            []string{"ignored", ".git"},
+					FilenameDateFormat: "2006-01-02",
 				},
 				Extra: map[string]string{
 					"hello": "world",
@@ -232,19 +295,24 @@ func TestParseComplete(t *testing.T) {
 				Hashtags:          false,
 				ColonTags:         true,
 				MultiwordTags:     true,
+				BracketTags:       true,
+				LowercaseTags:     true,
 				LinkFormat:        "custom",
 				LinkEncodePath:    true,
 				LinkDropExtension: false,
 			},
 		},
 		Tool: ToolConfig{
-			Editor:     opt.NewString("vim"),
-			Shell:      opt.NewString("/bin/bash"),
-			Pager:      opt.NewString("less"),
-			FzfPreview: opt.NewString("bat {1}"),
-			FzfLine:    opt.NewString("{{title}}"),
-			FzfOptions: opt.NewString("--border --height 40%"),
-			FzfBindNew: opt.NewString("Ctrl-C"),
+			Editor:         opt.NewString("vim"),
+			EditorMaxNotes: 10,
+			Shell:          opt.NewString("/bin/bash"),
+			AliasOverride:  true,
+			Pager:          opt.NewString("less"),
+			FzfPath:        opt.NewString("/opt/fzf/bin/fzf"),
+			FzfPreview:     opt.NewString("bat {1}"),
+			FzfLine:        opt.NewString("{{title}}"),
+			FzfOptions:     opt.NewString("--border --height 40%"),
+			FzfBindNew:     opt.NewString("Ctrl-C"),
 		},
 		LSP: LSPConfig{
 			Completion: LSPCompletionConfig{
@@ -275,6 +343,46 @@ func TestParseComplete(t *testing.T) {
 	})
 }
 
+// The notebook config overrides only the keys it sets from the global config.
+func TestParseMergesParentConfig(t *testing.T) {
+	global, err := ParseConfig([]byte(`
+		[note]
+		language = "fr"
+		id-length = 8
+
+		[tool]
+		editor = "vim"
+		pager = "less"
+
+		[alias]
+		ls = "zk list $@"
+		ed = "zk edit $@"
+	`), "/home/user/.config/zk/config.toml", NewDefaultConfig(), true)
+	assert.Nil(t, err)
+
+	conf, err := ParseConfig([]byte(`
+		[note]
+		id-length = 4
+
+		[tool]
+		pager = ""
+
+		[alias]
+		ls = "zk list --sort created $@"
+	`), "/notebook/.zk/config.toml", global, false)
+	assert.Nil(t, err)
+
+	assert.Equal(t, conf.Note.Lang, "fr")
+	assert.Equal(t, conf.Note.IDOptions.Length, 4)
+	assert.Equal(t, conf.Note.IDOptions.Charset, NewDefaultConfig().Note.IDOptions.Charset)
+	assert.Equal(t, conf.Tool.Editor, opt.NewString("vim"))
+	assert.Equal(t, conf.Tool.Pager, opt.NewString(""))
+	assert.Equal(t, conf.Aliases, map[string]string{
+		"ls": "zk list --sort created $@",
+		"ed": "zk edit $@",
+	})
+}
+
 func TestParseMergesGroupConfig(t *testing.T) {
 	conf, err := ParseConfig([]byte(`
 		[note]
@@ -308,6 +416,12 @@ func TestParseMergesGroupConfig(t *testing.T) {
 
 	assert.Nil(t, err)
 	assert.Equal(t, conf, Config{
+		Notebook: NotebookConfig{
+			Checksum:    ChecksumSHA256,
+			MaxNoteSize: 5242880,
+			BusyTimeout: 5 * time.Second,
+			RawContent:  true,
+		},
 		Note: NoteConfig{
 			FilenameTemplate: "root-filename",
 			Extension:        "txt",
@@ -374,6 +488,9 @@ func TestParseMergesGroupConfig(t *testing.T) {
 				LinkDropExtension: true,
 			},
 		},
+		Tool: ToolConfig{
+			EditorMaxNotes: 5,
+		},
 		LSP: LSPConfig{
 			Completion: LSPCompletionConfig{
 				Note: LSPCompletionTemplates{
@@ -428,6 +545,148 @@ func TestParseNotebook(t *testing.T) {
 	assert.Err(t, err, "notebook.dir should not be set on local configuration")
 }
 
+func TestParseNegativeMaxNoteSize(t *testing.T) {
+	_, err := ParseConfig([]byte(`
+		[notebook]
+		max-note-size = -1
+	`), ".zk/config.toml", NewDefaultConfig(), false)
+	assert.Err(t, err, "notebook.max-note-size should not be negative")
+}
+
+func TestParseBusyTimeout(t *testing.T) {
+	conf, err := ParseConfig([]byte(`
+		[notebook]
+		busy-timeout = "1m30s"
+	`), ".zk/config.toml", NewDefaultConfig(), false)
+	assert.Nil(t, err)
+	assert.Equal(t, conf.Notebook.BusyTimeout, 90*time.Second)
+
+	// Disables the waiting.
+	conf, err = ParseConfig([]byte(`
+		[notebook]
+		busy-timeout = "0s"
+	`), ".zk/config.toml", NewDefaultConfig(), false)
+	assert.Nil(t, err)
+	assert.Equal(t, conf.Notebook.BusyTimeout, time.Duration(0))
+
+	_, err = ParseConfig([]byte(`
+		[notebook]
+		busy-timeout = "soon"
+	`), ".zk/config.toml", NewDefaultConfig(), false)
+	assert.Err(t, err, "notebook.busy-timeout: time: invalid duration \"soon\"")
+
+	_, err = ParseConfig([]byte(`
+		[notebook]
+		busy-timeout = "-1s"
+	`), ".zk/config.toml", NewDefaultConfig(), false)
+	assert.Err(t, err, "notebook.busy-timeout should not be negative")
+}
+
+func TestParseNegativeIDLength(t *testing.T) {
+	_, err := ParseConfig([]byte(`
+		[note]
+		id-length = -4
+	`), ".zk/config.toml", NewDefaultConfig(), false)
+	assert.Err(t, err, "note.id-length should not be negative")
+
+	_, err = ParseConfig([]byte(`
+		[group.journal.note]
+		id-length = -4
+	`), ".zk/config.toml", NewDefaultConfig(), false)
+	assert.Err(t, err, "group.journal.note.id-length should not be negative")
+}
+
+func TestParseFilterWithUnknownSortTerm(t *testing.T) {
+	_, err := ParseConfig([]byte(`
+		[filter]
+		recents = "--sort created- --limit 20"
+		short = "-s title,wc+"
+		inline = "--sort=modified"
+	`), ".zk/config.toml", NewDefaultConfig(), false)
+	assert.Nil(t, err)
+
+	_, err = ParseConfig([]byte(`
+		[filter]
+		recents = "--sort creation- --limit 20"
+	`), ".zk/config.toml", NewDefaultConfig(), false)
+	assert.Err(t, err, "filter.recents: creation: unknown sorting term")
+
+	_, err = ParseConfig([]byte(`
+		[filter]
+		short = "-s title,size"
+	`), ".zk/config.toml", NewDefaultConfig(), false)
+	assert.Err(t, err, "filter.short: size: unknown sorting term")
+}
+
+func TestParseNegativeEditorMaxNotes(t *testing.T) {
+	_, err := ParseConfig([]byte(`
+		[tool]
+		editor-max-notes = -1
+	`), ".zk/config.toml", NewDefaultConfig(), false)
+	assert.Err(t, err, "tool.editor-max-notes should not be negative")
+}
+
+func TestParseExpandsPathsAndCommands(t *testing.T) {
+	usr, err := user.Current()
+	assert.Nil(t, err)
+	t.Setenv("ZK_TEST_EDITOR", "nvim")
+	t.Setenv("ZK_TEST_DIR", "/opt/zk")
+	t.Setenv("ZK_TEST_UNSET", "")
+
+	conf, err := ParseConfig([]byte(`
+		[notebook]
+		dir = "~/notes/inbox"
+
+		[note]
+		filename = "{{id}}-$ZK_TEST_DIR"
+		template = "${ZK_TEST_DIR}/default.md"
+
+		[group.journal.note]
+		template = "~/journal.md"
+
+		[tool]
+		editor = "$ZK_TEST_EDITOR"
+		shell = "~/bin/zsh -l"
+		pager = "$ZK_TEST_UNSET"
+		fzf-path = "$ZK_TEST_DIR/fzf"
+		fzf-preview = "bat $HOME/{-1}"
+
+		[alias]
+		ls = "zk list $@"
+	`), "/home/user/.config/zk/config.toml", NewDefaultConfig(), true)
+	assert.Nil(t, err)
+
+	assert.Equal(t, conf.Notebook.Dir, opt.NewString(usr.HomeDir+"/notes/inbox"))
+	assert.Equal(t, conf.Note.BodyTemplatePath, opt.NewString("/opt/zk/default.md"))
+	assert.Equal(t, conf.Groups["journal"].Note.BodyTemplatePath, opt.NewString(usr.HomeDir+"/journal.md"))
+	assert.Equal(t, conf.Tool.Editor, opt.NewString("nvim"))
+	assert.Equal(t, conf.Tool.Shell, opt.NewString(usr.HomeDir+"/bin/zsh -l"))
+	assert.Equal(t, conf.Tool.FzfPath, opt.NewString("/opt/zk/fzf"))
+	// A pager expanded to an empty string keeps the default one.
+	assert.Equal(t, conf.Tool.Pager, opt.NullString)
+
+	// The templates and aliases have their own syntax.
+	assert.Equal(t, conf.Note.FilenameTemplate, "{{id}}-$ZK_TEST_DIR")
+	assert.Equal(t, conf.Tool.FzfPreview, opt.NewString("bat $HOME/{-1}"))
+	assert.Equal(t, conf.Aliases["ls"], "zk list $@")
+}
+
+func TestParseKeepsTheVariablesInsideCommands(t *testing.T) {
+	t.Setenv("ZK_TEST_EDITOR", "nvim")
+
+	conf, err := ParseConfig([]byte(`
+		[tool]
+		editor = "sh -c 'vim \"$1\"' --"
+		shell = "$ZK_TEST_EDITOR -u NONE"
+		pager = "less ~/.lesskey"
+	`), ".zk/config.toml", NewDefaultConfig(), false)
+	assert.Nil(t, err)
+
+	assert.Equal(t, conf.Tool.Editor, opt.NewString(`sh -c 'vim "$1"' --`))
+	assert.Equal(t, conf.Tool.Shell, opt.NewString("$ZK_TEST_EDITOR -u NONE"))
+	assert.Equal(t, conf.Tool.Pager, opt.NewString("less ~/.lesskey"))
+}
+
 func TestParseIDCharset(t *testing.T) {
 	test := func(charset string, expected Charset) {
 		toml := fmt.Sprintf(`
@@ -465,7 +724,18 @@ func TestParseIDCase(t *testing.T) {
 	test("lower", CaseLower)
 	test("upper", CaseUpper)
 	test("mixed", CaseMixed)
-	test("unknown", CaseLower)
+
+	_, err := ParseConfig([]byte(`
+		[note]
+		id-case = "unknown"
+	`), ".zk/config.toml", NewDefaultConfig(), false)
+	assert.Err(t, err, "note.id-case: unknown: unknown case - may be lower, upper or mixed")
+
+	_, err = ParseConfig([]byte(`
+		[group.journal.note]
+		id-case = "unknown"
+	`), ".zk/config.toml", NewDefaultConfig(), false)
+	assert.Err(t, err, "group.journal.note.id-case: unknown: unknown case - may be lower, upper or mixed")
 }
 
 // If link-encode-path is not set explicitly, it defaults to true for
@@ -512,7 +782,7 @@ func TestParseLSPDiagnosticsSeverity(t *testing.T) {
 		wiki-title = "foobar"
 	`
 	_, err := ParseConfig([]byte(toml), ".zk/config.toml", NewDefaultConfig(), false)
-	assert.Err(t, err, "foobar: unknown LSP diagnostic severity - may be none, hint, info, warning or error")
+	assert.Err(t, err, "lsp.diagnostics.wiki-title: foobar: unknown LSP diagnostic severity - may be none, hint, info, warning or error")
 }
 
 func TestGroupConfigExcludeGlobs(t *testing.T) {
@@ -600,3 +870,81 @@ func TestGroupConfigClone(t *testing.T) {
 		},
 	})
 }
+
+func TestConfigGroupNameForPath(t *testing.T) {
+	conf := Config{
+		Groups: map[string]GroupConfig{
+			"log":     {Paths: []string{"log"}},
+			"work":    {Paths: []string{"log/work"}},
+			"drafts":  {Paths: []string{"drafts", "*-draft"}},
+			"journal": {Paths: []string{"journal"}},
+			"diary":   {Paths: []string{"journal"}},
+		},
+	}
+
+	test := func(path string, expected string) {
+		name, err := conf.GroupNameForPath(path)
+		assert.Nil(t, err)
+		assert.Equal(t, name, expected)
+	}
+
+	// default group
+	test("", "")
+	test("ref", "")
+	test("logbook", "")
+	// exact path
+	test("log", "log")
+	test("drafts", "drafts")
+	// descendants
+	test("log/2021", "log")
+	test("log/2021/01", "log")
+	// longest prefix
+	test("log/work", "work")
+	test("log/work/2021", "work")
+	test("log/workshop", "log")
+	// globs
+	test("book-draft", "drafts")
+	// same path in several groups
+	test("journal/2021", "diary")
+}
+
+func TestConfigGroupConfigForPath(t *testing.T) {
+	logConfig := GroupConfig{
+		Paths: []string{"log"},
+		Note: NoteConfig{
+			FilenameTemplate: "{{format-date now '%Y-%m-%d'}}",
+			Extension:        "txt",
+			BodyTemplatePath: opt.NewString("daily.md"),
+			IDOptions: IDOptions{
+				Length:  8,
+				Charset: CharsetHex,
+				Case:    CaseUpper,
+			},
+		},
+		Extra: map[string]string{},
+	}
+	conf := NewDefaultConfig()
+	conf.Groups["log"] = logConfig
+
+	group, err := conf.GroupConfigForPath("log/2021")
+	assert.Nil(t, err)
+	assert.Equal(t, group, logConfig)
+
+	// The root directory uses the default group config.
+	group, err = conf.GroupConfigForPath("ref")
+	assert.Nil(t, err)
+	assert.Equal(t, group, conf.RootGroupConfig())
+}
+
+func TestNoteConfigIndexedExtensions(t *testing.T) {
+	test := func(extension string, extensions []string, expected []string) {
+		conf := NoteConfig{Extension: extension, Extensions: extensions}
+		assert.Equal(t, conf.IndexedExtensions(), expected)
+	}
+
+	test("md", nil, []string{"md"})
+	test("md", []string{}, []string{"md"})
+	test("md", []string{"markdown", "txt"}, []string{"md", "markdown", "txt"})
+	// The extension of the new notes is always indexed.
+	test("txt", []string{"md", "txt"}, []string{"txt", "md"})
+}
@@ -1,5 +1,7 @@
 package core
 
+import "strings"
+
 // LinkID represents the unique ID of a note link relative to a given
 // NoteIndex implementation.
 type LinkID int64
@@ -28,6 +30,16 @@ type Link struct {
 	SnippetEnd int `json:"snippetEnd"`
 }
 
+// Fragment returns the part of the href following a #, which usually
+// identifies a heading in the target note, e.g. [[note#Section]].
+func (l Link) Fragment() string {
+	parts := strings.SplitN(l.Href, "#", 2)
+	if len(parts) < 2 {
+		return ""
+	}
+	return parts[1]
+}
+
 // ResolvedLink represents a link between two indexed notes.
 type ResolvedLink struct {
 	Link
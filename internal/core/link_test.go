@@ -0,0 +1,20 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/zk-org/zk/internal/util/test/assert"
+)
+
+func TestLinkFragment(t *testing.T) {
+	test := func(href string, expected string) {
+		assert.Equal(t, Link{Href: href}.Fragment(), expected)
+	}
+
+	test("", "")
+	test("f39c8", "")
+	test("f39c8#", "")
+	test("f39c8#Section", "Section")
+	test("dir/note.md#Section#Sub", "Section#Sub")
+	test("https://example.com/page#anchor", "anchor")
+}
@@ -47,6 +47,8 @@ type Note struct {
 	Links []Link
 	// List of tags found in the content.
 	Tags []string
+	// Alternative names of the note, from the frontmatter.
+	Aliases []string
 	// JSON dictionary of raw metadata extracted from the frontmatter.
 	Metadata map[string]interface{}
 	// Date of creation.
@@ -55,6 +57,8 @@ type Note struct {
 	Modified time.Time
 	// Checksum of the note content.
 	Checksum string
+	// Size of the note file, in bytes.
+	Size int64
 }
 
 func (n Note) AsMinimalNote() MinimalNote {
@@ -86,4 +90,12 @@ type ContextualNote struct {
 	Note
 	// List of context-sensitive excerpts from the note.
 	Snippets []string
+	// Relevance of the note for a full-text search, higher is better.
+	// It is zero for the other match strategies.
+	Score float64
+	// Line and column of the first matched term in the raw content of the
+	// note, starting at 1. They are zero when the note was not found with a
+	// match filter, or when the term could not be located.
+	MatchLine   int
+	MatchColumn int
 }
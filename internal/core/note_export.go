@@ -0,0 +1,283 @@
+package core
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/zk-org/zk/internal/util/errors"
+	"github.com/zk-org/zk/internal/util/paths"
+	strutil "github.com/zk-org/zk/internal/util/strings"
+)
+
+// NoteHTMLRenderer renders the raw content of a note to HTML.
+type NoteHTMLRenderer interface {
+	// RenderNoteHTML renders the given note content, rewriting the
+	// destinations of its links and images with the resolver.
+	RenderNoteHTML(content string, resolver HTMLLinkResolver) (string, error)
+}
+
+// HTMLLinkResolver rewrites the destinations of the links and images found
+// while rendering a note to HTML.
+type HTMLLinkResolver interface {
+	// ResolveLink returns the URL of the link with the given href, or false
+	// if it is a dead link.
+	ResolveLink(href string, linkType LinkType) (string, bool)
+	// ResolveImage returns the URL of the image with the given source.
+	ResolveImage(src string) string
+}
+
+// HTMLExportStats holds statistics about an HTML export.
+type HTMLExportStats struct {
+	// Number of exported notes.
+	NoteCount int
+	// Number of local images copied along the notes.
+	ImageCount int
+	// Number of other local files linked from the notes and copied along
+	// them, e.g. PDF documents.
+	FileCount int
+}
+
+// ExportHTML renders the given notes to HTML files in outputDir, keeping the
+// directory structure of the notebook, with an index.html page listing them.
+//
+// The links between exported notes are rewritten to target their HTML files,
+// using the links resolved by the index. Links to other notes are rendered as
+// dead links. Local images and the other local files linked from the notes are
+// copied in outputDir.
+func (n *Notebook) ExportHTML(notes []ContextualNote, outputDir string) (HTMLExportStats, error) {
+	wrap := errors.Wrapper("html export")
+	stats := HTMLExportStats{}
+
+	if n.htmlRenderer == nil {
+		return stats, wrap(errors.New("no HTML renderer available"))
+	}
+
+	notes = append([]ContextualNote{}, notes...)
+	sort.SliceStable(notes, func(i, j int) bool {
+		return notes[i].Path < notes[j].Path
+	})
+
+	ids := []NoteID{}
+	for _, note := range notes {
+		ids = append(ids, note.ID)
+	}
+	links, err := n.index.FindLinksBetweenNotes(ids)
+	if err != nil {
+		return stats, wrap(err)
+	}
+	// Target paths indexed by the source note and the link href.
+	targets := map[NoteID]map[string]string{}
+	for _, link := range links {
+		if targets[link.SourceID] == nil {
+			targets[link.SourceID] = map[string]string{}
+		}
+		targets[link.SourceID][link.Href] = link.TargetPath
+	}
+
+	copiedImages := map[string]bool{}
+	copiedFiles := map[string]bool{}
+	for _, note := range notes {
+		resolver := &htmlExportResolver{
+			notebook:     n,
+			note:         note.Note,
+			targets:      targets[note.ID],
+			outputDir:    outputDir,
+			copiedImages: copiedImages,
+			copiedFiles:  copiedFiles,
+		}
+		body, err := n.htmlRendererForPath(note.Path).RenderNoteHTML(note.RawContent, resolver)
+		if err != nil {
+			return stats, wrap(errors.Wrap(err, note.Path))
+		}
+
+		path := htmlExportPath(note.Path)
+		indexHref := htmlExportHref(filepath.Dir(path), "index.html", "")
+		page := fmt.Sprintf(htmlExportPageTemplate, html.EscapeString(htmlExportTitle(note.Note)), indexHref, body)
+		err = n.fs.Write(filepath.Join(outputDir, path), []byte(page))
+		if err != nil {
+			return stats, wrap(err)
+		}
+		stats.NoteCount++
+	}
+	stats.ImageCount = len(copiedImages)
+	stats.FileCount = len(copiedFiles)
+
+	err = n.fs.Write(filepath.Join(outputDir, "index.html"), []byte(htmlExportIndex(notes)))
+	return stats, wrap(err)
+}
+
+// htmlRendererForPath returns the HTML renderer of the note at the given path,
+// selected by its file extension among the NoteHTMLRenderers port, e.g. org.
+// Markdown is the default format.
+func (n *Notebook) htmlRendererForPath(path string) NoteHTMLRenderer {
+	ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(path), "."))
+	if renderer, ok := n.htmlRenderers[ext]; ok {
+		return renderer
+	}
+	return n.htmlRenderer
+}
+
+const htmlExportPageTemplate = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>%s</title>
+</head>
+<body>
+<nav><a href="%s">Index</a></nav>
+<main>
+%s</main>
+</body>
+</html>
+`
+
+// htmlExportIndex renders the index page listing the notes by directory,
+// starting with the root of the notebook.
+func htmlExportIndex(notes []ContextualNote) string {
+	dirs := []string{}
+	notesByDir := map[string][]Note{}
+	for _, note := range notes {
+		dir := filepath.Dir(note.Path)
+		if dir == "." {
+			dir = ""
+		}
+		if _, ok := notesByDir[dir]; !ok {
+			dirs = append(dirs, dir)
+		}
+		notesByDir[dir] = append(notesByDir[dir], note.Note)
+	}
+	sort.Strings(dirs)
+
+	var body strings.Builder
+	for _, dir := range dirs {
+		if dir != "" {
+			fmt.Fprintf(&body, "<h2>%s</h2>\n", html.EscapeString(filepath.ToSlash(dir)))
+		}
+		body.WriteString("<ul>\n")
+		for _, note := range notesByDir[dir] {
+			href := htmlExportHref("", htmlExportPath(note.Path), "")
+			fmt.Fprintf(&body, "<li><a href=\"%s\">%s</a></li>\n", href, html.EscapeString(htmlExportTitle(note)))
+		}
+		body.WriteString("</ul>\n")
+	}
+
+	return fmt.Sprintf(htmlExportPageTemplate, "Index", "index.html", body.String())
+}
+
+// htmlExportTitle returns the title of the HTML page of a note, falling back
+// on its filename.
+func htmlExportTitle(note Note) string {
+	if note.Title != "" {
+		return note.Title
+	}
+	return paths.FilenameStem(note.Path)
+}
+
+// htmlExportPath returns the path of the HTML file of the note at the given
+// path, relative to the output directory.
+func htmlExportPath(notePath string) string {
+	return paths.DropExt(notePath) + ".html"
+}
+
+// htmlExportHref returns the URL of the file at path, relative to the
+// directory baseDir.
+func htmlExportHref(baseDir string, path string, fragment string) string {
+	rel, err := filepath.Rel(baseDir, path)
+	if err != nil {
+		rel = path
+	}
+	u := url.URL{Path: filepath.ToSlash(rel), Fragment: fragment}
+	return u.String()
+}
+
+// htmlExportResolver implements HTMLLinkResolver for the links of a single
+// note.
+type htmlExportResolver struct {
+	notebook *Notebook
+	note     Note
+	// Paths of the target notes, indexed by the link hrefs.
+	targets   map[string]string
+	outputDir string
+	// Paths of the images and other files already copied, shared between
+	// the notes.
+	copiedImages map[string]bool
+	copiedFiles  map[string]bool
+}
+
+func (r *htmlExportResolver) ResolveLink(href string, linkType LinkType) (string, bool) {
+	if strings.HasPrefix(href, "#") || strutil.IsURL(href) {
+		return href, true
+	}
+	// The index stores the Markdown links relative to the notebook root.
+	key := href
+	if linkType == LinkTypeMarkdown {
+		key = filepath.Join(filepath.Dir(r.note.Path), href)
+	}
+	target, ok := r.targets[key]
+	if !ok {
+		if linkType == LinkTypeMarkdown && r.isLocalFile(href) {
+			return href, r.copy(href, "file", r.copiedFiles)
+		}
+		return "", false
+	}
+
+	fragment := ""
+	if i := strings.Index(href, "#"); i >= 0 {
+		fragment = href[i+1:]
+	}
+	return htmlExportHref(filepath.Dir(r.note.Path), htmlExportPath(target), fragment), true
+}
+
+func (r *htmlExportResolver) ResolveImage(src string) string {
+	if src == "" || strutil.IsURL(src) || filepath.IsAbs(src) {
+		return src
+	}
+	r.copy(src, "image", r.copiedImages)
+	return src
+}
+
+// isLocalFile returns whether href targets an existing file of the notebook
+// which is not a note, e.g. a PDF document.
+func (r *htmlExportResolver) isLocalFile(href string) bool {
+	href = strings.SplitN(href, "#", 2)[0]
+	if href == "" || filepath.IsAbs(href) {
+		return false
+	}
+	ext := strings.TrimPrefix(filepath.Ext(href), ".")
+	if strutil.Contains(r.notebook.Config.Note.IndexedExtensions(), ext) {
+		return false
+	}
+	path := filepath.Join(r.notebook.Path, filepath.Dir(r.note.Path), href)
+	exists, err := r.notebook.fs.FileExists(path)
+	return err == nil && exists
+}
+
+// copy copies the local file at href, relative to the note, in the output
+// directory, unless it was already copied. It returns whether the file is
+// available in the output directory.
+func (r *htmlExportResolver) copy(href string, kind string, copied map[string]bool) bool {
+	href = strings.SplitN(href, "#", 2)[0]
+	path := filepath.Join(filepath.Dir(r.note.Path), href)
+	if strings.HasPrefix(path, "..") {
+		r.notebook.logger.Printf("warning: %s: %s %s outside of the notebook is not copied", r.note.Path, kind, href)
+		return false
+	}
+	if copied[path] {
+		return true
+	}
+
+	content, err := r.notebook.fs.Read(filepath.Join(r.notebook.Path, path))
+	if err == nil {
+		err = r.notebook.fs.Write(filepath.Join(r.outputDir, path), content)
+	}
+	if err != nil {
+		r.notebook.logger.Err(errors.Wrapf(err, "%s: failed to copy %s", r.note.Path, kind))
+		return false
+	}
+	copied[path] = true
+	return true
+}
@@ -0,0 +1,173 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/zk-org/zk/internal/util"
+	"github.com/zk-org/zk/internal/util/test/assert"
+)
+
+func TestNotebookExportHTML(t *testing.T) {
+	fs := newFileStorageMock("/notebook", []string{})
+	fs.files["/notebook/images/tomato.svg"] = "<svg/>"
+	fs.files["/notebook/docs/plan.pdf"] = "%PDF"
+	// A note which is not exported stays a dead link.
+	fs.files["/notebook/journal/dead.md"] = "# Dead"
+
+	index := &noteIndexExportMock{links: []ResolvedLink{
+		{SourceID: 1, TargetID: 2, TargetPath: "journal/day.md", Link: Link{Href: "journal/day"}},
+		{SourceID: 1, TargetID: 2, TargetPath: "journal/day.md", Link: Link{Href: "journal/day#morning"}},
+		{SourceID: 2, TargetID: 1, TargetPath: "welcome.md", Link: Link{Href: "welcome.md"}},
+	}}
+	notebook := NewNotebook("/notebook", NewDefaultConfig(), NotebookPorts{
+		NoteIndex:        index,
+		NoteHTMLRenderer: &noteHTMLRendererMock{},
+		FS:               fs,
+		Logger:           &util.NullLogger,
+	})
+
+	notes := []ContextualNote{
+		{Note: Note{ID: 2, Path: "journal/day.md", Title: "", RawContent: "link ../welcome.md\nimage ../images/tomato.svg\nlink dead.md\nlink ../docs/plan.pdf"}},
+		{Note: Note{ID: 1, Path: "welcome.md", Title: "Welcome <home>", RawContent: "link journal/day\nlink journal/day#morning\nlink #section\nlink https://example.com\nimage images/tomato.svg\nimage ../outside.png"}},
+	}
+
+	stats, err := notebook.ExportHTML(notes, "/out")
+	assert.Nil(t, err)
+	assert.Equal(t, stats, HTMLExportStats{NoteCount: 2, ImageCount: 1, FileCount: 1})
+
+	assert.Equal(t, index.receivedIDs, []NoteID{2, 1})
+	assert.Equal(t, fs.files["/out/images/tomato.svg"], "<svg/>")
+	assert.Equal(t, fs.files["/out/docs/plan.pdf"], "%PDF")
+
+	assert.Equal(t, fs.files["/out/welcome.html"], `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Welcome &lt;home&gt;</title>
+</head>
+<body>
+<nav><a href="index.html">Index</a></nav>
+<main>
+journal/day.html
+journal/day.html#morning
+#section
+https://example.com
+images/tomato.svg
+../outside.png
+</main>
+</body>
+</html>
+`)
+
+	assert.Equal(t, fs.files["/out/journal/day.html"], `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>day</title>
+</head>
+<body>
+<nav><a href="../index.html">Index</a></nav>
+<main>
+../welcome.html
+../images/tomato.svg
+dead
+../docs/plan.pdf
+</main>
+</body>
+</html>
+`)
+
+	assert.Equal(t, fs.files["/out/index.html"], `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Index</title>
+</head>
+<body>
+<nav><a href="index.html">Index</a></nav>
+<main>
+<ul>
+<li><a href="welcome.html">Welcome &lt;home&gt;</a></li>
+</ul>
+<h2>journal</h2>
+<ul>
+<li><a href="journal/day.html">day</a></li>
+</ul>
+</main>
+</body>
+</html>
+`)
+}
+
+func TestNotebookExportHTMLSelectsTheRendererByExtension(t *testing.T) {
+	fs := newFileStorageMock("/notebook", []string{})
+	notebook := NewNotebook("/notebook", NewDefaultConfig(), NotebookPorts{
+		NoteIndex:        &noteIndexExportMock{},
+		NoteHTMLRenderer: &noteHTMLRendererMock{},
+		NoteHTMLRenderers: map[string]NoteHTMLRenderer{
+			"org": &noteHTMLRendererMock{prefix: "org "},
+		},
+		FS:     fs,
+		Logger: &util.NullLogger,
+	})
+
+	notes := []ContextualNote{
+		{Note: Note{ID: 1, Path: "sun.ORG", RawContent: "link #section"}},
+		{Note: Note{ID: 2, Path: "welcome.md", RawContent: "link #section"}},
+	}
+
+	_, err := notebook.ExportHTML(notes, "/out")
+	assert.Nil(t, err)
+	assert.True(t, strings.Contains(fs.files["/out/sun.html"], "<main>\norg #section\n</main>"))
+	assert.True(t, strings.Contains(fs.files["/out/welcome.html"], "<main>\n#section\n</main>"))
+}
+
+func TestHTMLExportHref(t *testing.T) {
+	test := func(baseDir, path, fragment, expected string) {
+		assert.Equal(t, htmlExportHref(baseDir, path, fragment), expected)
+	}
+
+	test("", "note.html", "", "note.html")
+	test(".", "dir/note.html", "", "dir/note.html")
+	test("dir", "note.html", "", "../note.html")
+	test("dir", "dir/sub/note.html", "section", "sub/note.html#section")
+	test("", "my note.html", "", "my%20note.html")
+}
+
+// noteIndexExportMock returns static links between the exported notes.
+type noteIndexExportMock struct {
+	noteIndexAddMock
+	links       []ResolvedLink
+	receivedIDs []NoteID
+}
+
+func (m *noteIndexExportMock) FindLinksBetweenNotes(ids []NoteID) ([]ResolvedLink, error) {
+	m.receivedIDs = ids
+	return m.links, nil
+}
+
+// noteHTMLRendererMock renders each `link <href>` or `image <src>` line of
+// the content to the URL returned by the resolver, after the prefix.
+type noteHTMLRendererMock struct {
+	prefix string
+}
+
+func (m *noteHTMLRendererMock) RenderNoteHTML(content string, resolver HTMLLinkResolver) (string, error) {
+	var out strings.Builder
+	for _, line := range strings.Split(content, "\n") {
+		kind, dest, _ := strings.Cut(line, " ")
+		switch kind {
+		case "link":
+			if url, ok := resolver.ResolveLink(dest, LinkTypeMarkdown); ok {
+				fmt.Fprintln(&out, m.prefix+url)
+			} else {
+				fmt.Fprintln(&out, "dead")
+			}
+		case "image":
+			fmt.Fprintln(&out, resolver.ResolveImage(dest))
+		}
+	}
+	return out.String(), nil
+}
@@ -13,10 +13,22 @@ type NoteFindOpts struct {
 	Match []string
 	// Text matching strategy used with Match.
 	MatchStrategy MatchStrategy
+	// Part of the notes searched with Match.
+	MatchScope MatchScope
 	// Filter by note hrefs.
 	IncludeHrefs []string
 	// Filter excluding notes at the given hrefs.
 	ExcludeHrefs []string
+	// Filter including notes at the given paths, matched verbatim. A path
+	// without extension also matches the notes with one of the configured
+	// extensions.
+	ExactPaths []string
+	// Filter including notes with a path matching the given regular
+	// expression.
+	PathRegex string
+	// Filter excluding notes with a path matching the given regular
+	// expression.
+	ExcludePathRegex string
 	// Indicates whether href options can match any portion of a path.
 	// This is used for wiki links.
 	AllowPartialHrefs bool
@@ -38,8 +50,12 @@ type NoteFindOpts struct {
 	Related []string
 	// Filter to select notes having no other notes linking to them.
 	Orphan bool
+	// Filter to select notes having at least one link to a missing note.
+	DeadLinks bool
 	// Filter to select notes having no tags.
 	Tagless bool
+	// Filter to select notes having no title.
+	Untitled bool
 	// Filter notes created after the given date.
 	CreatedStart *time.Time
 	// Filter notes created before the given date.
@@ -48,12 +64,41 @@ type NoteFindOpts struct {
 	ModifiedStart *time.Time
 	// Filter notes modified before the given date.
 	ModifiedEnd *time.Time
+	// Filter notes having all the given metadata values.
+	Metadata []MetadataFilter
+	// Filter notes by their number of words.
+	WordCount *WordCountFilter
+	// Maximum number of tokens in a full-text search snippet. Defaults to 20,
+	// with a maximum of 64.
+	SnippetLength int
+	// Maximum number of full-text search snippets for each note, taken from
+	// the title and body when they match. Defaults to 1.
+	MaxSnippets int
+	// Delimiters surrounding the matched terms in the snippets. Defaults to
+	// DefaultSnippetDelimiters when nil.
+	SnippetDelimiters *SnippetDelimiters
 	// Limits the number of results
 	Limit int
-	// Sorting criteria
+	// Number of results to skip, used to page through the notes.
+	Offset int
+	// Sorting criteria, applied in order.
+	// Without any sorter, notes are sorted by their title then path.
 	Sorters []NoteSorter
 }
 
+// SnippetDelimiters holds the strings surrounding the matched terms in a
+// snippet. Empty delimiters disable the highlighting.
+type SnippetDelimiters struct {
+	Open  string
+	Close string
+}
+
+// DefaultSnippetDelimiters are the delimiters expected by the note formatter.
+var DefaultSnippetDelimiters = SnippetDelimiters{
+	Open:  "<zk:match>",
+	Close: "</zk:match>",
+}
+
 // IncludingIDs creates a new FinderOpts after adding the given IDs to the list
 // of excluded note IDs.
 func (o NoteFindOpts) IncludingIDs(ids []NoteID) NoteFindOpts {
@@ -82,6 +127,25 @@ type LinkFilter struct {
 	Negate      bool
 	Recursive   bool
 	MaxDistance int
+	// IncludeUnresolved adds the targets of the unresolved links to the
+	// matches of a LinkedBy filter, as notes with only their Path set to the
+	// link href.
+	IncludeUnresolved bool
+}
+
+// MetadataFilter is a note filter used to select notes with the given value
+// in their metadata. Key is a dot-separated path to a nested value, which is
+// compared as a string, e.g. "true" for a boolean.
+type MetadataFilter struct {
+	Key   string
+	Value string
+}
+
+// WordCountFilter is a note filter used to select notes with a number of
+// words in the inclusive range [Min, Max]. A zero bound is unbounded.
+type WordCountFilter struct {
+	Min int
+	Max int
 }
 
 // NoteSorter represents an order term used to sort a list of notes.
@@ -106,6 +170,8 @@ const (
 	NoteSortTitle
 	// Sort by the number of words in the note bodies.
 	NoteSortWordCount
+	// Sort by relevance for a full-text search.
+	NoteSortRelevance
 )
 
 // NoteSortersFromStrings returns a list of NoteSorter from their string
@@ -148,8 +214,10 @@ func NoteSorterFromString(str string) (NoteSorter, error) {
 		sorter = NoteSorter{Field: NoteSortRandom, Ascending: true}
 	case "word-count", "wc":
 		sorter = NoteSorter{Field: NoteSortWordCount, Ascending: true}
+	case "relevance", "rel":
+		sorter = NoteSorter{Field: NoteSortRelevance, Ascending: false}
 	default:
-		return sorter, fmt.Errorf("%s: unknown sorting term\ntry created, modified, path, title, random or word-count", str)
+		return sorter, fmt.Errorf("%s: unknown sorting term\ntry created, modified, path, title, random, word-count or relevance", str)
 	}
 
 	switch orderSymbol {
@@ -172,8 +240,38 @@ const (
 	MatchStrategyExact
 	// Regular expression.
 	MatchStrategyRe
+	// Full text search of a literal phrase, without any query syntax.
+	MatchStrategyPhrase
+)
+
+// MatchScope represents the part of the notes searched when filtering notes
+// with `--match`.
+type MatchScope int
+
+const (
+	// Search both the title and the body of the notes.
+	MatchScopeAny MatchScope = iota
+	// Search only the title of the notes.
+	MatchScopeTitle
+	// Search only the body of the notes.
+	MatchScopeBody
 )
 
+// InvalidMatchQueryError is returned when a full-text search query can't be
+// parsed.
+type InvalidMatchQueryError struct {
+	Query string
+	Err   error
+}
+
+func (e InvalidMatchQueryError) Error() string {
+	return fmt.Sprintf("%q: invalid search query (%v)\ntry --match-strategy=phrase to search for a literal phrase", e.Query, e.Err)
+}
+
+func (e InvalidMatchQueryError) Unwrap() error {
+	return e.Err
+}
+
 // MatchStrategyFromString returns a MatchStrategy from its string representation.
 func MatchStrategyFromString(str string) (MatchStrategy, error) {
 	switch str {
@@ -183,7 +281,81 @@ func MatchStrategyFromString(str string) (MatchStrategy, error) {
 		return MatchStrategyRe, nil
 	case "exact", "e":
 		return MatchStrategyExact, nil
+	case "phrase", "p":
+		return MatchStrategyPhrase, nil
 	default:
-		return 0, fmt.Errorf("%s: unknown match strategy\ntry fts (full-text search), re (regular expression) or exact", str)
+		return 0, fmt.Errorf("%s: unknown match strategy\ntry fts (full-text search), re (regular expression), exact or phrase", str)
+	}
+}
+
+// IndexSnippetTerm returns the byte offset in content of the first term
+// surrounded by the delimiters in the given snippets, or -1 if none is found.
+//
+// The text preceding the term in the snippet is used to find the occurrence
+// of the term shown by the snippet, instead of an earlier one.
+func (d SnippetDelimiters) IndexSnippetTerm(content string, snippets []string) int {
+	if d.Open == "" || d.Close == "" {
+		return -1
+	}
+	terms := []string{}
+	for _, snippet := range snippets {
+		start := strings.Index(snippet, d.Open)
+		if start < 0 {
+			continue
+		}
+		end := strings.Index(snippet[start:], d.Close)
+		if end < 0 {
+			continue
+		}
+		term := snippet[start+len(d.Open) : start+end]
+		if term == "" {
+			continue
+		}
+		terms = append(terms, term)
+
+		// The snippet is truncated with an ellipsis.
+		prefix := strings.TrimPrefix(snippet[:start], "…")
+		prefix = strings.ReplaceAll(strings.ReplaceAll(prefix, d.Open, ""), d.Close, "")
+		if i := strings.Index(content, prefix+term); i >= 0 {
+			return i + len(prefix)
+		}
+	}
+	return IndexTerms(content, terms)
+}
+
+// IndexTerms returns the byte offset of the first occurrence in content of
+// any of the given terms, ignoring the case, or -1 if none is found.
+func IndexTerms(content string, terms []string) int {
+	index := -1
+	for _, term := range terms {
+		if term == "" {
+			continue
+		}
+		if i := indexFold(content, term); i >= 0 && (index < 0 || i < index) {
+			index = i
+		}
+	}
+	return index
+}
+
+// indexFold is a case-insensitive version of strings.Index.
+func indexFold(s, substr string) int {
+	for i := 0; i+len(substr) <= len(s); i++ {
+		if strings.EqualFold(s[i:i+len(substr)], substr) {
+			return i
+		}
+	}
+	return -1
+}
+
+// LocateOffset returns the line and column of the given byte offset in
+// content, both starting at 1. Like in Vim, columns count bytes.
+func LocateOffset(content string, offset int) (line, column int) {
+	if offset > len(content) {
+		offset = len(content)
 	}
+	before := content[:offset]
+	line = strings.Count(before, "\n") + 1
+	column = offset - strings.LastIndex(before, "\n")
+	return line, column
 }
@@ -39,6 +39,10 @@ func TestNoteSorterFromString(t *testing.T) {
 	test("word-count", NoteSortWordCount, true)
 	test("word-count-", NoteSortWordCount, false)
 
+	test("rel", NoteSortRelevance, false)
+	test("relevance", NoteSortRelevance, false)
+	test("relevance+", NoteSortRelevance, true)
+
 	_, err := NoteSorterFromString("foobar")
 	assert.Err(t, err, "foobar: unknown sorting term")
 }
@@ -85,6 +89,52 @@ func TestMatchStrategyFromString(t *testing.T) {
 	test("e", MatchStrategyExact)
 	test("exact", MatchStrategyExact)
 
+	test("p", MatchStrategyPhrase)
+	test("phrase", MatchStrategyPhrase)
+
 	_, err := MatchStrategyFromString("foobar")
-	assert.Err(t, err, "foobar: unknown match strategy\ntry fts (full-text search), re (regular expression) or exact")
+	assert.Err(t, err, "foobar: unknown match strategy\ntry fts (full-text search), re (regular expression), exact or phrase")
+}
+
+func TestSnippetDelimitersIndexSnippetTerm(t *testing.T) {
+	content := "# Title\n\nA quick fox and a Slow fox.\nThe slow turtle."
+	test := func(delimiters SnippetDelimiters, snippets []string, expected int) {
+		assert.Equal(t, delimiters.IndexSnippetTerm(content, snippets), expected)
+	}
+
+	test(DefaultSnippetDelimiters, []string{}, -1)
+	test(DefaultSnippetDelimiters, []string{"No highlighted term"}, -1)
+	test(DefaultSnippetDelimiters, []string{"A quick <zk:match>fox</zk:match> and"}, 17)
+	// The text preceding the term locates the occurrence of the snippet.
+	test(DefaultSnippetDelimiters, []string{"…and a Slow <zk:match>fox</zk:match>."}, 32)
+	test(DefaultSnippetDelimiters, []string{"…<zk:match>quick</zk:match> <zk:match>fox</zk:match> and"}, 11)
+	// Falls back on the first occurrence of the term.
+	test(DefaultSnippetDelimiters, []string{"…unknown <zk:match>slow</zk:match>"}, 27)
+	test(SnippetDelimiters{Open: "[", Close: "]"}, []string{"The [slow] turtle"}, 41)
+	test(SnippetDelimiters{}, []string{"The slow turtle"}, -1)
+}
+
+func TestIndexTerms(t *testing.T) {
+	content := "A quick fox and a Slow fox."
+	assert.Equal(t, IndexTerms(content, []string{}), -1)
+	assert.Equal(t, IndexTerms(content, []string{"turtle"}), -1)
+	assert.Equal(t, IndexTerms(content, []string{"fox"}), 8)
+	assert.Equal(t, IndexTerms(content, []string{"fox", "QUICK"}), 2)
+	assert.Equal(t, IndexTerms(content, []string{"", "slow"}), 18)
+}
+
+func TestLocateOffset(t *testing.T) {
+	content := "# Title\n\nA quick fox."
+	test := func(offset, expectedLine, expectedColumn int) {
+		line, column := LocateOffset(content, offset)
+		assert.Equal(t, line, expectedLine)
+		assert.Equal(t, column, expectedColumn)
+	}
+
+	test(0, 1, 1)
+	test(2, 1, 3)
+	test(8, 2, 1)
+	test(9, 3, 1)
+	test(17, 3, 9)
+	test(100, 3, 13)
 }
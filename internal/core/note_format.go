@@ -3,7 +3,10 @@ package core
 import (
 	"encoding/json"
 	"fmt"
+	"reflect"
 	"regexp"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -17,47 +20,144 @@ func newNoteFormatter(basePath string, template Template, linkFormatter LinkForm
 	}
 
 	return func(note ContextualNote) (string, error) {
-		path := NotebookPath{
-			Path:       note.Path,
-			BasePath:   basePath,
-			WorkingDir: fs.WorkingDir(),
-		}
-		relPath, err := path.PathRelToWorkingDir()
+		context, err := newNoteFormatRenderContext(note, basePath, linkFormatter, env, fs, termRepl)
 		if err != nil {
 			return "", err
 		}
+		return template.Render(context)
+	}, nil
+}
+
+// NoteFieldsFormatter formats the given fields of a note, e.g. to print the
+// note as a CSV row.
+type NoteFieldsFormatter func(note ContextualNote) ([]string, error)
+
+// newNoteFieldsFormatter creates a NoteFieldsFormatter for the given fields,
+// named like the template variables or the keys of the JSON format, e.g.
+// word-count or wordCount.
+func newNoteFieldsFormatter(basePath string, fields []string, linkFormatter LinkFormatter, env map[string]string, fs FileStorage) (NoteFieldsFormatter, error) {
+	indexes := make([]int, 0, len(fields))
+	for _, field := range fields {
+		index, ok := noteFormatFieldIndex(field)
+		if !ok {
+			return nil, fmt.Errorf("%s: unknown note field", field)
+		}
+		indexes = append(indexes, index)
+	}
+
+	return func(note ContextualNote) ([]string, error) {
+		// The matched terms of the snippets are not highlighted.
+		context, err := newNoteFormatRenderContext(note, basePath, linkFormatter, env, fs, "$1")
+		if err != nil {
+			return nil, err
+		}
+		value := reflect.ValueOf(context)
+		row := make([]string, 0, len(indexes))
+		for _, index := range indexes {
+			row = append(row, noteFormatFieldString(value.Field(index).Interface()))
+		}
+		return row, nil
+	}, nil
+}
+
+// noteFormatFieldIndex returns the index of the noteFormatRenderContext
+// field with the given template variable or JSON key.
+func noteFormatFieldIndex(name string) (int, bool) {
+	contextType := reflect.TypeOf(noteFormatRenderContext{})
+	for i := 0; i < contextType.NumField(); i++ {
+		field := contextType.Field(i)
+		key := field.Tag.Get("json")
+		if key == "-" {
+			continue
+		}
+		if name == key || name == field.Tag.Get("handlebars") {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// noteFormatFieldString converts the value of a note field to a string. The
+// dates are in UTC with the RFC 3339 format, like in the JSON format.
+func noteFormatFieldString(value interface{}) string {
+	switch value := value.(type) {
+	case string:
+		return value
+	case int:
+		return strconv.Itoa(value)
+	case time.Time:
+		return value.UTC().Format(time.RFC3339)
+	case []string:
+		return strings.Join(value, ", ")
+	case fmt.Stringer:
+		return value.String()
+	default:
+		res, err := json.Marshal(value)
+		if err != nil {
+			return ""
+		}
+		return string(res)
+	}
+}
+
+func newNoteFormatRenderContext(note ContextualNote, basePath string, linkFormatter LinkFormatter, env map[string]string, fs FileStorage, termRepl string) (noteFormatRenderContext, error) {
+	path := NotebookPath{
+		Path:       note.Path,
+		BasePath:   basePath,
+		WorkingDir: fs.WorkingDir(),
+	}
+	relPath, err := path.PathRelToWorkingDir()
+	if err != nil {
+		return noteFormatRenderContext{}, err
+	}
 
-		snippets := make([]string, 0)
-		for _, snippet := range note.Snippets {
-			snippets = append(snippets, noteTermRegex.ReplaceAllString(snippet, termRepl))
+	snippets := make([]string, 0)
+	for _, snippet := range note.Snippets {
+		snippets = append(snippets, noteTermRegex.ReplaceAllString(snippet, termRepl))
+	}
+
+	// Notes matched without a term are located at the start of the file,
+	// with their title as matched text. Otherwise, the text is the snippet
+	// highlighting the term, or the line containing it.
+	matchLine, matchColumn, matchText := 1, 1, note.Title
+	if note.MatchLine > 0 {
+		matchLine, matchColumn = note.MatchLine, note.MatchColumn
+		if len(note.Snippets) > 0 && noteTermRegex.MatchString(note.Snippets[0]) {
+			matchText = snippets[0]
+		} else if lines := strings.Split(note.RawContent, "\n"); matchLine <= len(lines) {
+			matchText = lines[matchLine-1]
 		}
+		matchText = strings.Join(strings.Fields(matchText), " ")
+	}
 
-		return template.Render(noteFormatRenderContext{
-			Filename:     note.Filename(),
-			FilenameStem: note.FilenameStem(),
-			Path:         relPath,
-			AbsPath:      path.AbsPath(),
-			Title:        note.Title,
-			Link: newLazyStringer(func() string {
-				context, err := NewLinkFormatterContext(path, note.Title, note.Metadata)
-				if err != nil {
-					return ""
-				}
-				link, _ := linkFormatter(context)
-				return link
-			}),
-			Lead:       note.Lead,
-			Body:       note.Body,
-			Snippets:   snippets,
-			Tags:       note.Tags,
-			RawContent: note.RawContent,
-			WordCount:  note.WordCount,
-			Metadata:   note.Metadata,
-			Created:    note.Created,
-			Modified:   note.Modified,
-			Checksum:   note.Checksum,
-			Env:        env,
-		})
+	return noteFormatRenderContext{
+		Filename:     note.Filename(),
+		FilenameStem: note.FilenameStem(),
+		Path:         relPath,
+		AbsPath:      path.AbsPath(),
+		Title:        note.Title,
+		Link: newLazyStringer(func() string {
+			context, err := NewLinkFormatterContext(path, note.Title, note.Metadata)
+			if err != nil {
+				return ""
+			}
+			link, _ := linkFormatter(context)
+			return link
+		}),
+		Lead:        note.Lead,
+		Body:        note.Body,
+		Snippets:    snippets,
+		Tags:        note.Tags,
+		RawContent:  note.RawContent,
+		WordCount:   note.WordCount,
+		Metadata:    note.Metadata,
+		Created:     note.Created,
+		Modified:    note.Modified,
+		Checksum:    note.Checksum,
+		MatchLine:   matchLine,
+		MatchColumn: matchColumn,
+		MatchText:   matchText,
+		Env:         env,
 	}, nil
 }
 
@@ -82,7 +182,32 @@ type noteFormatRenderContext struct {
 	Created      time.Time              `json:"created"`
 	Modified     time.Time              `json:"modified"`
 	Checksum     string                 `json:"checksum"`
-	Env          map[string]string      `json:"-"`
+	// Location of the first matched term, used by the vimgrep format.
+	MatchLine   int               `json:"-" handlebars:"match-line"`
+	MatchColumn int               `json:"-" handlebars:"match-column"`
+	MatchText   string            `json:"-" handlebars:"match-text"`
+	Env         map[string]string `json:"-"`
+}
+
+// MarshalJSON serializes the context with stable values, to be parsed by
+// other tools with the json and jsonl formats: the dates are in UTC and the
+// lists are never null.
+func (c noteFormatRenderContext) MarshalJSON() ([]byte, error) {
+	// Prevents an infinite recursion with json.Marshal.
+	type context noteFormatRenderContext
+
+	c.Created = c.Created.UTC()
+	c.Modified = c.Modified.UTC()
+	if c.Snippets == nil {
+		c.Snippets = []string{}
+	}
+	if c.Tags == nil {
+		c.Tags = []string{}
+	}
+	if c.Metadata == nil {
+		c.Metadata = map[string]interface{}{}
+	}
+	return json.Marshal(context(c))
 }
 
 func (c noteFormatRenderContext) Equal(other noteFormatRenderContext) bool {
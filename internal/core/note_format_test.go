@@ -1,6 +1,7 @@
 package core
 
 import (
+	"encoding/json"
 	"path/filepath"
 	"testing"
 	"time"
@@ -177,6 +178,124 @@ func TestNoteFormatterStylesSnippetTerm(t *testing.T) {
 	test("Hello <zk:match>world</zk:match> with <zk:match>several<zk:match> matches</zk:match>!", "Hello term(world) with term(several<zk:match> matches)!")
 }
 
+func TestNoteFormatterMatchLocation(t *testing.T) {
+	test := func(note ContextualNote, expectedLine, expectedColumn int, expectedText string) {
+		test := formatTest{}
+		test.setup()
+		formatter, err := test.run("format")
+		assert.Nil(t, err)
+		_, err = formatter(note)
+		assert.Nil(t, err)
+		context := test.template.Contexts[0].(noteFormatRenderContext)
+		assert.Equal(t, context.MatchLine, expectedLine)
+		assert.Equal(t, context.MatchColumn, expectedColumn)
+		assert.Equal(t, context.MatchText, expectedText)
+	}
+
+	note := Note{
+		Title:      "A note",
+		RawContent: "# A note\n\nThe   first line.\nThe second line.",
+	}
+
+	// Without a matched term, the note is located at its start.
+	test(ContextualNote{Note: note, Snippets: []string{"The first line."}}, 1, 1, "A note")
+	// The snippet highlighting the term is printed on a single line.
+	test(ContextualNote{
+		Note:        note,
+		Snippets:    []string{"…first line.\nThe <zk:match>second</zk:match> line."},
+		MatchLine:   4,
+		MatchColumn: 5,
+	}, 4, 5, "…first line. The term(second) line.")
+	// Otherwise, the line containing the term is printed.
+	test(ContextualNote{
+		Note:        note,
+		Snippets:    []string{"The first line."},
+		MatchLine:   3,
+		MatchColumn: 7,
+	}, 3, 7, "The first line.")
+}
+
+func TestNoteFormatRenderContextJSON(t *testing.T) {
+	test := func(context noteFormatRenderContext, expected string) {
+		actual, err := json.Marshal(context)
+		assert.Nil(t, err)
+		assert.Equal(t, string(actual), expected)
+	}
+
+	paris := time.FixedZone("CET", 3600)
+	test(noteFormatRenderContext{
+		Filename:     "note1.md",
+		FilenameStem: "note1",
+		Path:         "dir/note1.md",
+		AbsPath:      "/notebook/dir/note1.md",
+		Title:        `The "best" note`,
+		Link:         newLazyStringer(func() string { return `[The "best" note](dir/note1)` }),
+		Lead:         "Lead\nwith a newline",
+		Body:         "Body <html> & more",
+		Snippets:     []string{`A snippet with "quotes"`, "A snippet with\ttab"},
+		RawContent:   "Content",
+		WordCount:    42,
+		Tags:         []string{"tag1", "tag2"},
+		Metadata:     map[string]interface{}{"key": "value"},
+		Created:      time.Date(2009, 1, 17, 21, 34, 58, 0, paris),
+		Modified:     time.Date(2009, 2, 17, 20, 34, 58, 651387237, time.UTC),
+		Checksum:     "checksum1",
+		Env:          map[string]string{"HOME": "/home"},
+	}, `{"filename":"note1.md","filenameStem":"note1","path":"dir/note1.md","absPath":"/notebook/dir/note1.md","title":"The \"best\" note","link":"[The \"best\" note](dir/note1)","lead":"Lead\nwith a newline","body":"Body \u003chtml\u003e \u0026 more","snippets":["A snippet with \"quotes\"","A snippet with\ttab"],"rawContent":"Content","wordCount":42,"tags":["tag1","tag2"],"metadata":{"key":"value"},"created":"2009-01-17T20:34:58Z","modified":"2009-02-17T20:34:58.651387237Z","checksum":"checksum1"}`)
+
+	// The lists are never null.
+	test(noteFormatRenderContext{
+		Link: newLazyStringer(func() string { return "" }),
+	}, `{"filename":"","filenameStem":"","path":"","absPath":"","title":"","link":"","lead":"","body":"","snippets":[],"rawContent":"","wordCount":0,"tags":[],"metadata":{},"created":"0001-01-01T00:00:00Z","modified":"0001-01-01T00:00:00Z","checksum":""}`)
+}
+
+func TestNoteFieldsFormatter(t *testing.T) {
+	linkFormatter := func(context LinkFormatterContext) (string, error) {
+		return "[" + context.Title + "](" + context.Path + ")", nil
+	}
+	fs := newFileStorageMock("/notebook/dir", []string{})
+
+	formatter, err := newNoteFieldsFormatter("/notebook", []string{
+		"path", "abs-path", "title", "link", "snippets", "tags", "metadata",
+		"created", "modified", "word-count", "wordCount", "filename-stem",
+	}, linkFormatter, map[string]string{}, fs)
+	assert.Nil(t, err)
+
+	row, err := formatter(ContextualNote{
+		Note: Note{
+			Path:      "dir/note1.md",
+			Title:     `Note, "one"`,
+			WordCount: 42,
+			Tags:      []string{"tag1", "tag2"},
+			Metadata:  map[string]interface{}{"key": "value"},
+			Created:   time.Date(2009, 1, 17, 21, 34, 58, 651387237, time.FixedZone("CET", 3600)),
+			Modified:  time.Date(2009, 2, 17, 20, 34, 58, 0, time.UTC),
+		},
+		Snippets: []string{"A <zk:match>matched</zk:match> term"},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, row, []string{
+		"note1.md",
+		"/notebook/dir/note1.md",
+		`Note, "one"`,
+		`[Note, "one"](dir/note1.md)`,
+		"A matched term",
+		"tag1, tag2",
+		`{"key":"value"}`,
+		"2009-01-17T20:34:58Z",
+		"2009-02-17T20:34:58Z",
+		"42",
+		"42",
+		"note1",
+	})
+}
+
+func TestNoteFieldsFormatterUnknownField(t *testing.T) {
+	fs := newFileStorageMock("/notebook", []string{})
+	_, err := newNoteFieldsFormatter("/notebook", []string{"title", "env"}, nil, map[string]string{}, fs)
+	assert.Err(t, err, "env: unknown note field")
+}
+
 // formatTest builds and runs the SUT for note formatter test cases.
 type formatTest struct {
 	format         string
@@ -1,8 +1,12 @@
 package core
 
 import (
+	"context"
 	"fmt"
 	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/bmatcuk/doublestar/v4"
@@ -15,10 +19,19 @@ import (
 // NoteIndex persists and grants access to indexed information about the notes.
 type NoteIndex interface {
 	// Find retrieves the notes matching the given filtering and sorting criteria.
-	Find(opts NoteFindOpts) ([]ContextualNote, error)
+	//
+	// The queries of Find, FindMinimal, Count and Stats are interrupted with
+	// ctx.Err() when the context is cancelled.
+	Find(ctx context.Context, opts NoteFindOpts) ([]ContextualNote, error)
 	// FindMinimal retrieves lightweight metadata for the notes matching the
 	// given filtering and sorting criteria.
-	FindMinimal(opts NoteFindOpts) ([]MinimalNote, error)
+	FindMinimal(ctx context.Context, opts NoteFindOpts) ([]MinimalNote, error)
+	// Count returns the number of notes matching the given filtering
+	// criteria, without fetching them.
+	Count(ctx context.Context, opts NoteFindOpts) (int, error)
+	// Stats aggregates statistics about the notes matching the given
+	// filtering criteria.
+	Stats(ctx context.Context, opts NoteFindOpts) (NoteStats, error)
 
 	// Find link match returns the best note match for a given link href,
 	// relative to baseDir.
@@ -27,21 +40,62 @@ type NoteIndex interface {
 	// FindLinksBetweenNotes retrieves the links between the given notes.
 	FindLinksBetweenNotes(ids []NoteID) ([]ResolvedLink, error)
 
-	// FindCollections retrieves all the collections of the given kind.
-	FindCollections(kind CollectionKind, sorters []CollectionSorter) ([]Collection, error)
+	// FindDanglingLinks retrieves the internal links of the given notes
+	// which don't match any note.
+	FindDanglingLinks(ids []NoteID) ([]ResolvedLink, error)
+
+	// FindCollections retrieves the collections of the given kind matching
+	// the given criteria.
+	FindCollections(kind CollectionKind, opts CollectionFindOpts) ([]Collection, error)
 
 	// Indexed returns the list of indexed note file metadata.
 	IndexedPaths() (<-chan paths.Metadata, error)
+	// Exists returns whether a note is indexed at exactly the given path,
+	// relative to the notebook root.
+	Exists(path string) (bool, error)
 	// Add indexes a new note.
 	Add(note Note) (NoteID, error)
 	// Update resets the metadata of an already indexed note.
 	Update(note Note) error
 	// Remove deletes a note from the index.
 	Remove(path string) error
+	// Rename moves the note at oldPath to newPath, keeping its ID and
+	// relations.
+	Rename(oldPath string, newPath string) error
+	// FindByChecksum retrieves the notes whose content matches the given
+	// checksum.
+	FindByChecksum(checksum string) ([]MinimalNote, error)
 
 	// Commit performs a set of operations atomically.
 	Commit(transaction func(idx NoteIndex) error) error
 
+	// RebuildSearchIndex rebuilds and optimizes the full-text search index
+	// from the indexed notes.
+	RebuildSearchIndex() error
+
+	// Check verifies the integrity of the index. When repair is true, the
+	// full-text search index is rebuilt if it doesn't match the notes.
+	Check(repair bool) (NoteIndexCheckReport, error)
+
+	// ChecksumAlgorithm returns the algorithm used to compute the checksums
+	// of the indexed notes.
+	ChecksumAlgorithm() (ChecksumAlgorithm, error)
+	// SetChecksumAlgorithm records the algorithm used to compute the
+	// checksums of the indexed notes.
+	SetChecksumAlgorithm(algorithm ChecksumAlgorithm) error
+	// ChecksumTrimSpaces returns whether the trailing whitespace was ignored
+	// when computing the checksums of the indexed notes.
+	ChecksumTrimSpaces() (bool, error)
+	// SetChecksumTrimSpaces records whether the trailing whitespace was
+	// ignored when computing the checksums of the indexed notes.
+	SetChecksumTrimSpaces(trimSpaces bool) error
+	// SetChecksum updates the checksum of the note at the given path,
+	// without reindexing it.
+	SetChecksum(path string, checksum string) error
+	// SetModified updates the modification date and size of the note at
+	// the given path, when its content didn't change.
+	SetModified(path string, modified time.Time, size int64) error
+
 	// NeedsReindexing returns whether all notes should be reindexed.
 	NeedsReindexing() (bool, error)
 	// SetNeedsReindexing indicates whether all notes should be reindexed.
@@ -58,13 +112,25 @@ type NoteIndexingStats struct {
 	ModifiedCount int `json:"modifiedCount"`
 	// Number of notes removed since last indexing.
 	RemovedCount int `json:"removedCount"`
+	// Number of notes moved to another path since last indexing.
+	MovedCount int `json:"movedCount"`
+	// Number of notes which didn't change since last indexing.
+	UnchangedCount int `json:"unchangedCount"`
+	// Number of notes which could not be indexed.
+	FailedCount int `json:"failedCount"`
+	// Number of files skipped because they exceed the maximum note size.
+	SkippedCount int `json:"skippedCount"`
 	// Duration of the indexing process.
 	Duration time.Duration `json:"duration"`
+	// Errors of the notes which could not be indexed.
+	Errors []NoteIndexingFailure `json:"-"`
+	// Changes which would be applied to the index, for a dry run.
+	Changes []paths.DiffChange `json:"-"`
 }
 
 // String implements Stringer
 func (s NoteIndexingStats) String() string {
-	return fmt.Sprintf(`Indexed %d %v in %v
+	res := fmt.Sprintf(`Indexed %d %v in %v
   + %d added
   ~ %d modified
   - %d removed`,
@@ -73,13 +139,90 @@ func (s NoteIndexingStats) String() string {
 		s.Duration.Round(500*time.Millisecond),
 		s.AddedCount, s.ModifiedCount, s.RemovedCount,
 	)
+	if s.MovedCount > 0 {
+		res += fmt.Sprintf("\n  > %d moved", s.MovedCount)
+	}
+	if s.FailedCount > 0 {
+		res += fmt.Sprintf("\n  ! %d failed", s.FailedCount)
+	}
+	if s.SkippedCount > 0 {
+		res += fmt.Sprintf("\n  * %d skipped", s.SkippedCount)
+	}
+	return res
+}
+
+// NoteIndexingFailure is an error which prevented a note from being indexed.
+type NoteIndexingFailure struct {
+	// Path of the note, relative to the notebook.
+	Path string
+	Err  error
+}
+
+func (f NoteIndexingFailure) Error() string {
+	return f.Err.Error()
+}
+
+func (f NoteIndexingFailure) Unwrap() error {
+	return f.Err
+}
+
+// NoteIndexingError is returned when some notes could not be indexed, while
+// the other notes were indexed successfully.
+type NoteIndexingError struct {
+	Failures []NoteIndexingFailure
+}
+
+func (e NoteIndexingError) Error() string {
+	count := len(e.Failures)
+	return fmt.Sprintf("%d %s could not be indexed", count, strutil.Pluralize("note", count))
+}
+
+func (e NoteIndexingError) Unwrap() []error {
+	errs := []error{}
+	for _, failure := range e.Failures {
+		errs = append(errs, failure)
+	}
+	return errs
+}
+
+// NoteIndexCheckReport holds the inconsistencies found while checking the
+// integrity of a NoteIndex.
+type NoteIndexCheckReport interface {
+	fmt.Stringer
+	// IsHealthy returns whether no inconsistency was found, not counting the
+	// ones which were repaired.
+	IsHealthy() bool
 }
 
 // NoteIndexOpts holds the options for the indexing process.
 type NoteIndexOpts struct {
 	// When true, existing notes will be reindexed.
-	Force   bool
+	Force bool
+	// When true, the full-text search index will be rebuilt.
+	Rebuild bool
 	Verbose bool
+	// Number of notes parsed concurrently. Defaults to GOMAXPROCS.
+	Workers int
+	// Gitignore-style patterns of the files skipped while walking the
+	// notebook, in addition to the .zk/ignore file.
+	ExcludeGlobs []string
+	// When true, the changes are computed without being applied to the
+	// index.
+	DryRun bool
+	// Maximum delay to wait for another indexing of the notebook to
+	// complete. When zero, fails immediately if the notebook is already
+	// being indexed.
+	LockTimeout time.Duration
+}
+
+// NoteIndexProgress is notified of the progress of the indexing process, for
+// example to render a progress bar.
+type NoteIndexProgress interface {
+	// FileIndexed is called after each note file is processed, with the
+	// action applied to the index.
+	FileIndexed(path string, action paths.DiffKind)
+	// Done is called once the indexing succeeded.
+	Done(stats NoteIndexingStats)
 }
 
 // indexTask indexes the notes in the given directory with the NoteIndex.
@@ -87,10 +230,15 @@ type indexTask struct {
 	path    string
 	config  Config
 	force   bool
+	rebuild bool
 	verbose bool
-	index   NoteIndex
-	parser  NoteParser
-	logger  util.Logger
+	workers int
+	// Gitignore-style patterns of the skipped files.
+	excludeGlobs []string
+	index        NoteIndex
+	fs           FileStorage
+	parser       NoteParser
+	logger       util.Logger
 }
 
 func (t *indexTask) execute(callback func(change paths.DiffChange)) (NoteIndexingStats, error) {
@@ -131,8 +279,9 @@ func (t *indexTask) execute(callback func(change paths.DiffChange)) (NoteIndexin
 			return true, err
 		}
 
-		if filepath.Ext(path) != "."+group.Note.Extension {
-			notifyIgnored("expected extension \"" + group.Note.Extension + "\"")
+		exts := group.Note.IndexedExtensions()
+		if !strutil.Contains(exts, strings.TrimPrefix(filepath.Ext(path), ".")) {
+			notifyIgnored("expected extension \"" + strings.Join(exts, "\" or \"") + "\"")
 			return true, nil
 		}
 
@@ -150,53 +299,167 @@ func (t *indexTask) execute(callback func(change paths.DiffChange)) (NoteIndexin
 		return false, nil
 	}
 
+	ignore, err := readIgnoreRules(t.fs, t.path)
+	if err != nil {
+		return stats, wrap(err)
+	}
+
 	notebookPath := &NotebookPath{Path: t.path}
-	source := paths.Walk(t.path, t.logger, notebookPath.Filename(), shouldIgnorePath)
+	source := paths.Walk(t.path, t.logger, paths.WalkOpts{
+		NotebookRoot:     notebookPath.Filename(),
+		Ignore:           ignore.Append(paths.NewIgnoreRules(t.excludeGlobs)),
+		ShouldIgnorePath: shouldIgnorePath,
+		FollowSymlinks:   t.config.Notebook.FollowSymlinks,
+	})
+
+	largeFiles := &sync.Map{}
+	source = t.findLargeFiles(source, largeFiles)
 
 	target, err := t.index.IndexedPaths()
 	if err != nil {
 		return stats, wrap(err)
 	}
 
+	// Paths of the notes indexed during this pass, with an up-to-date checksum.
+	indexed := map[string]bool{}
+	// Paths of the removed notes, which are deleted at the end of the pass so
+	// that the renamed notes can be moved instead of being reindexed.
+	removed := []string{}
+	renamed := map[string]bool{}
+	// Error of the index, which aborts the indexing. The remaining changes
+	// are skipped.
+	var indexErr error
+
 	// FIXME: Use the FS?
-	count, err := paths.Diff(source, target, force, func(change paths.DiffChange) error {
+	count, diffErr := t.parseChanges(source, target, force, largeFiles, func(job *indexJob) {
+		if indexErr != nil {
+			return
+		}
+		change := job.change
+		if change.Kind == paths.DiffRemoved {
+			removed = append(removed, change.Path)
+			return
+		}
+		if job.tooLarge > 0 {
+			// The file is not read, so a note indexed before it exceeded the
+			// maximum size is left unchanged in the index.
+			t.logger.Printf("warning: skipping %s: its size of %d bytes exceeds the maximum note size of %d bytes", change.Path, job.tooLarge, t.config.Notebook.MaxNoteSize)
+			print("- skipped " + change.Path)
+			stats.SkippedCount += 1
+			return
+		}
+		if change.Kind == paths.DiffModified && !force && job.note != nil && job.note.Checksum == change.Checksum {
+			// The file was touched without changing its content, so the note
+			// doesn't need to be reindexed.
+			change.Kind = paths.DiffUnchanged
+		}
+
+		if change.Kind == paths.DiffAdded && job.note != nil {
+			if oldPath := t.findRenamedNote(*job.note, renamed); oldPath != "" {
+				renamed[oldPath] = true
+				change.Kind = paths.DiffMoved
+				change.OldPath = oldPath
+			}
+		}
+
 		callback(change)
-		print("- " + change.Kind.String() + " " + change.Path)
-		absPath := filepath.Join(t.path, change.Path)
+		if change.Kind == paths.DiffAdded || change.Kind == paths.DiffModified || change.Kind == paths.DiffMoved {
+			indexed[change.Path] = true
+		}
+		print("- " + change.String())
 
+		if job.err != nil {
+			stats.FailedCount += 1
+			stats.Errors = append(stats.Errors, NoteIndexingFailure{Path: change.Path, Err: job.err})
+			return
+		}
+
+		var err error
 		switch change.Kind {
 		case paths.DiffAdded:
 			stats.AddedCount += 1
-			note, err := t.parser.ParseNoteAt(absPath)
-			if note != nil {
-				_, err = t.index.Add(*note)
-			}
-			t.logger.Err(err)
+			_, err = t.index.Add(*job.note)
 
 		case paths.DiffModified:
 			stats.ModifiedCount += 1
-			note, err := t.parser.ParseNoteAt(absPath)
-			if note != nil {
-				err = t.index.Update(*note)
+			err = t.index.Update(*job.note)
+
+		case paths.DiffMoved:
+			stats.MovedCount += 1
+			err = t.index.Rename(change.OldPath, change.Path)
+			if err == nil {
+				err = t.index.Update(*job.note)
 			}
-			t.logger.Err(err)
 
-		case paths.DiffRemoved:
-			stats.RemovedCount += 1
-			err := t.index.Remove(change.Path)
-			t.logger.Err(err)
+		case paths.DiffUnchanged:
+			stats.UnchangedCount += 1
+			if job.note != nil {
+				err = t.index.SetModified(change.Path, job.note.Modified, job.note.Size)
+			}
 		}
-
-		return nil
+		indexErr = err
 	})
+	if indexErr != nil {
+		return stats, wrap(indexErr)
+	}
+	if diffErr != nil {
+		return stats, wrap(diffErr)
+	}
+
+	for _, path := range removed {
+		if renamed[path] {
+			continue
+		}
+		change := paths.DiffChange{Path: path, Kind: paths.DiffRemoved}
+		callback(change)
+		print("- " + change.String())
+		stats.RemovedCount += 1
+		if err := t.index.Remove(path); err != nil {
+			return stats, wrap(err)
+		}
+	}
+
+	for _, failure := range stats.Errors {
+		t.logger.Err(failure)
+	}
 
 	for _, ignored := range ignoredFiles {
 		print("- ignored " + ignored.Path + ": " + ignored.Reason)
 	}
 
-	stats.SourceCount = count
+	stats.SourceCount = count - stats.SkippedCount
 	stats.Duration = time.Since(startTime)
 
+	algorithm := t.config.Notebook.Checksum
+	indexedAlgorithm, err := t.index.ChecksumAlgorithm()
+	if err != nil {
+		return stats, wrap(err)
+	}
+	trimSpaces := t.config.Notebook.ChecksumTrimSpaces
+	indexedTrimSpaces, err := t.index.ChecksumTrimSpaces()
+	if err != nil {
+		return stats, wrap(err)
+	}
+	if indexedAlgorithm != algorithm || indexedTrimSpaces != trimSpaces {
+		msg := "- update the checksums with " + string(algorithm)
+		if trimSpaces {
+			msg += ", ignoring the trailing whitespace"
+		}
+		print(msg)
+		err = t.updateChecksums(algorithm, trimSpaces, indexed)
+		if err != nil {
+			return stats, wrap(err)
+		}
+	}
+
+	if t.rebuild {
+		print("- rebuild the full-text search index")
+		err = t.index.RebuildSearchIndex()
+		if err != nil {
+			return stats, wrap(err)
+		}
+	}
+
 	if needsReindexing {
 		err = t.index.SetNeedsReindexing(false)
 	}
@@ -204,3 +467,234 @@ func (t *indexTask) execute(callback func(change paths.DiffChange)) (NoteIndexin
 	print("")
 	return stats, wrap(err)
 }
+
+// findLargeFiles stores the sizes of the source files exceeding the maximum
+// note size in largeFiles, by path, before they are diffed. They are kept in the
+// source so that their indexed notes are not removed.
+func (t *indexTask) findLargeFiles(source <-chan paths.Metadata, largeFiles *sync.Map) <-chan paths.Metadata {
+	maxSize := t.config.Notebook.MaxNoteSize
+	if maxSize <= 0 {
+		return source
+	}
+
+	c := make(chan paths.Metadata, 50)
+	go func() {
+		defer close(c)
+		for metadata := range source {
+			if metadata.Size > maxSize {
+				largeFiles.Store(metadata.Path, metadata.Size)
+			}
+			c <- metadata
+		}
+	}()
+	return c
+}
+
+// readIgnoreRules reads the gitignore-style patterns of the .zk/ignore file
+// of the notebook, if it exists.
+func readIgnoreRules(fs FileStorage, notebookDir string) (paths.IgnoreRules, error) {
+	path := filepath.Join(notebookDir, ".zk", "ignore")
+	exists, err := fs.FileExists(path)
+	if err != nil || !exists {
+		return paths.IgnoreRules{}, err
+	}
+	content, err := fs.Read(path)
+	if err != nil {
+		return paths.IgnoreRules{}, errors.Wrapf(err, "failed to read %s", path)
+	}
+	return paths.ParseIgnoreRules(string(content)), nil
+}
+
+// findRenamedNote returns the path of an indexed note with the same content as
+// the given new note, but whose file doesn't exist anymore.
+//
+// Returns an empty string when there is no such note, or several of them.
+// Empty notes are never considered renamed, as they all share the same
+// checksum.
+func (t *indexTask) findRenamedNote(note Note, renamed map[string]bool) string {
+	if strings.TrimSpace(note.RawContent) == "" {
+		return ""
+	}
+
+	candidates, err := t.index.FindByChecksum(note.Checksum)
+	if err != nil {
+		t.logger.Err(err)
+		return ""
+	}
+
+	oldPath := ""
+	for _, candidate := range candidates {
+		if candidate.Path == note.Path || renamed[candidate.Path] {
+			continue
+		}
+		exists, err := t.fs.FileExists(filepath.Join(t.path, candidate.Path))
+		if err != nil || exists {
+			continue
+		}
+		if oldPath != "" {
+			return ""
+		}
+		oldPath = candidate.Path
+	}
+	return oldPath
+}
+
+// indexJob is a change of the notebook to apply to the index.
+type indexJob struct {
+	change paths.DiffChange
+	// Note parsed by a worker, for the added and modified files.
+	note *Note
+	err  error
+	// Size of an added or modified file exceeding the maximum note size,
+	// which was not parsed.
+	tooLarge int64
+	// Closed once the note is parsed.
+	done chan struct{}
+}
+
+// parseChanges diffs the notebook files with the index and parses the added
+// and modified notes on a pool of workers.
+//
+// The files stored in largeFiles are not parsed. The apply callback is called
+// on the current goroutine with each change in the diffing order, so that the
+// index is updated deterministically from a single writer. Returns the number
+// of files in the source, or the error which interrupted the diffing.
+func (t *indexTask) parseChanges(source, target <-chan paths.Metadata, force bool, largeFiles *sync.Map, apply func(job *indexJob)) (int, error) {
+	workers := t.workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	parsing := make(chan *indexJob)
+	for i := 0; i < workers; i++ {
+		go func() {
+			for job := range parsing {
+				absPath := filepath.Join(t.path, job.change.Path)
+				job.note, job.err = t.parser.ParseNoteAt(absPath)
+				close(job.done)
+			}
+		}()
+	}
+
+	// The pending jobs, in the diffing order.
+	queue := make(chan *indexJob, workers*2)
+	count := 0
+	var err error
+	go func() {
+		defer close(queue)
+		defer close(parsing)
+
+		count, err = paths.Diff(source, target, force, func(change paths.DiffChange) error {
+			job := &indexJob{change: change, done: make(chan struct{})}
+			if change.Kind == paths.DiffAdded || change.Kind == paths.DiffModified {
+				if size, ok := largeFiles.Load(change.Path); ok {
+					job.tooLarge = size.(int64)
+				}
+			}
+			if (change.Kind == paths.DiffAdded || change.Kind == paths.DiffModified) && job.tooLarge == 0 {
+				parsing <- job
+			} else {
+				close(job.done)
+			}
+			queue <- job
+			return nil
+		})
+	}()
+
+	for job := range queue {
+		<-job.done
+		apply(job)
+	}
+
+	// The queue is closed once the diffing is over, so count and err are set.
+	return count, err
+}
+
+// updateChecksums recomputes the checksums of the indexed notes with the
+// given algorithm and whitespace handling, except the ones which were just
+// reindexed.
+//
+// This is cheaper than reindexing the notes, as they are not parsed.
+func (t *indexTask) updateChecksums(algorithm ChecksumAlgorithm, trimSpaces bool, skipped map[string]bool) error {
+	indexedPaths, err := t.index.IndexedPaths()
+	if err != nil {
+		return err
+	}
+	notePaths := []string{}
+	maxSize := t.config.Notebook.MaxNoteSize
+	for metadata := range indexedPaths {
+		if !skipped[metadata.Path] && (maxSize <= 0 || metadata.Size <= maxSize) {
+			notePaths = append(notePaths, metadata.Path)
+		}
+	}
+
+	for _, path := range notePaths {
+		content, err := t.fs.Read(filepath.Join(t.path, path))
+		if err != nil {
+			return err
+		}
+		err = t.index.SetChecksum(path, algorithm.Sum(content, trimSpaces))
+		if err != nil {
+			return err
+		}
+	}
+
+	err = t.index.SetChecksumAlgorithm(algorithm)
+	if err != nil {
+		return err
+	}
+	return t.index.SetChecksumTrimSpaces(trimSpaces)
+}
+
+// dryRunNoteIndex is a NoteIndex ignoring all the writes.
+type dryRunNoteIndex struct {
+	NoteIndex
+}
+
+func (idx dryRunNoteIndex) Add(note Note) (NoteID, error) {
+	return 0, nil
+}
+
+func (idx dryRunNoteIndex) Update(note Note) error {
+	return nil
+}
+
+func (idx dryRunNoteIndex) Remove(path string) error {
+	return nil
+}
+
+func (idx dryRunNoteIndex) Rename(oldPath string, newPath string) error {
+	return nil
+}
+
+func (idx dryRunNoteIndex) Commit(transaction func(idx NoteIndex) error) error {
+	return transaction(idx)
+}
+
+func (idx dryRunNoteIndex) RebuildSearchIndex() error {
+	return nil
+}
+
+func (idx dryRunNoteIndex) Check(repair bool) (NoteIndexCheckReport, error) {
+	return idx.NoteIndex.Check(false)
+}
+
+func (idx dryRunNoteIndex) SetChecksumAlgorithm(algorithm ChecksumAlgorithm) error {
+	return nil
+}
+
+func (idx dryRunNoteIndex) SetChecksumTrimSpaces(trimSpaces bool) error {
+	return nil
+}
+
+func (idx dryRunNoteIndex) SetChecksum(path string, checksum string) error {
+	return nil
+}
+
+func (idx dryRunNoteIndex) SetModified(path string, modified time.Time, size int64) error {
+	return nil
+}
+
+func (idx dryRunNoteIndex) SetNeedsReindexing(needsReindexing bool) error {
+	return nil
+}
@@ -0,0 +1,628 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zk-org/zk/internal/util"
+	"github.com/zk-org/zk/internal/util/errors"
+	"github.com/zk-org/zk/internal/util/lockfile"
+	"github.com/zk-org/zk/internal/util/paths"
+	"github.com/zk-org/zk/internal/util/test/assert"
+)
+
+func TestIndexTaskParallelMatchesSequential(t *testing.T) {
+	dir := writeNotebookTree(t, 300)
+	indexed := map[string]paths.Metadata{}
+	for _, path := range []string{"dir-0/note-0.md", "dir-1/note-1.md", "removed.md"} {
+		indexed[path] = paths.Metadata{Path: path}
+	}
+
+	test := func(workers int) *noteIndexRecorderMock {
+		index := newNoteIndexRecorderMock(indexed)
+		logger := &loggerMock{}
+		task := indexTask{
+			path:    dir,
+			config:  NewDefaultConfig(),
+			workers: workers,
+			index:   index,
+			fs:      &osFileStorageMock{newFileStorageMock(dir, []string{})},
+			parser:  noteParserFunc(parseNoteForTest),
+			logger:  logger,
+		}
+
+		stats, err := task.execute(func(change paths.DiffChange) {})
+		assert.Nil(t, err)
+		assert.Equal(t, stats.SourceCount, 300)
+		assert.Equal(t, stats.AddedCount, 295)
+		assert.Equal(t, stats.ModifiedCount, 2)
+		assert.Equal(t, stats.RemovedCount, 1)
+		assert.Equal(t, stats.FailedCount, 3)
+		assert.Equal(t, len(stats.Errors), 3)
+
+		// The invalid notes are reported without aborting the indexing.
+		assert.Equal(t, len(logger.errs), 3)
+		for _, err := range logger.errs {
+			assert.True(t, strings.HasSuffix(err, ": invalid note"))
+		}
+		return index
+	}
+
+	sequential := test(1)
+	assert.Equal(t, len(sequential.changes), 298)
+
+	for _, workers := range []int{2, 8, 0} {
+		parallel := test(workers)
+		assert.Equal(t, parallel.changes, sequential.changes)
+	}
+}
+
+func TestIndexTaskSkipsNotesWithUnchangedContent(t *testing.T) {
+	dir := t.TempDir()
+	for path, content := range map[string]string{
+		"touched.md":  "# Touched\n",
+		"modified.md": "# Changed\n",
+	} {
+		assert.Nil(t, os.WriteFile(filepath.Join(dir, path), []byte(content), 0644))
+	}
+
+	// The indexed notes have the same size, but are older.
+	yesterday := time.Now().Add(-24 * time.Hour)
+	indexed := map[string]paths.Metadata{
+		"touched.md": {
+			Path:     "touched.md",
+			Modified: yesterday,
+			Size:     10,
+			Checksum: ChecksumSHA256.Sum([]byte("# Touched\n"), false),
+		},
+		"modified.md": {
+			Path:     "modified.md",
+			Modified: yesterday,
+			Size:     10,
+			Checksum: ChecksumSHA256.Sum([]byte("# Initial\n"), false),
+		},
+	}
+
+	test := func(force bool, expectedChanges []string, expectedStats NoteIndexingStats) {
+		index := newNoteIndexRecorderMock(indexed)
+		task := indexTask{
+			path:   dir,
+			config: NewDefaultConfig(),
+			force:  force,
+			index:  index,
+			fs:     &osFileStorageMock{newFileStorageMock(dir, []string{})},
+			parser: noteParserFunc(parseNoteForTest),
+			logger: &util.NullLogger,
+		}
+
+		stats, err := task.execute(func(change paths.DiffChange) {})
+		assert.Nil(t, err)
+		stats.Duration = 0
+		assert.Equal(t, stats, expectedStats)
+		assert.Equal(t, index.changes, expectedChanges)
+	}
+
+	test(false, []string{
+		"update modified.md # Changed " + ChecksumSHA256.Sum([]byte("# Changed\n"), false),
+		"touch touched.md 10",
+	}, NoteIndexingStats{SourceCount: 2, ModifiedCount: 1, UnchangedCount: 1})
+
+	// Forcing the indexing reindexes the unchanged notes.
+	test(true, []string{
+		"update modified.md # Changed " + ChecksumSHA256.Sum([]byte("# Changed\n"), false),
+		"update touched.md # Touched " + ChecksumSHA256.Sum([]byte("# Touched\n"), false),
+	}, NoteIndexingStats{SourceCount: 2, ModifiedCount: 2})
+}
+
+func TestNotebookIndexStats(t *testing.T) {
+	dir := t.TempDir()
+	write := func(path string, content string) {
+		assert.Nil(t, os.WriteFile(filepath.Join(dir, path), []byte(content), 0644))
+	}
+	write("a.md", "# Note A\n")
+	write("b.md", "# Note B\n")
+	write("c.md", "# Note C\n")
+	write("invalid.md", "# Invalid\n")
+
+	notebook := NewNotebook(dir, NewDefaultConfig(), NotebookPorts{
+		NoteIndex: newNoteIndexWatchMock(),
+		NoteContentParser: noteContentParserFunc(func(content string) (*NoteContent, error) {
+			if strings.HasPrefix(content, "# Invalid") {
+				return nil, errors.New("invalid note")
+			}
+			return parseTitleForTest(content)
+		}),
+		FS:     &osFileStorageMock{newFileStorageMock(dir, []string{})},
+		Logger: &util.NullLogger,
+	})
+
+	test := func(expected NoteIndexingStats) {
+		stats, err := notebook.Index(NoteIndexOpts{})
+		assert.Err(t, err, "indexing: 1 note could not be indexed")
+		assert.True(t, stats.Duration > 0)
+		assert.Equal(t, len(stats.Errors), stats.FailedCount)
+		for _, failure := range stats.Errors {
+			assert.Equal(t, failure.Path, "invalid.md")
+			assert.True(t, strings.HasSuffix(failure.Error(), ": invalid note"))
+		}
+
+		stats.Duration = 0
+		stats.Errors = nil
+		assert.Equal(t, stats, expected)
+	}
+
+	test(NoteIndexingStats{SourceCount: 4, AddedCount: 3, FailedCount: 1})
+
+	write("a.md", "# Note A, modified\n")
+	assert.Nil(t, os.Remove(filepath.Join(dir, "b.md")))
+
+	// The invalid note is not indexed, so it is added again.
+	test(NoteIndexingStats{SourceCount: 3, ModifiedCount: 1, RemovedCount: 1, UnchangedCount: 1, FailedCount: 1})
+}
+
+func TestNotebookIndexContinuesAfterUnreadableNote(t *testing.T) {
+	dir := t.TempDir()
+	for _, path := range []string{"a.md", "b.md", "c.md"} {
+		assert.Nil(t, os.WriteFile(filepath.Join(dir, path), []byte("# "+path+"\n"), 0644))
+	}
+
+	index := newNoteIndexWatchMock()
+	logger := &loggerMock{}
+	notebook := NewNotebook(dir, NewDefaultConfig(), NotebookPorts{
+		NoteIndex:         index,
+		NoteContentParser: noteContentParserFunc(parseTitleForTest),
+		FS: &unreadableFileStorageMock{
+			osFileStorageMock: &osFileStorageMock{newFileStorageMock(dir, []string{})},
+			path:              filepath.Join(dir, "b.md"),
+		},
+		Logger: logger,
+	})
+
+	stats, err := notebook.Index(NoteIndexOpts{})
+	assert.Err(t, err, "indexing: 1 note could not be indexed")
+
+	var indexingErr NoteIndexingError
+	assert.True(t, errors.As(err, &indexingErr))
+	assert.Equal(t, len(indexingErr.Failures), 1)
+	assert.True(t, errors.Is(err, os.ErrPermission))
+
+	assert.Equal(t, stats.AddedCount, 2)
+	assert.Equal(t, stats.FailedCount, 1)
+	assert.Equal(t, len(stats.Errors), 1)
+	assert.Equal(t, stats.Errors[0].Path, "b.md")
+	assert.Equal(t, logger.errs, []string{filepath.Join(dir, "b.md") + ": permission denied"})
+
+	// The other notes are committed.
+	assert.Equal(t, index.titles(), map[string]string{"a.md": "a.md", "c.md": "c.md"})
+}
+
+func TestNotebookIndexAbortsOnIndexError(t *testing.T) {
+	dir := t.TempDir()
+	for _, path := range []string{"a.md", "b.md"} {
+		assert.Nil(t, os.WriteFile(filepath.Join(dir, path), []byte("# "+path+"\n"), 0644))
+	}
+
+	notebook := NewNotebook(dir, NewDefaultConfig(), NotebookPorts{
+		NoteIndex:         &noteIndexFailingMock{noteIndexWatchMock: newNoteIndexWatchMock()},
+		NoteContentParser: noteContentParserFunc(parseTitleForTest),
+		FS:                &osFileStorageMock{newFileStorageMock(dir, []string{})},
+		Logger:            &util.NullLogger,
+	})
+
+	_, err := notebook.Index(NoteIndexOpts{})
+	assert.Err(t, err, "indexing: indexing failed: database is locked")
+	var indexingErr NoteIndexingError
+	assert.False(t, errors.As(err, &indexingErr))
+}
+
+func TestNotebookIndexIgnoreFile(t *testing.T) {
+	dir := t.TempDir()
+	for path, content := range map[string]string{
+		".zk/ignore":             "# Attachments\nattachments/\n*.md\n!/*.md\n",
+		"a.md":                   "# Note A\n",
+		"b.md":                   "# Note B\n",
+		"attachments/c.md":       "# Note C\n",
+		"dir/d.md":               "# Note D\n",
+		".obsidian/workspace.md": "# Workspace\n",
+	} {
+		path = filepath.Join(dir, path)
+		assert.Nil(t, os.MkdirAll(filepath.Dir(path), os.ModePerm))
+		assert.Nil(t, os.WriteFile(path, []byte(content), 0644))
+	}
+
+	index := newNoteIndexWatchMock()
+	notebook := NewNotebook(dir, NewDefaultConfig(), NotebookPorts{
+		NoteIndex:         index,
+		NoteContentParser: noteContentParserFunc(parseTitleForTest),
+		FS:                &osFileStorageMock{newFileStorageMock(dir, []string{})},
+		Logger:            &util.NullLogger,
+	})
+
+	stats, err := notebook.Index(NoteIndexOpts{ExcludeGlobs: []string{"b.md"}})
+	assert.Nil(t, err)
+	assert.Equal(t, stats.SourceCount, 1)
+	assert.Equal(t, index.titles(), map[string]string{"a.md": "Note A"})
+}
+
+func TestNotebookIndexSkipsLargeNotes(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"at-limit.md":    "# At limit\n" + strings.Repeat("a", 9),
+		"above-limit.md": "# Above limit\n" + strings.Repeat("a", 7),
+	}
+	for path, content := range files {
+		assert.Nil(t, os.WriteFile(filepath.Join(dir, path), []byte(content), 0644))
+	}
+	assert.Equal(t, len(files["at-limit.md"]), 20)
+	assert.Equal(t, len(files["above-limit.md"]), 21)
+
+	config := NewDefaultConfig()
+	config.Notebook.MaxNoteSize = 20
+
+	index := newNoteIndexWatchMock()
+	logger := &loggerMock{}
+	notebook := NewNotebook(dir, config, NotebookPorts{
+		NoteIndex: index,
+		NoteContentParser: noteContentParserFunc(func(content string) (*NoteContent, error) {
+			assert.True(t, len(content) <= 20)
+			return parseTitleForTest(content)
+		}),
+		FS:     &osFileStorageMock{newFileStorageMock(dir, []string{})},
+		Logger: logger,
+	})
+
+	stats, err := notebook.Index(NoteIndexOpts{})
+	assert.Nil(t, err)
+	stats.Duration = 0
+	assert.Equal(t, stats, NoteIndexingStats{SourceCount: 1, AddedCount: 1, SkippedCount: 1})
+	assert.Equal(t, index.titles(), map[string]string{"at-limit.md": "At limit"})
+	assert.Equal(t, logger.warnings, []string{
+		"warning: skipping above-limit.md: its size of 21 bytes exceeds the maximum note size of 20 bytes",
+	})
+}
+
+// A note growing above the maximum size is kept in the index as it was.
+func TestNotebookIndexKeepsNotesGrowingAboveMaxSize(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "note.md")
+	assert.Nil(t, os.WriteFile(path, []byte("# Small note\n"), 0644))
+
+	index := newNoteIndexWatchMock()
+	newNotebook := func(maxSize int64, logger util.Logger) *Notebook {
+		config := NewDefaultConfig()
+		config.Notebook.MaxNoteSize = maxSize
+		return NewNotebook(dir, config, NotebookPorts{
+			NoteIndex:         index,
+			NoteContentParser: noteContentParserFunc(parseTitleForTest),
+			FS:                &osFileStorageMock{newFileStorageMock(dir, []string{})},
+			Logger:            logger,
+		})
+	}
+
+	_, err := newNotebook(0, &util.NullLogger).Index(NoteIndexOpts{})
+	assert.Nil(t, err)
+
+	assert.Nil(t, os.WriteFile(path, []byte("# Large note\n"+strings.Repeat("a", 20)), 0644))
+	modified := time.Now().Add(time.Hour)
+	assert.Nil(t, os.Chtimes(path, modified, modified))
+
+	stats, err := newNotebook(20, &loggerMock{}).Index(NoteIndexOpts{})
+	assert.Nil(t, err)
+	stats.Duration = 0
+	assert.Equal(t, stats, NoteIndexingStats{SkippedCount: 1})
+	assert.Equal(t, index.titles(), map[string]string{"note.md": "Small note"})
+}
+
+func TestNotebookIndexDryRun(t *testing.T) {
+	dir := t.TempDir()
+	write := func(path string, content string) {
+		assert.Nil(t, os.WriteFile(filepath.Join(dir, path), []byte(content), 0644))
+	}
+	write("a.md", "# Note A\n")
+	write("b.md", "# Note B\n")
+	write("c.md", "# Note C\n")
+
+	index := newNoteIndexWatchMock()
+	notebook := NewNotebook(dir, NewDefaultConfig(), NotebookPorts{
+		NoteIndex:         index,
+		NoteContentParser: noteContentParserFunc(parseTitleForTest),
+		FS:                &osFileStorageMock{newFileStorageMock(dir, []string{})},
+		Logger:            &util.NullLogger,
+	})
+	_, err := notebook.Index(NoteIndexOpts{})
+	assert.Nil(t, err)
+
+	write("a.md", "# Note A, modified\n")
+	write("d.md", "# Note D\n")
+	assert.Nil(t, os.Rename(filepath.Join(dir, "b.md"), filepath.Join(dir, "e.md")))
+	assert.Nil(t, os.Remove(filepath.Join(dir, "c.md")))
+
+	expectedChanges := []paths.DiffChange{
+		{Path: "a.md", Kind: paths.DiffModified},
+		{Path: "d.md", Kind: paths.DiffAdded},
+		{Path: "e.md", Kind: paths.DiffMoved, OldPath: "b.md"},
+		{Path: "c.md", Kind: paths.DiffRemoved},
+	}
+	expectedStats := NoteIndexingStats{SourceCount: 3, AddedCount: 1, ModifiedCount: 1, RemovedCount: 1, MovedCount: 1}
+
+	stats, err := notebook.Index(NoteIndexOpts{DryRun: true})
+	assert.Nil(t, err)
+	for i := range stats.Changes {
+		stats.Changes[i].Checksum = ""
+	}
+	assert.Equal(t, stats.Changes, expectedChanges)
+	stats.Duration = 0
+	stats.Changes = nil
+	assert.Equal(t, stats, expectedStats)
+
+	// The index is left untouched.
+	assert.Equal(t, index.titles(), map[string]string{"a.md": "Note A", "b.md": "Note B", "c.md": "Note C"})
+
+	// The actual indexing applies the planned changes.
+	changes := []paths.DiffChange{}
+	stats, err = notebook.IndexWithCallback(NoteIndexOpts{}, func(change paths.DiffChange) {
+		change.Checksum = ""
+		changes = append(changes, change)
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, changes, expectedChanges)
+	stats.Duration = 0
+	assert.Equal(t, stats, expectedStats)
+	assert.Equal(t, index.titles(), map[string]string{"a.md": "Note A, modified", "d.md": "Note D", "e.md": "Note B"})
+}
+
+func TestNotebookIndexWithProgress(t *testing.T) {
+	dir := t.TempDir()
+	write := func(path string, content string) {
+		path = filepath.Join(dir, path)
+		assert.Nil(t, os.MkdirAll(filepath.Dir(path), os.ModePerm))
+		assert.Nil(t, os.WriteFile(path, []byte(content), 0644))
+	}
+	write("a.md", "# Note A\n")
+	write("b.md", "# Note B\n")
+	write("dir/c.md", "# Note C\n")
+	write("dir/image.png", "")
+
+	notebook := NewNotebook(dir, NewDefaultConfig(), NotebookPorts{
+		NoteIndex:         newNoteIndexWatchMock(),
+		NoteContentParser: noteContentParserFunc(parseTitleForTest),
+		FS:                &osFileStorageMock{newFileStorageMock(dir, []string{})},
+		Logger:            &util.NullLogger,
+	})
+
+	test := func(expectedEvents []string, expectedStats NoteIndexingStats) {
+		progress := &noteIndexProgressMock{}
+		stats, err := notebook.IndexWithProgress(NoteIndexOpts{}, progress)
+		assert.Nil(t, err)
+		assert.Equal(t, progress.events, expectedEvents)
+		assert.Equal(t, len(progress.done), 1)
+		assert.Equal(t, progress.done[0], stats)
+
+		stats.Duration = 0
+		assert.Equal(t, stats, expectedStats)
+	}
+
+	test([]string{
+		"added a.md",
+		"added b.md",
+		"added dir/c.md",
+	}, NoteIndexingStats{SourceCount: 3, AddedCount: 3})
+
+	write("b.md", "# Note B, modified\n")
+	write("d.md", "# Note D\n")
+	assert.Nil(t, os.Remove(filepath.Join(dir, "dir/c.md")))
+
+	test([]string{
+		"unchanged a.md",
+		"modified b.md",
+		"added d.md",
+		"removed dir/c.md",
+	}, NoteIndexingStats{SourceCount: 3, AddedCount: 1, ModifiedCount: 1, RemovedCount: 1, UnchangedCount: 1})
+	// A moved note is reported with its own kind.
+	assert.Nil(t, os.Rename(filepath.Join(dir, "d.md"), filepath.Join(dir, "dir/e.md")))
+
+	test([]string{
+		"unchanged a.md",
+		"unchanged b.md",
+		"moved dir/e.md",
+	}, NoteIndexingStats{SourceCount: 3, MovedCount: 1, UnchangedCount: 2})
+}
+
+func TestNotebookIndexFailsWhenAlreadyIndexing(t *testing.T) {
+	dir := t.TempDir()
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "a.md"), []byte("# Note A\n"), 0644))
+
+	index := newNoteIndexWatchMock()
+	notebook := NewNotebook(dir, NewDefaultConfig(), NotebookPorts{
+		NoteIndex:         index,
+		NoteContentParser: noteContentParserFunc(parseTitleForTest),
+		FS:                &osFileStorageMock{newFileStorageMock(dir, []string{})},
+		Logger:            &util.NullLogger,
+	})
+
+	lock, err := lockfile.Acquire(filepath.Join(dir, ".zk", "index.lock"))
+	assert.Nil(t, err)
+
+	_, err = notebook.Index(NoteIndexOpts{})
+	assert.NotNil(t, err)
+	assert.True(t, strings.Contains(err.Error(), "another index is in progress"))
+	assert.Equal(t, index.titles(), map[string]string{})
+
+	// Waits for the other indexing to complete.
+	go func() {
+		time.Sleep(150 * time.Millisecond)
+		lock.Release()
+	}()
+	_, err = notebook.Index(NoteIndexOpts{LockTimeout: 5 * time.Second})
+	assert.Nil(t, err)
+	assert.Equal(t, index.titles(), map[string]string{"a.md": "Note A"})
+
+	// The lock is released after indexing.
+	_, err = os.Stat(filepath.Join(dir, ".zk", "index.lock"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func BenchmarkIndexTask(b *testing.B) {
+	dir := writeNotebookTree(b, 500)
+
+	for _, workers := range []int{1, 0} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				task := indexTask{
+					path:    dir,
+					config:  NewDefaultConfig(),
+					workers: workers,
+					index:   newNoteIndexRecorderMock(map[string]paths.Metadata{}),
+					fs:      &osFileStorageMock{newFileStorageMock(dir, []string{})},
+					parser:  noteParserFunc(parseNoteForTest),
+					logger:  &util.NullLogger,
+				}
+				_, err := task.execute(func(change paths.DiffChange) {})
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// writeNotebookTree creates a temporary notebook with the given number of
+// notes, including a few invalid ones.
+func writeNotebookTree(t testing.TB, count int) string {
+	dir := t.TempDir()
+	for i := 0; i < count; i++ {
+		path := filepath.Join(dir, fmt.Sprintf("dir-%d", i%10), fmt.Sprintf("note-%d.md", i))
+		if i%100 == 42 {
+			path = strings.TrimSuffix(path, ".md") + "-invalid.md"
+		}
+		content := fmt.Sprintf("# Note %d\n\n%s\n", i, strings.Repeat("Some content. ", i%50))
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// parseNoteForTest parses a note with its first line as title, and fails for
+// the invalid notes.
+func parseNoteForTest(absPath string) (*Note, error) {
+	if strings.HasSuffix(absPath, "-invalid.md") {
+		return nil, errors.New(absPath + ": invalid note")
+	}
+	content, err := os.ReadFile(absPath)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(absPath)
+	if err != nil {
+		return nil, err
+	}
+	return &Note{
+		Path:       filepath.Base(absPath),
+		Title:      strings.SplitN(string(content), "\n", 2)[0],
+		RawContent: string(content),
+		Checksum:   ChecksumSHA256.Sum(content, false),
+		Size:       info.Size(),
+		Modified:   info.ModTime(),
+	}, nil
+}
+
+type noteParserFunc func(absPath string) (*Note, error)
+
+func (f noteParserFunc) ParseNoteAt(absPath string) (*Note, error) {
+	return f(absPath)
+}
+
+// noteIndexRecorderMock records the changes applied to the index, in order.
+type noteIndexRecorderMock struct {
+	noteIndexAddMock
+	indexed map[string]paths.Metadata
+	changes []string
+}
+
+func newNoteIndexRecorderMock(indexed map[string]paths.Metadata) *noteIndexRecorderMock {
+	return &noteIndexRecorderMock{indexed: indexed}
+}
+
+func (m *noteIndexRecorderMock) IndexedPaths() (<-chan paths.Metadata, error) {
+	sorted := []string{}
+	for path := range m.indexed {
+		sorted = append(sorted, path)
+	}
+	sort.Strings(sorted)
+
+	c := make(chan paths.Metadata, len(sorted))
+	for _, path := range sorted {
+		c <- m.indexed[path]
+	}
+	close(c)
+	return c, nil
+}
+
+func (m *noteIndexRecorderMock) Add(note Note) (NoteID, error) {
+	m.changes = append(m.changes, "add "+note.Path+" "+note.Title+" "+note.Checksum)
+	return NoteID(len(m.changes)), nil
+}
+
+func (m *noteIndexRecorderMock) Update(note Note) error {
+	m.changes = append(m.changes, "update "+note.Path+" "+note.Title+" "+note.Checksum)
+	return nil
+}
+
+func (m *noteIndexRecorderMock) SetModified(path string, modified time.Time, size int64) error {
+	m.changes = append(m.changes, fmt.Sprintf("touch %s %d", path, size))
+	return nil
+}
+
+func (m *noteIndexRecorderMock) Remove(path string) error {
+	m.changes = append(m.changes, "remove "+path)
+	return nil
+}
+
+// noteIndexProgressMock records the progress events of the indexing.
+type noteIndexProgressMock struct {
+	events []string
+	done   []NoteIndexingStats
+}
+
+func (m *noteIndexProgressMock) FileIndexed(path string, action paths.DiffKind) {
+	m.events = append(m.events, action.String()+" "+path)
+}
+
+func (m *noteIndexProgressMock) Done(stats NoteIndexingStats) {
+	m.done = append(m.done, stats)
+}
+
+// unreadableFileStorageMock fails to read the file at the given path.
+type unreadableFileStorageMock struct {
+	*osFileStorageMock
+	path string
+}
+
+func (fs *unreadableFileStorageMock) Read(path string) ([]byte, error) {
+	if path == fs.path {
+		return nil, os.ErrPermission
+	}
+	return fs.osFileStorageMock.Read(path)
+}
+
+// noteIndexFailingMock fails to add notes to the index.
+type noteIndexFailingMock struct {
+	*noteIndexWatchMock
+}
+
+func (m *noteIndexFailingMock) Add(note Note) (NoteID, error) {
+	return 0, errors.New("database is locked")
+}
+
+func (m *noteIndexFailingMock) Commit(transaction func(idx NoteIndex) error) error {
+	return transaction(m)
+}
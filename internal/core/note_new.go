@@ -10,12 +10,14 @@ import (
 
 type newNoteTask struct {
 	dir              Dir
+	group            string
 	title            string
 	content          string
 	date             time.Time
 	extra            map[string]string
 	env              map[string]string
 	fs               FileStorage
+	index            NoteIndex
 	filenameTemplate string
 	bodyTemplatePath opt.String
 	templates        TemplateLoader
@@ -38,12 +40,14 @@ func (t *newNoteTask) execute() (string, string, error) {
 	}
 
 	context := newNoteTemplateContext{
-		Title:   t.title,
-		Content: t.content,
-		Dir:     t.dir.Name,
-		Extra:   t.extra,
-		Now:     t.date,
-		Env:     t.env,
+		Title:    t.title,
+		Content:  t.content,
+		Dir:      t.dir.Name,
+		Group:    t.group,
+		Extra:    t.extra,
+		NoteDate: t.date,
+		Now:      t.date,
+		Env:      t.env,
 	}
 
 	path, context, err := t.generatePath(context, filenameTemplate)
@@ -66,12 +70,18 @@ func (t *newNoteTask) execute() (string, string, error) {
 	return path, content, nil
 }
 
+// newNoteMaxAttempts is the number of IDs generated before giving up on
+// finding a free path for a new note.
+const newNoteMaxAttempts = 50
+
+// generatePath renders the filename template with new IDs until the path is
+// free on the file system and in the index.
 func (c *newNoteTask) generatePath(context newNoteTemplateContext, filenameTemplate Template) (string, newNoteTemplateContext, error) {
 	var err error
 	var filename string
 	var path string
 
-	for i := 0; i < 50; i++ {
+	for i := 0; i < newNoteMaxAttempts; i++ {
 		context.ID = c.genID()
 
 		filename, err = filenameTemplate.Render(context)
@@ -80,7 +90,7 @@ func (c *newNoteTask) generatePath(context newNoteTemplateContext, filenameTempl
 		}
 
 		path = filepath.Join(c.dir.Path, filename)
-		exists, err := c.fs.FileExists(path)
+		exists, err := c.exists(path, filepath.Join(c.dir.Name, filename))
 		if err != nil {
 			return "", context, err
 		} else if !exists {
@@ -96,15 +106,27 @@ func (c *newNoteTask) generatePath(context newNoteTemplateContext, filenameTempl
 	}
 }
 
+// exists returns whether a note file exists at the given absolute path, or
+// is indexed at the given path relative to the notebook root.
+func (c *newNoteTask) exists(path string, relPath string) (bool, error) {
+	exists, err := c.fs.FileExists(path)
+	if err != nil || exists {
+		return exists, err
+	}
+	return c.index.Exists(relPath)
+}
+
 // newNoteTemplateContext holds the placeholder values which will be expanded in the templates.
 type newNoteTemplateContext struct {
 	ID           string `handlebars:"id"`
 	Title        string
 	Content      string
 	Dir          string
+	Group        string
 	Filename     string
 	FilenameStem string `handlebars:"filename-stem"`
 	Extra        map[string]string
+	NoteDate     time.Time `handlebars:"note-date"`
 	Now          time.Time
 	Env          map[string]string
 }
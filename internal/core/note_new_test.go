@@ -1,6 +1,7 @@
 package core
 
 import (
+	"context"
 	"fmt"
 	"testing"
 	"time"
@@ -46,6 +47,7 @@ func TestNotebookNewNote(t *testing.T) {
 			Filename:     "",
 			FilenameStem: "",
 			Extra:        map[string]string{"add-extra": "ec83da", "conf-extra": "38srnw"},
+			NoteDate:     now,
 			Now:          now,
 			Env:          map[string]string{"KEY1": "foo", "KEY2": "bar"},
 		},
@@ -59,6 +61,7 @@ func TestNotebookNewNote(t *testing.T) {
 			Filename:     "filename.ext",
 			FilenameStem: "filename",
 			Extra:        map[string]string{"add-extra": "ec83da", "conf-extra": "38srnw"},
+			NoteDate:     now,
 			Now:          now,
 			Env:          map[string]string{"KEY1": "foo", "KEY2": "bar"},
 		},
@@ -78,11 +81,12 @@ func TestNotebookNewNoteWithDefaultTitle(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, test.filenameTemplate.Contexts, []interface{}{
 		newNoteTemplateContext{
-			ID:    "id",
-			Title: "Titre par défaut",
-			Extra: map[string]string{"conf-extra": "38srnw"},
-			Now:   now,
-			Env:   map[string]string{"KEY1": "foo", "KEY2": "bar"},
+			ID:       "id",
+			Title:    "Titre par défaut",
+			Extra:    map[string]string{"conf-extra": "38srnw"},
+			NoteDate: now,
+			Now:      now,
+			Env:      map[string]string{"KEY1": "foo", "KEY2": "bar"},
 		},
 	})
 }
@@ -129,6 +133,7 @@ func TestNotebookNewNoteInDir(t *testing.T) {
 			Filename:     "",
 			FilenameStem: "",
 			Extra:        map[string]string{"conf-extra": "38srnw"},
+			NoteDate:     now,
 			Now:          now,
 			Env:          map[string]string{"KEY1": "foo", "KEY2": "bar"},
 		},
@@ -142,6 +147,7 @@ func TestNotebookNewNoteInDir(t *testing.T) {
 			Filename:     "filename.ext",
 			FilenameStem: "filename",
 			Extra:        map[string]string{"conf-extra": "38srnw"},
+			NoteDate:     now,
 			Now:          now,
 			Env:          map[string]string{"KEY1": "foo", "KEY2": "bar"},
 		},
@@ -202,9 +208,11 @@ func TestNotebookNewNoteInDirWithGroup(t *testing.T) {
 			Title:        "Group default title",
 			Content:      "",
 			Dir:          "a-dir",
+			Group:        "group-a",
 			Filename:     "",
 			FilenameStem: "",
 			Extra:        map[string]string{"group-extra": "e48rs"},
+			NoteDate:     now,
 			Now:          now,
 			Env:          map[string]string{"KEY1": "foo", "KEY2": "bar"},
 		},
@@ -215,9 +223,11 @@ func TestNotebookNewNoteInDirWithGroup(t *testing.T) {
 			Title:        "Group default title",
 			Content:      "",
 			Dir:          "a-dir",
+			Group:        "group-a",
 			Filename:     "group-filename.group-ext",
 			FilenameStem: "group-filename",
 			Extra:        map[string]string{"group-extra": "e48rs"},
+			NoteDate:     now,
 			Now:          now,
 			Env:          map[string]string{"KEY1": "foo", "KEY2": "bar"},
 		},
@@ -277,9 +287,11 @@ func TestNotebookNewNoteWithGroup(t *testing.T) {
 			Title:        "Group default title",
 			Content:      "",
 			Dir:          "",
+			Group:        "group-a",
 			Filename:     "",
 			FilenameStem: "",
 			Extra:        map[string]string{"group-extra": "e48rs"},
+			NoteDate:     now,
 			Now:          now,
 			Env:          map[string]string{"KEY1": "foo", "KEY2": "bar"},
 		},
@@ -290,15 +302,159 @@ func TestNotebookNewNoteWithGroup(t *testing.T) {
 			Title:        "Group default title",
 			Content:      "",
 			Dir:          "",
+			Group:        "group-a",
 			Filename:     "group-filename.group-ext",
 			FilenameStem: "group-filename",
 			Extra:        map[string]string{"group-extra": "e48rs"},
+			NoteDate:     now,
 			Now:          now,
 			Env:          map[string]string{"KEY1": "foo", "KEY2": "bar"},
 		},
 	})
 }
 
+// An explicit group overrides the group of the target directory.
+func TestNotebookNewNoteInDirWithExplicitGroup(t *testing.T) {
+	test := newNoteTest{
+		rootDir: "/notebook",
+		dirs:    []string{"/notebook/log"},
+		groups: map[string]GroupConfig{
+			"log": {
+				Paths: []string{"log"},
+				Note: NoteConfig{
+					FilenameTemplate: "log-filename",
+					Extension:        "ext",
+				},
+				Extra: map[string]string{},
+			},
+			"draft": {
+				Paths: []string{"drafts"},
+				Note: NoteConfig{
+					FilenameTemplate: "draft-filename",
+					Extension:        "ext",
+				},
+				Extra: map[string]string{},
+			},
+		},
+	}
+	test.setup()
+
+	test.templateLoader.SpyString("log-filename.ext")
+	draftTemplate := test.templateLoader.SpyString("draft-filename.ext")
+
+	note, err := test.run(NewNoteOpts{
+		Directory: opt.NewString("log"),
+		Date:      now,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, note.Path, "log/log-filename.ext")
+
+	note, err = test.run(NewNoteOpts{
+		Directory: opt.NewString("log"),
+		Group:     opt.NewString("draft"),
+		Date:      now,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, note.Path, "log/draft-filename.ext")
+	assert.Equal(t, draftTemplate.Contexts[0].(newNoteTemplateContext).Group, "draft")
+}
+
+// A note created in a subdirectory of one of the group paths inherits its
+// config, and the group name is available to the templates.
+func TestNotebookNewNoteInNestedDirOfGroup(t *testing.T) {
+	test := newNoteTest{
+		rootDir: "/notebook",
+		dirs:    []string{"/notebook/journal/2021", "/notebook/log/2021/01"},
+		groups: map[string]GroupConfig{
+			"daily": {
+				Paths: []string{"log", "journal"},
+				Note: NoteConfig{
+					FilenameTemplate: "daily-filename",
+					Extension:        "ext",
+				},
+				Extra: map[string]string{},
+			},
+		},
+	}
+	test.setup()
+
+	filenameTemplate := test.templateLoader.SpyString("daily-filename.ext")
+
+	note, err := test.run(NewNoteOpts{
+		Directory: opt.NewString("journal/2021"),
+		Date:      now,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, note.Path, "journal/2021/daily-filename.ext")
+
+	note, err = test.run(NewNoteOpts{
+		Directory: opt.NewString("log/2021/01"),
+		Date:      now,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, note.Path, "log/2021/01/daily-filename.ext")
+
+	assert.Equal(t, len(filenameTemplate.Contexts), 2)
+	for _, context := range filenameTemplate.Contexts {
+		assert.Equal(t, context.(newNoteTemplateContext).Group, "daily")
+	}
+}
+
+// The extra variables of a group override the ones of the notebook, and are
+// overridden by the ones given when creating the note.
+func TestNotebookNewNoteExtraPrecedence(t *testing.T) {
+	test := newNoteTest{
+		rootDir: "/notebook",
+		dirs:    []string{"/notebook/log"},
+		groups: map[string]GroupConfig{
+			"log": {
+				Paths: []string{"log"},
+				Note: NoteConfig{
+					FilenameTemplate: "filename",
+					Extension:        "ext",
+				},
+				Extra: map[string]string{
+					"conf-extra":  "group",
+					"group-extra": "group",
+				},
+			},
+		},
+	}
+	test.setup()
+
+	_, err := test.run(NewNoteOpts{
+		Directory: opt.NewString("log"),
+		Extra:     map[string]string{"group-extra": "cli", "cli-extra": "cli"},
+		Date:      now,
+		DryRun:    true,
+	})
+	assert.Nil(t, err)
+
+	_, err = test.run(NewNoteOpts{
+		Extra:  map[string]string{"conf-extra": "cli"},
+		Date:   now,
+		DryRun: true,
+	})
+	assert.Nil(t, err)
+
+	_, err = test.run(NewNoteOpts{
+		Date:   now,
+		DryRun: true,
+	})
+	assert.Nil(t, err)
+
+	extras := []map[string]string{}
+	for _, context := range test.filenameTemplate.Contexts {
+		extras = append(extras, context.(newNoteTemplateContext).Extra)
+	}
+	assert.Equal(t, extras, []map[string]string{
+		{"conf-extra": "group", "group-extra": "cli", "cli-extra": "cli"},
+		{"conf-extra": "cli"},
+		// The extra variables of the previous notes didn't leak in the config.
+		{"conf-extra": "38srnw"},
+	})
+}
+
 func TestNotebookNewNoteWithUnknownGroup(t *testing.T) {
 	test := newNoteTest{
 		rootDir: "/notebook",
@@ -329,6 +485,31 @@ func TestNotebookNewNoteWithCustomTemplate(t *testing.T) {
 	assert.Equal(t, test.fs.files["/notebook/"+note.Path], "custom body template")
 }
 
+// The date of the note is provided as note-date and now.
+func TestNotebookNewNoteWithPastDate(t *testing.T) {
+	test := newNoteTest{
+		rootDir: "/notebook",
+	}
+	test.setup()
+
+	yesterday := now.AddDate(0, 0, -1)
+	_, err := test.run(NewNoteOpts{
+		Date: yesterday,
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, test.filenameTemplate.Contexts, []interface{}{
+		newNoteTemplateContext{
+			ID:       "id",
+			Title:    "Titre par défaut",
+			Extra:    map[string]string{"conf-extra": "38srnw"},
+			NoteDate: yesterday,
+			Now:      yesterday,
+			Env:      map[string]string{"KEY1": "foo", "KEY2": "bar"},
+		},
+	})
+}
+
 // Tries to generate a filename until one is free.
 func TestNotebookNewNoteTriesUntilFreePath(t *testing.T) {
 	test := newNoteTest{
@@ -379,6 +560,51 @@ func TestNotebookNewNoteErrorWhenNoFreePath(t *testing.T) {
 	assert.Equal(t, test.fs.files, files)
 }
 
+// Skips the IDs of notes which are indexed but not found on the file system.
+func TestNotebookNewNoteSkipsIndexedPath(t *testing.T) {
+	test := newNoteTest{
+		rootDir: "/notebook",
+		dirs:    []string{"/notebook/a-dir"},
+		indexed: []string{"a-dir/filename1.ext", "a-dir/filename2.ext"},
+		filenameTemplateRender: func(context newNoteTemplateContext) string {
+			return "filename" + context.ID + ".ext"
+		},
+		idGeneratorFactory: incrementingID,
+	}
+	test.setup()
+
+	note, err := test.run(NewNoteOpts{
+		Directory: opt.NewString("a-dir"),
+		Date:      now,
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, note.Path, "a-dir/filename3.ext")
+}
+
+func TestNotebookNewNoteErrorWhenNoFreeIndexedPath(t *testing.T) {
+	indexed := []string{}
+	for i := 1; i <= newNoteMaxAttempts; i++ {
+		indexed = append(indexed, fmt.Sprintf("filename%d.ext", i))
+	}
+	test := newNoteTest{
+		rootDir: "/notebook",
+		indexed: indexed,
+		filenameTemplateRender: func(context newNoteTemplateContext) string {
+			return "filename" + context.ID + ".ext"
+		},
+		idGeneratorFactory: incrementingID,
+	}
+	test.setup()
+
+	_, err := test.run(NewNoteOpts{
+		Date: now,
+	})
+
+	assert.Err(t, err, "/notebook/filename50.ext: note already exists")
+	assert.Equal(t, test.fs.files, map[string]string{})
+}
+
 var now = time.Date(2009, 11, 17, 20, 34, 58, 651387237, time.UTC)
 
 // newNoteTest builds and runs the SUT for new note test cases.
@@ -386,6 +612,7 @@ type newNoteTest struct {
 	rootDir                string
 	files                  map[string]string
 	dirs                   []string
+	indexed                []string
 	fs                     *fileStorageMock
 	index                  *noteIndexAddMock
 	parser                 *noteContentParserMock
@@ -415,7 +642,10 @@ func (t *newNoteTest) setup() {
 		t.fs.files = t.files
 	}
 
-	t.index = &noteIndexAddMock{ReturnedID: 42}
+	t.index = &noteIndexAddMock{ReturnedID: 42, Indexed: map[string]bool{}}
+	for _, path := range t.indexed {
+		t.index.Indexed[path] = true
+	}
 	t.parser = newNoteContentParserMock(map[string]*NoteContent{})
 
 	t.templateLoader = newTemplateLoaderMock()
@@ -500,23 +730,57 @@ func incrementingID(opts IDOptions) func() string {
 
 type noteIndexAddMock struct {
 	ReturnedID NoteID
+	// Indexed holds the paths of the notes already indexed.
+	Indexed map[string]bool
 }
 
-func (m *noteIndexAddMock) Find(opts NoteFindOpts) ([]ContextualNote, error)     { return nil, nil }
-func (m *noteIndexAddMock) FindMinimal(opts NoteFindOpts) ([]MinimalNote, error) { return nil, nil }
+func (m *noteIndexAddMock) Find(ctx context.Context, opts NoteFindOpts) ([]ContextualNote, error) {
+	return nil, nil
+}
+func (m *noteIndexAddMock) FindMinimal(ctx context.Context, opts NoteFindOpts) ([]MinimalNote, error) {
+	return nil, nil
+}
+func (m *noteIndexAddMock) Count(ctx context.Context, opts NoteFindOpts) (int, error) { return 0, nil }
+func (m *noteIndexAddMock) Stats(ctx context.Context, opts NoteFindOpts) (NoteStats, error) {
+	return NoteStats{}, nil
+}
 func (m *noteIndexAddMock) FindLinkMatch(baseDir string, href string, linkType LinkType) (NoteID, error) {
 	return 0, nil
 }
 func (m *noteIndexAddMock) FindLinksBetweenNotes(ids []NoteID) ([]ResolvedLink, error) {
 	return nil, nil
 }
-func (m *noteIndexAddMock) FindCollections(kind CollectionKind, sorters []CollectionSorter) ([]Collection, error) {
+func (m *noteIndexAddMock) FindDanglingLinks(ids []NoteID) ([]ResolvedLink, error) {
+	return nil, nil
+}
+func (m *noteIndexAddMock) FindCollections(kind CollectionKind, opts CollectionFindOpts) ([]Collection, error) {
+	return nil, nil
+}
+func (m *noteIndexAddMock) IndexedPaths() (<-chan paths.Metadata, error) { return nil, nil }
+func (m *noteIndexAddMock) Exists(path string) (bool, error)             { return m.Indexed[path], nil }
+func (m *noteIndexAddMock) Add(note Note) (NoteID, error)                { return m.ReturnedID, nil }
+func (m *noteIndexAddMock) Update(note Note) error                       { return nil }
+func (m *noteIndexAddMock) Remove(path string) error                     { return nil }
+func (m *noteIndexAddMock) Rename(oldPath string, newPath string) error {
+	return nil
+}
+func (m *noteIndexAddMock) FindByChecksum(checksum string) ([]MinimalNote, error) {
 	return nil, nil
 }
-func (m *noteIndexAddMock) IndexedPaths() (<-chan paths.Metadata, error)       { return nil, nil }
-func (m *noteIndexAddMock) Add(note Note) (NoteID, error)                      { return m.ReturnedID, nil }
-func (m *noteIndexAddMock) Update(note Note) error                             { return nil }
-func (m *noteIndexAddMock) Remove(path string) error                           { return nil }
 func (m *noteIndexAddMock) Commit(transaction func(idx NoteIndex) error) error { return nil }
-func (m *noteIndexAddMock) NeedsReindexing() (bool, error)                     { return false, nil }
-func (m *noteIndexAddMock) SetNeedsReindexing(needsReindexing bool) error      { return nil }
+func (m *noteIndexAddMock) RebuildSearchIndex() error                          { return nil }
+func (m *noteIndexAddMock) Check(repair bool) (NoteIndexCheckReport, error) {
+	return nil, nil
+}
+func (m *noteIndexAddMock) ChecksumAlgorithm() (ChecksumAlgorithm, error) { return ChecksumSHA256, nil }
+func (m *noteIndexAddMock) SetChecksumAlgorithm(algorithm ChecksumAlgorithm) error {
+	return nil
+}
+func (m *noteIndexAddMock) ChecksumTrimSpaces() (bool, error)              { return false, nil }
+func (m *noteIndexAddMock) SetChecksumTrimSpaces(trimSpaces bool) error    { return nil }
+func (m *noteIndexAddMock) SetChecksum(path string, checksum string) error { return nil }
+func (m *noteIndexAddMock) SetModified(path string, modified time.Time, size int64) error {
+	return nil
+}
+func (m *noteIndexAddMock) NeedsReindexing() (bool, error)                { return false, nil }
+func (m *noteIndexAddMock) SetNeedsReindexing(needsReindexing bool) error { return nil }
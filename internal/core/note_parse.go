@@ -1,16 +1,15 @@
 package core
 
 import (
-	"crypto/sha256"
-	"fmt"
 	"path/filepath"
 	"strings"
 	"time"
 
+	"github.com/relvacode/iso8601"
+	"github.com/zk-org/zk/internal/util"
 	"github.com/zk-org/zk/internal/util/errors"
 	"github.com/zk-org/zk/internal/util/opt"
 	strutil "github.com/zk-org/zk/internal/util/strings"
-	"github.com/relvacode/iso8601"
 	"gopkg.in/djherbis/times.v1"
 )
 
@@ -34,10 +33,25 @@ type NoteContent struct {
 	Body opt.String
 	// Tags is the list of tags found in the note content.
 	Tags []string
+	// Aliases is the list of alternative names of the note.
+	Aliases []string
 	// Links is the list of outbound links found in the note.
 	Links []Link
 	// Additional metadata. For example, extracted from a YAML frontmatter.
 	Metadata map[string]interface{}
+	// WordCount is the number of words in the note prose.
+	WordCount int
+}
+
+// ParserForPath returns the content parser of the note at the given path,
+// selected by its file extension among the NoteContentParsers port, e.g. org.
+// Markdown is the default format.
+func (n *Notebook) ParserForPath(path string) NoteContentParser {
+	ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(path), "."))
+	if parser, ok := n.parsers[ext]; ok {
+		return parser
+	}
+	return n.Parser
 }
 
 // ParseNoteAt implements NoteParser.
@@ -61,32 +75,37 @@ func (n *Notebook) ParseNoteWithContent(absPath string, content []byte) (*Note,
 	}
 
 	contentStr := string(content)
-	contentParts, err := n.Parser.ParseNoteContent(contentStr)
+	contentParts, err := n.ParserForPath(relPath).ParseNoteContent(contentStr)
 	if err != nil {
 		return nil, wrap(err)
 	}
 
 	note := Note{
 		Path:       relPath,
-		Title:      contentParts.Title.String(),
+		Title:      contentParts.Title.OrString(filenameTitle(relPath)).String(),
 		Lead:       contentParts.Lead.String(),
 		Body:       contentParts.Body.String(),
 		RawContent: contentStr,
-		WordCount:  len(strings.Fields(contentStr)),
+		WordCount:  contentParts.WordCount,
 		Links:      make([]Link, 0),
 		Tags:       contentParts.Tags,
+		Aliases:    contentParts.Aliases,
 		Metadata:   contentParts.Metadata,
-		Checksum:   fmt.Sprintf("%x", sha256.Sum256(content)),
+		Checksum:   n.Config.Notebook.Checksum.Sum(content, n.Config.Notebook.ChecksumTrimSpaces),
+		Size:       int64(len(content)),
 	}
 
 	for _, link := range contentParts.Links {
-		if !strutil.IsURL(link.Href) && link.Type == LinkTypeMarkdown {
+		// Anchors in the note itself, e.g. #section, are not resolved.
+		if !strutil.IsURL(link.Href) && !strings.HasPrefix(link.Href, "#") && link.Type == LinkTypeMarkdown {
 			// Make the href relative to the notebook root.
 			href := filepath.Join(filepath.Dir(absPath), link.Href)
-			link.Href, err = n.RelPath(href)
-			if err != nil {
+			if relHref, err := n.RelPath(href); err != nil {
+				// The link targets a file outside the notebook, it is kept
+				// as-is and will stay unresolved.
 				n.logger.Err(err)
-				continue
+			} else {
+				link.Href = relHref
 			}
 		}
 		note.Links = append(note.Links, link)
@@ -95,27 +114,28 @@ func (n *Notebook) ParseNoteWithContent(absPath string, content []byte) (*Note,
 	times, err := times.Stat(absPath)
 	if err == nil {
 		note.Modified = times.ModTime().UTC()
-		note.Created = creationDateFrom(note.Metadata, times)
+
+		filenameDateFormat := ""
+		if group, err := n.Config.GroupConfigForPath(relPath); err == nil {
+			filenameDateFormat = group.Note.FilenameDateFormat
+		}
+		note.Created = creationDateFrom(note.Metadata, note.FilenameStem(), filenameDateFormat, times, n.logger)
 	}
 
 	return &note, nil
 }
 
-func creationDateFrom(metadata map[string]interface{}, times times.Timespec) time.Time {
-	// Read the creation date from the YAML frontmatter `date` key.
-	if dateVal, ok := metadata["date"]; ok {
-		if dateStr, ok := dateVal.(string); ok {
-			if time, err := iso8601.ParseString(dateStr); err == nil {
-				return time
-			}
-			// Omitting the `T` is common
-			if time, err := time.Parse("2006-01-02 15:04:05", dateStr); err == nil {
-				return time
-			}
-			if time, err := time.Parse("2006-01-02 15:04", dateStr); err == nil {
-				return time
-			}
-		}
+// creationDateFrom returns the creation date of a note, read in order from:
+//
+//  1. the `date` or `created` keys of the frontmatter,
+//  2. a date embedded in the filename, matching the given Go layout,
+//  3. the file creation time.
+func creationDateFrom(metadata map[string]interface{}, filename string, filenameDateFormat string, times times.Timespec, logger util.Logger) time.Time {
+	if date, ok := creationDateFromMetadata(metadata, logger); ok {
+		return date
+	}
+	if date, ok := creationDateFromFilename(filename, filenameDateFormat, logger); ok {
+		return date
 	}
 
 	if times.HasBirthTime() {
@@ -124,3 +144,64 @@ func creationDateFrom(metadata map[string]interface{}, times times.Timespec) tim
 
 	return time.Now().UTC()
 }
+
+func creationDateFromMetadata(metadata map[string]interface{}, logger util.Logger) (time.Time, bool) {
+	// Read the creation date from the YAML frontmatter `date` or `created`
+	// keys.
+	for _, key := range []string{"date", "created"} {
+		if dateVal, ok := metadata[key]; ok {
+			if dateStr, ok := dateVal.(string); ok {
+				if time, err := iso8601.ParseString(dateStr); err == nil {
+					return time, true
+				}
+				// Omitting the `T` is common
+				if time, err := time.Parse("2006-01-02 15:04:05", dateStr); err == nil {
+					return time, true
+				}
+				if time, err := time.Parse("2006-01-02 15:04", dateStr); err == nil {
+					return time, true
+				}
+				logger.Printf("warning: %s: invalid creation date in the frontmatter `%s` key", dateStr, key)
+			}
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// creationDateFromFilename finds a date matching the given Go layout in the
+// filename, e.g. 2006-01-02 for log/2021-01-03.md.
+func creationDateFromFilename(filename string, layout string, logger util.Logger) (time.Time, bool) {
+	if layout == "" || len(filename) < len(layout) {
+		return time.Time{}, false
+	}
+
+	var dates []time.Time
+	for i := 0; i+len(layout) <= len(filename); i++ {
+		date, err := time.Parse(layout, filename[i:i+len(layout)])
+		if err != nil {
+			continue
+		}
+		if len(dates) == 0 || !date.Equal(dates[len(dates)-1]) {
+			dates = append(dates, date)
+		}
+		i += len(layout) - 1
+	}
+
+	switch len(dates) {
+	case 0:
+		return time.Time{}, false
+	case 1:
+		return dates[0], true
+	default:
+		logger.Printf("warning: %s: ambiguous creation date in the filename, several dates match %s", filename, layout)
+		return time.Time{}, false
+	}
+}
+
+// filenameTitle returns the title of a note without a heading nor a title in
+// its frontmatter, which is its filename without extension.
+func filenameTitle(path string) string {
+	filename := filepath.Base(path)
+	return strings.TrimSuffix(filename, filepath.Ext(filename))
+}
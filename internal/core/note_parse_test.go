@@ -1,5 +1,15 @@
 package core
 
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/zk-org/zk/internal/util"
+	"github.com/zk-org/zk/internal/util/opt"
+	"github.com/zk-org/zk/internal/util/test/assert"
+)
+
 type noteContentParserMock struct {
 	results map[string]*NoteContent
 }
@@ -16,3 +26,167 @@ func (p *noteContentParserMock) ParseNoteContent(content string) (*NoteContent,
 	}
 	return &NoteContent{}, nil
 }
+
+func TestParseNoteResolvesMarkdownLinks(t *testing.T) {
+	links := []Link{
+		{Href: "../ref/test/a.md", Type: LinkTypeMarkdown},
+		{Href: "b.md#section", Type: LinkTypeMarkdown},
+		{Href: "#section", Type: LinkTypeMarkdown},
+		{Href: "../../../outside.md", Type: LinkTypeMarkdown},
+		{Href: "https://example.com/a.md", Type: LinkTypeMarkdown, IsExternal: true},
+		{Href: "mailto:hi@example.com", Type: LinkTypeMarkdown, IsExternal: true},
+		{Href: "../wiki", Type: LinkTypeWikiLink},
+	}
+	logger := &loggerMock{}
+	notebook := NewNotebook("/notebook", NewDefaultConfig(), NotebookPorts{
+		NoteContentParser: newNoteContentParserMock(map[string]*NoteContent{
+			"content": {Links: links},
+		}),
+		FS:     newFileStorageMock("/notebook", []string{}),
+		Logger: logger,
+	})
+
+	note, err := notebook.ParseNoteWithContent("/notebook/log/daily/note.md", []byte("content"))
+	assert.Nil(t, err)
+	assert.Equal(t, note.Path, "log/daily/note.md")
+	assert.Equal(t, note.Links, []Link{
+		{Href: "log/ref/test/a.md", Type: LinkTypeMarkdown},
+		{Href: "log/daily/b.md#section", Type: LinkTypeMarkdown},
+		{Href: "#section", Type: LinkTypeMarkdown},
+		// Links escaping the notebook are kept unresolved.
+		{Href: "../../../outside.md", Type: LinkTypeMarkdown},
+		{Href: "https://example.com/a.md", Type: LinkTypeMarkdown, IsExternal: true},
+		{Href: "mailto:hi@example.com", Type: LinkTypeMarkdown, IsExternal: true},
+		{Href: "../wiki", Type: LinkTypeWikiLink},
+	})
+	assert.Equal(t, logger.errs, []string{
+		"/outside.md: path is outside the notebook at /notebook",
+	})
+}
+
+func TestParseNoteSelectsParserByExtension(t *testing.T) {
+	notebook := NewNotebook("/notebook", NewDefaultConfig(), NotebookPorts{
+		NoteContentParser: newNoteContentParserMock(map[string]*NoteContent{
+			"content": {Title: opt.NewString("Markdown")},
+		}),
+		NoteContentParsers: map[string]NoteContentParser{
+			"org": newNoteContentParserMock(map[string]*NoteContent{
+				"content": {Title: opt.NewString("Org"), Links: []Link{{Href: "../a.org", Type: LinkTypeMarkdown}}},
+			}),
+		},
+		FS:     newFileStorageMock("/notebook", []string{}),
+		Logger: &util.NullLogger,
+	})
+
+	test := func(path string, expectedTitle string) {
+		note, err := notebook.ParseNoteWithContent(path, []byte("content"))
+		assert.Nil(t, err)
+		assert.Equal(t, note.Title, expectedTitle)
+	}
+
+	test("/notebook/note.md", "Markdown")
+	test("/notebook/note.markdown", "Markdown")
+	test("/notebook/note.org", "Org")
+	test("/notebook/NOTE.ORG", "Org")
+
+	// The links of the other formats are resolved like Markdown links.
+	note, err := notebook.ParseNoteWithContent("/notebook/dir/note.org", []byte("content"))
+	assert.Nil(t, err)
+	assert.Equal(t, note.Links, []Link{{Href: "a.org", Type: LinkTypeMarkdown}})
+}
+
+// The title parsed from the content of the note falls back on its filename.
+func TestParseNoteTitleFallsBackOnFilename(t *testing.T) {
+	notebook := NewNotebook("/notebook", NewDefaultConfig(), NotebookPorts{
+		NoteContentParser: newNoteContentParserMock(map[string]*NoteContent{
+			"titled":   {Title: opt.NewString("Heading")},
+			"untitled": {Title: opt.NullString},
+		}),
+		FS:     newFileStorageMock("/notebook", []string{}),
+		Logger: &util.NullLogger,
+	})
+
+	test := func(path string, content string, expectedTitle string) {
+		note, err := notebook.ParseNoteWithContent(path, []byte(content))
+		assert.Nil(t, err)
+		assert.Equal(t, note.Title, expectedTitle)
+	}
+
+	test("/notebook/dir/note.md", "titled", "Heading")
+	test("/notebook/dir/note.md", "untitled", "note")
+	test("/notebook/2021-01-03.daily.md", "untitled", "2021-01-03.daily")
+	test("/notebook/README", "untitled", "README")
+}
+
+func TestCreationDateFromMetadata(t *testing.T) {
+	birth := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	test := func(metadata map[string]interface{}, expected time.Time) {
+		assert.Equal(t, creationDateFrom(metadata, "note", "", timespecMock{birth: birth}, &util.NullLogger), expected)
+	}
+
+	test(map[string]interface{}{}, birth)
+	test(map[string]interface{}{"date": "2011-05-16 09:58:57"}, time.Date(2011, 5, 16, 9, 58, 57, 0, time.UTC))
+	test(map[string]interface{}{"created": "2011-05-16 09:58"}, time.Date(2011, 5, 16, 9, 58, 0, 0, time.UTC))
+	test(map[string]interface{}{"created": "2011-05-16T09:58:57Z"}, time.Date(2011, 5, 16, 9, 58, 57, 0, time.UTC))
+	// `date` takes precedence over `created`.
+	test(map[string]interface{}{"date": "2011-05-16 09:58", "created": "2012-05-16 09:58"}, time.Date(2011, 5, 16, 9, 58, 0, 0, time.UTC))
+	test(map[string]interface{}{"created": "not a date"}, birth)
+}
+
+func TestCreationDateFromFilename(t *testing.T) {
+	birth := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	test := func(metadata map[string]interface{}, filename string, layout string, expected time.Time, expectedWarnings []string) {
+		logger := &loggerMock{}
+		assert.Equal(t, creationDateFrom(metadata, filename, layout, timespecMock{birth: birth}, logger), expected)
+		assert.Equal(t, logger.warnings, expectedWarnings)
+	}
+
+	daily := time.Date(2021, 1, 3, 0, 0, 0, 0, time.UTC)
+
+	// A daily note.
+	test(map[string]interface{}{}, "2021-01-03", "2006-01-02", daily, nil)
+	test(map[string]interface{}{}, "journal 2021-01-03 review", "2006-01-02", daily, nil)
+	test(map[string]interface{}{}, "202101031530", "200601021504", time.Date(2021, 1, 3, 15, 30, 0, 0, time.UTC), nil)
+	// The frontmatter date takes precedence over the filename.
+	test(map[string]interface{}{"date": "2011-05-16 09:58"}, "2021-01-03", "2006-01-02", time.Date(2011, 5, 16, 9, 58, 0, 0, time.UTC), nil)
+	// An invalid frontmatter date falls back on the filename.
+	test(map[string]interface{}{"date": "yesterday"}, "2021-01-03", "2006-01-02", daily, []string{
+		"warning: yesterday: invalid creation date in the frontmatter `date` key",
+	})
+	// Neither a frontmatter nor a filename date uses the file time.
+	test(map[string]interface{}{}, "an-idea", "2006-01-02", birth, nil)
+	test(map[string]interface{}{}, "2021-01-03", "", birth, nil)
+	test(map[string]interface{}{}, "2021-13-45", "2006-01-02", birth, nil)
+	// Several dates in the filename are ambiguous.
+	test(map[string]interface{}{}, "2021-01-03 to 2021-01-10", "2006-01-02", birth, []string{
+		"warning: 2021-01-03 to 2021-01-10: ambiguous creation date in the filename, several dates match 2006-01-02",
+	})
+}
+
+// timespecMock implements times.Timespec with a fixed birth time.
+type timespecMock struct {
+	birth time.Time
+}
+
+func (t timespecMock) ModTime() time.Time    { return t.birth }
+func (t timespecMock) AccessTime() time.Time { return t.birth }
+func (t timespecMock) ChangeTime() time.Time { return t.birth }
+func (t timespecMock) BirthTime() time.Time  { return t.birth }
+func (t timespecMock) HasChangeTime() bool   { return true }
+func (t timespecMock) HasBirthTime() bool    { return true }
+
+// loggerMock records the logged warnings and errors.
+type loggerMock struct {
+	warnings []string
+	errs     []string
+}
+
+func (l *loggerMock) Printf(format string, v ...interface{}) {
+	l.warnings = append(l.warnings, fmt.Sprintf(format, v...))
+}
+func (l *loggerMock) Println(v ...interface{}) {}
+func (l *loggerMock) Err(err error) {
+	if err != nil {
+		l.errs = append(l.errs, fmt.Sprint(err))
+	}
+}
@@ -0,0 +1,43 @@
+package core
+
+// NoteStats holds statistics aggregated over a set of notes.
+type NoteStats struct {
+	// Number of notes.
+	NoteCount int `json:"noteCount"`
+	// Total number of words in the notes.
+	WordCount int `json:"wordCount"`
+	// Number of notes in each top-level directory, `.` being the notebook
+	// root.
+	Directories []StatsEntry `json:"directories"`
+	// Number of notes tagged with each tag.
+	Tags []StatsEntry `json:"tags"`
+	// Outbound links of the notes.
+	Links LinkStats `json:"links"`
+	// Number of notes created each month, formatted as YYYY-MM.
+	CreationMonths []StatsEntry `json:"creationMonths"`
+}
+
+// AverageWordCount returns the average number of words per note.
+func (s NoteStats) AverageWordCount() float64 {
+	if s.NoteCount == 0 {
+		return 0
+	}
+	return float64(s.WordCount) / float64(s.NoteCount)
+}
+
+// StatsEntry is a number of notes associated with a name, e.g. a tag.
+// The entries are ordered by decreasing count.
+type StatsEntry struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// LinkStats counts the outbound links of a set of notes.
+type LinkStats struct {
+	// Links to other notes of the notebook.
+	Internal int `json:"internal"`
+	// Links to external resources, e.g. URLs.
+	External int `json:"external"`
+	// Internal links which don't match any note.
+	Dead int `json:"dead"`
+}
@@ -0,0 +1,179 @@
+package core
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/zk-org/zk/internal/util/errors"
+	"github.com/zk-org/zk/internal/util/paths"
+	strutil "github.com/zk-org/zk/internal/util/strings"
+)
+
+// NoteWatchOpts holds the options for watching the notebook.
+type NoteWatchOpts struct {
+	// Options of the initial indexing.
+	NoteIndexOpts
+	// Delay without file system events after which the changes are indexed.
+	// Defaults to 500 milliseconds.
+	Debounce time.Duration
+}
+
+// Watch indexes the notebook, then reindexes it incrementally when notes are
+// created, modified, renamed or removed, until the context is cancelled.
+//
+// The file system events are coalesced until none happened during the
+// debounce delay, to index a burst of changes at once, e.g. after a git
+// checkout. The callback is called with each change applied to the index.
+func (n *Notebook) Watch(ctx context.Context, opts NoteWatchOpts, callback func(change paths.DiffChange)) error {
+	wrap := errors.Wrapper("watching failed")
+
+	debounce := opts.Debounce
+	if debounce <= 0 {
+		debounce = 500 * time.Millisecond
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return wrap(err)
+	}
+	defer watcher.Close()
+
+	ignore, err := readIgnoreRules(n.fs, n.Path)
+	if err != nil {
+		return wrap(err)
+	}
+	ignore = ignore.Append(paths.NewIgnoreRules(opts.ExcludeGlobs))
+
+	err = n.watchDir(watcher, n.Path, ignore)
+	if err != nil {
+		return wrap(err)
+	}
+
+	_, err = n.IndexWithCallback(opts.NoteIndexOpts, callback)
+	if err != nil {
+		return wrap(err)
+	}
+
+	// Only the initial indexing is forced.
+	indexOpts := NoteIndexOpts{
+		Workers:      opts.Workers,
+		ExcludeGlobs: opts.ExcludeGlobs,
+		LockTimeout:  opts.LockTimeout,
+	}
+
+	var timer *time.Timer
+	var timeout <-chan time.Time
+	schedule := func() {
+		if timer != nil {
+			timer.Stop()
+		}
+		timer = time.NewTimer(debounce)
+		timeout = timer.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			if timer != nil {
+				timer.Stop()
+			}
+			return nil
+
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if !n.isWatchedEvent(event, ignore) {
+				continue
+			}
+			if event.Op&fsnotify.Create != 0 {
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					n.logger.Err(n.watchDir(watcher, event.Name, ignore))
+				}
+			}
+			schedule()
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			// Some events might have been lost, so we reindex anyway.
+			n.logger.Err(errors.Wrap(err, "watching failed"))
+			schedule()
+
+		case <-timeout:
+			timer = nil
+			timeout = nil
+			_, err := n.IndexWithCallback(indexOpts, callback)
+			n.logger.Err(err)
+		}
+	}
+}
+
+// watchDir adds the given directory and its descendants to the watcher,
+// except the hidden and ignored ones.
+func (n *Notebook) watchDir(watcher *fsnotify.Watcher, dir string, ignore paths.IgnoreRules) error {
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			// The directory might have been removed in the meantime.
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		if path == n.Path {
+			return watcher.Add(path)
+		}
+		rel, err := filepath.Rel(n.Path, path)
+		if err != nil || strings.HasPrefix(info.Name(), ".") || ignore.Match(rel, true) {
+			return filepath.SkipDir
+		}
+		return watcher.Add(path)
+	})
+}
+
+// isWatchedEvent returns whether the file system event might change the
+// indexed notes.
+//
+// Hidden files, such as the notebook database or editor swap files, ignored
+// files and files without a note extension are skipped. The note exclude
+// globs are checked when diffing the notebook.
+func (n *Notebook) isWatchedEvent(event fsnotify.Event, ignore paths.IgnoreRules) bool {
+	if event.Op == fsnotify.Chmod {
+		return false
+	}
+
+	path, err := filepath.Rel(n.Path, event.Name)
+	if err != nil {
+		return false
+	}
+	for _, component := range strings.Split(path, string(filepath.Separator)) {
+		if strings.HasPrefix(component, ".") {
+			return false
+		}
+	}
+
+	if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+		return !ignore.Match(path, true)
+	}
+	if ignore.Match(path, false) {
+		return false
+	}
+	// A removed or renamed directory usually has no extension.
+	ext := filepath.Ext(path)
+	if ext == "" {
+		return true
+	}
+	group, err := n.Config.GroupConfigForPath(path)
+	if err != nil {
+		return true
+	}
+	return strutil.Contains(group.Note.IndexedExtensions(), strings.TrimPrefix(ext, "."))
+}
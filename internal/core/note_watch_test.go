@@ -0,0 +1,273 @@
+package core
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zk-org/zk/internal/util"
+	"github.com/zk-org/zk/internal/util/opt"
+	"github.com/zk-org/zk/internal/util/paths"
+	"github.com/zk-org/zk/internal/util/test/assert"
+)
+
+func TestNotebookWatch(t *testing.T) {
+	dir := t.TempDir()
+	write := func(path string, content string) {
+		path = filepath.Join(dir, path)
+		assert.Nil(t, os.MkdirAll(filepath.Dir(path), os.ModePerm))
+		assert.Nil(t, os.WriteFile(path, []byte(content), 0644))
+	}
+
+	write("a.md", "# Note A\n")
+
+	index := newNoteIndexWatchMock()
+	notebook := NewNotebook(dir, NewDefaultConfig(), NotebookPorts{
+		NoteIndex:         index,
+		NoteContentParser: noteContentParserFunc(parseTitleForTest),
+		FS:                &osFileStorageMock{newFileStorageMock(dir, []string{})},
+		Logger:            &util.NullLogger,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- notebook.Watch(ctx, NoteWatchOpts{Debounce: 50 * time.Millisecond}, func(change paths.DiffChange) {})
+	}()
+
+	// Waits until the index contains the expected notes, with their title.
+	expect := func(expected map[string]string) {
+		deadline := time.Now().Add(5 * time.Second)
+		for time.Now().Before(deadline) && !reflect.DeepEqual(index.titles(), expected) {
+			time.Sleep(10 * time.Millisecond)
+		}
+		assert.Equal(t, index.titles(), expected)
+	}
+
+	expect(map[string]string{"a.md": "Note A"})
+	idA := index.id("a.md")
+
+	// Created notes, including in new directories.
+	write("b.md", "# Note B\n")
+	write("dir/sub/c.md", "# Note C\n")
+	expect(map[string]string{"a.md": "Note A", "b.md": "Note B", "dir/sub/c.md": "Note C"})
+
+	// Modified notes.
+	write("b.md", "# Note B, modified\n")
+	expect(map[string]string{"a.md": "Note A", "b.md": "Note B, modified", "dir/sub/c.md": "Note C"})
+
+	// Renamed notes keep their ID.
+	assert.Nil(t, os.Rename(filepath.Join(dir, "a.md"), filepath.Join(dir, "dir/moved.md")))
+	expect(map[string]string{"dir/moved.md": "Note A", "b.md": "Note B, modified", "dir/sub/c.md": "Note C"})
+	assert.Equal(t, index.id("dir/moved.md"), idA)
+
+	// Removed notes.
+	assert.Nil(t, os.Remove(filepath.Join(dir, "b.md")))
+	assert.Nil(t, os.RemoveAll(filepath.Join(dir, "dir/sub")))
+	expect(map[string]string{"dir/moved.md": "Note A"})
+
+	// Hidden files and unknown extensions are ignored.
+	write(".hidden.md", "# Hidden\n")
+	write("image.png", "")
+	write("d.md", "# Note D\n")
+	expect(map[string]string{"dir/moved.md": "Note A", "d.md": "Note D"})
+
+	cancel()
+	assert.Nil(t, <-done)
+}
+
+func TestIndexTaskRenamesMovedNotes(t *testing.T) {
+	dir := t.TempDir()
+	for path, content := range map[string]string{
+		"a-moved.md": "# Note A\n",
+		"z-moved.md": "# Note Z\n",
+		"copy.md":    "# Copy\n",
+	} {
+		assert.Nil(t, os.WriteFile(filepath.Join(dir, path), []byte(content), 0644))
+	}
+
+	index := newNoteIndexWatchMock()
+	for _, note := range []Note{
+		// Renamed before and after their new path.
+		{Path: "b.md", Title: "Note A", Checksum: ChecksumSHA256.Sum([]byte("# Note A\n"), false)},
+		{Path: "m.md", Title: "Note Z", Checksum: ChecksumSHA256.Sum([]byte("# Note Z\n"), false)},
+		// Several removed notes have the same content.
+		{Path: "copy-1.md", Title: "Copy", Checksum: ChecksumSHA256.Sum([]byte("# Copy\n"), false)},
+		{Path: "copy-2.md", Title: "Copy", Checksum: ChecksumSHA256.Sum([]byte("# Copy\n"), false)},
+	} {
+		_, err := index.Add(note)
+		assert.Nil(t, err)
+	}
+	idA := index.id("b.md")
+	idZ := index.id("m.md")
+
+	notebook := NewNotebook(dir, NewDefaultConfig(), NotebookPorts{
+		NoteIndex:         index,
+		NoteContentParser: noteContentParserFunc(parseTitleForTest),
+		FS:                &osFileStorageMock{newFileStorageMock(dir, []string{})},
+		Logger:            &util.NullLogger,
+	})
+
+	stats, err := notebook.Index(NoteIndexOpts{})
+	assert.Nil(t, err)
+	assert.Equal(t, stats.AddedCount, 1)
+	assert.Equal(t, stats.ModifiedCount, 0)
+	assert.Equal(t, stats.RemovedCount, 2)
+	assert.Equal(t, stats.MovedCount, 2)
+
+	assert.Equal(t, index.titles(), map[string]string{
+		"a-moved.md": "Note A",
+		"z-moved.md": "Note Z",
+		"copy.md":    "Copy",
+	})
+	assert.Equal(t, index.id("a-moved.md"), idA)
+	assert.Equal(t, index.id("z-moved.md"), idZ)
+}
+
+type noteContentParserFunc func(content string) (*NoteContent, error)
+
+func (f noteContentParserFunc) ParseNoteContent(content string) (*NoteContent, error) {
+	return f(content)
+}
+
+// parseTitleForTest parses the first line of the content as the note title.
+func parseTitleForTest(content string) (*NoteContent, error) {
+	title := strings.TrimPrefix(strings.SplitN(content, "\n", 2)[0], "# ")
+	return &NoteContent{Title: opt.NewNotEmptyString(title)}, nil
+}
+
+// osFileStorageMock reads the files from the actual file system.
+type osFileStorageMock struct {
+	*fileStorageMock
+}
+
+func (fs *osFileStorageMock) FileExists(path string) (bool, error) {
+	_, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (fs *osFileStorageMock) Read(path string) ([]byte, error) {
+	return os.ReadFile(path)
+}
+
+// noteIndexWatchMock is an in-memory NoteIndex, safe for concurrent use.
+type noteIndexWatchMock struct {
+	noteIndexAddMock
+	mutex  sync.Mutex
+	nextID NoteID
+	notes  map[string]Note
+}
+
+func newNoteIndexWatchMock() *noteIndexWatchMock {
+	return &noteIndexWatchMock{notes: map[string]Note{}}
+}
+
+// titles returns the titles of the indexed notes, by their path.
+func (m *noteIndexWatchMock) titles() map[string]string {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	res := map[string]string{}
+	for path, note := range m.notes {
+		res[path] = note.Title
+	}
+	return res
+}
+
+func (m *noteIndexWatchMock) id(path string) NoteID {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.notes[path].ID
+}
+
+func (m *noteIndexWatchMock) Commit(transaction func(idx NoteIndex) error) error {
+	return transaction(m)
+}
+
+func (m *noteIndexWatchMock) IndexedPaths() (<-chan paths.Metadata, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	sorted := []string{}
+	for path := range m.notes {
+		sorted = append(sorted, path)
+	}
+	sort.Strings(sorted)
+
+	c := make(chan paths.Metadata, len(sorted))
+	for _, path := range sorted {
+		note := m.notes[path]
+		c <- paths.Metadata{
+			Path:     path,
+			Modified: note.Modified,
+			Size:     note.Size,
+			Checksum: note.Checksum,
+		}
+	}
+	close(c)
+	return c, nil
+}
+
+func (m *noteIndexWatchMock) Add(note Note) (NoteID, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.nextID++
+	note.ID = m.nextID
+	m.notes[note.Path] = note
+	return note.ID, nil
+}
+
+func (m *noteIndexWatchMock) Update(note Note) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	note.ID = m.notes[note.Path].ID
+	m.notes[note.Path] = note
+	return nil
+}
+
+func (m *noteIndexWatchMock) Remove(path string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	delete(m.notes, path)
+	return nil
+}
+
+func (m *noteIndexWatchMock) Rename(oldPath string, newPath string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	note := m.notes[oldPath]
+	note.Path = newPath
+	delete(m.notes, oldPath)
+	m.notes[newPath] = note
+	return nil
+}
+
+func (m *noteIndexWatchMock) FindByChecksum(checksum string) ([]MinimalNote, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	res := []MinimalNote{}
+	for _, note := range m.notes {
+		if note.Checksum == checksum {
+			res = append(res, MinimalNote{ID: note.ID, Path: note.Path})
+		}
+	}
+	return res, nil
+}
+
+func (m *noteIndexWatchMock) SetModified(path string, modified time.Time, size int64) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	note := m.notes[path]
+	note.Modified = modified
+	note.Size = size
+	m.notes[path] = note
+	return nil
+}
@@ -1,6 +1,7 @@
 package core
 
 import (
+	"context"
 	"fmt"
 	"path/filepath"
 	"strings"
@@ -8,6 +9,7 @@ import (
 
 	"github.com/zk-org/zk/internal/util"
 	"github.com/zk-org/zk/internal/util/errors"
+	"github.com/zk-org/zk/internal/util/lockfile"
 	"github.com/zk-org/zk/internal/util/opt"
 	"github.com/zk-org/zk/internal/util/paths"
 )
@@ -18,7 +20,10 @@ type Notebook struct {
 	Config Config
 	Parser NoteContentParser
 
+	parsers               map[string]NoteContentParser
 	index                 NoteIndex
+	htmlRenderer          NoteHTMLRenderer
+	htmlRenderers         map[string]NoteHTMLRenderer
 	templateLoaderFactory TemplateLoaderFactory
 	idGeneratorFactory    IDGeneratorFactory
 	fs                    FileStorage
@@ -36,7 +41,10 @@ func NewNotebook(
 		Path:                  path,
 		Config:                config,
 		Parser:                ports.NoteContentParser,
+		parsers:               ports.NoteContentParsers,
 		index:                 ports.NoteIndex,
+		htmlRenderer:          ports.NoteHTMLRenderer,
+		htmlRenderers:         ports.NoteHTMLRenderers,
 		templateLoaderFactory: ports.TemplateLoaderFactory,
 		idGeneratorFactory:    ports.IDGeneratorFactory,
 		fs:                    ports.FS,
@@ -48,6 +56,9 @@ func NewNotebook(
 type NotebookPorts struct {
 	NoteIndex             NoteIndex
 	NoteContentParser     NoteContentParser
+	NoteContentParsers    map[string]NoteContentParser
+	NoteHTMLRenderer      NoteHTMLRenderer
+	NoteHTMLRenderers     map[string]NoteHTMLRenderer
 	TemplateLoaderFactory TemplateLoaderFactory
 	IDGeneratorFactory    IDGeneratorFactory
 	FS                    FileStorage
@@ -63,26 +74,106 @@ func (n *Notebook) Index(opts NoteIndexOpts) (stats NoteIndexingStats, err error
 	return n.IndexWithCallback(opts, func(change paths.DiffChange) {})
 }
 
+// IndexWithProgress indexes the content of the notebook to be searchable,
+// while reporting its progress.
+func (n *Notebook) IndexWithProgress(opts NoteIndexOpts, progress NoteIndexProgress) (stats NoteIndexingStats, err error) {
+	stats, err = n.IndexWithCallback(opts, func(change paths.DiffChange) {
+		progress.FileIndexed(change.Path, change.Kind)
+	})
+	var indexingErr NoteIndexingError
+	if err == nil || errors.As(err, &indexingErr) {
+		progress.Done(stats)
+	}
+	return
+}
+
 // Index indexes the content of the notebook to be searchable.
 func (n *Notebook) IndexWithCallback(opts NoteIndexOpts, callback func(change paths.DiffChange)) (stats NoteIndexingStats, err error) {
+	lock, err := n.lockIndex(opts.LockTimeout)
+	if err != nil {
+		err = errors.Wrap(err, "indexing")
+		return
+	}
+	defer func() {
+		n.logger.Err(lock.Release())
+	}()
+
+	var changes []paths.DiffChange
+	if opts.DryRun {
+		apply := callback
+		callback = func(change paths.DiffChange) {
+			changes = append(changes, change)
+			apply(change)
+		}
+	}
+
 	err = n.index.Commit(func(index NoteIndex) error {
+		if opts.DryRun {
+			index = dryRunNoteIndex{index}
+		}
 		task := indexTask{
-			path:    n.Path,
-			config:  n.Config,
-			force:   opts.Force,
-			verbose: opts.Verbose,
-			index:   index,
-			parser:  n,
-			logger:  n.logger,
+			path:         n.Path,
+			config:       n.Config,
+			force:        opts.Force,
+			rebuild:      opts.Rebuild,
+			verbose:      opts.Verbose,
+			workers:      opts.Workers,
+			excludeGlobs: opts.ExcludeGlobs,
+			index:        index,
+			fs:           n.fs,
+			parser:       n,
+			logger:       n.logger,
 		}
 		stats, err = task.execute(callback)
+		if err == nil && opts.DryRun {
+			// Rolls back the transaction, in case anything was written.
+			return errDryRun
+		}
 		return err
 	})
 
+	if err == errDryRun {
+		err = nil
+		stats.Changes = changes
+	}
+	if err == nil && len(stats.Errors) > 0 {
+		err = NoteIndexingError{Failures: stats.Errors}
+	}
 	err = errors.Wrap(err, "indexing")
 	return
 }
 
+var errDryRun = errors.New("dry run")
+
+// CheckIndex verifies the integrity of the notebook index. When repair is
+// true, the full-text search index is rebuilt if it doesn't match the notes.
+func (n *Notebook) CheckIndex(repair bool) (NoteIndexCheckReport, error) {
+	return n.index.Check(repair)
+}
+
+// lockIndex prevents concurrent indexing of the notebook, from other zk
+// processes. When timeout is positive, waits for the current indexing to
+// complete, up to the given timeout.
+func (n *Notebook) lockIndex(timeout time.Duration) (*lockfile.Lock, error) {
+	path := filepath.Join(n.Path, ".zk", "index.lock")
+
+	var lock *lockfile.Lock
+	var err error
+	if timeout > 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		lock, err = lockfile.AcquireWait(ctx, path)
+	} else {
+		lock, err = lockfile.Acquire(path)
+	}
+
+	var lockedErr lockfile.LockedError
+	if errors.As(err, &lockedErr) {
+		err = errors.Wrap(err, "another index is in progress")
+	}
+	return lock, err
+}
+
 // NewNoteOpts holds the options used to create a new note in a Notebook.
 type NewNoteOpts struct {
 	// Title of the new note.
@@ -97,7 +188,8 @@ type NewNoteOpts struct {
 	Template opt.String
 	// Extra variables passed to the templates.
 	Extra map[string]string
-	// Creation date provided to the templates.
+	// Date of the note provided to the templates as note-date and now, e.g.
+	// the day of a daily note.
 	Date time.Time
 	// Don't save the generated note on the file system.
 	DryRun bool
@@ -127,12 +219,18 @@ func (n *Notebook) NewNote(opts NewNoteOpts) (*Note, error) {
 		return nil, wrap(err)
 	}
 
-	config, err := n.Config.GroupConfigNamed(opts.Group.OrString(dir.Group).Unwrap())
+	group := opts.Group.OrString(dir.Group).Unwrap()
+	config, err := n.Config.GroupConfigNamed(group)
 	if err != nil {
 		return nil, wrap(err)
 	}
 
-	extra := config.Extra
+	// The extra variables given for this note take precedence over the ones of
+	// the group, without altering the config of the notebook.
+	extra := map[string]string{}
+	for k, v := range config.Extra {
+		extra[k] = v
+	}
 	for k, v := range opts.Extra {
 		extra[k] = v
 	}
@@ -153,12 +251,14 @@ func (n *Notebook) NewNote(opts NewNoteOpts) (*Note, error) {
 
 	task := newNoteTask{
 		dir:              dir,
+		group:            group,
 		title:            opts.Title.OrString(config.Note.DefaultTitle).Unwrap(),
 		content:          opts.Content,
 		date:             opts.Date,
 		extra:            extra,
 		env:              n.osEnv(),
 		fs:               n.fs,
+		index:            n.index,
 		filenameTemplate: config.Note.FilenameTemplate + "." + config.Note.Extension,
 		bodyTemplatePath: opts.Template.Or(config.Note.BodyTemplatePath),
 		templates:        templates,
@@ -188,7 +288,49 @@ func (n *Notebook) NewNote(opts NewNoteOpts) (*Note, error) {
 
 // FindNotes retrieves the notes matching the given filtering options.
 func (n *Notebook) FindNotes(opts NoteFindOpts) ([]ContextualNote, error) {
-	return n.index.Find(opts)
+	return n.FindNotesContext(context.Background(), opts)
+}
+
+// FindNotesContext is like FindNotes, but the query is interrupted when the
+// context is cancelled.
+func (n *Notebook) FindNotesContext(ctx context.Context, opts NoteFindOpts) ([]ContextualNote, error) {
+	return n.index.Find(ctx, opts)
+}
+
+// CountNotes returns the number of notes matching the given filtering
+// options, without retrieving them. The limit and offset are taken into
+// account.
+func (n *Notebook) CountNotes(opts NoteFindOpts) (int, error) {
+	return n.CountNotesContext(context.Background(), opts)
+}
+
+// CountNotesContext is like CountNotes, but the query is interrupted when the
+// context is cancelled.
+func (n *Notebook) CountNotesContext(ctx context.Context, opts NoteFindOpts) (int, error) {
+	count, err := n.index.Count(ctx, opts)
+	if err != nil {
+		return 0, err
+	}
+	count -= opts.Offset
+	if count < 0 {
+		count = 0
+	}
+	if opts.Limit > 0 && count > opts.Limit {
+		count = opts.Limit
+	}
+	return count, nil
+}
+
+// NoteStats aggregates statistics about the notes matching the given
+// filtering options.
+func (n *Notebook) NoteStats(opts NoteFindOpts) (NoteStats, error) {
+	return n.NoteStatsContext(context.Background(), opts)
+}
+
+// NoteStatsContext is like NoteStats, but the query is interrupted when the
+// context is cancelled.
+func (n *Notebook) NoteStatsContext(ctx context.Context, opts NoteFindOpts) (NoteStats, error) {
+	return n.index.Stats(ctx, opts)
 }
 
 // FindNote retrieves the first note matching the given filtering options.
@@ -208,7 +350,13 @@ func (n *Notebook) FindNote(opts NoteFindOpts) (*Note, error) {
 // FindMinimalNotes retrieves lightweight metadata for the notes matching
 // the given filtering options.
 func (n *Notebook) FindMinimalNotes(opts NoteFindOpts) ([]MinimalNote, error) {
-	return n.index.FindMinimal(opts)
+	return n.FindMinimalNotesContext(context.Background(), opts)
+}
+
+// FindMinimalNotesContext is like FindMinimalNotes, but the query is
+// interrupted when the context is cancelled.
+func (n *Notebook) FindMinimalNotesContext(ctx context.Context, opts NoteFindOpts) ([]MinimalNote, error) {
+	return n.index.FindMinimal(ctx, opts)
 }
 
 // FindMinimalNotes retrieves lightweight metadata for the first note matching
@@ -240,9 +388,16 @@ func (n *Notebook) FindLinksBetweenNotes(ids []NoteID) ([]ResolvedLink, error) {
 	return n.index.FindLinksBetweenNotes(ids)
 }
 
-// FindCollections retrieves all the collections of the given kind.
-func (n *Notebook) FindCollections(kind CollectionKind, sorters []CollectionSorter) ([]Collection, error) {
-	return n.index.FindCollections(kind, sorters)
+// FindDanglingLinks retrieves the internal links of the given notes which
+// don't match any note.
+func (n *Notebook) FindDanglingLinks(ids []NoteID) ([]ResolvedLink, error) {
+	return n.index.FindDanglingLinks(ids)
+}
+
+// FindCollections retrieves the collections of the given kind matching the
+// given criteria.
+func (n *Notebook) FindCollections(kind CollectionKind, opts CollectionFindOpts) ([]Collection, error) {
+	return n.index.FindCollections(kind, opts)
 }
 
 // RelPath returns the path relative to the notebook root to the given path.
@@ -329,11 +484,26 @@ func (n *Notebook) RequireDirAt(path string) (Dir, error) {
 
 // NewNoteFormatter returns a NoteFormatter used to format notes with the given template.
 func (n *Notebook) NewNoteFormatter(templateString string) (NoteFormatter, error) {
+	return n.newNoteFormatter(func(templates TemplateLoader) (Template, error) {
+		return templates.LoadTemplate(templateString)
+	})
+}
+
+// NewNoteFormatterAt returns a NoteFormatter used to format notes with the
+// template file at the given path, which may be relative to the template
+// directories.
+func (n *Notebook) NewNoteFormatterAt(path string) (NoteFormatter, error) {
+	return n.newNoteFormatter(func(templates TemplateLoader) (Template, error) {
+		return templates.LoadTemplateAt(path)
+	})
+}
+
+func (n *Notebook) newNoteFormatter(load func(templates TemplateLoader) (Template, error)) (NoteFormatter, error) {
 	templates, err := n.templateLoaderFactory(n.Config.Note.Lang)
 	if err != nil {
 		return nil, err
 	}
-	template, err := templates.LoadTemplate(templateString)
+	template, err := load(templates)
 	if err != nil {
 		return nil, err
 	}
@@ -346,6 +516,21 @@ func (n *Notebook) NewNoteFormatter(templateString string) (NoteFormatter, error
 	return newNoteFormatter(n.Path, template, linkFormatter, n.osEnv(), n.fs)
 }
 
+// NewNoteFieldsFormatter returns a NoteFieldsFormatter used to format the
+// given fields of notes, e.g. to print them as CSV.
+func (n *Notebook) NewNoteFieldsFormatter(fields []string) (NoteFieldsFormatter, error) {
+	templates, err := n.templateLoaderFactory(n.Config.Note.Lang)
+	if err != nil {
+		return nil, err
+	}
+	linkFormatter, err := NewLinkFormatter(n.Config.Format.Markdown, templates)
+	if err != nil {
+		return nil, err
+	}
+
+	return newNoteFieldsFormatter(n.Path, fields, linkFormatter, n.osEnv(), n.fs)
+}
+
 // NewCollectionFormatter returns a CollectionFormatter used to format notes with the given template.
 func (n *Notebook) NewCollectionFormatter(templateString string) (CollectionFormatter, error) {
 	templates, err := n.templateLoaderFactory(n.Config.Note.Lang)
@@ -292,6 +292,8 @@ multiword-tags = true
 {{else}}
 multiword-tags = false
 {{/if}}
+# Convert the tags to lowercase.
+#lowercase-tags = false
 
 
 # EXTERNAL TOOLS
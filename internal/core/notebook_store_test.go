@@ -0,0 +1,70 @@
+package core
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/zk-org/zk/internal/util/test/assert"
+)
+
+func newNotebookStoreTest(fs *fileStorageMock) *NotebookStore {
+	return NewNotebookStore(NewDefaultConfig(), NotebookStorePorts{
+		FS: fs,
+		NotebookFactory: func(path string, config Config) (*Notebook, error) {
+			return &Notebook{Path: path, Config: config}, nil
+		},
+	})
+}
+
+func TestNotebookStoreOpenAtRoot(t *testing.T) {
+	store := newNotebookStoreTest(newFileStorageMock("/", []string{"/notebook/.zk"}))
+
+	notebook, err := store.Open("/notebook")
+	assert.Nil(t, err)
+	assert.Equal(t, notebook.Path, "/notebook")
+}
+
+func TestNotebookStoreOpenFromNestedDirectory(t *testing.T) {
+	store := newNotebookStoreTest(newFileStorageMock("/", []string{"/notebook/.zk"}))
+
+	notebook, err := store.Open("/notebook/journal/2021")
+	assert.Nil(t, err)
+	assert.Equal(t, notebook.Path, "/notebook")
+}
+
+func TestNotebookStoreOpenRelativePath(t *testing.T) {
+	store := newNotebookStoreTest(newFileStorageMock("/notebook/journal", []string{"/notebook/.zk"}))
+
+	notebook, err := store.Open("daily")
+	assert.Nil(t, err)
+	assert.Equal(t, notebook.Path, "/notebook")
+}
+
+// The closest notebook is opened when they are nested.
+func TestNotebookStoreOpenNestedNotebooks(t *testing.T) {
+	store := newNotebookStoreTest(newFileStorageMock("/", []string{"/notebook/.zk", "/notebook/sub/.zk"}))
+
+	notebook, err := store.Open("/notebook/sub/dir")
+	assert.Nil(t, err)
+	assert.Equal(t, notebook.Path, "/notebook/sub")
+}
+
+func TestNotebookStoreOpenReadsConfig(t *testing.T) {
+	fs := newFileStorageMock("/", []string{"/notebook/.zk"})
+	fs.files["/notebook/.zk/config.toml"] = "[note]\nlanguage = \"fr\"\n"
+	store := newNotebookStoreTest(fs)
+
+	notebook, err := store.Open("/notebook/dir")
+	assert.Nil(t, err)
+	assert.Equal(t, notebook.Config.Note.Lang, "fr")
+}
+
+func TestNotebookStoreOpenNotFound(t *testing.T) {
+	store := newNotebookStoreTest(newFileStorageMock("/", []string{"/other/.zk"}))
+
+	_, err := store.Open("/notebook/dir")
+	assert.Err(t, err, "failed to open notebook: no notebook found in /notebook/dir or a parent directory")
+
+	var errNotFound ErrNotebookNotFound
+	assert.True(t, errors.As(err, &errNotFound))
+}
@@ -1,5 +1,7 @@
 package core
 
+import "fmt"
+
 // Template produces a string using a given context.
 type Template interface {
 
@@ -47,6 +49,16 @@ type TemplateLoader interface {
 	LoadTemplateAt(path string) (Template, error)
 }
 
+// TemplateNotFoundError is returned by TemplateLoader.LoadTemplateAt when no
+// template file exists at the given path.
+type TemplateNotFoundError struct {
+	Path string
+}
+
+func (e TemplateNotFoundError) Error() string {
+	return fmt.Sprintf("cannot find template at %s", e.Path)
+}
+
 // TemplateLoaderFactory creates a new instance of an implementation of the
 // TemplateLoader port.
 type TemplateLoaderFactory func(language string) (TemplateLoader, error)
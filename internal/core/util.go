@@ -1,6 +1,6 @@
 package core
 
-import "strconv"
+import "encoding/json"
 
 // lazyStringer implements Stringer and wait for String() to be called the first
 // time before computing its value.
@@ -26,5 +26,5 @@ func (s *lazyStringer) String() string {
 }
 
 func (s *lazyStringer) MarshalJSON() ([]byte, error) {
-	return []byte(strconv.Quote(s.String())), nil
+	return json.Marshal(s.String())
 }
@@ -0,0 +1,383 @@
+package date
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	naturaldate "github.com/tj/go-naturaldate"
+)
+
+// Precision is the period of time covered by a parsed date, e.g. a whole
+// month for `2021-01`.
+type Precision int
+
+const (
+	// The date is an exact point in time, e.g. `2 hours ago`.
+	PrecisionExact Precision = iota + 1
+	// The date covers a whole day, e.g. `yesterday`.
+	PrecisionDay
+	// The date covers a whole week starting on Monday, as in ISO 8601, e.g.
+	// `last week`.
+	PrecisionWeek
+	// The date covers a whole month, e.g. `2021-01`.
+	PrecisionMonth
+	// The date covers a whole year, e.g. `2021`.
+	PrecisionYear
+)
+
+// Start returns the beginning of the period of the given precision containing
+// t.
+func (p Precision) Start(t time.Time) time.Time {
+	year, month, day := t.Date()
+	switch p {
+	case PrecisionDay:
+		return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+	case PrecisionWeek:
+		daysSinceMonday := (int(t.Weekday()) + 6) % 7
+		return time.Date(year, month, day-daysSinceMonday, 0, 0, 0, 0, t.Location())
+	case PrecisionMonth:
+		return time.Date(year, month, 1, 0, 0, 0, 0, t.Location())
+	case PrecisionYear:
+		return time.Date(year, time.January, 1, 0, 0, 0, 0, t.Location())
+	default:
+		return t
+	}
+}
+
+// Next returns the same point in time, one period of the given precision
+// later. An exact date has no period, so it is returned unchanged.
+func (p Precision) Next(t time.Time) time.Time {
+	switch p {
+	case PrecisionDay:
+		return t.AddDate(0, 0, 1)
+	case PrecisionWeek:
+		return t.AddDate(0, 0, 7)
+	case PrecisionMonth:
+		return t.AddDate(0, 1, 0)
+	case PrecisionYear:
+		return t.AddDate(1, 0, 0)
+	default:
+		return t
+	}
+}
+
+// Parse parses a human date relative to now, e.g. `yesterday`, `last week`,
+// `two days ago`, `monday`, `jan 3` or `2021-01`.
+//
+// Dates without a year, such as `jan 3` or `monday`, are resolved in the
+// past. Ambiguous numeric dates such as `01/02/2021` are rejected.
+func Parse(s string, now time.Time) (time.Time, Precision, error) {
+	date := strings.TrimSpace(s)
+	if date == "" {
+		return time.Time{}, 0, fmt.Errorf("empty date")
+	}
+
+	if t, p, ok := parseISO(date, now); ok {
+		return t, p, nil
+	}
+	if isoLikeRegex.MatchString(date) {
+		return time.Time{}, 0, fmt.Errorf("%s: invalid date", s)
+	}
+	if ambiguousRegex.MatchString(date) {
+		return time.Time{}, 0, fmt.Errorf("%s: ambiguous date, use the YYYY-MM-DD format", s)
+	}
+
+	words := []string{}
+	for _, word := range strings.Fields(strings.ToLower(strings.ReplaceAll(date, ",", " "))) {
+		if word != "of" {
+			words = append(words, word)
+		}
+	}
+	t, p, ok, err := parseWords(words, now)
+	if err != nil {
+		return t, p, fmt.Errorf("%s: %w", s, err)
+	} else if ok {
+		return t, p, nil
+	}
+
+	// Fallback on the naturaldate package for more complex expressions, e.g.
+	// `yesterday at 5pm`. It returns the reference date when it doesn't
+	// recognize the input.
+	t, err = naturaldate.Parse(date, now, naturaldate.WithDirection(naturaldate.Past))
+	if err != nil || t.Equal(now) {
+		return time.Time{}, 0, fmt.Errorf("%s: invalid date", s)
+	}
+	if t.Equal(PrecisionDay.Start(t)) {
+		return t, PrecisionDay, nil
+	}
+	return t, PrecisionExact, nil
+}
+
+var (
+	// Dates looking like ISO 8601 dates, which failed to be parsed.
+	isoLikeRegex = regexp.MustCompile(`^\d{4}-\d{1,2}(-\d{1,2})?([T ].*)?$`)
+	// Numeric dates which could be either day or month first.
+	ambiguousRegex = regexp.MustCompile(`^\d{1,2}[/.-]\d{1,2}([/.-]\d{2,4})?$`)
+)
+
+// isoLayouts are the supported ISO 8601 layouts, with their precision.
+var isoLayouts = []struct {
+	layout    string
+	precision Precision
+}{
+	{"2006-01-02T15:04:05", PrecisionExact},
+	{"2006-01-02T15:04", PrecisionExact},
+	{"2006-01-02", PrecisionDay},
+	{"2006-01", PrecisionMonth},
+	{"2006", PrecisionYear},
+}
+
+func parseISO(date string, now time.Time) (time.Time, Precision, bool) {
+	if t, err := time.Parse(time.RFC3339, date); err == nil {
+		return t, PrecisionExact, true
+	}
+	for _, l := range isoLayouts {
+		if t, err := time.ParseInLocation(l.layout, date, now.Location()); err == nil {
+			return t, l.precision, true
+		}
+	}
+	// A time of the current day.
+	if t, err := time.ParseInLocation("15:04", date, now.Location()); err == nil {
+		year, month, day := now.Date()
+		return time.Date(year, month, day, t.Hour(), t.Minute(), 0, 0, now.Location()), PrecisionExact, true
+	}
+	return time.Time{}, 0, false
+}
+
+// parseWords parses the dates supported natively, returning false when the
+// words are not recognized.
+func parseWords(words []string, now time.Time) (time.Time, Precision, bool, error) {
+	switch len(words) {
+	case 1:
+		switch words[0] {
+		case "now":
+			return now, PrecisionExact, true, nil
+		case "today":
+			return PrecisionDay.Start(now), PrecisionDay, true, nil
+		case "yesterday":
+			return PrecisionDay.Start(now.AddDate(0, 0, -1)), PrecisionDay, true, nil
+		case "tomorrow":
+			return PrecisionDay.Start(now.AddDate(0, 0, 1)), PrecisionDay, true, nil
+		}
+		if weekday, ok := weekdays[words[0]]; ok {
+			return lastWeekday(now, weekday, true), PrecisionDay, true, nil
+		}
+		if month, ok := months[words[0]]; ok {
+			return lastMonth(now, month), PrecisionMonth, true, nil
+		}
+
+	case 2:
+		if t, p, ok, err := parseRelativePeriod(words[0], words[1], now); ok || err != nil {
+			return t, p, ok, err
+		}
+		if t, p, ok, err := parseMonthDate(words, now); ok || err != nil {
+			return t, p, ok, err
+		}
+	}
+
+	// <count> <unit> ago
+	if len(words) == 3 && words[2] == "ago" {
+		if t, p, ok := parseOffset(words[0], words[1], -1, now); ok {
+			return t, p, true, nil
+		}
+	}
+	// in <count> <unit>
+	if len(words) == 3 && words[0] == "in" {
+		if t, p, ok := parseOffset(words[1], words[2], 1, now); ok {
+			return t, p, true, nil
+		}
+	}
+
+	if len(words) == 3 {
+		return parseMonthDate(words, now)
+	}
+
+	return time.Time{}, 0, false, nil
+}
+
+// parseRelativePeriod parses dates such as `last week`, `this month` or
+// `next monday`.
+func parseRelativePeriod(modifier string, unit string, now time.Time) (time.Time, Precision, bool, error) {
+	var direction int
+	switch modifier {
+	case "last":
+		direction = -1
+	case "this":
+		direction = 0
+	case "next":
+		direction = 1
+	default:
+		return time.Time{}, 0, false, nil
+	}
+
+	if weekday, ok := weekdays[unit]; ok {
+		switch direction {
+		case -1:
+			return lastWeekday(now, weekday, false), PrecisionDay, true, nil
+		case 1:
+			return nextWeekday(now, weekday), PrecisionDay, true, nil
+		default:
+			// Could be either in the past or in the future.
+			return time.Time{}, 0, false, fmt.Errorf("ambiguous date")
+		}
+	}
+
+	switch unit {
+	case "week":
+		return PrecisionWeek.Start(now).AddDate(0, 0, 7*direction), PrecisionWeek, true, nil
+	case "month":
+		return PrecisionMonth.Start(now).AddDate(0, direction, 0), PrecisionMonth, true, nil
+	case "year":
+		return PrecisionYear.Start(now).AddDate(direction, 0, 0), PrecisionYear, true, nil
+	}
+	return time.Time{}, 0, false, nil
+}
+
+// parseOffset parses a number of units from now, e.g. `2 days`, in the given
+// direction.
+func parseOffset(count string, unit string, direction int, now time.Time) (time.Time, Precision, bool) {
+	n, ok := parseCount(count)
+	if !ok {
+		return time.Time{}, 0, false
+	}
+	n *= direction
+
+	switch strings.TrimSuffix(unit, "s") {
+	case "minute":
+		return now.Add(time.Duration(n) * time.Minute), PrecisionExact, true
+	case "hour":
+		return now.Add(time.Duration(n) * time.Hour), PrecisionExact, true
+	case "day":
+		return PrecisionDay.Start(now.AddDate(0, 0, n)), PrecisionDay, true
+	case "week":
+		return PrecisionDay.Start(now.AddDate(0, 0, 7*n)), PrecisionDay, true
+	case "month":
+		return PrecisionDay.Start(now.AddDate(0, n, 0)), PrecisionDay, true
+	case "year":
+		return PrecisionDay.Start(now.AddDate(n, 0, 0)), PrecisionDay, true
+	}
+	return time.Time{}, 0, false
+}
+
+// parseMonthDate parses dates with a month name, e.g. `jan 3`, `3rd january`,
+// `january 3 2021` or `january 2021`.
+func parseMonthDate(words []string, now time.Time) (time.Time, Precision, bool, error) {
+	if len(words) < 2 || len(words) > 3 {
+		return time.Time{}, 0, false, nil
+	}
+
+	var month time.Month
+	var others []string
+	if m, ok := months[words[0]]; ok {
+		month, others = m, words[1:]
+	} else if m, ok := months[words[1]]; ok {
+		month, others = m, append([]string{words[0]}, words[2:]...)
+	} else {
+		return time.Time{}, 0, false, nil
+	}
+
+	// <month> <year>
+	if len(words) == 2 && others[0] == words[1] && yearRegex.MatchString(others[0]) {
+		year, _ := strconv.Atoi(others[0])
+		return time.Date(year, month, 1, 0, 0, 0, 0, now.Location()), PrecisionMonth, true, nil
+	}
+
+	day, err := strconv.Atoi(ordinalSuffixRegex.ReplaceAllString(others[0], ""))
+	if err != nil {
+		return time.Time{}, 0, false, nil
+	}
+
+	year := now.Year()
+	if len(others) == 2 {
+		if !yearRegex.MatchString(others[1]) {
+			return time.Time{}, 0, false, nil
+		}
+		year, _ = strconv.Atoi(others[1])
+	} else if time.Date(year, month, day, 0, 0, 0, 0, now.Location()).After(now) {
+		// Dates without a year are resolved in the past.
+		year--
+	}
+
+	t := time.Date(year, month, day, 0, 0, 0, 0, now.Location())
+	if t.Day() != day {
+		return time.Time{}, 0, false, fmt.Errorf("invalid date")
+	}
+	return t, PrecisionDay, true, nil
+}
+
+var (
+	ordinalSuffixRegex = regexp.MustCompile(`(st|nd|rd|th)$`)
+	yearRegex          = regexp.MustCompile(`^\d{4}$`)
+)
+
+// parseCount parses a count written with digits or in letters.
+func parseCount(count string) (int, bool) {
+	if n, err := strconv.Atoi(count); err == nil && n >= 0 {
+		return n, true
+	}
+	n, ok := counts[count]
+	return n, ok
+}
+
+// lastWeekday returns the most recent given weekday before now, or today if
+// includeToday is true.
+func lastWeekday(now time.Time, weekday time.Weekday, includeToday bool) time.Time {
+	days := (int(now.Weekday()) - int(weekday) + 7) % 7
+	if days == 0 && !includeToday {
+		days = 7
+	}
+	return PrecisionDay.Start(now.AddDate(0, 0, -days))
+}
+
+// nextWeekday returns the first given weekday after now.
+func nextWeekday(now time.Time, weekday time.Weekday) time.Time {
+	days := (int(weekday) - int(now.Weekday()) + 7) % 7
+	if days == 0 {
+		days = 7
+	}
+	return PrecisionDay.Start(now.AddDate(0, 0, days))
+}
+
+// lastMonth returns the beginning of the most recent given month, including
+// the current one.
+func lastMonth(now time.Time, month time.Month) time.Time {
+	year := now.Year()
+	if month > now.Month() {
+		year--
+	}
+	return time.Date(year, month, 1, 0, 0, 0, 0, now.Location())
+}
+
+var weekdays = map[string]time.Weekday{
+	"sunday": time.Sunday, "sun": time.Sunday,
+	"monday": time.Monday, "mon": time.Monday,
+	"tuesday": time.Tuesday, "tue": time.Tuesday, "tues": time.Tuesday,
+	"wednesday": time.Wednesday, "wed": time.Wednesday,
+	"thursday": time.Thursday, "thu": time.Thursday, "thurs": time.Thursday,
+	"friday": time.Friday, "fri": time.Friday,
+	"saturday": time.Saturday, "sat": time.Saturday,
+}
+
+var months = map[string]time.Month{
+	"january": time.January, "jan": time.January,
+	"february": time.February, "feb": time.February,
+	"march": time.March, "mar": time.March,
+	"april": time.April, "apr": time.April,
+	"may":  time.May,
+	"june": time.June, "jun": time.June,
+	"july": time.July, "jul": time.July,
+	"august": time.August, "aug": time.August,
+	"september": time.September, "sep": time.September, "sept": time.September,
+	"october": time.October, "oct": time.October,
+	"november": time.November, "nov": time.November,
+	"december": time.December, "dec": time.December,
+}
+
+var counts = map[string]int{
+	"a": 1, "an": 1, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5,
+	"six": 6, "seven": 7, "eight": 8, "nine": 9, "ten": 10, "eleven": 11,
+	"twelve": 12,
+}
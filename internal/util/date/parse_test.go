@@ -0,0 +1,161 @@
+package date
+
+import (
+	"testing"
+	"time"
+
+	"github.com/zk-org/zk/internal/util/test/assert"
+)
+
+// Wednesday, February 17th 2021.
+var now = time.Date(2021, 2, 17, 14, 30, 12, 0, time.UTC)
+
+func day(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		date      string
+		expected  time.Time
+		precision Precision
+	}{
+		// ISO 8601
+		{"2021-01-23T13:55:48+01:00", time.Date(2021, 1, 23, 13, 55, 48, 0, time.FixedZone("", 3600)), PrecisionExact},
+		{"2021-01-23T13:55:48Z", time.Date(2021, 1, 23, 13, 55, 48, 0, time.UTC), PrecisionExact},
+		{"2021-01-23T13:55:48", time.Date(2021, 1, 23, 13, 55, 48, 0, time.UTC), PrecisionExact},
+		{"2021-01-23T13:55", time.Date(2021, 1, 23, 13, 55, 0, 0, time.UTC), PrecisionExact},
+		{"2021-01-23", day(2021, 1, 23), PrecisionDay},
+		{"2021-01", day(2021, 1, 1), PrecisionMonth},
+		{"2021", day(2021, 1, 1), PrecisionYear},
+		{"09:15", time.Date(2021, 2, 17, 9, 15, 0, 0, time.UTC), PrecisionExact},
+		{"  2021-01-23  ", day(2021, 1, 23), PrecisionDay},
+
+		// Keywords
+		{"now", now, PrecisionExact},
+		{"today", day(2021, 2, 17), PrecisionDay},
+		{"Today", day(2021, 2, 17), PrecisionDay},
+		{"yesterday", day(2021, 2, 16), PrecisionDay},
+		{"tomorrow", day(2021, 2, 18), PrecisionDay},
+
+		// Weekdays
+		{"monday", day(2021, 2, 15), PrecisionDay},
+		{"mon", day(2021, 2, 15), PrecisionDay},
+		{"wednesday", day(2021, 2, 17), PrecisionDay},
+		{"thursday", day(2021, 2, 11), PrecisionDay},
+		{"last monday", day(2021, 2, 15), PrecisionDay},
+		{"last wednesday", day(2021, 2, 10), PrecisionDay},
+		{"next monday", day(2021, 2, 22), PrecisionDay},
+		{"next wednesday", day(2021, 2, 24), PrecisionDay},
+		{"next thu", day(2021, 2, 18), PrecisionDay},
+
+		// Relative periods
+		{"last week", day(2021, 2, 8), PrecisionWeek},
+		{"this week", day(2021, 2, 15), PrecisionWeek},
+		{"next week", day(2021, 2, 22), PrecisionWeek},
+		{"last month", day(2021, 1, 1), PrecisionMonth},
+		{"this month", day(2021, 2, 1), PrecisionMonth},
+		{"next month", day(2021, 3, 1), PrecisionMonth},
+		{"last year", day(2020, 1, 1), PrecisionYear},
+		{"this year", day(2021, 1, 1), PrecisionYear},
+		{"next year", day(2022, 1, 1), PrecisionYear},
+
+		// Offsets
+		{"2 days ago", day(2021, 2, 15), PrecisionDay},
+		{"two days ago", day(2021, 2, 15), PrecisionDay},
+		{"1 day ago", day(2021, 2, 16), PrecisionDay},
+		{"a week ago", day(2021, 2, 10), PrecisionDay},
+		{"2 weeks ago", day(2021, 2, 3), PrecisionDay},
+		{"three months ago", day(2020, 11, 17), PrecisionDay},
+		{"one year ago", day(2020, 2, 17), PrecisionDay},
+		{"an hour ago", time.Date(2021, 2, 17, 13, 30, 12, 0, time.UTC), PrecisionExact},
+		{"10 minutes ago", time.Date(2021, 2, 17, 14, 20, 12, 0, time.UTC), PrecisionExact},
+		{"in 2 days", day(2021, 2, 19), PrecisionDay},
+		{"in 3 hours", time.Date(2021, 2, 17, 17, 30, 12, 0, time.UTC), PrecisionExact},
+
+		// Month names
+		{"jan 3", day(2021, 1, 3), PrecisionDay},
+		{"Jan 3", day(2021, 1, 3), PrecisionDay},
+		{"january 3rd", day(2021, 1, 3), PrecisionDay},
+		{"3 jan", day(2021, 1, 3), PrecisionDay},
+		{"3rd of january", day(2021, 1, 3), PrecisionDay},
+		{"January 2nd", day(2021, 1, 2), PrecisionDay},
+		{"feb 17", day(2021, 2, 17), PrecisionDay},
+		{"feb 18", day(2020, 2, 18), PrecisionDay},
+		{"December 24th", day(2020, 12, 24), PrecisionDay},
+		{"jan 3 2020", day(2020, 1, 3), PrecisionDay},
+		{"jan 3, 2020", day(2020, 1, 3), PrecisionDay},
+		{"3 january 2020", day(2020, 1, 3), PrecisionDay},
+		{"january 2020", day(2020, 1, 1), PrecisionMonth},
+		{"january", day(2021, 1, 1), PrecisionMonth},
+		{"feb", day(2021, 2, 1), PrecisionMonth},
+		{"march", day(2020, 3, 1), PrecisionMonth},
+
+		// Fallback on more complex expressions.
+		{"yesterday at 5pm", time.Date(2021, 2, 16, 17, 0, 0, 0, time.UTC), PrecisionExact},
+	}
+
+	for _, test := range tests {
+		actual, precision, err := Parse(test.date, now)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.date, err)
+			continue
+		}
+		if !actual.Equal(test.expected) {
+			t.Errorf("%s: expected %v, got %v", test.date, test.expected, actual)
+		}
+		if precision != test.precision {
+			t.Errorf("%s: expected precision %v, got %v", test.date, test.precision, precision)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		date     string
+		expected string
+	}{
+		{"", "empty date"},
+		{"   ", "empty date"},
+		{"foobar", "foobar: invalid date"},
+		{"next", "next: invalid date"},
+		{"2021-13-45", "2021-13-45: invalid date"},
+		{"2021-02-30", "2021-02-30: invalid date"},
+		{"2021-02-17T25:00", "2021-02-17T25:00: invalid date"},
+		{"jan 32", "jan 32: invalid date"},
+		{"feb 30 2021", "feb 30 2021: invalid date"},
+		// Ambiguous inputs.
+		{"01/02/2021", "01/02/2021: ambiguous date, use the YYYY-MM-DD format"},
+		{"1/2", "1/2: ambiguous date, use the YYYY-MM-DD format"},
+		{"02-03", "02-03: ambiguous date, use the YYYY-MM-DD format"},
+		{"02.03.21", "02.03.21: ambiguous date, use the YYYY-MM-DD format"},
+		{"this monday", "this monday: ambiguous date"},
+	}
+
+	for _, test := range tests {
+		_, _, err := Parse(test.date, now)
+		assert.Err(t, err, test.expected)
+	}
+}
+
+func TestPrecisionStart(t *testing.T) {
+	date := time.Date(2021, 2, 17, 14, 30, 12, 0, time.UTC)
+	assert.Equal(t, PrecisionExact.Start(date), date)
+	assert.Equal(t, PrecisionDay.Start(date), day(2021, 2, 17))
+	assert.Equal(t, PrecisionWeek.Start(date), day(2021, 2, 15))
+	assert.Equal(t, PrecisionWeek.Start(day(2021, 2, 15)), day(2021, 2, 15))
+	assert.Equal(t, PrecisionWeek.Start(day(2021, 2, 21)), day(2021, 2, 15))
+	// A week starting in the previous year.
+	assert.Equal(t, PrecisionWeek.Start(day(2021, 1, 1)), day(2020, 12, 28))
+	assert.Equal(t, PrecisionMonth.Start(date), day(2021, 2, 1))
+	assert.Equal(t, PrecisionYear.Start(date), day(2021, 1, 1))
+}
+
+func TestPrecisionNext(t *testing.T) {
+	date := day(2021, 2, 1)
+	assert.Equal(t, PrecisionExact.Next(date), date)
+	assert.Equal(t, PrecisionDay.Next(date), day(2021, 2, 2))
+	assert.Equal(t, PrecisionWeek.Next(date), day(2021, 2, 8))
+	assert.Equal(t, PrecisionMonth.Next(date), day(2021, 3, 1))
+	assert.Equal(t, PrecisionYear.Next(date), day(2022, 2, 1))
+}
@@ -33,3 +33,7 @@ func New(text string) error {
 func As(err error, target interface{}) bool {
 	return errors.As(err, target)
 }
+
+func Is(err error, target error) bool {
+	return errors.Is(err, target)
+}
@@ -101,3 +101,9 @@ func ConvertQuery(query string) string {
 	closeTerm()
 	return out
 }
+
+// QuotePhrase transforms a literal string into a SQLite FTS5 phrase, ignoring
+// any query syntax.
+func QuotePhrase(phrase string) string {
+	return `"` + strings.ReplaceAll(phrase, `"`, `""`) + `"`
+}
@@ -82,3 +82,17 @@ func TestConvertQuery(t *testing.T) {
 	// NEAR is not supported
 	test(`NEAR(foo, bar, 4)`, `"NEAR"("foo," "bar," "4")`)
 }
+
+func TestQuotePhrase(t *testing.T) {
+	test := func(phrase, expected string) {
+		assert.Equal(t, QuotePhrase(phrase), expected)
+	}
+
+	test(``, `""`)
+	test(`foo`, `"foo"`)
+	test(`foo bar`, `"foo bar"`)
+	test(`c++ -O2`, `"c++ -O2"`)
+	test(`foo AND NEAR(bar)`, `"foo AND NEAR(bar)"`)
+	test(`title: foo*`, `"title: foo*"`)
+	test(`say "hello"`, `"say ""hello"""`)
+}
@@ -0,0 +1,177 @@
+package lockfile
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zk-org/zk/internal/util/errors"
+)
+
+// Lock is an advisory lock held by the current process, materialized by a
+// file containing its PID.
+type Lock struct {
+	path string
+}
+
+// LockedError is returned when the lock is held by another process.
+type LockedError struct {
+	// Path of the lock file.
+	Path string
+	// PID of the process holding the lock, or 0 if it is unknown.
+	PID int
+}
+
+func (e LockedError) Error() string {
+	if e.PID == 0 {
+		return fmt.Sprintf("%s is locked by another process", e.Path)
+	}
+	return fmt.Sprintf("%s is locked by process %d", e.Path, e.PID)
+}
+
+// Acquire takes the lock at the given path, creating its parent directories
+// if needed.
+//
+// A lock left by a process which is not running anymore is stale, and broken
+// automatically. Returns a LockedError if another running process holds the
+// lock.
+func Acquire(path string) (*Lock, error) {
+	wrap := errors.Wrapperf("failed to acquire the lock %s", path)
+
+	err := os.MkdirAll(filepath.Dir(path), os.ModePerm)
+	if err != nil {
+		return nil, wrap(err)
+	}
+
+	lock, err := create(path)
+	if !os.IsExist(err) {
+		return lock, wrap(err)
+	}
+
+	pid, err := readPID(path)
+	if os.IsNotExist(err) {
+		// Released in the meantime.
+		lock, err = create(path)
+		return lock, wrapLocked(path, err)
+	} else if err != nil {
+		return nil, LockedError{Path: path}
+	}
+	if pid == os.Getpid() || isProcessRunning(pid) {
+		return nil, LockedError{Path: path, PID: pid}
+	}
+
+	// Breaks the stale lock. It is first moved aside atomically, so that only
+	// one of the processes finding it stale can take it over.
+	beforeBreakingStaleLock()
+	stale, err := moveAside(path)
+	if os.IsNotExist(err) {
+		// Broken in the meantime by another process.
+		lock, err = create(path)
+		return lock, wrapLocked(path, err)
+	} else if err != nil {
+		return nil, wrap(err)
+	}
+	defer os.Remove(stale)
+
+	movedPID, err := readPID(stale)
+	if err != nil || movedPID != pid {
+		// Another process broke the stale lock and took it over before it
+		// was moved, so its lock is restored.
+		os.Link(stale, path)
+		if err != nil {
+			return nil, LockedError{Path: path}
+		}
+		return nil, LockedError{Path: path, PID: movedPID}
+	}
+
+	lock, err = create(path)
+	return lock, wrapLocked(path, err)
+}
+
+// beforeBreakingStaleLock is called when a stale lock was found, so that the
+// tests can synchronize the concurrent callers.
+var beforeBreakingStaleLock = func() {}
+
+// moveAside renames the lock file to a new unique path in the same
+// directory, which is returned.
+func moveAside(path string) (string, error) {
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.stale")
+	if err != nil {
+		return "", err
+	}
+	tmpPath := file.Name()
+	file.Close()
+
+	err = os.Rename(path, tmpPath)
+	if err != nil {
+		os.Remove(tmpPath)
+		return "", err
+	}
+	return tmpPath, nil
+}
+
+// AcquireWait is like Acquire, but waits for the lock to be released by
+// another process until the context is cancelled.
+func AcquireWait(ctx context.Context, path string) (*Lock, error) {
+	for {
+		lock, err := Acquire(path)
+		var lockedErr LockedError
+		if !errors.As(err, &lockedErr) {
+			return lock, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, err
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+// Release removes the lock file.
+func (l *Lock) Release() error {
+	err := os.Remove(l.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return errors.Wrapf(err, "failed to release the lock %s", l.path)
+}
+
+// create creates the lock file with the PID of the current process, failing
+// if it already exists.
+func create(path string) (*Lock, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return nil, err
+	}
+	_, err = file.WriteString(strconv.Itoa(os.Getpid()))
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+	return &Lock{path: path}, nil
+}
+
+func readPID(path string) (int, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(string(content)))
+}
+
+// wrapLocked reports a lock file created concurrently by another process as
+// a LockedError.
+func wrapLocked(path string, err error) error {
+	if os.IsExist(err) {
+		return LockedError{Path: path}
+	}
+	return errors.Wrapf(err, "failed to acquire the lock %s", path)
+}
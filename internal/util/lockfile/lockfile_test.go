@@ -0,0 +1,168 @@
+package lockfile
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zk-org/zk/internal/util/errors"
+	"github.com/zk-org/zk/internal/util/test/assert"
+)
+
+func TestAcquireCreatesLockFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".zk", "index.lock")
+
+	lock, err := Acquire(path)
+	assert.Nil(t, err)
+	assertLockPID(t, path, os.Getpid())
+
+	assert.Nil(t, lock.Release())
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestAcquireFailsWhenAlreadyLocked(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index.lock")
+
+	lock, err := Acquire(path)
+	assert.Nil(t, err)
+	defer lock.Release()
+
+	_, err = Acquire(path)
+	assertLocked(t, err, os.Getpid())
+}
+
+func TestAcquireFailsWhenLockedByRunningProcess(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index.lock")
+	writeLock(t, path, strconv.Itoa(os.Getppid()))
+
+	_, err := Acquire(path)
+	assertLocked(t, err, os.Getppid())
+	assertLockPID(t, path, os.Getppid())
+}
+
+func TestAcquireFailsWithUnreadableLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index.lock")
+	writeLock(t, path, "not a PID")
+
+	_, err := Acquire(path)
+	assertLocked(t, err, 0)
+}
+
+func TestAcquireAfterRelease(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index.lock")
+
+	lock, err := Acquire(path)
+	assert.Nil(t, err)
+	assert.Nil(t, lock.Release())
+
+	lock, err = Acquire(path)
+	assert.Nil(t, err)
+	assert.Nil(t, lock.Release())
+}
+
+func TestAcquireBreaksStaleLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index.lock")
+	// Exceeds the maximum PID of the supported platforms.
+	writeLock(t, path, "2147483647")
+
+	lock, err := Acquire(path)
+	assert.Nil(t, err)
+	defer lock.Release()
+	assertLockPID(t, path, os.Getpid())
+}
+
+// Only one of the concurrent callers finding the lock stale takes it over.
+func TestAcquireBreaksStaleLockOnce(t *testing.T) {
+	const callers = 8
+	defer func() { beforeBreakingStaleLock = func() {} }()
+
+	for i := 0; i < 20; i++ {
+		path := filepath.Join(t.TempDir(), "index.lock")
+		writeLock(t, path, "2147483647")
+
+		// All the callers find the lock stale before any of them breaks it.
+		var found sync.WaitGroup
+		found.Add(callers)
+		beforeBreakingStaleLock = func() {
+			found.Done()
+			found.Wait()
+		}
+
+		results := make(chan error, callers)
+		for j := 0; j < callers; j++ {
+			go func() {
+				_, err := Acquire(path)
+				results <- err
+			}()
+		}
+
+		acquired := 0
+		for j := 0; j < callers; j++ {
+			err := <-results
+			if err == nil {
+				acquired += 1
+			} else {
+				var lockedErr LockedError
+				assert.True(t, errors.As(err, &lockedErr))
+			}
+		}
+		assert.Equal(t, acquired, 1)
+		assertLockPID(t, path, os.Getpid())
+
+		// The stale lock moved aside is removed.
+		files, err := os.ReadDir(filepath.Dir(path))
+		assert.Nil(t, err)
+		assert.Equal(t, len(files), 1)
+	}
+}
+
+func TestAcquireWaitTimesOut(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index.lock")
+
+	lock, err := Acquire(path)
+	assert.Nil(t, err)
+	defer lock.Release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	_, err = AcquireWait(ctx, path)
+	assertLocked(t, err, os.Getpid())
+}
+
+func TestAcquireWaitUntilReleased(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index.lock")
+
+	lock, err := Acquire(path)
+	assert.Nil(t, err)
+	go func() {
+		time.Sleep(150 * time.Millisecond)
+		lock.Release()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	waitLock, err := AcquireWait(ctx, path)
+	assert.Nil(t, err)
+	assert.Nil(t, waitLock.Release())
+}
+
+func writeLock(t *testing.T, path string, content string) {
+	assert.Nil(t, os.WriteFile(path, []byte(content), 0644))
+}
+
+func assertLocked(t *testing.T, err error, pid int) {
+	var lockedErr LockedError
+	assert.True(t, errors.As(err, &lockedErr))
+	assert.Equal(t, lockedErr.PID, pid)
+}
+
+func assertLockPID(t *testing.T, path string, pid int) {
+	actual, err := readPID(path)
+	assert.Nil(t, err)
+	assert.Equal(t, actual, pid)
+}
@@ -0,0 +1,15 @@
+//go:build !windows
+// +build !windows
+
+package lockfile
+
+import "syscall"
+
+// isProcessRunning returns whether a process with the given PID exists.
+func isProcessRunning(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	err := syscall.Kill(pid, 0)
+	return err == nil || err == syscall.EPERM
+}
@@ -0,0 +1,24 @@
+package lockfile
+
+import "syscall"
+
+const (
+	processQueryLimitedInformation = 0x1000
+	stillActive                    = 259
+)
+
+// isProcessRunning returns whether a process with the given PID exists.
+func isProcessRunning(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	handle, err := syscall.OpenProcess(processQueryLimitedInformation, false, uint32(pid))
+	if err != nil {
+		return false
+	}
+	defer syscall.CloseHandle(handle)
+
+	var code uint32
+	err = syscall.GetExitCodeProcess(handle, &code)
+	return err == nil && code == stillActive
+}
@@ -0,0 +1,43 @@
+package pager
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/zk-org/zk/internal/util"
+	"github.com/zk-org/zk/internal/util/opt"
+	"github.com/zk-org/zk/internal/util/test/assert"
+)
+
+func TestPagerReceivesOutput(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake pager is a shell command")
+	}
+	t.Setenv("ZK_PAGER", "")
+	t.Setenv("ZK_SHELL", "sh")
+	out := filepath.Join(t.TempDir(), "out")
+
+	// The fake pager records its standard input.
+	pager, err := New(opt.NewString("cat > '"+out+"'"), &util.NullLogger)
+	assert.Nil(t, err)
+	assert.Nil(t, pager.WriteString("Line 1"))
+	assert.Nil(t, pager.WriteString("Line 2"))
+	assert.Nil(t, pager.Close())
+
+	content, err := os.ReadFile(out)
+	assert.Nil(t, err)
+	assert.Equal(t, string(content), "Line 1\nLine 2\n")
+}
+
+func TestPagerPrecedence(t *testing.T) {
+	t.Setenv("ZK_PAGER", "")
+	t.Setenv("PAGER", "more")
+
+	assert.Equal(t, selectPagerCmd(opt.NewString("less")), opt.NewString("less"))
+	assert.Equal(t, selectPagerCmd(opt.NullString), opt.NewString("more"))
+
+	t.Setenv("ZK_PAGER", "most")
+	assert.Equal(t, selectPagerCmd(opt.NewString("less")), opt.NewString("most"))
+}
@@ -1,15 +1,25 @@
 package paths
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
 
 // DiffChange represents a file change made in a directory.
 type DiffChange struct {
 	Path string
 	Kind DiffKind
+	// Checksum of the target file, for the modified files.
+	Checksum string
+	// Previous path of a moved file.
+	OldPath string
 }
 
 // String implements Stringer.
 func (c DiffChange) String() string {
+	if c.Kind == DiffMoved {
+		return fmt.Sprintf("%v %v to %v", c.Kind, c.OldPath, c.Path)
+	}
 	return fmt.Sprintf("%v %v", c.Kind, c.Path)
 }
 
@@ -21,6 +31,9 @@ const (
 	DiffModified
 	DiffRemoved
 	DiffUnchanged
+	// A file moved to another path, which is never reported by Diff but
+	// detected by the indexing from the content of the file.
+	DiffMoved
 )
 
 // String implements Stringer.
@@ -34,6 +47,8 @@ func (k DiffKind) String() string {
 		return "removed"
 	case DiffUnchanged:
 		return "unchanged"
+	case DiffMoved:
+		return "moved"
 	default:
 		panic(fmt.Sprintf("%d: unknown DiffKind", int(k)))
 	}
@@ -50,13 +65,18 @@ func (k DiffKind) Symbol() string {
 		return "-"
 	case DiffUnchanged:
 		return "/"
+	case DiffMoved:
+		return ">"
 	default:
 		panic(fmt.Sprintf("%d: unknown DiffKind", int(k)))
 	}
 }
 
 // Diff compares two sources of Metadata and report the file changes, using the
-// file modification date.
+// file modification date and size. Unchanged files don't need to be read.
+//
+// The modification dates are compared to the second, as file systems don't
+// have the same precision.
 //
 // Returns the number of files in the source.
 //
@@ -111,31 +131,37 @@ func (p *diffPair) diff(forceModified bool) *DiffChange {
 		break
 
 	case p.source == nil && p.target != nil: // Source channel is closed
-		change = &DiffChange{p.target.Path, DiffRemoved}
+		change = &DiffChange{Path: p.target.Path, Kind: DiffRemoved}
 		p.target = nil
 
 	case p.source != nil && p.target == nil: // Target channel is closed
-		change = &DiffChange{p.source.Path, DiffAdded}
+		change = &DiffChange{Path: p.source.Path, Kind: DiffAdded}
 		p.source = nil
 
-	case p.source.Path == p.target.Path: // Same files, compare their modification date.
-		if forceModified || p.source.Modified != p.target.Modified {
-			change = &DiffChange{p.source.Path, DiffModified}
+	case p.source.Path == p.target.Path: // Same files, compare their modification date and size.
+		if forceModified || !sameSecond(p.source.Modified, p.target.Modified) || p.source.Size != p.target.Size {
+			change = &DiffChange{Path: p.source.Path, Kind: DiffModified, Checksum: p.target.Checksum}
 		} else {
-			change = &DiffChange{p.source.Path, DiffUnchanged}
+			change = &DiffChange{Path: p.source.Path, Kind: DiffUnchanged}
 		}
 		p.source = nil
 		p.target = nil
 
 	default: // Different files, one has been added or removed.
 		if p.source.Path < p.target.Path {
-			change = &DiffChange{p.source.Path, DiffAdded}
+			change = &DiffChange{Path: p.source.Path, Kind: DiffAdded}
 			p.source = nil
 		} else {
-			change = &DiffChange{p.target.Path, DiffRemoved}
+			change = &DiffChange{Path: p.target.Path, Kind: DiffRemoved}
 			p.target = nil
 		}
 	}
 
 	return change
 }
+
+// sameSecond returns whether the two dates are equal, ignoring the sub-second
+// precision.
+func sameSecond(a, b time.Time) bool {
+	return a.Truncate(time.Second).Equal(b.Truncate(time.Second))
+}
@@ -97,6 +97,71 @@ func TestDiff(t *testing.T) {
 	})
 }
 
+// A file with the same modification date but a different size is modified.
+func TestDiffSizeChanged(t *testing.T) {
+	source := []Metadata{
+		{
+			Path:     "a/1",
+			Modified: date1,
+			Size:     42,
+		},
+		{
+			Path:     "a/2",
+			Modified: date2,
+			Size:     12,
+		},
+	}
+
+	target := []Metadata{
+		{
+			Path:     "a/1",
+			Modified: date1,
+			Size:     40,
+			Checksum: "qwfpgj",
+		},
+		{
+			Path:     "a/2",
+			Modified: date2,
+			Size:     12,
+			Checksum: "arstdh",
+		},
+	}
+
+	test(t, source, target, false, []DiffChange{
+		{Path: "a/1", Kind: DiffModified, Checksum: "qwfpgj"},
+		{Path: "a/2", Kind: DiffUnchanged},
+	})
+}
+
+func TestDiffIgnoresSubSecondModification(t *testing.T) {
+	source := []Metadata{
+		{
+			Path:     "a/1",
+			Modified: date1,
+		},
+		{
+			Path:     "a/2",
+			Modified: date2.Add(time.Second),
+		},
+	}
+
+	target := []Metadata{
+		{
+			Path:     "a/1",
+			Modified: date1.Truncate(time.Second),
+		},
+		{
+			Path:     "a/2",
+			Modified: date2,
+		},
+	}
+
+	test(t, source, target, false, []DiffChange{
+		{Path: "a/1", Kind: DiffUnchanged},
+		{Path: "a/2", Kind: DiffModified},
+	})
+}
+
 func TestDiffForceModified(t *testing.T) {
 	source := []Metadata{
 		{
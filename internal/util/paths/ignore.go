@@ -0,0 +1,110 @@
+package paths
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// IgnoreRules matches paths against a list of gitignore-style patterns.
+//
+// A pattern without slash matches a file or directory name at any depth,
+// while a pattern containing a slash is relative to the root directory. A
+// trailing slash matches only directories, and a leading ! re-includes the
+// paths excluded by a previous pattern. The last matching pattern wins.
+type IgnoreRules struct {
+	patterns []ignorePattern
+}
+
+type ignorePattern struct {
+	glob    string
+	negated bool
+	dirOnly bool
+}
+
+// ParseIgnoreRules parses the content of an ignore file, with one pattern per
+// line. Blank lines and lines starting with # are skipped.
+func ParseIgnoreRules(content string) IgnoreRules {
+	return NewIgnoreRules(strings.Split(content, "\n"))
+}
+
+// NewIgnoreRules creates an IgnoreRules from the given patterns.
+func NewIgnoreRules(patterns []string) IgnoreRules {
+	rules := IgnoreRules{}
+	for _, line := range patterns {
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		pattern := ignorePattern{}
+		if strings.HasPrefix(line, "!") {
+			pattern.negated = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			pattern.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+
+		if strings.Contains(line, "/") {
+			line = strings.TrimPrefix(line, "/")
+		} else {
+			line = "**/" + line
+		}
+		pattern.glob = line
+		rules.patterns = append(rules.patterns, pattern)
+	}
+	return rules
+}
+
+// Append returns the rules followed by the patterns of other, which take
+// precedence.
+func (r IgnoreRules) Append(other IgnoreRules) IgnoreRules {
+	patterns := make([]ignorePattern, 0, len(r.patterns)+len(other.patterns))
+	patterns = append(patterns, r.patterns...)
+	patterns = append(patterns, other.patterns...)
+	return IgnoreRules{patterns: patterns}
+}
+
+// IsEmpty returns whether the rules don't have any pattern.
+func (r IgnoreRules) IsEmpty() bool {
+	return len(r.patterns) == 0
+}
+
+// Match returns whether the given path, relative to the root directory, is
+// ignored. Like with git, a path can't be re-included when one of its parent
+// directories is ignored.
+func (r IgnoreRules) Match(path string, isDir bool) bool {
+	if r.IsEmpty() {
+		return false
+	}
+
+	path = filepath.ToSlash(path)
+	parts := strings.Split(path, "/")
+	for i := 1; i < len(parts); i++ {
+		if r.matchPath(strings.Join(parts[:i], "/"), true) {
+			return true
+		}
+	}
+	return r.matchPath(path, isDir)
+}
+
+func (r IgnoreRules) matchPath(path string, isDir bool) bool {
+	ignored := false
+	for _, pattern := range r.patterns {
+		if pattern.dirOnly && !isDir {
+			continue
+		}
+		if matches, _ := doublestar.Match(pattern.glob, path); matches {
+			ignored = !pattern.negated
+		}
+	}
+	return ignored
+}
@@ -0,0 +1,63 @@
+package paths
+
+import (
+	"testing"
+
+	"github.com/zk-org/zk/internal/util/test/assert"
+)
+
+func TestIgnoreRulesMatch(t *testing.T) {
+	rules := ParseIgnoreRules(`
+# Comment
+attachments/
+*.pdf
+!keep.pdf
+/draft.md
+journal/**/*.tmp
+\#hash.md
+`)
+
+	test := func(path string, isDir bool, expected bool) {
+		t.Helper()
+		assert.Equal(t, rules.Match(path, isDir), expected)
+	}
+
+	// Directories are ignored at any depth, with their content.
+	test("attachments", true, true)
+	test("dir/attachments", true, true)
+	test("attachments/note.md", false, true)
+	test("dir/attachments/sub/note.md", false, true)
+	// Patterns with a trailing slash only match directories.
+	test("attachments", false, false)
+
+	// Patterns without slash match the filename.
+	test("doc.pdf", false, true)
+	test("dir/doc.pdf", false, true)
+	test("dir/doc.md", false, false)
+
+	// Negated patterns re-include the files.
+	test("keep.pdf", false, false)
+	test("dir/keep.pdf", false, false)
+	// ...except when their parent directory is ignored.
+	test("attachments/keep.pdf", false, true)
+
+	// Patterns with a slash are relative to the root.
+	test("draft.md", false, true)
+	test("dir/draft.md", false, false)
+	test("journal/2021/01/note.tmp", false, true)
+	test("journal/note.tmp", false, true)
+	test("other/note.tmp", false, false)
+
+	// Comments and escaped characters.
+	test("# Comment", false, false)
+	test("#hash.md", false, true)
+}
+
+func TestIgnoreRulesAppend(t *testing.T) {
+	rules := NewIgnoreRules([]string{"*.pdf"}).Append(NewIgnoreRules([]string{"!keep.pdf"}))
+	assert.True(t, rules.Match("doc.pdf", false))
+	assert.False(t, rules.Match("keep.pdf", false))
+
+	assert.True(t, IgnoreRules{}.IsEmpty())
+	assert.False(t, IgnoreRules{}.Match("doc.pdf", false))
+}
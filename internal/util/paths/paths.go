@@ -14,6 +14,10 @@ import (
 type Metadata struct {
 	Path     string
 	Modified time.Time
+	// Size of the file, in bytes.
+	Size int64
+	// Checksum of the file content, only known for indexed files.
+	Checksum string
 }
 
 // Exists returns whether the given path exists on the file system.
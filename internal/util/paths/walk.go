@@ -8,55 +8,155 @@ import (
 	"github.com/zk-org/zk/internal/util"
 )
 
+// WalkOpts holds the options used to walk a directory.
+type WalkOpts struct {
+	// Name of the notebook root directory, which is walked even when hidden.
+	NotebookRoot string
+	// Files and directories matching these rules are skipped, without being
+	// given to ShouldIgnorePath.
+	Ignore IgnoreRules
+	// Returns whether the file at the given relative path should be ignored.
+	ShouldIgnorePath func(path string) (bool, error)
+	// When true, the symbolic links to directories are walked as regular
+	// directories, unless they target a directory which is already walked.
+	// Symbolic links to files are always emitted.
+	FollowSymlinks bool
+}
+
 // Walk emits the metadata of each file stored in the directory if they pass
-// the given shouldIgnorePath closure. Hidden files and directories are ignored.
-func Walk(basePath string, logger util.Logger, notebookRoot string, shouldIgnorePath func(string) (bool, error)) <-chan Metadata {
+// the given options. Hidden files and directories are ignored.
+//
+// The files reached through a symbolic link are emitted with the path of the
+// link, relative to basePath, but with the metadata of their target. Broken
+// symbolic links are skipped with a warning.
+func Walk(basePath string, logger util.Logger, opts WalkOpts) <-chan Metadata {
 	c := make(chan Metadata, 50)
 	go func() {
 		defer close(c)
 
-		err := filepath.Walk(basePath, func(abs string, info os.FileInfo, err error) error {
-			if err != nil {
-				return err
-			}
+		w := walker{
+			logger: logger,
+			opts:   opts,
+			c:      c,
+		}
+		if realPath, err := filepath.EvalSymlinks(basePath); err == nil {
+			w.walkedDirs = append(w.walkedDirs, realPath)
+		}
 
-			filename := info.Name()
-			isHidden := strings.HasPrefix(filename, ".")
-			isNotebookRoot := filename == notebookRoot
+		err := w.walk(basePath, ".")
+		if err != nil {
+			logger.Println(err)
+		}
+	}()
 
-			if info.IsDir() {
-				if isHidden && !isNotebookRoot {
-					return filepath.SkipDir
-				}
+	return c
+}
 
-			} else {
-				path, err := filepath.Rel(basePath, abs)
-				if err != nil {
-					logger.Println(err)
-					return nil
-				}
-				shouldIgnore, err := shouldIgnorePath(path)
-				if err != nil {
-					logger.Println(err)
-					return nil
-				}
-				if isHidden || shouldIgnore {
+type walker struct {
+	logger util.Logger
+	opts   WalkOpts
+	c      chan<- Metadata
+	// Real paths of the root directory and of the symlinked directories
+	// already walked, to prevent cycles and duplicated notes.
+	walkedDirs []string
+}
+
+// walk emits the files of the directory at dirPath, as if it was located at
+// the relative path logicalDir.
+func (w *walker) walk(dirPath string, logicalDir string) error {
+	return filepath.Walk(dirPath, func(abs string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(dirPath, abs)
+		if err != nil {
+			w.logger.Println(err)
+			return nil
+		}
+		path := filepath.Join(logicalDir, rel)
+		if path == "." {
+			// The root directory is walked even when hidden.
+			return nil
+		}
+
+		filename := filepath.Base(path)
+		isHidden := strings.HasPrefix(filename, ".")
+		isNotebookRoot := filename == w.opts.NotebookRoot
+
+		if info.Mode()&os.ModeSymlink != 0 {
+			target, err := os.Stat(abs)
+			if err != nil {
+				w.logger.Printf("warning: skipping broken symbolic link %s: %v", path, err)
+				return nil
+			}
+			if target.IsDir() {
+				if !w.opts.FollowSymlinks || isHidden || w.opts.Ignore.matchPath(filepath.ToSlash(path), true) {
 					return nil
 				}
+				return w.walkSymlinkedDir(abs, path)
+			}
+			info = target
+		}
 
-				c <- Metadata{
-					Path:     path,
-					Modified: info.ModTime().UTC(),
-				}
+		if info.IsDir() {
+			if rel == "." {
+				// The symbolic link to this directory was already matched.
+				return nil
+			}
+			if isHidden && !isNotebookRoot {
+				return filepath.SkipDir
+			}
+			// The parent directories were already matched, so there's no
+			// need to use IgnoreRules.Match.
+			if w.opts.Ignore.matchPath(filepath.ToSlash(path), true) {
+				return filepath.SkipDir
 			}
 
-			return nil
-		})
+		} else {
+			if w.opts.Ignore.matchPath(filepath.ToSlash(path), false) {
+				return nil
+			}
+			shouldIgnore, err := w.opts.ShouldIgnorePath(path)
+			if err != nil {
+				w.logger.Println(err)
+				return nil
+			}
+			if isHidden || shouldIgnore {
+				return nil
+			}
 
-		if err != nil {
-			logger.Println(err)
+			w.c <- Metadata{
+				Path:     path,
+				Modified: info.ModTime().UTC(),
+				Size:     info.Size(),
+			}
 		}
-	}()
 
-	return c
+		return nil
+	})
+}
+
+// walkSymlinkedDir walks the target of a symbolic link to a directory, unless
+// it overlaps with a directory which is already walked.
+func (w *walker) walkSymlinkedDir(abs string, path string) error {
+	realPath, err := filepath.EvalSymlinks(abs)
+	if err != nil {
+		w.logger.Printf("warning: skipping broken symbolic link %s: %v", path, err)
+		return nil
+	}
+	for _, dir := range w.walkedDirs {
+		if isSameOrDescendant(realPath, dir) || isSameOrDescendant(dir, realPath) {
+			w.logger.Printf("warning: skipping symbolic link %s: %s is already walked", path, realPath)
+			return nil
+		}
+	}
+
+	w.walkedDirs = append(w.walkedDirs, realPath)
+	return w.walk(realPath, path)
+}
+
+// isSameOrDescendant returns whether path is dir or one of its descendants.
+func isSameOrDescendant(path string, dir string) bool {
+	return path == dir || strings.HasPrefix(path, strings.TrimSuffix(dir, string(filepath.Separator))+string(filepath.Separator))
 }
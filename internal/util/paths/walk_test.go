@@ -1,7 +1,10 @@
 package paths
 
 import (
+	"fmt"
+	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/zk-org/zk/internal/util"
@@ -18,8 +21,11 @@ func TestWalk(t *testing.T) {
 
 	notebookRoot := filepath.Base(path)
 	actual := make([]string, 0)
-	for m := range Walk(path, &util.NullLogger, notebookRoot, shouldIgnore) {
+	for m := range Walk(path, &util.NullLogger, WalkOpts{NotebookRoot: notebookRoot, ShouldIgnorePath: shouldIgnore}) {
 		assert.NotNil(t, m.Modified)
+		info, err := os.Stat(filepath.Join(path, m.Path))
+		assert.Nil(t, err)
+		assert.Equal(t, m.Size, info.Size())
 		actual = append(actual, m.Path)
 	}
 
@@ -47,7 +53,7 @@ func TestWalkHidden(t *testing.T) {
 
 	notebookRoot := filepath.Base(path)
 	actual := make([]string, 0)
-	for m := range Walk(path, &util.NullLogger, notebookRoot, shouldIgnore) {
+	for m := range Walk(path, &util.NullLogger, WalkOpts{NotebookRoot: notebookRoot, ShouldIgnorePath: shouldIgnore}) {
 		assert.NotNil(t, m.Modified)
 		actual = append(actual, m.Path)
 	}
@@ -63,3 +69,138 @@ func TestWalkHidden(t *testing.T) {
 		"dir2/a.md",
 	})
 }
+
+func TestWalkIgnoreRules(t *testing.T) {
+	dir := t.TempDir()
+	for _, path := range []string{
+		"a.md",
+		"doc.pdf",
+		"keep.pdf",
+		"attachments/b.md",
+		"attachments/keep.pdf",
+		"dir/c.md",
+		"dir/.obsidian/d.md",
+		"dir/drafts/e.md",
+		"dir/drafts/keep.md",
+	} {
+		path = filepath.Join(dir, path)
+		assert.Nil(t, os.MkdirAll(filepath.Dir(path), os.ModePerm))
+		assert.Nil(t, os.WriteFile(path, []byte{}, 0644))
+	}
+
+	ignored := []string{}
+	shouldIgnore := func(path string) (bool, error) {
+		ignored = append(ignored, path)
+		return false, nil
+	}
+
+	actual := make([]string, 0)
+	opts := WalkOpts{
+		NotebookRoot:     filepath.Base(dir),
+		Ignore:           NewIgnoreRules([]string{"attachments/", "*.pdf", "!keep.pdf", "dir/drafts/*", "!dir/drafts/keep.md"}),
+		ShouldIgnorePath: shouldIgnore,
+	}
+	for m := range Walk(dir, &util.NullLogger, opts) {
+		actual = append(actual, m.Path)
+	}
+
+	assert.Equal(t, actual, []string{
+		"a.md",
+		"dir/c.md",
+		"dir/drafts/keep.md",
+		"keep.pdf",
+	})
+	// The ignored files are not given to the closure.
+	assert.Equal(t, ignored, actual)
+}
+
+func TestWalkSymlinks(t *testing.T) {
+	root, err := filepath.EvalSymlinks(t.TempDir())
+	assert.Nil(t, err)
+	dir := filepath.Join(root, "notebook")
+	external := filepath.Join(root, "external")
+	for _, path := range []string{
+		"notebook/a.md",
+		"notebook/dir/b.md",
+		"external/c.md",
+		"external/sub/d.md",
+	} {
+		path = filepath.Join(root, path)
+		assert.Nil(t, os.MkdirAll(filepath.Dir(path), os.ModePerm))
+		assert.Nil(t, os.WriteFile(path, []byte("Content of "+filepath.Base(path)), 0644))
+	}
+	symlink := func(target string, link string) {
+		assert.Nil(t, os.Symlink(target, filepath.Join(dir, link)))
+	}
+	symlink(external, "linked")
+	symlink(filepath.Join(external, "c.md"), "linked-file.md")
+	symlink(filepath.Join(dir, "missing.md"), "broken.md")
+	symlink("..", "dir/loop")
+	symlink("dir", "duplicate")
+	// The external directory links back to the notebook.
+	assert.Nil(t, os.Symlink(dir, filepath.Join(external, "notebook")))
+
+	test := func(followSymlinks bool, expected []string, expectedWarnings []string) {
+		logger := &loggerMock{}
+		opts := WalkOpts{
+			NotebookRoot: filepath.Base(dir),
+			ShouldIgnorePath: func(path string) (bool, error) {
+				return filepath.Ext(path) != ".md", nil
+			},
+			FollowSymlinks: followSymlinks,
+		}
+
+		actual := make([]string, 0)
+		for m := range Walk(dir, logger, opts) {
+			actual = append(actual, m.Path)
+			// The metadata are the ones of the symlink target.
+			info, err := os.Stat(filepath.Join(dir, m.Path))
+			assert.Nil(t, err)
+			assert.Equal(t, m.Size, info.Size())
+		}
+		assert.Equal(t, actual, expected)
+
+		warnings := []string{}
+		for _, warning := range logger.warnings {
+			warnings = append(warnings, strings.ReplaceAll(warning, root, "<root>"))
+		}
+		assert.Equal(t, warnings, expectedWarnings)
+	}
+
+	test(false, []string{
+		"a.md",
+		"dir/b.md",
+		"linked-file.md",
+	}, []string{
+		"warning: skipping broken symbolic link broken.md: stat <root>/notebook/broken.md: no such file or directory",
+	})
+
+	test(true, []string{
+		"a.md",
+		"dir/b.md",
+		"linked/c.md",
+		"linked/sub/d.md",
+		"linked-file.md",
+	}, []string{
+		"warning: skipping broken symbolic link broken.md: stat <root>/notebook/broken.md: no such file or directory",
+		"warning: skipping symbolic link dir/loop: <root>/notebook is already walked",
+		"warning: skipping symbolic link duplicate: <root>/notebook/dir is already walked",
+		"warning: skipping symbolic link linked/notebook: <root>/notebook is already walked",
+	})
+}
+
+type loggerMock struct {
+	warnings []string
+}
+
+func (l *loggerMock) Printf(format string, v ...interface{}) {
+	l.warnings = append(l.warnings, fmt.Sprintf(format, v...))
+}
+
+func (l *loggerMock) Println(v ...interface{}) {
+	l.warnings = append(l.warnings, fmt.Sprint(v...))
+}
+
+func (l *loggerMock) Err(err error) {
+	l.warnings = append(l.warnings, err.Error())
+}
@@ -11,26 +11,24 @@ import (
 // NewIDGenerator returns a function generating string IDs using the given options.
 // Inspired by https://www.calhoun.io/creating-random-strings-in-go/
 func NewIDGenerator(options core.IDOptions) func() string {
+	return NewIDGeneratorWithSource(options, rand.NewSource(time.Now().UnixNano()))
+}
+
+// NewIDGeneratorWithSource returns a function generating string IDs using the
+// given options, drawing from the given source of random numbers.
+//
+// The generated IDs are deterministic for a given source seed.
+func NewIDGeneratorWithSource(options core.IDOptions, source rand.Source) func() string {
 	if options.Length < 1 {
 		panic("IDOptions.Length must be at least 1")
 	}
 
-	var charset []rune
-	for _, char := range options.Charset {
-		switch options.Case {
-		case core.CaseLower:
-			charset = append(charset, unicode.ToLower(char))
-		case core.CaseUpper:
-			charset = append(charset, unicode.ToUpper(char))
-		case core.CaseMixed:
-			charset = append(charset, unicode.ToLower(char))
-			charset = append(charset, unicode.ToUpper(char))
-		default:
-			panic("unknown zk.Case value")
-		}
+	charset := charsetWithCase(options.Charset, options.Case)
+	if len(charset) == 0 {
+		panic("IDOptions.Charset must not be empty")
 	}
 
-	rand := rand.New(rand.NewSource(time.Now().UnixNano()))
+	rand := rand.New(source)
 
 	return func() string {
 		buf := make([]rune, options.Length)
@@ -41,3 +39,32 @@ func NewIDGenerator(options core.IDOptions) func() string {
 		return string(buf)
 	}
 }
+
+// charsetWithCase returns the characters of the charset converted to the
+// given letter case, without duplicates.
+func charsetWithCase(charset core.Charset, letterCase core.Case) []rune {
+	var res []rune
+	seen := map[rune]bool{}
+	add := func(char rune) {
+		if !seen[char] {
+			seen[char] = true
+			res = append(res, char)
+		}
+	}
+
+	for _, char := range charset {
+		switch letterCase {
+		case core.CaseLower:
+			add(unicode.ToLower(char))
+		case core.CaseUpper:
+			add(unicode.ToUpper(char))
+		case core.CaseMixed:
+			add(unicode.ToLower(char))
+			add(unicode.ToUpper(char))
+		default:
+			panic("unknown zk.Case value")
+		}
+	}
+
+	return res
+}
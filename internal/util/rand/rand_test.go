@@ -0,0 +1,58 @@
+package rand
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/zk-org/zk/internal/core"
+	"github.com/zk-org/zk/internal/util/test/assert"
+)
+
+func TestIDGeneratorCharsets(t *testing.T) {
+	test := func(charset core.Charset, idCase core.Case, expectedChars string) {
+		gen := NewIDGeneratorWithSource(core.IDOptions{
+			Length:  8,
+			Charset: charset,
+			Case:    idCase,
+		}, rand.NewSource(42))
+
+		for i := 0; i < 100; i++ {
+			id := gen()
+			assert.Equal(t, len([]rune(id)), 8)
+			for _, char := range id {
+				if !strings.ContainsRune(expectedChars, char) {
+					t.Fatalf("%q: unexpected character %q in ID %q", expectedChars, char, id)
+				}
+			}
+		}
+	}
+
+	test(core.CharsetAlphanum, core.CaseLower, "0123456789abcdefghijklmnopqrstuvwxyz")
+	test(core.CharsetAlphanum, core.CaseUpper, "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ")
+	test(core.CharsetHex, core.CaseLower, "0123456789abcdef")
+	test(core.CharsetHex, core.CaseUpper, "0123456789ABCDEF")
+	test(core.CharsetLetters, core.CaseLower, "abcdefghijklmnopqrstuvwxyz")
+	test(core.CharsetLetters, core.CaseMixed, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ")
+	test(core.CharsetNumbers, core.CaseMixed, "0123456789")
+	test(core.Charset("xyz-é"), core.CaseLower, "xyz-é")
+	test(core.Charset("xyz-é"), core.CaseUpper, "XYZ-É")
+}
+
+func TestIDGeneratorIsDeterministicWithSource(t *testing.T) {
+	opts := core.IDOptions{
+		Length:  4,
+		Charset: core.CharsetAlphanum,
+		Case:    core.CaseLower,
+	}
+	gen1 := NewIDGeneratorWithSource(opts, rand.NewSource(1))
+	gen2 := NewIDGeneratorWithSource(opts, rand.NewSource(1))
+
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, gen1(), gen2())
+	}
+}
+
+func TestIDGeneratorDeduplicatesMixedCaseCharset(t *testing.T) {
+	assert.Equal(t, charsetWithCase(core.CharsetHex, core.CaseMixed), []rune("0123456789aAbBcCdDeEfF"))
+}
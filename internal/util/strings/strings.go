@@ -7,6 +7,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"unicode"
 )
 
 // Prepend prefixes each lines of a string with the given prefix.
@@ -66,6 +67,16 @@ func JoinInt64(ints []int64, delimiter string) string {
 	return strings.Join(strs, delimiter)
 }
 
+// opaqueURLSchemes are the URL schemes which don't have a host, e.g.
+// mailto:hi@example.com.
+var opaqueURLSchemes = map[string]bool{
+	"mailto": true,
+	"tel":    true,
+	"urn":    true,
+	"data":   true,
+	"magnet": true,
+}
+
 // IsURL returns whether the given string is a valid URL.
 func IsURL(s string) bool {
 	_, err := url.ParseRequestURI(s)
@@ -74,9 +85,12 @@ func IsURL(s string) bool {
 	}
 
 	u, err := url.Parse(s)
-	if err != nil || u.Scheme == "" || u.Host == "" {
+	if err != nil || u.Scheme == "" {
 		return false
 	}
+	if u.Host == "" {
+		return (u.Opaque != "" || u.RawQuery != "") && opaqueURLSchemes[strings.ToLower(u.Scheme)]
+	}
 
 	return true
 }
@@ -150,6 +164,36 @@ func WordAt(str string, index int) string {
 
 var wordRegex = regexp.MustCompile(`[^ \t\n\f\r,;\[\]\"\']+`)
 
+// CountWords returns the number of words in the given text.
+//
+// Words are separated by spaces, except for the Chinese and Japanese scripts
+// which don't use them: each of their characters counts as a word.
+func CountWords(text string) int {
+	count := 0
+	for _, field := range strings.Fields(text) {
+		inWord := false
+		for _, r := range field {
+			switch {
+			case isIdeographic(r):
+				count++
+				inWord = false
+			case unicode.IsLetter(r) || unicode.IsNumber(r):
+				if !inWord {
+					count++
+					inWord = true
+				}
+			}
+		}
+	}
+	return count
+}
+
+// isIdeographic returns whether the rune belongs to a script written without
+// spaces between the words.
+func isIdeographic(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana)
+}
+
 func CopyList(list []string) []string {
 	out := make([]string, len(list))
 	copy(out, list)
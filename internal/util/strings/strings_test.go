@@ -79,6 +79,12 @@ func TestIsURL(t *testing.T) {
 	test("https://example.com/dir", true)
 	test("http://example.com/dir", true)
 	test("ftp://example.com/", true)
+	test("mailto:hi@example.com", true)
+	test("tel:+33123456789", true)
+	test("magnet:?xt=urn:btih:c12fe1c06bba254a9dc9f519b335aa7c1367a88a", true)
+	test("mailto:", false)
+	test("note:with-colon", false)
+	test("C:/dir/note.md", false)
 }
 
 func TestRemoveDuplicates(t *testing.T) {
@@ -152,6 +158,21 @@ func TestWordAt(t *testing.T) {
 	test("one @:~two three", 5, "@:~two")
 }
 
+func TestCountWords(t *testing.T) {
+	test := func(s string, expected int) {
+		assert.Equal(t, CountWords(s), expected)
+	}
+
+	test("", 0)
+	test("  \n\t ", 0)
+	test("one two  three", 3)
+	test("one\ntwo", 2)
+	test("can't stop", 2)
+	test("-- * 42 !", 1)
+	test("日本語", 3)
+	test("zk 日本", 3)
+}
+
 func TestByteIndexToRuneIndex(t *testing.T) {
 	test := func(s string, index int, expected int) {
 		assert.Equal(t, ByteIndexToRuneIndex(s, index), expected)
@@ -10,8 +10,10 @@ import (
 	"regexp"
 	"runtime"
 	"strings"
+	"time"
 
 	"github.com/alecthomas/kong"
+	"github.com/zk-org/zk/internal/adapter/term"
 	"github.com/zk-org/zk/internal/cli"
 	"github.com/zk-org/zk/internal/cli/cmd"
 	"github.com/zk-org/zk/internal/core"
@@ -22,25 +24,33 @@ var Version = "dev"
 var Build = "dev"
 
 var root struct {
-	Init  cmd.Init  `cmd group:"zk" help:"Create a new notebook in the given directory."`
-	Index cmd.Index `cmd group:"zk" help:"Index the notes to be searchable."`
-
-	New   cmd.New   `cmd group:"notes" help:"Create a new note in the given notebook directory."`
-	List  cmd.List  `cmd group:"notes" help:"List notes matching the given criteria."`
-	Graph cmd.Graph `cmd group:"notes" help:"Produce a graph of the notes matching the given criteria."`
-	Edit  cmd.Edit  `cmd group:"notes" help:"Edit notes matching the given criteria."`
-	Tag   cmd.Tag   `cmd group:"notes" help:"Manage the note tags."`
+	Init       cmd.Init       `cmd group:"zk" help:"Create a new notebook in the given directory."`
+	Index      cmd.Index      `cmd group:"zk" help:"Index the notes to be searchable."`
+	Completion cmd.Completion `cmd group:"zk" help:"Print a completion script for the given shell."`
+
+	New    cmd.New    `cmd group:"notes" help:"Create a new note in the given notebook directory."`
+	List   cmd.List   `cmd group:"notes" help:"List notes matching the given criteria."`
+	Graph  cmd.Graph  `cmd group:"notes" help:"Produce a graph of the notes matching the given criteria."`
+	Edit   cmd.Edit   `cmd group:"notes" help:"Edit notes matching the given criteria."`
+	Tag    cmd.Tag    `cmd group:"notes" help:"Manage the note tags."`
+	Stats  cmd.Stats  `cmd group:"notes" help:"Print statistics about the notes matching the given criteria."`
+	Export cmd.Export `cmd group:"notes" help:"Export the notes matching the given criteria to HTML files."`
 
 	NotebookDir string  `type:path placeholder:PATH help:"Turn off notebook auto-discovery and set manually the notebook where commands are run."`
 	WorkingDir  string  `short:W type:path placeholder:PATH help:"Run as if zk was started in <PATH> instead of the current working directory."`
 	NoInput     NoInput `help:"Never prompt or ask for confirmation."`
+	Color       Color   `default:auto placeholder:WHEN enum:"auto,always,never" help:"Colorize the output: auto, always or never. auto disables the colors when the output is not a terminal or NO_COLOR is set."`
 	// ForceInput is a debugging flag overriding the default value of interaction prompts.
 	ForceInput string `hidden xor:"input"`
 	Debug      bool   `default:"0" hidden help:"Print a debug stacktrace on SIGINT."`
 	DebugStyle bool   `default:"0" hidden help:"Force styling output as XML tags."`
+	// NoIndex is used by nested zk commands, e.g. the fzf preview, to read
+	// the notes already indexed by the parent command.
+	NoIndex bool `hidden help:"Do not index the notebook before running the command."`
 
 	ShowHelp ShowHelp         `cmd hidden default:"1"`
 	LSP      cmd.LSP          `cmd hidden`
+	Complete cmd.Complete     `cmd hidden`
 	Version  kong.VersionFlag `hidden help:"Print zk version."`
 }
 
@@ -52,6 +62,14 @@ func (f NoInput) BeforeApply(container *cli.Container) error {
 	return nil
 }
 
+// Color is a flag controlling when the output is colorized.
+type Color string
+
+func (f Color) AfterApply(container *cli.Container) error {
+	container.Terminal.SetColorMode(term.ColorMode(f))
+	return nil
+}
+
 // ShowHelp is the default command run. It's equivalent to `zk --help`.
 type ShowHelp struct{}
 
@@ -82,12 +100,13 @@ func main() {
 	err = container.SetCurrentNotebook(searchDirs)
 	fatalIfError(err)
 
+	parser, err := kong.New(&root, options(container)...)
+	fatalIfError(err)
+
 	// Run the alias or command.
-	if isAlias, err := runAlias(container, args); isAlias {
+	if isAlias, err := runAlias(container, builtinCommands(parser.Model), args); isAlias {
 		fatalIfError(err)
 	} else {
-		parser, err := kong.New(&root, options(container)...)
-		fatalIfError(err)
 		ctx, err := parser.Parse(args)
 		fatalIfError(err)
 
@@ -102,15 +121,25 @@ func main() {
 
 		// Index the current notebook except if the user is running the `index`
 		// command, otherwise it would hide the stats.
-		if ctx.Command() != "index" {
+		if ctx.Command() != "index" && !root.NoIndex {
 			if notebook, err := container.CurrentNotebook(); err == nil {
-				index := cmd.Index{Quiet: true}
+				// Waits for a concurrent indexing, e.g. from the LSP server.
+				index := cmd.Index{Quiet: true, Wait: 10 * time.Second}
 				err = index.RunWithNotebook(container, notebook)
-				ctx.FatalIfErrorf(err)
+				// The notes which could not be indexed are already reported,
+				// and should not prevent running the command.
+				var indexingErr core.NoteIndexingError
+				if !errors.As(err, &indexingErr) {
+					ctx.FatalIfErrorf(err)
+				}
 			}
 		}
 
 		err = ctx.Run(container)
+		var exitErr cli.ExitCodeError
+		if errors.As(err, &exitErr) {
+			os.Exit(int(exitErr))
+		}
 		ctx.FatalIfErrorf(err)
 	}
 }
@@ -162,7 +191,10 @@ func setupDebugMode() {
 }
 
 // runAlias will execute a user alias if the command is one of them.
-func runAlias(container *cli.Container, args []string) (bool, error) {
+//
+// The aliases shadowing one of the built-in commands are ignored, unless
+// tool.alias-override is enabled.
+func runAlias(container *cli.Container, builtins map[string]bool, args []string) (bool, error) {
 	if len(args) < 1 {
 		return false, nil
 	}
@@ -173,6 +205,12 @@ func runAlias(container *cli.Container, args []string) (bool, error) {
 			continue
 		}
 
+		// Built-in commands can only be shadowed when explicitly allowed.
+		if !container.Config.Tool.AliasOverride && builtins[alias] {
+			fmt.Fprintf(os.Stderr, "zk: warning: the alias `%s` is ignored because it shadows a built-in command, set tool.alias-override to allow it\n", alias)
+			return false, nil
+		}
+
 		// Prevent infinite loop if an alias calls itself.
 		os.Setenv("ZK_RUNNING_ALIAS", alias)
 
@@ -200,6 +238,19 @@ func runAlias(container *cli.Container, args []string) (bool, error) {
 	return false, nil
 }
 
+// builtinCommands returns the names and aliases of the zk commands in the
+// given parser model.
+func builtinCommands(model *kong.Application) map[string]bool {
+	names := map[string]bool{}
+	for _, node := range model.Children {
+		names[node.Name] = true
+		for _, alias := range node.Aliases {
+			names[alias] = true
+		}
+	}
+	return names
+}
+
 // notebookSearchDirs returns the places where zk will look for a notebook.
 // The first successful candidate will be used as the working directory from
 // which path arguments are relative from.